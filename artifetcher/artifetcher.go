@@ -2,28 +2,65 @@
 package artifetcher
 
 import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/compozed/deployadactyl/clock"
 	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
 	"github.com/op/go-logging"
 	"github.com/spf13/afero"
 )
 
 // Artifetcher fetches artifacts within a file system with an Extractor.
+// MinTLSVersion is the minimum TLS version accepted when downloading an artifact.
+// Retries is the number of times a download is retried after a network error or a 5xx response,
+// waiting RetryDelay before the first retry and doubling the wait before each one after that.
+// S3Region is the region of the buckets of s3:// URLs and S3Credentials returns the credentials S3 requests are signed with.
+// The credentials are read from the environment when S3Credentials is nil.
+// S3Endpoint replaces the AWS endpoint of s3:// URLs, for S3 compatible stores.
+// ProgressInterval is the least time between two download progress lines, which defaults to DefaultProgressInterval.
+// Cache keeps the artifacts downloaded with a checksum so that they are not downloaded again. Nothing is cached when it is nil.
+// Clock is used to wait between retries and to time the progress, the S3 signatures and the fetches. The time package is used when it is nil.
+// EventManager is told about every fetched artifact with an artifact.fetched event. No event is emitted when it is nil.
 type Artifetcher struct {
-	FileSystem *afero.Afero
-	Extractor  I.Extractor
-	Log        *logging.Logger
+	FileSystem    *afero.Afero
+	Extractor     I.Extractor
+	Log           *logging.Logger
+	MinTLSVersion uint16
+	Retries       int
+	RetryDelay    time.Duration
+	S3Region      string
+	S3Credentials func() (S3Credentials, error)
+	S3Endpoint    string
+
+	ProgressInterval time.Duration
+	Cache            *Cache
+	Clock            I.Clock
+	EventManager     I.EventManager
 }
 
-// Fetch downloads an artifact located at URL.
+// Fetch downloads the artifact located at the ArtifactURL of the deployment, sending the ArtifactToken as a bearer token when it is not empty.
+// An s3:// URL, or an https S3 virtual-host URL when there are S3 credentials, is downloaded with a signed S3 request instead.
+// If an ArtifactSHA256 checksum is given the downloaded artifact must match it, and it is taken from the Cache instead when it was downloaded before.
+// An artifact fetched with a token is neither cached nor taken from the Cache, so the token is checked by the server every time.
+// The progress of the download is written to out, unless it is nil.
+// The size of the artifact and how long it took to fetch are emitted in an artifact.fetched event with the deployment.
 // It then passes it to the extractor with the manifest for unzipping.
 //
 // Returns a string to the unzipped artifacts path and an error.
-func (a *Artifetcher) Fetch(url, manifest string) (string, error) {
+func (a *Artifetcher) Fetch(deploymentInfo S.DeploymentInfo, manifest string, out io.Writer) (string, error) {
+	url, token, checksum := deploymentInfo.ArtifactURL, deploymentInfo.ArtifactToken, deploymentInfo.ArtifactSHA256
+
 	a.Log.Info("fetching artifact")
 	a.Log.Debug("artifact URL: %s", url)
 
@@ -34,38 +71,26 @@ func (a *Artifetcher) Fetch(url, manifest string) (string, error) {
 	defer artifactFile.Close()
 	defer a.FileSystem.Remove(artifactFile.Name())
 
-	var client = &http.Client{
-		Timeout: 4 * time.Minute,
-		Transport: &http.Transport{
-			Dial: (&net.Dialer{
-				Timeout:   60 * time.Second,
-				KeepAlive: 60 * time.Second,
-			}).Dial,
-			TLSHandshakeTimeout:   15 * time.Second,
-			ResponseHeaderTimeout: 15 * time.Second,
-			ExpectContinueTimeout: 2 * time.Second,
-		},
-	}
+	startTime := a.clock().Now()
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", ArtifactoryRequestError{err}
-	}
+	cacheable := a.Cache != nil && checksum != "" && token == ""
 
-	response, err := client.Do(req)
-	if err != nil {
-		return "", GetUrlError{url, err}
-	}
-	defer response.Body.Close()
+	cached := cacheable && a.fetchCached(url, checksum, artifactFile, out)
+	if !cached {
+		err = a.download(url, token, checksum, artifactFile, out)
+		if err != nil {
+			return "", err
+		}
 
-	if response.StatusCode != http.StatusOK {
-		return "", GetStatusError{url, response.Status}
+		if cacheable {
+			err = a.Cache.Add(url, checksum, artifactFile.Name())
+			if err != nil {
+				a.Log.Errorf("artifact was not cached: %s", err)
+			}
+		}
 	}
 
-	_, err = io.Copy(artifactFile, response.Body)
-	if err != nil {
-		return "", WriteResponseError{err}
-	}
+	a.emitFetched(deploymentInfo, artifactFile, cached, a.clock().Now().Sub(startTime))
 
 	unzippedPath, err := a.FileSystem.TempDir("", "deployadactyl-unzipped-")
 	if err != nil {
@@ -83,10 +108,122 @@ func (a *Artifetcher) Fetch(url, manifest string) (string, error) {
 	return unzippedPath, nil
 }
 
-// FetchZipFromRequest fetches files from a compressed zip file in the request body.
+// fetchCached copies the artifact cached for the url and checksum to artifactFile and says so in out, unless it is nil.
+// A cached artifact that does not match the checksum is removed from the cache.
+//
+// Returns false when artifactFile must be downloaded.
+func (a *Artifetcher) fetchCached(url, checksum string, artifactFile afero.File, out io.Writer) bool {
+	hash := sha256.New()
+	found, err := a.Cache.Get(url, checksum, io.MultiWriter(artifactFile, hash))
+	if err == nil && found {
+		actual := hex.EncodeToString(hash.Sum(nil))
+		if strings.EqualFold(actual, checksum) {
+			a.Log.Info("using cached artifact")
+			if out != nil {
+				fmt.Fprintln(out, "using cached artifact")
+			}
+			return true
+		}
+
+		a.Log.Errorf("removing cached artifact: %s", ChecksumMismatchError{checksum, actual})
+		a.Cache.Remove(url, checksum)
+	} else if err != nil {
+		a.Log.Errorf("cannot use cached artifact: %s", err)
+	}
+
+	artifactFile.Truncate(0)
+	artifactFile.Seek(0, os.SEEK_SET)
+	return false
+}
+
+// emitFetched emits an artifact.fetched event with the size of artifactFile.
+// An event that cannot be emitted is logged without failing the fetch.
+func (a *Artifetcher) emitFetched(deploymentInfo S.DeploymentInfo, artifactFile afero.File, cached bool, duration time.Duration) {
+	if a.EventManager == nil {
+		return
+	}
+
+	info, err := artifactFile.Stat()
+	if err != nil {
+		a.Log.Errorf("cannot emit an artifact.fetched event: %s", err)
+		return
+	}
+
+	err = a.EventManager.Emit(S.Event{Type: "artifact.fetched", Data: S.ArtifactFetchedEventData{
+		URL:            deploymentInfo.ArtifactURL,
+		Bytes:          info.Size(),
+		Duration:       duration,
+		Cached:         cached,
+		DeploymentInfo: &deploymentInfo,
+	}})
+	if err != nil {
+		a.Log.Errorf("an error occurred in the artifact.fetched event: %s", err)
+	}
+}
+
+// download writes the artifact at url to artifactFile and verifies it against the checksum, when one is given.
+func (a *Artifetcher) download(url, token, checksum string, artifactFile io.Writer, out io.Writer) error {
+	response, err := a.get(url, token)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	hash := sha256.New()
+	writers := []io.Writer{artifactFile, hash}
+
+	var progress *progressWriter
+	if out != nil {
+		progress = newProgressWriter(out, response.ContentLength, a.ProgressInterval, a.clock())
+		writers = append(writers, progress)
+	}
+
+	_, err = io.Copy(io.MultiWriter(writers...), response.Body)
+	if err != nil {
+		return WriteResponseError{err}
+	}
+
+	if progress != nil {
+		progress.done()
+	}
+
+	if checksum != "" {
+		actual := hex.EncodeToString(hash.Sum(nil))
+		if !strings.EqualFold(actual, checksum) {
+			return ChecksumMismatchError{checksum, actual}
+		}
+		a.Log.Debug("artifact checksum verified: %s", actual)
+	}
+
+	return nil
+}
+
+// FetchManifest downloads a manifest located at URL separately from the artifact.
+//
+// Returns the contents of the manifest and an error.
+func (a *Artifetcher) FetchManifest(url string) (string, error) {
+	a.Log.Info("fetching manifest")
+	a.Log.Debug("manifest URL: %s", url)
+
+	response, err := a.get(url, "")
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	manifest, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", ReadManifestError{err}
+	}
+
+	return string(manifest), nil
+}
+
+// FetchFromZip fetches files from a compressed zip file read from body, such as the body of a deploy request.
+// The zip file is streamed to a temp file and extracted from there, so it is never held in memory.
 //
 // Returns a string to the unzipped application path and an error.
-func (a *Artifetcher) FetchZipFromRequest(req *http.Request) (string, error) {
+func (a *Artifetcher) FetchFromZip(body io.Reader) (string, error) {
 	zipFile, err := a.FileSystem.TempFile("", "deployadactyl-")
 	if err != nil {
 		return "", CreateTempFileError{err}
@@ -96,7 +233,7 @@ func (a *Artifetcher) FetchZipFromRequest(req *http.Request) (string, error) {
 
 	a.Log.Info("fetching zip file %s", zipFile.Name())
 
-	if _, err = io.Copy(zipFile, req.Body); err != nil {
+	if _, err = io.Copy(zipFile, body); err != nil {
 		return "", WriteResponseError{err}
 	}
 
@@ -114,3 +251,102 @@ func (a *Artifetcher) FetchZipFromRequest(req *http.Request) (string, error) {
 	a.Log.Debug("fetched and unzipped to tempdir %s", unzippedPath)
 	return unzippedPath, nil
 }
+
+// get downloads url, retrying with exponential backoff on network errors and 5xx responses.
+// Any other response that is not a 200 fails immediately.
+func (a *Artifetcher) get(url, token string) (*http.Response, error) {
+	client := a.newClient()
+	delay := a.RetryDelay
+
+	for attempt := 1; ; attempt++ {
+		req, err := a.newRequest(url, token)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := client.Do(req)
+		if err == nil && response.StatusCode == http.StatusOK {
+			return response, nil
+		}
+
+		if err != nil {
+			err = GetUrlError{url, err}
+		} else {
+			response.Body.Close()
+			err = GetStatusError{url, response.Status}
+
+			if response.StatusCode < http.StatusInternalServerError {
+				return nil, err
+			}
+		}
+
+		if attempt > a.Retries {
+			return nil, err
+		}
+
+		a.Log.Debugf("retry %d of %d in %s: %s", attempt, a.Retries, delay, err)
+		a.clock().Sleep(delay)
+		delay *= 2
+	}
+}
+
+// newRequest returns a signed S3 request for S3 URLs and a plain GET request for every other URL.
+// An https S3 URL is requested without signing it when there are no S3 credentials, so that public buckets still work.
+func (a *Artifetcher) newRequest(url, token string) (*http.Request, error) {
+	req, region, isS3, err := a.s3Request(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if isS3 {
+		credentials, err := a.s3Credentials()
+		if err == nil {
+			signS3Request(req, credentials, region, a.clock().Now())
+			return req, nil
+		}
+		if strings.HasPrefix(url, "s3://") {
+			return nil, err
+		}
+		a.Log.Debugf("requesting S3 url without signing it: %s", err)
+	}
+
+	req, err = http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, ArtifactoryRequestError{err}
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return req, nil
+}
+
+func (a *Artifetcher) s3Credentials() (S3Credentials, error) {
+	if a.S3Credentials == nil {
+		return EnvironmentS3Credentials(os.Getenv)()
+	}
+	return a.S3Credentials()
+}
+
+func (a *Artifetcher) clock() I.Clock {
+	if a.Clock == nil {
+		return clock.Clock{}
+	}
+	return a.Clock
+}
+
+func (a *Artifetcher) newClient() *http.Client {
+	return &http.Client{
+		Timeout: 4 * time.Minute,
+		Transport: &http.Transport{
+			Dial: (&net.Dialer{
+				Timeout:   60 * time.Second,
+				KeepAlive: 60 * time.Second,
+			}).Dial,
+			TLSClientConfig:       &tls.Config{MinVersion: a.MinTLSVersion},
+			TLSHandshakeTimeout:   15 * time.Second,
+			ResponseHeaderTimeout: 15 * time.Second,
+			ExpectContinueTimeout: 2 * time.Second,
+		},
+	}
+}
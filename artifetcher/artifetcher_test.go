@@ -1,10 +1,19 @@
 package artifetcher_test
 
 import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -16,6 +25,7 @@ import (
 	"github.com/compozed/deployadactyl/logger"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
+	S "github.com/compozed/deployadactyl/structs"
 )
 
 var _ = Describe("Artifetcher", func() {
@@ -28,10 +38,14 @@ var _ = Describe("Artifetcher", func() {
 	)
 
 	BeforeEach(func() {
-		logger := logger.DefaultLogger(GinkgoWriter, logging.DEBUG, "artifetcher_test")
+		logger := logger.DefaultLogger(GinkgoWriter, logging.DEBUG, "artifetcher_test", logger.TextFormat)
 		af = &afero.Afero{Fs: afero.NewMemMapFs()}
 		extractor = &mocks.Extractor{}
-		artifetcher = &Artifetcher{af, extractor, logger}
+		artifetcher = &Artifetcher{
+			FileSystem: af,
+			Extractor:  extractor,
+			Log:        logger,
+		}
 		manifest = "manifest-" + randomizer.StringRunes(10)
 
 		testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -47,7 +61,7 @@ var _ = Describe("Artifetcher", func() {
 		It("can fetch a jar file", func() {
 			extractor.UnzipCall.Returns.Error = nil
 
-			unzippedPath, err := artifetcher.Fetch(testserver.URL, "")
+			unzippedPath, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL}, "", nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(af.IsDir(unzippedPath)).To(BeTrue())
@@ -57,8 +71,325 @@ var _ = Describe("Artifetcher", func() {
 			Expect(extractor.UnzipCall.Received.Manifest).To(BeEmpty())
 		})
 
+		It("sends the artifact token as a bearer token", func() {
+			token := "token-" + randomizer.StringRunes(10)
+
+			var authorization string
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				authorization = r.Header.Get("Authorization")
+				http.ServeFile(w, r, "./fixtures/deployadactyl-fixture.jar")
+			}))
+
+			_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL, ArtifactToken: token}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(authorization).To(Equal("Bearer " + token))
+		})
+
+		It("does not send an authorization header without an artifact token", func() {
+			var authorization string
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				authorization = r.Header.Get("Authorization")
+				http.ServeFile(w, r, "./fixtures/deployadactyl-fixture.jar")
+			}))
+
+			_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(authorization).To(BeEmpty())
+		})
+
+		Describe("reporting the download progress", func() {
+			var (
+				out     *bytes.Buffer
+				fixture []byte
+			)
+
+			BeforeEach(func() {
+				out = &bytes.Buffer{}
+				fixture, _ = ioutil.ReadFile("./fixtures/deployadactyl-fixture.jar")
+			})
+
+			It("writes the bytes downloaded of the total and a percentage", func() {
+				_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL}, "", out)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(out.String()).To(HaveSuffix(fmt.Sprintf("downloaded %d of %d bytes (100%%)\n", len(fixture), len(fixture))))
+			})
+
+			It("only writes the bytes downloaded when the size is unknown", func() {
+				testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write(fixture[:10])
+					w.(http.Flusher).Flush()
+					w.Write(fixture[10:])
+				}))
+
+				_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL}, "", out)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(out.String()).To(Equal(fmt.Sprintf("downloaded %d bytes\n", len(fixture))))
+			})
+
+			It("throttles the progress lines", func() {
+				_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL}, "", out)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(strings.Count(out.String(), "downloaded")).To(Equal(1))
+			})
+
+			It("writes a line for every write when the interval is short enough", func() {
+				artifetcher.ProgressInterval = time.Nanosecond
+				testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write(fixture[:10])
+					w.(http.Flusher).Flush()
+					time.Sleep(10 * time.Millisecond)
+					w.Write(fixture[10:])
+				}))
+
+				_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL}, "", out)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(out.String()).To(HavePrefix("downloaded 10 bytes\n"))
+			})
+		})
+
+		Describe("verifying the artifact checksum", func() {
+			var checksum string
+
+			BeforeEach(func() {
+				fixture, err := ioutil.ReadFile("./fixtures/deployadactyl-fixture.jar")
+				Expect(err).ToNot(HaveOccurred())
+
+				sum := sha256.Sum256(fixture)
+				checksum = hex.EncodeToString(sum[:])
+			})
+
+			It("fetches the artifact when the checksum matches", func() {
+				unzippedPath, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL, ArtifactSHA256: checksum}, "", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(extractor.UnzipCall.Received.Destination).To(Equal(unzippedPath))
+			})
+
+			It("returns an error without extracting when the checksum does not match", func() {
+				badChecksum := strings.Repeat("0", 64)
+
+				_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL, ArtifactSHA256: badChecksum}, "", nil)
+				Expect(err).To(MatchError(ChecksumMismatchError{badChecksum, checksum}))
+
+				Expect(extractor.UnzipCall.Received.Source).To(BeEmpty())
+			})
+
+			It("does not verify the artifact when no checksum is given", func() {
+				_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL}, "", nil)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Describe("emitting an artifact.fetched event", func() {
+			var (
+				eventManager *mocks.EventManager
+				fixtureSize  int64
+			)
+
+			BeforeEach(func() {
+				eventManager = &mocks.EventManager{}
+				artifetcher.EventManager = eventManager
+
+				info, err := os.Stat("./fixtures/deployadactyl-fixture.jar")
+				Expect(err).ToNot(HaveOccurred())
+				fixtureSize = info.Size()
+			})
+
+			It("emits the size of the downloaded artifact and how long the download took", func() {
+				clock := &mocks.Clock{}
+				artifetcher.Clock = clock
+				artifetcher.Retries = 1
+				artifetcher.RetryDelay = 2 * time.Second
+
+				requests := 0
+				testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					requests++
+					if requests == 1 {
+						http.Error(w, "bad gateway", http.StatusBadGateway)
+						return
+					}
+					http.ServeFile(w, r, "./fixtures/deployadactyl-fixture.jar")
+				}))
+
+				deploymentInfo := S.DeploymentInfo{
+					ArtifactURL: testserver.URL,
+					Environment: "environment-" + randomizer.StringRunes(10),
+					AppName:     "appName-" + randomizer.StringRunes(10),
+					RequestID:   "requestID-" + randomizer.StringRunes(10),
+				}
+
+				_, err := artifetcher.Fetch(deploymentInfo, "", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(eventManager.EmitCall.Received.Events).To(HaveLen(1))
+				Expect(eventManager.EmitCall.Received.Events[0].Type).To(Equal("artifact.fetched"))
+				Expect(eventManager.EmitCall.Received.Events[0].Data).To(Equal(S.ArtifactFetchedEventData{
+					URL:            testserver.URL,
+					Bytes:          fixtureSize,
+					Duration:       2 * time.Second,
+					Cached:         false,
+					DeploymentInfo: &deploymentInfo,
+				}))
+			})
+
+			It("emits that the artifact was taken from the cache", func() {
+				fixture, err := ioutil.ReadFile("./fixtures/deployadactyl-fixture.jar")
+				Expect(err).ToNot(HaveOccurred())
+
+				sum := sha256.Sum256(fixture)
+				checksum := hex.EncodeToString(sum[:])
+
+				artifetcher.Cache, err = NewCache(af, "/cache", 0)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL, ArtifactSHA256: checksum}, "", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL, ArtifactSHA256: checksum}, "", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(eventManager.EmitCall.Received.Events).To(HaveLen(2))
+
+				downloaded := eventManager.EmitCall.Received.Events[0].Data.(S.ArtifactFetchedEventData)
+				Expect(downloaded.Cached).To(BeFalse())
+				Expect(downloaded.Bytes).To(Equal(fixtureSize))
+
+				cached := eventManager.EmitCall.Received.Events[1].Data.(S.ArtifactFetchedEventData)
+				Expect(cached.Cached).To(BeTrue())
+				Expect(cached.Bytes).To(Equal(fixtureSize))
+				Expect(cached.Duration).To(BeNumerically(">=", 0))
+			})
+
+			It("does not emit an event when the download fails", func() {
+				testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					http.Error(w, "not found", http.StatusNotFound)
+				}))
+
+				_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL}, "", nil)
+				Expect(err).To(HaveOccurred())
+
+				Expect(eventManager.EmitCall.Received.Events).To(BeEmpty())
+			})
+		})
+
+		Describe("caching the artifact", func() {
+			var (
+				checksum string
+				requests int
+			)
+
+			BeforeEach(func() {
+				fixture, err := ioutil.ReadFile("./fixtures/deployadactyl-fixture.jar")
+				Expect(err).ToNot(HaveOccurred())
+
+				sum := sha256.Sum256(fixture)
+				checksum = hex.EncodeToString(sum[:])
+
+				requests = 0
+				testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					requests++
+					w.Write(fixture)
+				}))
+
+				artifetcher.Cache, err = NewCache(af, "/cache", 0)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("does not download the artifact again the second time it is fetched", func() {
+				_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL, ArtifactSHA256: checksum}, "", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				out := &bytes.Buffer{}
+				unzippedPath, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL, ArtifactSHA256: checksum}, "", out)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(requests).To(Equal(1))
+				Expect(out.String()).To(Equal("using cached artifact\n"))
+				Expect(extractor.UnzipCall.Received.Destination).To(Equal(unzippedPath))
+			})
+
+			It("extracts a copy of the cached artifact", func() {
+				_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL, ArtifactSHA256: checksum}, "", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL, ArtifactSHA256: checksum}, "", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(extractor.UnzipCall.Received.Source).To(ContainSubstring("deployadactyl-zip"))
+				Expect(extractor.UnzipCall.Received.Source).ToNot(HavePrefix("/cache"))
+			})
+
+			It("downloads the artifact again for another checksum", func() {
+				_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL, ArtifactSHA256: checksum}, "", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL, ArtifactSHA256: strings.Repeat("0", 64)}, "", nil)
+				Expect(err).To(HaveOccurred())
+
+				Expect(requests).To(Equal(2))
+			})
+
+			It("does not cache an artifact fetched without a checksum", func() {
+				_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL}, "", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL}, "", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(requests).To(Equal(2))
+			})
+
+			It("does not cache an artifact fetched with a token", func() {
+				_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL, ArtifactToken: "token", ArtifactSHA256: checksum}, "", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL, ArtifactToken: "token", ArtifactSHA256: checksum}, "", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(requests).To(Equal(2))
+			})
+
+			It("does not take an artifact fetched with a token from the cache", func() {
+				_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL, ArtifactSHA256: checksum}, "", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL, ArtifactToken: "token", ArtifactSHA256: checksum}, "", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(requests).To(Equal(2))
+			})
+
+			It("does not cache an artifact that does not match its checksum", func() {
+				badChecksum := strings.Repeat("0", 64)
+
+				artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL, ArtifactSHA256: badChecksum}, "", nil)
+				artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL, ArtifactSHA256: badChecksum}, "", nil)
+
+				Expect(requests).To(Equal(2))
+			})
+
+			It("invalidates a cached artifact that does not match its checksum", func() {
+				Expect(af.WriteFile("/corrupt.jar", []byte("corrupt"), 0644)).To(Succeed())
+				Expect(artifetcher.Cache.Add(testserver.URL, checksum, "/corrupt.jar")).To(Succeed())
+
+				_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL, ArtifactSHA256: checksum}, "", nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(requests).To(Equal(1))
+
+				_, err = artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL, ArtifactSHA256: checksum}, "", nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(requests).To(Equal(1))
+			})
+		})
+
 		It("returns an error when an invalid url is given", func() {
-			_, err := artifetcher.Fetch("example://example.example", manifest)
+			_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: "example://example.example"}, manifest, nil)
 			Expect(err).To(HaveOccurred())
 		})
 
@@ -67,39 +398,211 @@ var _ = Describe("Artifetcher", func() {
 				http.Error(w, "not found", 404)
 			}))
 
-			_, err := artifetcher.Fetch(testserver.URL, manifest)
+			_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL}, manifest, nil)
 			Expect(err).To(HaveOccurred())
 		})
 
+		Context("when the artifact server fails", func() {
+			var requests int
+
+			BeforeEach(func() {
+				requests = 0
+				artifetcher.Retries = 3
+				artifetcher.RetryDelay = 0
+			})
+
+			It("retries a 5xx response until the download succeeds", func() {
+				testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					requests++
+					if requests < 3 {
+						http.Error(w, "bad gateway", http.StatusBadGateway)
+						return
+					}
+					http.ServeFile(w, r, "./fixtures/deployadactyl-fixture.jar")
+				}))
+
+				_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL}, "", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(requests).To(Equal(3))
+			})
+
+			It("gives up after the configured number of retries", func() {
+				testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					requests++
+					http.Error(w, "bad gateway", http.StatusBadGateway)
+				}))
+
+				_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL}, "", nil)
+				Expect(err).To(MatchError(GetStatusError{testserver.URL, "502 Bad Gateway"}))
+
+				Expect(requests).To(Equal(4))
+			})
+
+			It("doubles the RetryDelay before each retry", func() {
+				clock := &mocks.Clock{}
+				artifetcher.Clock = clock
+				artifetcher.RetryDelay = time.Second
+
+				testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					http.Error(w, "bad gateway", http.StatusBadGateway)
+				}))
+
+				artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL}, "", nil)
+
+				Expect(clock.SleepCall.Received.Durations).To(Equal([]time.Duration{time.Second, 2 * time.Second, 4 * time.Second}))
+			})
+
+			It("does not retry a 404 not found", func() {
+				testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					requests++
+					http.Error(w, "not found", http.StatusNotFound)
+				}))
+
+				_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL}, "", nil)
+				Expect(err).To(MatchError(GetStatusError{testserver.URL, "404 Not Found"}))
+
+				Expect(requests).To(Equal(1))
+			})
+		})
+
 		Context("when extractor fails", func() {
 			It("returns an error", func() {
 				extractor.UnzipCall.Returns.Error = errors.New("unzip call failed")
 
-				_, err := artifetcher.Fetch(testserver.URL, "")
+				_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: testserver.URL}, "", nil)
 
 				Expect(err).To(MatchError(UnzipError{errors.New("unzip call failed")}))
 			})
 		})
 	})
 
-	Describe("fetching a zip file from a request", func() {
+	Describe("fetching an artifact from s3", func() {
+		var (
+			request     *http.Request
+			credentials S3Credentials
+		)
+
+		BeforeEach(func() {
+			request = nil
+			credentials = S3Credentials{
+				AccessKeyID:     "accessKeyID-" + randomizer.StringRunes(10),
+				SecretAccessKey: "secretAccessKey-" + randomizer.StringRunes(10),
+				SessionToken:    "sessionToken-" + randomizer.StringRunes(10),
+			}
+
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				request = r
+				http.ServeFile(w, r, "./fixtures/deployadactyl-fixture.jar")
+			}))
+
+			artifetcher.S3Region = "eu-west-1"
+			artifetcher.S3Endpoint = testserver.URL
+			artifetcher.S3Credentials = func() (S3Credentials, error) { return credentials, nil }
+		})
+
+		It("downloads the key from the bucket with a signed request", func() {
+			_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: "s3://bucket/path/to/artifact.jar"}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(request.URL.Path).To(Equal("/bucket/path/to/artifact.jar"))
+			Expect(request.Header.Get("Authorization")).To(HavePrefix("AWS4-HMAC-SHA256 Credential=" + credentials.AccessKeyID + "/"))
+			Expect(request.Header.Get("Authorization")).To(ContainSubstring("/eu-west-1/s3/aws4_request"))
+			Expect(request.Header.Get("X-Amz-Date")).ToNot(BeEmpty())
+			Expect(request.Header.Get("X-Amz-Security-Token")).To(Equal(credentials.SessionToken))
+
+			Expect(extractor.UnzipCall.Received.Source).To(ContainSubstring("deployadactyl-zip"))
+		})
+
+		It("does not send the artifact token", func() {
+			_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: "s3://bucket/artifact.jar", ArtifactToken: "token"}, "", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(request.Header.Get("Authorization")).ToNot(ContainSubstring("Bearer"))
+		})
+
+		It("returns an error when there are no credentials", func() {
+			artifetcher.S3Credentials = EnvironmentS3Credentials(func(string) string { return "" })
+
+			_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: "s3://bucket/artifact.jar"}, "", nil)
+
+			Expect(err).To(MatchError(MissingS3CredentialsError{"the environment"}))
+			Expect(request).To(BeNil())
+		})
+
+		It("returns an error when the url has no key", func() {
+			_, err := artifetcher.Fetch(S.DeploymentInfo{ArtifactURL: "s3://bucket"}, "", nil)
+
+			Expect(err).To(MatchError(InvalidS3URLError{"s3://bucket"}))
+		})
+
+		It("reads the credentials from the shared credentials file", func() {
+			Expect(af.WriteFile("/credentials", []byte("[default]\naws_access_key_id = default\n\n[deploy]\naws_access_key_id = deployID\naws_secret_access_key = deploySecret\n"), 0600)).To(Succeed())
+
+			env := map[string]string{"AWS_SHARED_CREDENTIALS_FILE": "/credentials", "AWS_PROFILE": "deploy"}
+			sharedCredentials, err := SharedFileS3Credentials(func(key string) string { return env[key] }, af)()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(sharedCredentials).To(Equal(S3Credentials{AccessKeyID: "deployID", SecretAccessKey: "deploySecret"}))
+		})
+	})
+
+	Describe("fetching a manifest", func() {
+		It("returns the contents of the manifest", func() {
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(manifest))
+			}))
+
+			fetchedManifest, err := artifetcher.FetchManifest(testserver.URL)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fetchedManifest).To(Equal(manifest))
+		})
+
+		It("returns an error when the URL returns a 404 not found", func() {
+			testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "not found", 404)
+			}))
+
+			_, err := artifetcher.FetchManifest(testserver.URL)
+			Expect(err).To(MatchError(GetStatusError{testserver.URL, "404 Not Found"}))
+		})
+	})
+
+	Describe("fetching a zip file from a request body", func() {
 		It("returns the path to the unzipped directory", func() {
 			extractor.UnzipCall.Returns.Error = nil
 
 			body, err := os.Open("./fixtures/artifact-with-manifest.jar")
 			Expect(err).ToNot(HaveOccurred())
+			defer body.Close()
 
-			// for go 1.7 change this to httptest
-			req, err := http.NewRequest("POST", "https://example.com", body)
-			Expect(err).ToNot(HaveOccurred())
-
-			path, err := artifetcher.FetchZipFromRequest(req)
+			path, err := artifetcher.FetchFromZip(body)
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(path).To(ContainSubstring("deployadactyl-"))
 			Expect(extractor.UnzipCall.Received.Destination).To(Equal(path))
 		})
 
+		It("streams a large zip file to disk instead of reading it into memory", func() {
+			zipBuffer := &bytes.Buffer{}
+			zipWriter := zip.NewWriter(zipBuffer)
+			file, err := zipWriter.CreateHeader(&zip.FileHeader{Name: "large.bin", Method: zip.Store})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = file.Write(bytes.Repeat([]byte(randomizer.StringRunes(1024)), 8*1024))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(zipWriter.Close()).To(Succeed())
+
+			extractor.UnzipCall.Returns.Error = nil
+			body := &chunkReader{reader: bytes.NewReader(zipBuffer.Bytes())}
+
+			_, err = artifetcher.FetchFromZip(body)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(body.read).To(Equal(zipBuffer.Len()))
+			Expect(body.largestRead).To(BeNumerically("<=", 64*1024))
+		})
+
 		Context("when extractor fails", func() {
 			It("returns an error", func() {
 				errorMessage := "test extract fail"
@@ -107,12 +610,9 @@ var _ = Describe("Artifetcher", func() {
 
 				body, err := os.Open("./fixtures/artifact-with-manifest.jar")
 				Expect(err).ToNot(HaveOccurred())
+				defer body.Close()
 
-				// for go 1.7 change this to httptest
-				req, err := http.NewRequest("POST", "https://example.com", body)
-				Expect(err).ToNot(HaveOccurred())
-
-				path, err := artifetcher.FetchZipFromRequest(req)
+				path, err := artifetcher.FetchFromZip(body)
 				Expect(err).To(MatchError(UnzipError{errors.New(errorMessage)}))
 
 				Expect(path).To(BeEmpty())
@@ -120,3 +620,22 @@ var _ = Describe("Artifetcher", func() {
 		})
 	})
 })
+
+// chunkReader records how much of a body is read at once. It hides the io.WriterTo of the reader it wraps
+// so that the body is read the way a request body is.
+type chunkReader struct {
+	reader      io.Reader
+	read        int
+	largestRead int
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(p) > r.largestRead {
+		r.largestRead = len(p)
+	}
+
+	n, err := r.reader.Read(p)
+	r.read += n
+
+	return n, err
+}
@@ -0,0 +1,233 @@
+package artifetcher
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/spf13/afero"
+)
+
+// DefaultCacheSize is the most bytes a Cache keeps when no size is given.
+const DefaultCacheSize = 1 << 30
+
+const cacheTempSuffix = ".tmp"
+
+// Cache keeps downloaded artifacts in Directory, keyed by their URL and checksum.
+// The least recently used artifacts are evicted once the cache holds more than MaxSize bytes.
+// An artifact larger than MaxSize is never cached.
+type Cache struct {
+	FileSystem *afero.Afero
+	Directory  string
+	MaxSize    int64
+
+	mutex   sync.Mutex
+	size    int64
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key  string
+	size int64
+}
+
+// NewCache returns a Cache in directory that holds up to maxSize bytes, or DefaultCacheSize when maxSize is not positive.
+// The directory is created when it does not exist. Artifacts already in it are kept, the least recently used first to be evicted.
+func NewCache(fileSystem *afero.Afero, directory string, maxSize int64) (*Cache, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultCacheSize
+	}
+
+	cache := &Cache{
+		FileSystem: fileSystem,
+		Directory:  directory,
+		MaxSize:    maxSize,
+		order:      list.New(),
+		entries:    map[string]*list.Element{},
+	}
+
+	err := fileSystem.MkdirAll(directory, 0755)
+	if err != nil {
+		return nil, CreateCacheDirectoryError{directory, err}
+	}
+
+	files, err := fileSystem.ReadDir(directory)
+	if err != nil {
+		return nil, CreateCacheDirectoryError{directory, err}
+	}
+
+	sort.Sort(newestFirst(files))
+
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(file.Name(), cacheTempSuffix) {
+			fileSystem.Remove(path.Join(directory, file.Name()))
+			continue
+		}
+		cache.entries[file.Name()] = cache.order.PushBack(&cacheEntry{file.Name(), file.Size()})
+		cache.size += file.Size()
+	}
+	cache.evict()
+
+	return cache, nil
+}
+
+// Get copies the artifact cached for the url and checksum to w and marks it as the most recently used.
+// The artifact is opened while the cache is locked and copied after it is unlocked, so a slow w does not hold up
+// other deploys. An artifact evicted or replaced during the copy is still read in full from the file that was opened.
+//
+// Returns false when the artifact is not cached.
+func (c *Cache) Get(url, checksum string, w io.Writer) (bool, error) {
+	file, found := c.open(cacheKey(url, checksum))
+	if !found {
+		return false, nil
+	}
+	defer file.Close()
+
+	_, err := io.Copy(w, file)
+	if err != nil {
+		return false, ReadCacheError{err}
+	}
+
+	return true, nil
+}
+
+// open opens the artifact cached for key and marks it as the most recently used.
+func (c *Cache) open(key string) (afero.File, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	file, err := c.FileSystem.Open(c.path(key))
+	if err != nil {
+		c.remove(element)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	now := time.Now()
+	c.FileSystem.Chtimes(c.path(key), now, now)
+
+	return file, true
+}
+
+// Add copies the artifact at filename into the cache for the url and checksum,
+// then evicts the least recently used artifacts until the cache fits in MaxSize.
+// The artifact is copied to a temporary file before the cache is locked and only renamed into place while it is locked.
+func (c *Cache) Add(url, checksum, filename string) error {
+	info, err := c.FileSystem.Stat(filename)
+	if err != nil {
+		return WriteCacheError{err}
+	}
+	if info.Size() > c.MaxSize {
+		return nil
+	}
+
+	key := cacheKey(url, checksum)
+
+	tempName, err := c.copy(filename, key)
+	if err != nil {
+		return WriteCacheError{err}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+
+	err = c.FileSystem.Rename(tempName, c.path(key))
+	if err != nil {
+		c.FileSystem.Remove(tempName)
+		return WriteCacheError{err}
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key, info.Size()})
+	c.size += info.Size()
+	c.evict()
+
+	return nil
+}
+
+// Remove drops the artifact cached for the url and checksum.
+func (c *Cache) Remove(url, checksum string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[cacheKey(url, checksum)]; ok {
+		c.remove(element)
+	}
+}
+
+// copy writes the file at source to a temporary file in the cache directory, named after key with a random suffix
+// so that concurrent copies of the same artifact do not write to the same file.
+//
+// Returns the name of the temporary file.
+func (c *Cache) copy(source, key string) (string, error) {
+	sourceFile, err := c.FileSystem.Open(source)
+	if err != nil {
+		return "", err
+	}
+	defer sourceFile.Close()
+
+	tempName := c.path(key) + "-" + randomizer.StringRunes(10) + cacheTempSuffix
+	tempFile, err := c.FileSystem.Create(tempName)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = io.Copy(tempFile, sourceFile)
+	tempFile.Close()
+	if err != nil {
+		c.FileSystem.Remove(tempName)
+		return "", err
+	}
+
+	return tempName, nil
+}
+
+func (c *Cache) evict() {
+	for c.size > c.MaxSize && c.order.Len() > 0 {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *Cache) remove(element *list.Element) {
+	entry := c.order.Remove(element).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+	c.FileSystem.Remove(c.path(entry.key))
+}
+
+// newestFirst sorts cached files from the most to the least recently used.
+type newestFirst []os.FileInfo
+
+func (f newestFirst) Len() int           { return len(f) }
+func (f newestFirst) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
+func (f newestFirst) Less(i, j int) bool { return f[i].ModTime().After(f[j].ModTime()) }
+
+func (c *Cache) path(key string) string {
+	return path.Join(c.Directory, key)
+}
+
+// cacheKey names the cached artifact of a url and checksum. The checksum is not case sensitive.
+func cacheKey(url, checksum string) string {
+	hash := sha256.Sum256([]byte(url + "\x00" + strings.ToLower(checksum)))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,183 @@
+package artifetcher_test
+
+import (
+	"bytes"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+
+	. "github.com/compozed/deployadactyl/artifetcher"
+)
+
+var _ = Describe("Cache", func() {
+	var (
+		af    *afero.Afero
+		cache *Cache
+	)
+
+	BeforeEach(func() {
+		af = &afero.Afero{Fs: afero.NewMemMapFs()}
+		Expect(af.WriteFile("/artifact-1", []byte("0123456789"), 0644)).To(Succeed())
+		Expect(af.WriteFile("/artifact-2", []byte("abcdefghij"), 0644)).To(Succeed())
+		Expect(af.WriteFile("/artifact-3", []byte("ABCDEFGHIJ"), 0644)).To(Succeed())
+
+		var err error
+		cache, err = NewCache(af, "/cache", 25)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	get := func(url, checksum string) (string, bool) {
+		out := &bytes.Buffer{}
+		found, err := cache.Get(url, checksum, out)
+		Expect(err).ToNot(HaveOccurred())
+		return out.String(), found
+	}
+
+	It("creates the cache directory", func() {
+		Expect(af.IsDir("/cache")).To(BeTrue())
+	})
+
+	It("returns the cached artifact for the url and checksum", func() {
+		Expect(cache.Add("http://example.com/1", "abc", "/artifact-1")).To(Succeed())
+
+		artifact, found := get("http://example.com/1", "abc")
+		Expect(found).To(BeTrue())
+		Expect(artifact).To(Equal("0123456789"))
+	})
+
+	It("ignores the case of the checksum", func() {
+		Expect(cache.Add("http://example.com/1", "abc", "/artifact-1")).To(Succeed())
+
+		_, found := get("http://example.com/1", "ABC")
+		Expect(found).To(BeTrue())
+	})
+
+	It("does not find an artifact cached for another url or checksum", func() {
+		Expect(cache.Add("http://example.com/1", "abc", "/artifact-1")).To(Succeed())
+
+		_, found := get("http://example.com/2", "abc")
+		Expect(found).To(BeFalse())
+
+		_, found = get("http://example.com/1", "def")
+		Expect(found).To(BeFalse())
+	})
+
+	It("removes an artifact", func() {
+		Expect(cache.Add("http://example.com/1", "abc", "/artifact-1")).To(Succeed())
+
+		cache.Remove("http://example.com/1", "abc")
+
+		_, found := get("http://example.com/1", "abc")
+		Expect(found).To(BeFalse())
+	})
+
+	It("does not hold up other artifacts while one is copied out", func() {
+		Expect(cache.Add("http://example.com/1", "abc", "/artifact-1")).To(Succeed())
+
+		writer := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+		got := make(chan bool, 1)
+		go func() {
+			found, _ := cache.Get("http://example.com/1", "abc", writer)
+			got <- found
+		}()
+		Eventually(writer.started).Should(BeClosed())
+
+		added := make(chan error, 1)
+		go func() {
+			added <- cache.Add("http://example.com/2", "abc", "/artifact-2")
+		}()
+		Eventually(added).Should(Receive(BeNil()))
+
+		_, found := get("http://example.com/2", "abc")
+		Expect(found).To(BeTrue())
+
+		close(writer.release)
+		Eventually(got).Should(Receive(BeTrue()))
+	})
+
+	It("evicts the least recently used artifact when it is full", func() {
+		Expect(cache.Add("http://example.com/1", "abc", "/artifact-1")).To(Succeed())
+		Expect(cache.Add("http://example.com/2", "abc", "/artifact-2")).To(Succeed())
+
+		_, found := get("http://example.com/1", "abc")
+		Expect(found).To(BeTrue())
+
+		Expect(cache.Add("http://example.com/3", "abc", "/artifact-3")).To(Succeed())
+
+		_, found = get("http://example.com/2", "abc")
+		Expect(found).To(BeFalse())
+
+		_, found = get("http://example.com/1", "abc")
+		Expect(found).To(BeTrue())
+		_, found = get("http://example.com/3", "abc")
+		Expect(found).To(BeTrue())
+
+		files, err := af.ReadDir("/cache")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(HaveLen(2))
+	})
+
+	It("does not cache an artifact larger than the cache", func() {
+		Expect(af.WriteFile("/large", bytes.Repeat([]byte("x"), 26), 0644)).To(Succeed())
+
+		Expect(cache.Add("http://example.com/large", "abc", "/large")).To(Succeed())
+
+		_, found := get("http://example.com/large", "abc")
+		Expect(found).To(BeFalse())
+	})
+
+	It("keeps the artifacts already in the directory", func() {
+		Expect(cache.Add("http://example.com/1", "abc", "/artifact-1")).To(Succeed())
+
+		reopened, err := NewCache(af, "/cache", 25)
+		Expect(err).ToNot(HaveOccurred())
+
+		found, err := reopened.Get("http://example.com/1", "abc", &bytes.Buffer{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+	})
+
+	It("evicts the least recently used artifacts already in the directory when it is smaller", func() {
+		Expect(cache.Add("http://example.com/1", "abc", "/artifact-1")).To(Succeed())
+		Expect(cache.Add("http://example.com/2", "abc", "/artifact-2")).To(Succeed())
+
+		files, err := af.ReadDir("/cache")
+		Expect(err).ToNot(HaveOccurred())
+		hourAgo := time.Now().Add(-time.Hour)
+		for _, file := range files {
+			Expect(af.Chtimes("/cache/"+file.Name(), hourAgo, hourAgo)).To(Succeed())
+		}
+
+		_, found := get("http://example.com/1", "abc")
+		Expect(found).To(BeTrue())
+
+		reopened, err := NewCache(af, "/cache", 15)
+		Expect(err).ToNot(HaveOccurred())
+
+		found, err = reopened.Get("http://example.com/1", "abc", &bytes.Buffer{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+
+		found, err = reopened.Get("http://example.com/2", "abc", &bytes.Buffer{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+})
+
+// blockingWriter closes started on its first write and does not return until release is closed.
+type blockingWriter struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case <-w.started:
+	default:
+		close(w.started)
+	}
+	<-w.release
+	return len(p), nil
+}
@@ -44,6 +44,15 @@ func (e WriteResponseError) Error() string {
 	return fmt.Sprintf("cannot write response to file: %s", e.Err)
 }
 
+type ChecksumMismatchError struct {
+	Expected string
+	Actual   string
+}
+
+func (e ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("artifact checksum mismatch: expected sha256 %s: got %s", e.Expected, e.Actual)
+}
+
 type CreateTempDirectoryError struct {
 	Err error
 }
@@ -59,3 +68,61 @@ type UnzipError struct {
 func (e UnzipError) Error() string {
 	return fmt.Sprintf("cannot unzip artifact: %s", e.Err)
 }
+
+type InvalidS3URLError struct {
+	Url string
+}
+
+func (e InvalidS3URLError) Error() string {
+	return fmt.Sprintf("invalid s3 url: %s: must be s3://bucket/key", e.Url)
+}
+
+type MissingS3CredentialsError struct {
+	Source string
+}
+
+func (e MissingS3CredentialsError) Error() string {
+	return fmt.Sprintf("cannot find s3 credentials in %s", e.Source)
+}
+
+type ReadS3CredentialsError struct {
+	Filename string
+	Err      error
+}
+
+func (e ReadS3CredentialsError) Error() string {
+	return fmt.Sprintf("cannot read s3 credentials file %s: %s", e.Filename, e.Err)
+}
+
+type ReadManifestError struct {
+	Err error
+}
+
+func (e ReadManifestError) Error() string {
+	return fmt.Sprintf("cannot read manifest: %s", e.Err)
+}
+
+type CreateCacheDirectoryError struct {
+	Directory string
+	Err       error
+}
+
+func (e CreateCacheDirectoryError) Error() string {
+	return fmt.Sprintf("cannot create artifact cache directory %s: %s", e.Directory, e.Err)
+}
+
+type ReadCacheError struct {
+	Err error
+}
+
+func (e ReadCacheError) Error() string {
+	return fmt.Sprintf("cannot read cached artifact: %s", e.Err)
+}
+
+type WriteCacheError struct {
+	Err error
+}
+
+func (e WriteCacheError) Error() string {
+	return fmt.Sprintf("cannot cache artifact: %s", e.Err)
+}
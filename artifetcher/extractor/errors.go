@@ -23,6 +23,24 @@ Once you've confirmed that it's valid, please try again.`
 	return fmt.Sprintf("cannot open zip file: %s: %s\n%s", e.Source, e.Err, niceFixYourZipMessage)
 }
 
+type OpenTarError struct {
+	Source string
+	Err    error
+}
+
+func (e OpenTarError) Error() string {
+	return fmt.Sprintf("cannot open tar file: %s: %s", e.Source, e.Err)
+}
+
+type OpenGzipError struct {
+	Source string
+	Err    error
+}
+
+func (e OpenGzipError) Error() string {
+	return fmt.Sprintf("cannot open gzip file: %s: %s", e.Source, e.Err)
+}
+
 type ExtractFileError struct {
 	FileName string
 	Err      error
@@ -74,3 +92,29 @@ type WriteFileError struct {
 func (e WriteFileError) Error() string {
 	return fmt.Sprintf("cannot write to file: %s: %s", e.SavedLocation, e.Err)
 }
+
+type ChangeModeError struct {
+	SavedLocation string
+	Err           error
+}
+
+func (e ChangeModeError) Error() string {
+	return fmt.Sprintf("cannot change the mode of file: %s: %s", e.SavedLocation, e.Err)
+}
+
+type IllegalFilePathError struct {
+	Name string
+}
+
+func (e IllegalFilePathError) Error() string {
+	return fmt.Sprintf("illegal file path in archive: %s", e.Name)
+}
+
+type ArchiveTooLargeError struct {
+	MaxSize  int64
+	MaxFiles int
+}
+
+func (e ArchiveTooLargeError) Error() string {
+	return fmt.Sprintf("archive exceeds size limit: an archive can extract to at most %d bytes and %d files", e.MaxSize, e.MaxFiles)
+}
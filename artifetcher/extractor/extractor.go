@@ -2,24 +2,56 @@
 package extractor
 
 import (
+	"archive/tar"
 	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"io"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/op/go-logging"
 	"github.com/spf13/afero"
 )
 
+// The limits an Extractor uses when its MaxSize or MaxFiles are not set.
+const (
+	DefaultMaxSize  int64 = 1 << 30
+	DefaultMaxFiles       = 10000
+)
+
 // Extractor has a file system from which files are extracted from.
+// MaxSize is the most bytes and MaxFiles the most files an archive can extract to.
 type Extractor struct {
 	Log        *logging.Logger
 	FileSystem *afero.Afero
+	MaxSize    int64
+	MaxFiles   int
 }
 
+// limit keeps track of the size and number of the files written while an archive is extracted.
+type limit struct {
+	maxSize  int64
+	maxFiles int
+	size     int64
+	files    int
+	written  []string
+}
+
+func (l *limit) exceeded() error {
+	return ArchiveTooLargeError{l.maxSize, l.maxFiles}
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	tarMagic  = []byte("ustar")
+)
+
 // Unzip unzips from source into destination.
-// If there is no manifest provided to this function, it will attempt to read a manifest file within the zip file.
+// The archive type is detected from its first bytes, so zip, tar and gzip compressed tar archives are all supported.
+// If there is no manifest provided to this function, it will attempt to read a manifest file within the archive.
 func (e *Extractor) Unzip(source, destination, manifest string) error {
 	e.Log.Info("extracting application")
 	e.Log.Debug(`parameters for extractor:
@@ -37,21 +69,50 @@ func (e *Extractor) Unzip(source, destination, manifest string) error {
 	}
 	defer file.Close()
 
-	fileStat, err := file.Stat()
-	if err != nil {
+	header := make([]byte, 512)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
 		return err
 	}
+	header = header[:n]
 
-	reader, err := zip.NewReader(file, fileStat.Size())
+	_, err = file.Seek(0, 0)
 	if err != nil {
-		return OpenZipError{source, err}
+		return err
 	}
 
-	for _, file := range reader.File {
-		err := e.unzipFile(destination, file)
+	limit := &limit{maxSize: e.MaxSize, maxFiles: e.MaxFiles}
+	if limit.maxSize <= 0 {
+		limit.maxSize = DefaultMaxSize
+	}
+	if limit.maxFiles <= 0 {
+		limit.maxFiles = DefaultMaxFiles
+	}
+
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		e.Log.Debug("extracting a gzip compressed tar archive")
+		var gzipReader *gzip.Reader
+		gzipReader, err = gzip.NewReader(file)
 		if err != nil {
-			return ExtractFileError{file.Name, err}
+			return OpenGzipError{source, err}
 		}
+		defer gzipReader.Close()
+
+		err = e.untar(source, destination, tar.NewReader(gzipReader), limit)
+	case isTar(header):
+		e.Log.Debug("extracting a tar archive")
+		err = e.untar(source, destination, tar.NewReader(file), limit)
+	default:
+		err = e.unzip(source, destination, file, limit)
+	}
+	if _, ok := err.(ArchiveTooLargeError); ok {
+		for _, written := range limit.written {
+			e.FileSystem.Remove(written)
+		}
+	}
+	if err != nil {
+		return err
 	}
 
 	if manifest != "" {
@@ -71,7 +132,55 @@ func (e *Extractor) Unzip(source, destination, manifest string) error {
 	return nil
 }
 
-func (e *Extractor) unzipFile(destination string, file *zip.File) error {
+func (e *Extractor) unzip(source, destination string, file afero.File, limit *limit) error {
+	fileStat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	reader, err := zip.NewReader(file, fileStat.Size())
+	if err != nil {
+		return OpenZipError{source, err}
+	}
+
+	for _, file := range reader.File {
+		err := e.unzipFile(destination, file, limit)
+		if _, ok := err.(ArchiveTooLargeError); ok {
+			return err
+		}
+		if err != nil {
+			return ExtractFileError{file.Name, err}
+		}
+	}
+
+	return nil
+}
+
+func (e *Extractor) untar(source, destination string, reader *tar.Reader, limit *limit) error {
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return OpenTarError{source, err}
+		}
+
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		err = e.writeFile(destination, header.Name, header.FileInfo().Mode(), reader, limit)
+		if _, ok := err.(ArchiveTooLargeError); ok {
+			return err
+		}
+		if err != nil {
+			return ExtractFileError{header.Name, err}
+		}
+	}
+}
+
+func (e *Extractor) unzipFile(destination string, file *zip.File, limit *limit) error {
 	contents, err := file.Open()
 	if err != nil {
 		return ExtractFileError{file.Name, err}
@@ -82,24 +191,60 @@ func (e *Extractor) unzipFile(destination string, file *zip.File) error {
 		return nil
 	}
 
-	savedLocation := path.Join(destination, file.Name)
+	return e.writeFile(destination, file.Name, file.Mode(), contents, limit)
+}
+
+// writeFile writes the contents of an archive entry into destination.
+// Entries that would resolve outside of destination, such as ../../etc/cron.d/evil, are rejected before anything is written.
+// Writing stops with an ArchiveTooLargeError as soon as the archive goes over the size or file limit.
+// The file keeps the permissions of the entry, so executables stay executable. Entries without permissions are written as 0644.
+func (e *Extractor) writeFile(destination, name string, mode os.FileMode, contents io.Reader, limit *limit) error {
+	savedLocation := path.Join(destination, name)
+	if !strings.HasPrefix(savedLocation, path.Clean(destination)+"/") {
+		return IllegalFilePathError{name}
+	}
+
+	if limit.files == limit.maxFiles {
+		return limit.exceeded()
+	}
+	limit.files++
+
 	directory := path.Dir(savedLocation)
-	err = e.FileSystem.MkdirAll(directory, 0755)
+	err := e.FileSystem.MkdirAll(directory, 0755)
 	if err != nil {
 		return MakeDirectoryError{directory, err}
 	}
 
-	mode := file.Mode()
-	newFile, err := e.FileSystem.OpenFile(savedLocation, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	perm := mode.Perm()
+	if perm == 0 {
+		perm = 0644
+	}
+
+	newFile, err := e.FileSystem.OpenFile(savedLocation, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
 	if err != nil {
 		return OpenFileError{savedLocation, err}
 	}
 	defer newFile.Close()
+	limit.written = append(limit.written, savedLocation)
 
-	_, err = io.Copy(newFile, contents)
-	if err != nil {
+	written, err := io.CopyN(newFile, contents, limit.maxSize-limit.size+1)
+	if err != nil && err != io.EOF {
 		return WriteFileError{savedLocation, err}
 	}
 
+	limit.size += written
+	if limit.size > limit.maxSize {
+		return limit.exceeded()
+	}
+
+	err = e.FileSystem.Chmod(savedLocation, perm)
+	if err != nil {
+		return ChangeModeError{savedLocation, err}
+	}
+
 	return nil
 }
+
+func isTar(header []byte) bool {
+	return len(header) >= 262 && bytes.Equal(header[257:262], tarMagic)
+}
@@ -1,7 +1,12 @@
 package extractor_test
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"fmt"
 	"io/ioutil"
+	"os"
 	"path"
 
 	. "github.com/onsi/ginkgo"
@@ -34,7 +39,7 @@ var _ = Describe("Extracting", func() {
 		file = "/artifact.jar"
 		destination = "../fixtures/deployadactyl-fixture"
 		af = &afero.Afero{Fs: afero.NewMemMapFs()}
-		extractor = Extractor{logger.DefaultLogger(GinkgoWriter, logging.DEBUG, "extractor_test"), af}
+		extractor = Extractor{Log: logger.DefaultLogger(GinkgoWriter, logging.DEBUG, "extractor_test", logger.TextFormat), FileSystem: af}
 
 		fileBytes, err := ioutil.ReadFile("../fixtures/deployadactyl-fixture.jar")
 		Expect(err).ToNot(HaveOccurred())
@@ -78,12 +83,189 @@ var _ = Describe("Extracting", func() {
 		})
 	})
 
+	for _, fixture := range []string{"deployadactyl-fixture.tar.gz", "deployadactyl-fixture.tar"} {
+		fixture := fixture
+
+		Context("when the artifact is "+fixture, func() {
+			BeforeEach(func() {
+				file = "/" + fixture
+
+				fileBytes, err := ioutil.ReadFile("../fixtures/" + fixture)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(af.WriteFile(file, fileBytes, 0644)).To(Succeed())
+			})
+
+			It("extracts the same layout as the zip", func() {
+				Expect(extractor.Unzip(file, destination, "")).To(Succeed())
+
+				extractedFile, err := af.ReadFile(path.Join(destination, "index.html"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(extractedFile).To(ContainSubstring("public/assets/images/pterodactyl.png"))
+
+				Expect(af.Exists(path.Join(destination, "public/assets/images/pterodactyl.png"))).To(BeTrue())
+				Expect(af.Exists(path.Join(destination, "Staticfile"))).To(BeTrue())
+
+				extractedManifest, err := af.ReadFile(path.Join(destination, "manifest.yml"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(extractedManifest).To(BeEquivalentTo(deployadactylManifest))
+			})
+
+			It("overwrites the manifest when one is provided", func() {
+				manifestContents := "manifestContents-" + randomizer.StringRunes(10)
+				Expect(extractor.Unzip(file, destination, manifestContents)).To(Succeed())
+
+				extractedManifest, err := af.ReadFile(path.Join(destination, "manifest.yml"))
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(extractedManifest).To(BeEquivalentTo(manifestContents))
+			})
+		})
+	}
+
+	Context("when an entry would be written outside of the destination", func() {
+		It("fails a zip without writing outside of the destination", func() {
+			buffer := &bytes.Buffer{}
+			zipWriter := zip.NewWriter(buffer)
+			entry, err := zipWriter.Create("../../evil.txt")
+			Expect(err).ToNot(HaveOccurred())
+			_, err = entry.Write([]byte("evil"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(zipWriter.Close()).To(Succeed())
+
+			Expect(af.WriteFile(file, buffer.Bytes(), 0644)).To(Succeed())
+
+			err = extractor.Unzip(file, destination, "")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("illegal file path in archive: ../../evil.txt"))
+
+			Expect(af.Exists(path.Join(destination, "../../evil.txt"))).To(BeFalse())
+		})
+
+		It("fails a tar without writing outside of the destination", func() {
+			buffer := &bytes.Buffer{}
+			tarWriter := tar.NewWriter(buffer)
+			Expect(tarWriter.WriteHeader(&tar.Header{Name: "../../evil.txt", Mode: 0644, Size: 4, Typeflag: tar.TypeReg})).To(Succeed())
+			_, err := tarWriter.Write([]byte("evil"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tarWriter.Close()).To(Succeed())
+
+			Expect(af.WriteFile(file, buffer.Bytes(), 0644)).To(Succeed())
+
+			err = extractor.Unzip(file, destination, "")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("illegal file path in archive: ../../evil.txt"))
+
+			Expect(af.Exists(path.Join(destination, "../../evil.txt"))).To(BeFalse())
+		})
+	})
+
+	Context("when an entry is executable", func() {
+		It("keeps the mode of every zip entry", func() {
+			fileBytes, err := ioutil.ReadFile("../fixtures/executable-fixture.zip")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(af.WriteFile(file, fileBytes, 0644)).To(Succeed())
+
+			Expect(extractor.Unzip(file, destination, "")).To(Succeed())
+
+			startScript, err := af.Stat(path.Join(destination, "start.sh"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(startScript.Mode().Perm()).To(Equal(os.FileMode(0755)))
+
+			index, err := af.Stat(path.Join(destination, "index.html"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(index.Mode().Perm()).To(Equal(os.FileMode(0644)))
+		})
+
+		It("keeps the mode of every tar entry", func() {
+			buffer := &bytes.Buffer{}
+			tarWriter := tar.NewWriter(buffer)
+			Expect(tarWriter.WriteHeader(&tar.Header{Name: "start.sh", Mode: 0755, Size: 4, Typeflag: tar.TypeReg})).To(Succeed())
+			_, err := tarWriter.Write([]byte("exit"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tarWriter.Close()).To(Succeed())
+
+			Expect(af.WriteFile(file, buffer.Bytes(), 0644)).To(Succeed())
+
+			Expect(extractor.Unzip(file, destination, "")).To(Succeed())
+
+			startScript, err := af.Stat(path.Join(destination, "start.sh"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(startScript.Mode().Perm()).To(Equal(os.FileMode(0755)))
+		})
+	})
+
+	Context("when an archive exceeds the size limit", func() {
+		var zipArchive = func(sizes ...int) []byte {
+			buffer := &bytes.Buffer{}
+			zipWriter := zip.NewWriter(buffer)
+			for i, size := range sizes {
+				entry, err := zipWriter.Create(fmt.Sprintf("file-%d.txt", i))
+				Expect(err).ToNot(HaveOccurred())
+				_, err = entry.Write(bytes.Repeat([]byte("a"), size))
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(zipWriter.Close()).To(Succeed())
+
+			return buffer.Bytes()
+		}
+
+		It("fails and removes the files it already wrote", func() {
+			extractor.MaxSize = 100
+			Expect(af.WriteFile(file, zipArchive(60, 60), 0644)).To(Succeed())
+
+			err := extractor.Unzip(file, destination, "")
+			Expect(err).To(MatchError(ArchiveTooLargeError{100, DefaultMaxFiles}))
+
+			Expect(af.Exists(path.Join(destination, "file-0.txt"))).To(BeFalse())
+			Expect(af.Exists(path.Join(destination, "file-1.txt"))).To(BeFalse())
+		})
+
+		It("fails a tar that exceeds the size limit", func() {
+			extractor.MaxSize = 100
+			buffer := &bytes.Buffer{}
+			tarWriter := tar.NewWriter(buffer)
+			Expect(tarWriter.WriteHeader(&tar.Header{Name: "big.txt", Mode: 0644, Size: 200, Typeflag: tar.TypeReg})).To(Succeed())
+			_, err := tarWriter.Write(bytes.Repeat([]byte("a"), 200))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tarWriter.Close()).To(Succeed())
+
+			Expect(af.WriteFile(file, buffer.Bytes(), 0644)).To(Succeed())
+
+			err = extractor.Unzip(file, destination, "")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("archive exceeds size limit"))
+
+			Expect(af.Exists(path.Join(destination, "big.txt"))).To(BeFalse())
+		})
+
+		It("fails when there are more files than the file limit", func() {
+			extractor.MaxFiles = 2
+			Expect(af.WriteFile(file, zipArchive(1, 1, 1), 0644)).To(Succeed())
+
+			err := extractor.Unzip(file, destination, "")
+			Expect(err).To(MatchError(ArchiveTooLargeError{DefaultMaxSize, 2}))
+
+			Expect(af.Exists(path.Join(destination, "file-0.txt"))).To(BeFalse())
+		})
+
+		It("extracts an archive that is exactly at the limits", func() {
+			extractor.MaxSize = 120
+			extractor.MaxFiles = 2
+			Expect(af.WriteFile(file, zipArchive(60, 60), 0644)).To(Succeed())
+
+			Expect(extractor.Unzip(file, destination, "")).To(Succeed())
+
+			Expect(af.Exists(path.Join(destination, "file-1.txt"))).To(BeTrue())
+		})
+	})
+
 	It("can not unzip an invalid file", func() {
 		file := "../fixtures/bad-deployadactyl-fixture.tgz"
 		destination = "../fixtures/bad-deployadactyl-fixture"
 		af = &afero.Afero{Fs: afero.NewMemMapFs()}
 
-		extractor := Extractor{logger.DefaultLogger(GinkgoWriter, logging.DEBUG, "extractor_test"), af}
+		extractor := Extractor{Log: logger.DefaultLogger(GinkgoWriter, logging.DEBUG, "extractor_test", logger.TextFormat), FileSystem: af}
 
 		Expect(extractor.Unzip(file, destination, "")).ToNot(Succeed())
 	})
@@ -0,0 +1,67 @@
+package artifetcher
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+)
+
+// DefaultProgressInterval is the least time between two progress lines when no ProgressInterval is set.
+const DefaultProgressInterval = 5 * time.Second
+
+// progressWriter counts the bytes of a download and writes a progress line to out at most once every interval.
+// total is the size of the download, which is unknown when it is not positive.
+type progressWriter struct {
+	out        io.Writer
+	total      int64
+	written    int64
+	interval   time.Duration
+	lastReport time.Time
+	clock      I.Clock
+}
+
+func newProgressWriter(out io.Writer, total int64, interval time.Duration, clock I.Clock) *progressWriter {
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+
+	return &progressWriter{
+		out:        out,
+		total:      total,
+		interval:   interval,
+		lastReport: clock.Now(),
+		clock:      clock,
+	}
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+
+	if p.clock.Now().Sub(p.lastReport) >= p.interval {
+		p.report()
+	}
+
+	return len(b), nil
+}
+
+// done writes the last progress line once the download has finished.
+func (p *progressWriter) done() {
+	p.report()
+}
+
+func (p *progressWriter) report() {
+	p.lastReport = p.clock.Now()
+
+	if p.total > 0 {
+		fmt.Fprintf(p.out, "downloaded %d of %d bytes (%d%%)\n", p.written, p.total, p.written*100/p.total)
+	} else {
+		fmt.Fprintf(p.out, "downloaded %d bytes\n", p.written)
+	}
+
+	if flusher, ok := p.out.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
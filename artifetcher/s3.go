@@ -0,0 +1,269 @@
+package artifetcher
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// DefaultS3Region is the region of an s3:// URL when no S3Region is set.
+const DefaultS3Region = "us-east-1"
+
+// emptyPayloadHash is the SHA256 of the empty body of a GET request.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// s3VirtualHost matches the host of an https S3 virtual-host URL, such as bucket.s3.us-west-2.amazonaws.com.
+// The second group is the region of the bucket when the host has one.
+var s3VirtualHost = regexp.MustCompile(`^(.+)\.s3(?:[.-]([a-z0-9-]+))?\.amazonaws\.com$`)
+
+// S3Credentials are the AWS credentials an S3 request is signed with.
+type S3Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// EnvironmentS3Credentials returns a function that reads S3Credentials from
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+func EnvironmentS3Credentials(getenv func(string) string) func() (S3Credentials, error) {
+	return func() (S3Credentials, error) {
+		credentials := S3Credentials{
+			AccessKeyID:     getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    getenv("AWS_SESSION_TOKEN"),
+		}
+
+		if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+			return S3Credentials{}, MissingS3CredentialsError{"the environment"}
+		}
+
+		return credentials, nil
+	}
+}
+
+// SharedFileS3Credentials returns a function that reads S3Credentials from the AWS shared credentials file.
+// The file is AWS_SHARED_CREDENTIALS_FILE or ~/.aws/credentials and the profile is AWS_PROFILE or default.
+func SharedFileS3Credentials(getenv func(string) string, fileSystem *afero.Afero) func() (S3Credentials, error) {
+	return func() (S3Credentials, error) {
+		filename := getenv("AWS_SHARED_CREDENTIALS_FILE")
+		if filename == "" {
+			filename = filepath.Join(getenv("HOME"), ".aws", "credentials")
+		}
+
+		profile := getenv("AWS_PROFILE")
+		if profile == "" {
+			profile = "default"
+		}
+
+		file, err := fileSystem.ReadFile(filename)
+		if err != nil {
+			return S3Credentials{}, ReadS3CredentialsError{filename, err}
+		}
+
+		credentials := parseSharedCredentials(file, profile)
+		if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+			return S3Credentials{}, MissingS3CredentialsError{fmt.Sprintf("profile %s of %s", profile, filename)}
+		}
+
+		return credentials, nil
+	}
+}
+
+// parseSharedCredentials returns the credentials of profile in the ini formatted shared credentials file.
+func parseSharedCredentials(file []byte, profile string) S3Credentials {
+	var (
+		credentials S3Credentials
+		inProfile   bool
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(file))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inProfile = strings.TrimSpace(line[1:len(line)-1]) == profile
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if !inProfile || len(parts) != 2 {
+			continue
+		}
+
+		value := strings.TrimSpace(parts[1])
+		switch strings.TrimSpace(parts[0]) {
+		case "aws_access_key_id":
+			credentials.AccessKeyID = value
+		case "aws_secret_access_key":
+			credentials.SecretAccessKey = value
+		case "aws_session_token":
+			credentials.SessionToken = value
+		}
+	}
+
+	return credentials
+}
+
+// s3Request returns a GET request for an S3 URL and the region of its bucket.
+// An s3://bucket/key URL is sent to S3Endpoint when it is set and to the AWS endpoint of the region otherwise.
+// ok is false when the URL is not an S3 URL.
+func (a *Artifetcher) s3Request(rawurl string) (req *http.Request, region string, ok bool, err error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, "", false, nil
+	}
+
+	region = a.S3Region
+	if region == "" {
+		region = DefaultS3Region
+	}
+
+	switch {
+	case u.Scheme == "s3":
+		if u.Host == "" || strings.TrimPrefix(u.Path, "/") == "" {
+			return nil, "", true, InvalidS3URLError{rawurl}
+		}
+
+		endpoint := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/", u.Host, region)
+		key := strings.TrimPrefix(u.Path, "/")
+		if a.S3Endpoint != "" {
+			endpoint = strings.TrimSuffix(a.S3Endpoint, "/") + "/" + u.Host + "/"
+		}
+
+		u, err = url.Parse(endpoint)
+		if err != nil {
+			return nil, "", true, InvalidS3URLError{rawurl}
+		}
+		u.Path += key
+
+	case u.Scheme == "https" && s3VirtualHost.MatchString(u.Host):
+		if hostRegion := s3VirtualHost.FindStringSubmatch(u.Host)[2]; hostRegion != "" {
+			region = hostRegion
+		}
+
+	default:
+		return nil, "", false, nil
+	}
+
+	u.RawPath = s3Escape(u.Path, false)
+
+	req, err = http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, "", true, ArtifactoryRequestError{err}
+	}
+
+	return req, region, true, nil
+}
+
+// signS3Request signs req for S3 with AWS Signature Version 4.
+// The host and every header already set on req are signed.
+func signS3Request(req *http.Request, credentials S3Credentials, region string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", amzDate[:8], region)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3Escape(req.URL.Path, false),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256(canonicalRequest),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), amzDate[:8])
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		credentials.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func s3CanonicalQuery(query url.Values) string {
+	pairs := []string{}
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(name, true)+"="+s3Escape(value, true))
+		}
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, "&")
+}
+
+// s3Escape percent-encodes every byte of s that is not unreserved, leaving / as it is unless encodeSlash is set.
+func s3Escape(s string, encodeSlash bool) string {
+	var escaped bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			escaped.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			escaped.WriteByte(c)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+
+	return escaped.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func hexSHA256(data string) string {
+	hash := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(hash[:])
+}
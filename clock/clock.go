@@ -0,0 +1,22 @@
+// Package clock is used for telling and waiting on the time.
+package clock
+
+import "time"
+
+// Clock tells the time with the time package.
+type Clock struct{}
+
+// Now returns the current time.
+func (c Clock) Now() time.Time {
+	return time.Now()
+}
+
+// After returns a channel that receives the current time once duration has passed.
+func (c Clock) After(duration time.Duration) <-chan time.Time {
+	return time.After(duration)
+}
+
+// Sleep pauses the current goroutine for duration.
+func (c Clock) Sleep(duration time.Duration) {
+	time.Sleep(duration)
+}
@@ -0,0 +1,39 @@
+package clock_test
+
+import (
+	"time"
+
+	. "github.com/compozed/deployadactyl/clock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Clock", func() {
+	var clock Clock
+
+	Describe("Now", func() {
+		It("returns the current time", func() {
+			Expect(clock.Now()).To(BeTemporally("~", time.Now(), time.Second))
+		})
+	})
+
+	Describe("After", func() {
+		It("receives the time once the duration has passed", func() {
+			start := time.Now()
+
+			Eventually(clock.After(10 * time.Millisecond)).Should(Receive())
+			Expect(time.Since(start)).To(BeNumerically(">=", 10*time.Millisecond))
+		})
+	})
+
+	Describe("Sleep", func() {
+		It("returns once the duration has passed", func() {
+			start := time.Now()
+
+			clock.Sleep(10 * time.Millisecond)
+
+			Expect(time.Since(start)).To(BeNumerically(">=", 10*time.Millisecond))
+		})
+	})
+})
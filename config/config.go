@@ -2,38 +2,180 @@
 package config
 
 import (
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/cloudfoundry-incubator/candiedyaml"
 	"github.com/compozed/deployadactyl/geterrors"
+	"github.com/compozed/deployadactyl/logger"
 )
 
-const defaultConfigPath = "./config.yml"
+const (
+	defaultConfigPath     = "./config.yml"
+	defaultMinTLSVersion  = tls.VersionTLS12
+	defaultHistorySize    = 100
+	defaultResultSentinel = "__DEPLOYADACTYL_RESULT__"
+	defaultAuthRealm      = "deployadactyl"
+	defaultJobTTL         = time.Hour
+
+	defaultArtifactCacheSize  = 1 << 30
+	defaultMaxRequestBodySize = 2 << 30
+	defaultDeployLogRetention = 7 * 24 * time.Hour
+)
+
+// The sources S3 credentials can be read from.
+const (
+	S3CredentialsEnvironment = "environment"
+	S3CredentialsSharedFile  = "shared_file"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
 
 // Config is a representation of a config yaml. It can contain multiple Environments.
+// MinTLSVersion is the minimum TLS version used by every outbound connection.
+// HistorySize is the number of completed deployments kept in the deploy history.
+// HistoryFile is where the deploy history is saved so that it survives a restart. The history is only kept in memory when it is empty.
+// ResultSentinel prefixes the JSON result trailer written as the last line of every deploy response.
+// AuthRealm is the realm of the WWW-Authenticate header of a deploy that is rejected for missing basic auth.
+// DeployDebounce is how long a deploy is held so that a newer deploy of the same application can supersede it.
+// JobTTL is how long a finished asynchronous deploy is kept before it is dropped.
+// RedeployWindow is how long after a deploy completes that an identical deploy is skipped. Identical deploys are never skipped when it is zero.
+// MaxConcurrentDeploys is the number of deploys that run at once, with up to MaxQueuedDeploys more waiting. Deploys are not limited when it is zero.
+// EnableFailureInjection allows requests to force a deploy stage to fail and must only be set for chaos testing.
+// ResultSigningKey signs every DeployResult stored in the deploy history. Results are not signed when it is empty.
+// LogFormat is the format of the log lines, either text or json.
+// DefaultFoundationTimeout is the Timeout of every Environment that does not set its own.
+// DefaultLoginTimeout is the LoginTimeout of every Environment that does not set its own.
+// S3Region is the region of the buckets of s3:// artifact URLs.
+// S3CredentialSource is where the S3 credentials are read from, either S3CredentialsEnvironment or S3CredentialsSharedFile.
+// SlackWebhookURL is the Slack webhook of every Environment that does not set its own, and SlackTemplate overrides the Slack message.
+// ArtifactCacheDirectory is where downloaded artifacts are cached, up to ArtifactCacheSize bytes. Artifacts are not cached when it is empty.
+// DeployLogDirectory is where the output of every deploy is written, kept for the DeployLogRetention. Deploys are not logged when it is empty.
+// MaxRequestBodySize is the most bytes a deploy request body can have.
 type Config struct {
-	Username     string
-	Password     string
-	Environments map[string]Environment
-	Port         int
+	Username                 string
+	Password                 string
+	Environments             map[string]Environment
+	Port                     int
+	MinTLSVersion            uint16
+	HistorySize              int
+	HistoryFile              string
+	ResultSentinel           string
+	AuthRealm                string
+	DeployDebounce           time.Duration
+	JobTTL                   time.Duration
+	RedeployWindow           time.Duration
+	MaxConcurrentDeploys     int
+	MaxQueuedDeploys         int
+	EnableFailureInjection   bool
+	ResultSigningKey         string
+	LogFormat                string
+	DefaultFoundationTimeout time.Duration
+	DefaultLoginTimeout      time.Duration
+	S3Region                 string
+	S3CredentialSource       string
+	SlackWebhookURL          string
+	SlackTemplate            string
+	ArtifactCacheDirectory   string
+	ArtifactCacheSize        int64
+	DeployLogDirectory       string
+	DeployLogRetention       time.Duration
+	MaxRequestBodySize       int64
 }
 
 // Environment is representation of a single environment configuration.
+// Timeout limits how long the foundations of the environment are given to respond to a precheck or a Cloud Foundry command.
+// It is parsed from the timeout key, and Deployadactyl's own timeouts are used when it is zero.
+// LoginTimeout limits how long logging in to a foundation can take, so that an unreachable foundation fails the deploy
+// long before the Timeout has passed. It is parsed from the login_timeout key, and the Timeout is used when it is zero.
+// Username and Password replace the global CF_USERNAME and CF_PASSWORD for deploys to the environment when they are set.
+// CustomDomains are the domains the route of a deployed application is mapped on as well as the Domain.
+// ClientCert and ClientKey are the PEM files of the client certificate presented to foundations that require mutual TLS.
+// The ClientCertificate is loaded from them when the config is read.
+// SmokeTestURL is requested after every successful push to the environment, and the deploy is rolled back unless it responds
+// with the SmokeTestStatus, or 200 when it is zero. There is no smoke test when it is empty.
 type Environment struct {
 	Name                       string
 	Domain                     string
+	CustomDomains              []string `yaml:"custom_domains,flow" json:"custom_domains"`
 	Foundations                []string `yaml:",flow"`
 	Authenticate               bool
-	SkipSSL                    bool `yaml:"skip_ssl"`
-	DisableFirstDeployRollback bool `yaml:"disable_first_deploy_rollback"`
+	SkipSSL                    bool `yaml:"skip_ssl" json:"skip_ssl"`
+	DisableFirstDeployRollback bool `yaml:"disable_first_deploy_rollback" json:"disable_first_deploy_rollback"`
+	DisableRollback            bool `yaml:"disable_rollback" json:"disable_rollback"`
 	Instances                  uint16
+	OrgTemplate                string        `yaml:"org_template" json:"org_template"`
+	SpaceTemplate              string        `yaml:"space_template" json:"space_template"`
+	TokenURL                   string        `yaml:"token_url" json:"token_url"`
+	ClientID                   string        `yaml:"client_id" json:"client_id"`
+	ClientSecret               string        `yaml:"client_secret" json:"client_secret"`
+	RequiredEnvVars            []string      `yaml:"required_env_vars,flow" json:"required_env_vars"`
+	MaxRoutesPerApp            int           `yaml:"max_routes_per_app" json:"max_routes_per_app"`
+	PreflightPush              bool          `yaml:"preflight_push" json:"preflight_push"`
+	WebhookURL                 string        `yaml:"webhook_url" json:"webhook_url"`
+	SlackWebhookURL            string        `yaml:"slack_webhook_url" json:"slack_webhook_url"`
+	SmokeTestURL               string        `yaml:"smoke_test_url" json:"smoke_test_url"`
+	SmokeTestStatus            int           `yaml:"smoke_test_status" json:"smoke_test_status"`
+	Timeout                    time.Duration `yaml:"-" json:"-"`
+	LoginTimeout               time.Duration `yaml:"-" json:"-"`
+	Username                   string        `yaml:"username" json:"username"`
+	Password                   string        `yaml:"password" json:"password"`
+	Retention                  int           `yaml:"retention" json:"retention"`
+
+	ClientCert        string           `yaml:"client_cert" json:"client_cert"`
+	ClientKey         string           `yaml:"client_key" json:"client_key"`
+	ClientCertificate *tls.Certificate `yaml:"-" json:"-"`
 }
 
 type configYaml struct {
-	Environments []Environment `yaml:",flow"`
+	Environments   []Environment `yaml:",flow"`
+	MinTLSVersion  string        `yaml:"min_tls_version" json:"min_tls_version"`
+	HistorySize    int           `yaml:"history_size" json:"history_size"`
+	HistoryFile    string        `yaml:"history_file" json:"history_file"`
+	ResultSentinel string        `yaml:"result_sentinel" json:"result_sentinel"`
+	AuthRealm      string        `yaml:"auth_realm" json:"auth_realm"`
+	DeployDebounce string        `yaml:"deploy_debounce" json:"deploy_debounce"`
+	JobTTL         string        `yaml:"job_ttl" json:"job_ttl"`
+	RedeployWindow string        `yaml:"redeploy_window" json:"redeploy_window"`
+
+	MaxConcurrentDeploys int `yaml:"max_concurrent_deploys" json:"max_concurrent_deploys"`
+	MaxQueuedDeploys     int `yaml:"max_queued_deploys" json:"max_queued_deploys"`
+
+	DefaultFoundationTimeout string `yaml:"default_foundation_timeout" json:"default_foundation_timeout"`
+	DefaultLoginTimeout      string `yaml:"default_login_timeout" json:"default_login_timeout"`
+	S3Region                 string `yaml:"s3_region" json:"s3_region"`
+	S3CredentialSource       string `yaml:"s3_credential_source" json:"s3_credential_source"`
+	SlackWebhookURL          string `yaml:"slack_webhook_url" json:"slack_webhook_url"`
+	SlackTemplate            string `yaml:"slack_template" json:"slack_template"`
+
+	ArtifactCacheDirectory string `yaml:"artifact_cache_directory" json:"artifact_cache_directory"`
+	ArtifactCacheSize      int    `yaml:"artifact_cache_size" json:"artifact_cache_size"`
+
+	DeployLogDirectory string `yaml:"deploy_log_directory" json:"deploy_log_directory"`
+	DeployLogRetention string `yaml:"deploy_log_retention" json:"deploy_log_retention"`
+
+	MaxRequestBodySize int `yaml:"max_request_body_size" json:"max_request_body_size"`
+}
+
+// environmentTimeoutYaml holds the timeouts of each environment as they are written in the config file
+// because they cannot be unmarshaled straight into the time.Duration of an Environment.
+type environmentTimeoutYaml struct {
+	Environments []struct {
+		Timeout      string `yaml:"timeout" json:"timeout"`
+		LoginTimeout string `yaml:"login_timeout" json:"login_timeout"`
+	} `yaml:",flow"`
 }
 
 type foundationYaml struct {
@@ -42,24 +184,46 @@ type foundationYaml struct {
 
 // Default returns a new Config struct with information from environment variables and the default config file (./config.yml).
 func Default(getenv func(string) string) (Config, error) {
-	environments, err := getEnvironmentsFromFile(defaultConfigPath)
+	fileConfig, err := getConfigFromFile(getenv, defaultConfigPath)
 	if err != nil {
 		return Config{}, err
 	}
-	return createConfig(getenv, environments)
+	return createConfig(getenv, fileConfig)
+}
+
+// CustomMulti returns a new Config struct with information from environment variables and several custom config files.
+// The environments of every file are merged. An environment can only be defined in one of the files.
+// The other settings take their value from the last file that sets them.
+func CustomMulti(getenv func(string) string, configPaths ...string) (Config, error) {
+	fileConfig, err := getConfigFromFiles(getenv, configPaths)
+	if err != nil {
+		return Config{}, err
+	}
+	return createConfig(getenv, fileConfig)
 }
 
 // Custom returns a new Config struct with information from environment variables and a custom config file.
+// The config file is read as JSON when it has a .json extension and as YAML otherwise.
+// Every ${VAR} in the config file is replaced with the value of VAR from getenv before it is parsed.
 func Custom(getenv func(string) string, configPath string) (Config, error) {
-	environments, err := getEnvironmentsFromFile(configPath)
+	fileConfig, err := getConfigFromFile(getenv, configPath)
 	if err != nil {
 		return Config{}, err
 	}
-	return createConfig(getenv, environments)
+	return createConfig(getenv, fileConfig)
 }
 
-func createConfig(getenv func(string) string, environments map[string]Environment) (Config, error) {
-	getter := geterrors.WrapFunc(getenv)
+func createConfig(getenv func(string) string, fileConfig Config) (Config, error) {
+	credentials := map[string]string{}
+	for _, key := range []string{"CF_USERNAME", "CF_PASSWORD"} {
+		credential, err := getCredentialFromEnv(getenv, key)
+		if err != nil {
+			return Config{}, err
+		}
+		credentials[key] = credential
+	}
+
+	getter := geterrors.WrapFunc(func(key string) string { return credentials[key] })
 
 	username := getter.Get("CF_USERNAME")
 	password := getter.Get("CF_PASSWORD")
@@ -73,15 +237,43 @@ func createConfig(getenv func(string) string, environments map[string]Environmen
 		return Config{}, err
 	}
 
-	config := Config{
-		Username:     username,
-		Password:     password,
-		Port:         port,
-		Environments: environments,
+	enableFailureInjection, err := getEnableFailureInjectionFromEnv(getenv)
+	if err != nil {
+		return Config{}, err
 	}
+
+	logFormat, err := getLogFormatFromEnv(getenv)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config := fileConfig
+	config.Username = username
+	config.Password = password
+	config.Port = port
+	config.EnableFailureInjection = enableFailureInjection
+	config.ResultSigningKey = getenv("RESULT_SIGNING_KEY")
+	config.LogFormat = logFormat
+
 	return config, nil
 }
 
+// getCredentialFromEnv reads the credential from the file named by key_FILE, such as a mounted secret, when it is set.
+// The trailing whitespace of the file is trimmed. The credential is read from key when key_FILE is not set.
+func getCredentialFromEnv(getenv func(string) string, key string) (string, error) {
+	filename := getenv(key + "_FILE")
+	if filename == "" {
+		return getenv(key), nil
+	}
+
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", CredentialFileError{key + "_FILE", filename, err}
+	}
+
+	return strings.TrimRightFunc(string(contents), unicode.IsSpace), nil
+}
+
 func getPortFromEnv(getenv func(string) string) (int, error) {
 	envPort := getenv("PORT")
 	if envPort == "" {
@@ -96,23 +288,401 @@ func getPortFromEnv(getenv func(string) string) (int, error) {
 	return cfgPort, nil
 }
 
-func getEnvironmentsFromFile(filename string) (map[string]Environment, error) {
+func getEnableFailureInjectionFromEnv(getenv func(string) string) (bool, error) {
+	envEnable := getenv("ENABLE_FAILURE_INJECTION")
+	if envEnable == "" {
+		return false, nil
+	}
+
+	enable, err := strconv.ParseBool(envEnable)
+	if err != nil {
+		return false, fmt.Errorf("cannot parse $ENABLE_FAILURE_INJECTION: %s: %s", envEnable, err)
+	}
+
+	return enable, nil
+}
+
+func getLogFormatFromEnv(getenv func(string) string) (string, error) {
+	logFormat := getenv("LOG_FORMAT")
+	switch logFormat {
+	case "":
+		return logger.TextFormat, nil
+	case logger.TextFormat, logger.JSONFormat:
+		return logFormat, nil
+	default:
+		return "", InvalidLogFormatError{logFormat}
+	}
+}
+
+func getConfigFromFile(getenv func(string) string, filename string) (Config, error) {
+	return getConfigFromFiles(getenv, []string{filename})
+}
+
+func getConfigFromFiles(getenv func(string) string, filenames []string) (Config, error) {
+	var (
+		foundationConfig configYaml
+		timeoutConfig    environmentTimeoutYaml
+		environmentFiles = map[string]string{}
+	)
+
+	for _, filename := range filenames {
+		fileFoundationConfig, fileTimeoutConfig, err := readConfigFile(getenv, filename)
+		if err != nil {
+			return Config{}, err
+		}
+
+		fileEnvironments := map[string]bool{}
+		for _, environment := range fileFoundationConfig.Environments {
+			key := strings.ToLower(environment.Name)
+			if otherFile, ok := environmentFiles[key]; ok && !fileEnvironments[key] {
+				return Config{}, DuplicateEnvironmentError{environment.Name, otherFile, filename}
+			}
+			environmentFiles[key] = filename
+			fileEnvironments[key] = true
+		}
+
+		foundationConfig = mergeConfigYaml(foundationConfig, fileFoundationConfig)
+		timeoutConfig.Environments = append(timeoutConfig.Environments, fileTimeoutConfig.Environments...)
+	}
+
+	defaultFoundationTimeout, err := getTimeout("default_foundation_timeout", foundationConfig.DefaultFoundationTimeout, 0)
+	if err != nil {
+		return Config{}, err
+	}
+
+	defaultLoginTimeout, err := getTimeout("default_login_timeout", foundationConfig.DefaultLoginTimeout, 0)
+	if err != nil {
+		return Config{}, err
+	}
+
+	environments, err := getEnvironments(foundationConfig, timeoutConfig, defaultFoundationTimeout, defaultLoginTimeout)
+	if err != nil {
+		return Config{}, err
+	}
+
+	minTLSVersion, err := getMinTLSVersion(foundationConfig.MinTLSVersion)
+	if err != nil {
+		return Config{}, err
+	}
+
+	historySize, err := getHistorySize(foundationConfig.HistorySize)
+	if err != nil {
+		return Config{}, err
+	}
+
+	resultSentinel := foundationConfig.ResultSentinel
+	if resultSentinel == "" {
+		resultSentinel = defaultResultSentinel
+	}
+
+	authRealm := foundationConfig.AuthRealm
+	if authRealm == "" {
+		authRealm = defaultAuthRealm
+	}
+
+	deployDebounce, err := getDeployDebounce(foundationConfig.DeployDebounce)
+	if err != nil {
+		return Config{}, err
+	}
+
+	jobTTL, err := getJobTTL(foundationConfig.JobTTL)
+	if err != nil {
+		return Config{}, err
+	}
+
+	redeployWindow, err := getRedeployWindow(foundationConfig.RedeployWindow)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if foundationConfig.MaxConcurrentDeploys < 0 {
+		return Config{}, InvalidDeployLimitError{"max_concurrent_deploys", foundationConfig.MaxConcurrentDeploys}
+	}
+	if foundationConfig.MaxQueuedDeploys < 0 {
+		return Config{}, InvalidDeployLimitError{"max_queued_deploys", foundationConfig.MaxQueuedDeploys}
+	}
+
+	s3CredentialSource, err := getS3CredentialSource(foundationConfig.S3CredentialSource)
+	if err != nil {
+		return Config{}, err
+	}
+
+	artifactCacheSize, err := getArtifactCacheSize(foundationConfig.ArtifactCacheSize)
+	if err != nil {
+		return Config{}, err
+	}
+
+	deployLogRetention, err := getDeployLogRetention(foundationConfig.DeployLogRetention)
+	if err != nil {
+		return Config{}, err
+	}
+
+	maxRequestBodySize, err := getMaxRequestBodySize(foundationConfig.MaxRequestBodySize)
+	if err != nil {
+		return Config{}, err
+	}
+
+	return Config{
+		Environments:   environments,
+		MinTLSVersion:  minTLSVersion,
+		HistorySize:    historySize,
+		HistoryFile:    foundationConfig.HistoryFile,
+		ResultSentinel: resultSentinel,
+		AuthRealm:      authRealm,
+		DeployDebounce: deployDebounce,
+		JobTTL:         jobTTL,
+		RedeployWindow: redeployWindow,
+
+		MaxConcurrentDeploys:     foundationConfig.MaxConcurrentDeploys,
+		MaxQueuedDeploys:         foundationConfig.MaxQueuedDeploys,
+		DefaultFoundationTimeout: defaultFoundationTimeout,
+		DefaultLoginTimeout:      defaultLoginTimeout,
+		S3Region:                 foundationConfig.S3Region,
+		S3CredentialSource:       s3CredentialSource,
+		SlackWebhookURL:          foundationConfig.SlackWebhookURL,
+		SlackTemplate:            foundationConfig.SlackTemplate,
+		ArtifactCacheDirectory:   foundationConfig.ArtifactCacheDirectory,
+		ArtifactCacheSize:        artifactCacheSize,
+		DeployLogDirectory:       foundationConfig.DeployLogDirectory,
+		DeployLogRetention:       deployLogRetention,
+		MaxRequestBodySize:       maxRequestBodySize,
+	}, nil
+}
+
+func readConfigFile(getenv func(string) string, filename string) (configYaml, environmentTimeoutYaml, error) {
 	file, err := ioutil.ReadFile(filename)
 	if err != nil {
-		return nil, err
+		return configYaml{}, environmentTimeoutYaml{}, err
 	}
 
-	foundationConfig, err := parseYamlFromBody(file)
+	file, err = interpolate(getenv, file)
 	if err != nil {
-		return nil, err
+		return configYaml{}, environmentTimeoutYaml{}, err
 	}
 
+	unmarshal := getUnmarshaler(filename)
+
+	foundationConfig, err := parseConfigFromBody(file, unmarshal)
+	if err != nil {
+		return configYaml{}, environmentTimeoutYaml{}, err
+	}
+
+	var timeoutConfig environmentTimeoutYaml
+	err = unmarshal(file, &timeoutConfig)
+	if err != nil {
+		return configYaml{}, environmentTimeoutYaml{}, err
+	}
+
+	return foundationConfig, timeoutConfig, nil
+}
+
+// mergeConfigYaml appends the environments of next to the environments of config.
+// The other fields take the value from next unless it is not set.
+func mergeConfigYaml(config, next configYaml) configYaml {
+	config.Environments = append(config.Environments, next.Environments...)
+
+	if next.MinTLSVersion != "" {
+		config.MinTLSVersion = next.MinTLSVersion
+	}
+	if next.HistorySize != 0 {
+		config.HistorySize = next.HistorySize
+	}
+	if next.HistoryFile != "" {
+		config.HistoryFile = next.HistoryFile
+	}
+	if next.ResultSentinel != "" {
+		config.ResultSentinel = next.ResultSentinel
+	}
+	if next.AuthRealm != "" {
+		config.AuthRealm = next.AuthRealm
+	}
+	if next.DeployDebounce != "" {
+		config.DeployDebounce = next.DeployDebounce
+	}
+	if next.JobTTL != "" {
+		config.JobTTL = next.JobTTL
+	}
+	if next.RedeployWindow != "" {
+		config.RedeployWindow = next.RedeployWindow
+	}
+	if next.MaxConcurrentDeploys != 0 {
+		config.MaxConcurrentDeploys = next.MaxConcurrentDeploys
+	}
+	if next.MaxQueuedDeploys != 0 {
+		config.MaxQueuedDeploys = next.MaxQueuedDeploys
+	}
+	if next.DefaultFoundationTimeout != "" {
+		config.DefaultFoundationTimeout = next.DefaultFoundationTimeout
+	}
+	if next.DefaultLoginTimeout != "" {
+		config.DefaultLoginTimeout = next.DefaultLoginTimeout
+	}
+	if next.S3Region != "" {
+		config.S3Region = next.S3Region
+	}
+	if next.S3CredentialSource != "" {
+		config.S3CredentialSource = next.S3CredentialSource
+	}
+	if next.SlackWebhookURL != "" {
+		config.SlackWebhookURL = next.SlackWebhookURL
+	}
+	if next.SlackTemplate != "" {
+		config.SlackTemplate = next.SlackTemplate
+	}
+	if next.ArtifactCacheDirectory != "" {
+		config.ArtifactCacheDirectory = next.ArtifactCacheDirectory
+	}
+	if next.ArtifactCacheSize != 0 {
+		config.ArtifactCacheSize = next.ArtifactCacheSize
+	}
+	if next.MaxRequestBodySize != 0 {
+		config.MaxRequestBodySize = next.MaxRequestBodySize
+	}
+	if next.DeployLogDirectory != "" {
+		config.DeployLogDirectory = next.DeployLogDirectory
+	}
+	if next.DeployLogRetention != "" {
+		config.DeployLogRetention = next.DeployLogRetention
+	}
+
+	return config
+}
+
+func getDeployDebounce(debounce string) (time.Duration, error) {
+	if debounce == "" {
+		return 0, nil
+	}
+
+	deployDebounce, err := time.ParseDuration(debounce)
+	if err != nil || deployDebounce < 0 {
+		return 0, InvalidDeployDebounceError{debounce}
+	}
+
+	return deployDebounce, nil
+}
+
+func getRedeployWindow(window string) (time.Duration, error) {
+	if window == "" {
+		return 0, nil
+	}
+
+	redeployWindow, err := time.ParseDuration(window)
+	if err != nil || redeployWindow < 0 {
+		return 0, InvalidRedeployWindowError{window}
+	}
+
+	return redeployWindow, nil
+}
+
+// getTimeout parses the timeout set by key, returning defaultTimeout when it is not set.
+func getTimeout(key, timeout string, defaultTimeout time.Duration) (time.Duration, error) {
+	if timeout == "" {
+		return defaultTimeout, nil
+	}
+
+	duration, err := time.ParseDuration(timeout)
+	if err != nil || duration <= 0 {
+		return 0, InvalidTimeoutError{key, timeout}
+	}
+
+	return duration, nil
+}
+
+func getJobTTL(ttl string) (time.Duration, error) {
+	if ttl == "" {
+		return defaultJobTTL, nil
+	}
+
+	jobTTL, err := time.ParseDuration(ttl)
+	if err != nil || jobTTL < 0 {
+		return 0, InvalidJobTTLError{ttl}
+	}
+
+	return jobTTL, nil
+}
+
+func getS3CredentialSource(source string) (string, error) {
+	switch source {
+	case "":
+		return S3CredentialsEnvironment, nil
+	case S3CredentialsEnvironment, S3CredentialsSharedFile:
+		return source, nil
+	default:
+		return "", InvalidS3CredentialSourceError{source}
+	}
+}
+
+func getHistorySize(size int) (int, error) {
+	if size == 0 {
+		return defaultHistorySize, nil
+	}
+
+	if size < 0 {
+		return 0, InvalidHistorySizeError{size}
+	}
+
+	return size, nil
+}
+
+func getDeployLogRetention(retention string) (time.Duration, error) {
+	if retention == "" {
+		return defaultDeployLogRetention, nil
+	}
+
+	deployLogRetention, err := time.ParseDuration(retention)
+	if err != nil || deployLogRetention < 0 {
+		return 0, InvalidDeployLogRetentionError{retention}
+	}
+
+	return deployLogRetention, nil
+}
+
+// getArtifactCacheSize converts the artifact_cache_size in megabytes to bytes.
+func getArtifactCacheSize(megabytes int) (int64, error) {
+	if megabytes == 0 {
+		return defaultArtifactCacheSize, nil
+	}
+
+	if megabytes < 0 {
+		return 0, InvalidArtifactCacheSizeError{megabytes}
+	}
+
+	return int64(megabytes) << 20, nil
+}
+
+// getMaxRequestBodySize converts the max_request_body_size in megabytes to bytes.
+func getMaxRequestBodySize(megabytes int) (int64, error) {
+	if megabytes == 0 {
+		return defaultMaxRequestBodySize, nil
+	}
+
+	if megabytes < 0 {
+		return 0, InvalidMaxRequestBodySizeError{megabytes}
+	}
+
+	return int64(megabytes) << 20, nil
+}
+
+func getMinTLSVersion(version string) (uint16, error) {
+	if version == "" {
+		return defaultMinTLSVersion, nil
+	}
+
+	tlsVersion, ok := tlsVersions[version]
+	if !ok {
+		return 0, InvalidTLSVersionError{version}
+	}
+
+	return tlsVersion, nil
+}
+
+func getEnvironments(foundationConfig configYaml, timeoutConfig environmentTimeoutYaml, defaultTimeout, defaultLoginTimeout time.Duration) (map[string]Environment, error) {
 	if foundationConfig.Environments == nil || len(foundationConfig.Environments) == 0 {
 		return nil, EnvironmentsNotSpecifiedError{}
 	}
 
 	environments := map[string]Environment{}
-	for _, environment := range foundationConfig.Environments {
+	for i, environment := range foundationConfig.Environments {
 		if environment.Name == "" || environment.Domain == "" || environment.Foundations == nil || len(environment.Foundations) == 0 {
 			return nil, MissingParameterError{}
 		}
@@ -121,19 +691,87 @@ func getEnvironmentsFromFile(filename string) (map[string]Environment, error) {
 			environment.Instances = 1
 		}
 
+		if environment.Retention < 0 {
+			return nil, InvalidRetentionError{environment.Name, environment.Retention}
+		}
+
+		var timeout, loginTimeout string
+		if i < len(timeoutConfig.Environments) {
+			timeout = timeoutConfig.Environments[i].Timeout
+			loginTimeout = timeoutConfig.Environments[i].LoginTimeout
+		}
+
+		var err error
+		environment.Timeout, err = getTimeout(fmt.Sprintf("timeout for environment %s", environment.Name), timeout, defaultTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		environment.LoginTimeout, err = getTimeout(fmt.Sprintf("login timeout for environment %s", environment.Name), loginTimeout, defaultLoginTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		environment.ClientCertificate, err = getClientCertificate(environment)
+		if err != nil {
+			return nil, err
+		}
+
 		environments[strings.ToLower(environment.Name)] = environment
 	}
 
 	return environments, nil
 }
 
-func parseYamlFromBody(data []byte) (configYaml, error) {
+// getClientCertificate loads the client certificate of the environment from its ClientCert and ClientKey files.
+// An environment without either of them has no client certificate.
+func getClientCertificate(environment Environment) (*tls.Certificate, error) {
+	if environment.ClientCert == "" && environment.ClientKey == "" {
+		return nil, nil
+	}
+	if environment.ClientCert == "" || environment.ClientKey == "" {
+		return nil, MissingClientCertificateError{environment.Name}
+	}
+
+	certificate, err := tls.LoadX509KeyPair(environment.ClientCert, environment.ClientKey)
+	if err != nil {
+		return nil, InvalidClientCertificateError{environment.Name, err}
+	}
+
+	return &certificate, nil
+}
+
+func parseConfigFromBody(data []byte, unmarshal unmarshaler) (configYaml, error) {
 	var foundationConfig configYaml
 
-	err := candiedyaml.Unmarshal(data, &foundationConfig)
+	err := unmarshal(data, &foundationConfig)
 	if err != nil {
-		return configYaml{}, ParseYamlError{err}
+		return configYaml{}, err
 	}
 
 	return foundationConfig, nil
 }
+
+// unmarshaler decodes the contents of a config file.
+type unmarshaler func(data []byte, v interface{}) error
+
+// getUnmarshaler returns a JSON unmarshaler for .json files and a YAML unmarshaler for every other file.
+func getUnmarshaler(filename string) unmarshaler {
+	if strings.ToLower(filepath.Ext(filename)) == ".json" {
+		return func(data []byte, v interface{}) error {
+			err := json.Unmarshal(data, v)
+			if err != nil {
+				return ParseJSONError{err}
+			}
+			return nil
+		}
+	}
+
+	return func(data []byte, v interface{}) error {
+		err := candiedyaml.Unmarshal(data, v)
+		if err != nil {
+			return ParseYamlError{err}
+		}
+		return nil
+	}
+}
@@ -1,8 +1,19 @@
 package config_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
 	"io/ioutil"
+	"math/big"
 	"os"
+	"path"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -30,8 +41,34 @@ environments:
   - api4.example.com
   skip_ssl: false
   disable_first_deploy_rollback: true
+  org_template: "{{.Environment}}-org"
+  space_template: "{{.AppName}}-space"
+`
+	badConfigPath    = "./test_bad_config.yml"
+	secondConfigPath = "./custom_test_second_config.yml"
+
+	jsonConfigPath = "./custom_test_config.json"
+	testJSONConfig = `{
+  "environments": [
+    {
+      "name": "Test",
+      "domain": "test.example.com",
+      "foundations": ["api1.example.com", "api2.example.com"],
+      "skip_ssl": true,
+      "instances": 3
+    },
+    {
+      "name": "Prod",
+      "domain": "example.com",
+      "foundations": ["api3.example.com", "api4.example.com"],
+      "skip_ssl": false,
+      "disable_first_deploy_rollback": true,
+      "org_template": "{{.Environment}}-org",
+      "space_template": "{{.AppName}}-space"
+    }
+  ]
+}
 `
-	badConfigPath = "./test_bad_config.yml"
 )
 
 var _ = Describe("Config", func() {
@@ -64,43 +101,978 @@ var _ = Describe("Config", func() {
 				SkipSSL:                    false,
 				DisableFirstDeployRollback: true,
 				Instances:                  1,
+				OrgTemplate:                "{{.Environment}}-org",
+				SpaceTemplate:              "{{.AppName}}-space",
 			},
 		}
 
-		Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
-	})
+		Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(customConfigPath)).To(Succeed())
+		Expect(os.RemoveAll(badConfigPath)).To(Succeed())
+		Expect(os.RemoveAll(jsonConfigPath)).To(Succeed())
+		Expect(os.RemoveAll(secondConfigPath)).To(Succeed())
+	})
+
+	Context("when all environment variables are present", func() {
+		It("returns a valid config", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+			env.GetCall.Returns.Values["PORT"] = ""
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Username).To(Equal(cfUsername))
+			Expect(config.Password).To(Equal(cfPassword))
+			Expect(config.Environments).To(Equal(envMap))
+			Expect(config.Port).To(Equal(8080))
+		})
+	})
+
+	Context("when PORT is in the environment", func() {
+		It("uses the value as the port", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+			env.GetCall.Returns.Values["PORT"] = "42"
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Port).To(Equal(42))
+		})
+	})
+
+	Describe("reading the credentials from files", func() {
+		const (
+			usernameFile = "./test_cf_username"
+			passwordFile = "./test_cf_password"
+		)
+
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(usernameFile, []byte(cfUsername+"\n"), 0600)).To(Succeed())
+			Expect(ioutil.WriteFile(passwordFile, []byte(cfPassword+" \r\n"), 0600)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(usernameFile)).To(Succeed())
+			Expect(os.RemoveAll(passwordFile)).To(Succeed())
+		})
+
+		It("reads the credentials from the files without the trailing whitespace", func() {
+			env.GetCall.Returns.Values["CF_USERNAME_FILE"] = usernameFile
+			env.GetCall.Returns.Values["CF_PASSWORD_FILE"] = passwordFile
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Username).To(Equal(cfUsername))
+			Expect(config.Password).To(Equal(cfPassword))
+		})
+
+		It("reads the credentials from the environment variables when there are no files", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Username).To(Equal(cfUsername))
+			Expect(config.Password).To(Equal(cfPassword))
+		})
+
+		It("prefers the files to the environment variables", func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = "env-username"
+			env.GetCall.Returns.Values["CF_PASSWORD"] = "env-password"
+			env.GetCall.Returns.Values["CF_USERNAME_FILE"] = usernameFile
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Username).To(Equal(cfUsername))
+			Expect(config.Password).To(Equal("env-password"))
+		})
+
+		It("returns an error when a file cannot be read", func() {
+			env.GetCall.Returns.Values["CF_USERNAME_FILE"] = "./missing_cf_username"
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).To(BeAssignableToTypeOf(CredentialFileError{}))
+			Expect(err.Error()).To(ContainSubstring("CF_USERNAME_FILE"))
+		})
+
+		It("reports an empty file as a missing credential", func() {
+			Expect(ioutil.WriteFile(usernameFile, []byte("\n"), 0600)).To(Succeed())
+			env.GetCall.Returns.Values["CF_USERNAME_FILE"] = usernameFile
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).To(MatchError("missing environment variables: CF_USERNAME"))
+		})
+	})
+
+	Describe("reading a json config file", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("returns the same config as the yaml config file", func() {
+			Expect(ioutil.WriteFile(jsonConfigPath, []byte(testJSONConfig), 0644)).To(Succeed())
+
+			yamlConfig, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			jsonConfig, err := Custom(env.Get, jsonConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(jsonConfig.Environments).To(Equal(envMap))
+			Expect(jsonConfig).To(Equal(yamlConfig))
+		})
+
+		It("parses timeouts on the environments", func() {
+			Expect(ioutil.WriteFile(jsonConfigPath, []byte(`{"default_foundation_timeout": "2m", "environments": [{"name": "production", "domain": "example.com", "foundations": ["api1.example.com"], "timeout": "90s"}]}`), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, jsonConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.DefaultFoundationTimeout).To(Equal(2 * time.Minute))
+			Expect(config.Environments["production"].Timeout).To(Equal(90 * time.Second))
+		})
+
+		It("validates the config", func() {
+			Expect(ioutil.WriteFile(jsonConfigPath, []byte(`{"environments": [{"name": "production", "domain": "example.com", "foundations": []}]}`), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, jsonConfigPath)
+
+			Expect(err).To(MatchError(MissingParameterError{}))
+		})
+
+		It("returns an error when the environments key is missing", func() {
+			Expect(ioutil.WriteFile(jsonConfigPath, []byte(`{}`), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, jsonConfigPath)
+
+			Expect(err).To(MatchError(EnvironmentsNotSpecifiedError{}))
+		})
+
+		It("returns an error when the json is invalid", func() {
+			Expect(ioutil.WriteFile(jsonConfigPath, []byte(`{"environments": [`), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, jsonConfigPath)
+
+			Expect(err).To(BeAssignableToTypeOf(ParseJSONError{}))
+		})
+	})
+
+	Describe("merging several config files", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("merges the environments of every file", func() {
+			Expect(ioutil.WriteFile(secondConfigPath, []byte(`---
+environments:
+- name: Dev
+  domain: dev.example.com
+  foundations:
+  - api5.example.com
+  timeout: 90s
+`), 0644)).To(Succeed())
+
+			config, err := CustomMulti(env.Get, customConfigPath, secondConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Environments).To(HaveLen(3))
+			Expect(config.Environments["test"]).To(Equal(envMap["test"]))
+			Expect(config.Environments["prod"]).To(Equal(envMap["prod"]))
+			Expect(config.Environments["dev"].Foundations).To(ConsistOf("api5.example.com"))
+			Expect(config.Environments["dev"].Timeout).To(Equal(90 * time.Second))
+		})
+
+		It("takes the other settings from the last file that sets them", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"history_size: 5\njob_ttl: 30m\n"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(secondConfigPath, []byte("history_size: 10\n"), 0644)).To(Succeed())
+
+			config, err := CustomMulti(env.Get, customConfigPath, secondConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.HistorySize).To(Equal(10))
+			Expect(config.JobTTL).To(Equal(30 * time.Minute))
+		})
+
+		It("returns an error when an environment is defined in more than one file", func() {
+			Expect(ioutil.WriteFile(secondConfigPath, []byte(`---
+environments:
+- name: prod
+  domain: other.example.com
+  foundations:
+  - api5.example.com
+`), 0644)).To(Succeed())
+
+			_, err := CustomMulti(env.Get, customConfigPath, secondConfigPath)
+
+			Expect(err).To(MatchError(DuplicateEnvironmentError{"prod", customConfigPath, secondConfigPath}))
+		})
+
+		It("returns an error when none of the files have environments", func() {
+			Expect(ioutil.WriteFile(secondConfigPath, []byte("history_size: 10\n"), 0644)).To(Succeed())
+
+			_, err := CustomMulti(env.Get, secondConfigPath)
+
+			Expect(err).To(MatchError(EnvironmentsNotSpecifiedError{}))
+		})
+	})
+
+	Describe("interpolating environment variables", func() {
+		var interpolatedConfig = func(domain string) string {
+			return `---
+environments:
+- name: production
+  foundations:
+  - https://${CF_API_HOST}
+  domain: ` + domain + `
+`
+		}
+
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("replaces variables with their values", func() {
+			env.GetCall.Returns.Values["CF_API_HOST"] = "api1.example.com"
+			env.GetCall.Returns.Values["DOMAIN"] = "example.com"
+			Expect(ioutil.WriteFile(badConfigPath, []byte(interpolatedConfig("${DOMAIN}")), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, badConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Environments["production"].Foundations).To(ConsistOf("https://api1.example.com"))
+			Expect(config.Environments["production"].Domain).To(Equal("example.com"))
+		})
+
+		It("writes $$ as a literal dollar sign", func() {
+			env.GetCall.Returns.Values["CF_API_HOST"] = "api1.example.com"
+			Expect(ioutil.WriteFile(badConfigPath, []byte(interpolatedConfig("$${DOMAIN}$$")), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, badConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Environments["production"].Domain).To(Equal("${DOMAIN}$"))
+			Expect(env.GetCall.Received.Keys).ToNot(ContainElement("DOMAIN"))
+		})
+
+		It("returns an error when a variable is undefined", func() {
+			Expect(ioutil.WriteFile(badConfigPath, []byte(interpolatedConfig("example.com")), 0644)).To(Succeed())
+
+			_, err := Custom(env.Get, badConfigPath)
+
+			Expect(err).To(MatchError("config references undefined variable CF_API_HOST"))
+		})
+	})
+
+	Describe("enabling failure injection", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("is disabled by default", func() {
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.EnableFailureInjection).To(BeFalse())
+		})
+
+		It("is enabled when ENABLE_FAILURE_INJECTION is true", func() {
+			env.GetCall.Returns.Values["ENABLE_FAILURE_INJECTION"] = "true"
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.EnableFailureInjection).To(BeTrue())
+		})
+
+		It("returns an error when ENABLE_FAILURE_INJECTION is not a boolean", func() {
+			env.GetCall.Returns.Values["ENABLE_FAILURE_INJECTION"] = "bork"
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err.Error()).To(ContainSubstring("cannot parse $ENABLE_FAILURE_INJECTION: bork"))
+		})
+	})
+
+	Describe("signing deploy results", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("does not sign by default", func() {
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.ResultSigningKey).To(BeEmpty())
+		})
+
+		It("uses the key in RESULT_SIGNING_KEY", func() {
+			env.GetCall.Returns.Values["RESULT_SIGNING_KEY"] = "signing-key"
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.ResultSigningKey).To(Equal("signing-key"))
+		})
+	})
+
+	Describe("choosing the log format", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("is text by default", func() {
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.LogFormat).To(Equal("text"))
+		})
+
+		It("uses the format in LOG_FORMAT", func() {
+			env.GetCall.Returns.Values["LOG_FORMAT"] = "json"
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.LogFormat).To(Equal("json"))
+		})
+
+		It("returns an error when LOG_FORMAT is not text or json", func() {
+			env.GetCall.Returns.Values["LOG_FORMAT"] = "bork"
+
+			_, err := Custom(env.Get, customConfigPath)
+
+			Expect(err).To(MatchError(InvalidLogFormatError{"bork"}))
+		})
+	})
+
+	Describe("setting the minimum TLS version", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		Context("when min_tls_version is not specified", func() {
+			It("defaults to TLS 1.2", func() {
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.MinTLSVersion).To(Equal(uint16(tls.VersionTLS12)))
+			})
+		})
+
+		Context("when min_tls_version is specified", func() {
+			It("uses the specified version", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"min_tls_version: \"1.1\"\n"), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.MinTLSVersion).To(Equal(uint16(tls.VersionTLS11)))
+			})
+		})
+
+		Context("when min_tls_version is invalid", func() {
+			It("returns an error", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"min_tls_version: \"0.9\"\n"), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, customConfigPath)
+
+				Expect(err).To(MatchError(InvalidTLSVersionError{"0.9"}))
+			})
+		})
+	})
+
+	Describe("setting the history size", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		Context("when history_size is not specified", func() {
+			It("defaults to 100", func() {
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.HistorySize).To(Equal(100))
+			})
+		})
+
+		Context("when history_size is specified", func() {
+			It("uses the specified size", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"history_size: 5\n"), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.HistorySize).To(Equal(5))
+			})
+		})
+
+		Context("when history_size is negative", func() {
+			It("returns an error", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"history_size: -1\n"), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, customConfigPath)
+
+				Expect(err).To(MatchError(InvalidHistorySizeError{-1}))
+			})
+		})
+	})
+
+	Describe("setting the history file", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("is empty when history_file is not specified", func() {
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.HistoryFile).To(BeEmpty())
+		})
+
+		It("is read from history_file", func() {
+			Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"history_file: /var/deployadactyl/history.json\n"), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.HistoryFile).To(Equal("/var/deployadactyl/history.json"))
+		})
+	})
+
+	Describe("setting the deploy debounce", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		Context("when deploy_debounce is not specified", func() {
+			It("does not debounce", func() {
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.DeployDebounce).To(BeZero())
+			})
+		})
+
+		Context("when deploy_debounce is specified", func() {
+			It("uses the specified window", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"deploy_debounce: 5s\n"), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.DeployDebounce).To(Equal(5 * time.Second))
+			})
+		})
+
+		Context("when deploy_debounce is invalid", func() {
+			It("returns an error", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"deploy_debounce: -5s\n"), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, customConfigPath)
+
+				Expect(err).To(MatchError(InvalidDeployDebounceError{"-5s"}))
+			})
+		})
+	})
+
+	Describe("setting the redeploy window", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		Context("when redeploy_window is not specified", func() {
+			It("does not skip identical deploys", func() {
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.RedeployWindow).To(BeZero())
+			})
+		})
+
+		Context("when redeploy_window is specified", func() {
+			It("uses the specified window", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"redeploy_window: 10m\n"), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.RedeployWindow).To(Equal(10 * time.Minute))
+			})
+		})
+
+		Context("when redeploy_window is invalid", func() {
+			It("returns an error", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"redeploy_window: bork\n"), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, customConfigPath)
+
+				Expect(err).To(MatchError(InvalidRedeployWindowError{"bork"}))
+			})
+		})
+	})
+
+	Describe("limiting concurrent deploys", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		Context("when max_concurrent_deploys is not specified", func() {
+			It("does not limit deploys", func() {
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.MaxConcurrentDeploys).To(BeZero())
+				Expect(config.MaxQueuedDeploys).To(BeZero())
+			})
+		})
+
+		Context("when max_concurrent_deploys and max_queued_deploys are specified", func() {
+			It("uses the specified limits", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"max_concurrent_deploys: 4\nmax_queued_deploys: 10\n"), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.MaxConcurrentDeploys).To(Equal(4))
+				Expect(config.MaxQueuedDeploys).To(Equal(10))
+			})
+		})
+
+		Context("when max_concurrent_deploys is negative", func() {
+			It("returns an error", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"max_concurrent_deploys: -1\n"), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, customConfigPath)
+
+				Expect(err).To(MatchError(InvalidDeployLimitError{"max_concurrent_deploys", -1}))
+			})
+		})
+	})
+
+	Describe("setting the job ttl", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		Context("when job_ttl is not specified", func() {
+			It("defaults to an hour", func() {
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.JobTTL).To(Equal(time.Hour))
+			})
+		})
+
+		Context("when job_ttl is specified", func() {
+			It("uses the specified ttl", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"job_ttl: 30m\n"), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.JobTTL).To(Equal(30 * time.Minute))
+			})
+		})
+
+		Context("when job_ttl is invalid", func() {
+			It("returns an error", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"job_ttl: bork\n"), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, customConfigPath)
+
+				Expect(err).To(MatchError(InvalidJobTTLError{"bork"}))
+			})
+		})
+	})
+
+	Describe("setting the s3 settings", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		Context("when they are not specified", func() {
+			It("reads the credentials from the environment", func() {
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.S3Region).To(BeEmpty())
+				Expect(config.S3CredentialSource).To(Equal(S3CredentialsEnvironment))
+			})
+		})
+
+		Context("when they are specified", func() {
+			It("uses the specified region and credential source", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"s3_region: eu-west-1\ns3_credential_source: shared_file\n"), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.S3Region).To(Equal("eu-west-1"))
+				Expect(config.S3CredentialSource).To(Equal(S3CredentialsSharedFile))
+			})
+		})
+
+		Context("when the credential source is invalid", func() {
+			It("returns an error", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"s3_credential_source: bork\n"), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, customConfigPath)
+
+				Expect(err).To(MatchError(InvalidS3CredentialSourceError{"bork"}))
+			})
+		})
+	})
+
+	Describe("setting the artifact cache", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		Context("when it is not specified", func() {
+			It("does not cache artifacts", func() {
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.ArtifactCacheDirectory).To(BeEmpty())
+				Expect(config.ArtifactCacheSize).To(Equal(int64(1 << 30)))
+			})
+		})
+
+		Context("when it is specified", func() {
+			It("uses the specified directory and size in megabytes", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"artifact_cache_directory: /tmp/artifacts\nartifact_cache_size: 512\n"), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.ArtifactCacheDirectory).To(Equal("/tmp/artifacts"))
+				Expect(config.ArtifactCacheSize).To(Equal(int64(512 << 20)))
+			})
+		})
+
+		Context("when the size is negative", func() {
+			It("returns an error", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"artifact_cache_size: -1\n"), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, customConfigPath)
+
+				Expect(err).To(MatchError(InvalidArtifactCacheSizeError{-1}))
+			})
+		})
+	})
+
+	Describe("setting the max request body size", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		Context("when it is not specified", func() {
+			It("defaults to two gigabytes", func() {
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.MaxRequestBodySize).To(Equal(int64(2 << 30)))
+			})
+		})
+
+		Context("when it is specified", func() {
+			It("uses the specified size in megabytes", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"max_request_body_size: 256\n"), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.MaxRequestBodySize).To(Equal(int64(256 << 20)))
+			})
+		})
+
+		Context("when it is negative", func() {
+			It("returns an error", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"max_request_body_size: -1\n"), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, customConfigPath)
+
+				Expect(err).To(MatchError(InvalidMaxRequestBodySizeError{-1}))
+			})
+		})
+	})
+
+	Describe("setting the deploy logs", func() {
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		Context("when they are not specified", func() {
+			It("does not log deploys and keeps logs for a week", func() {
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.DeployLogDirectory).To(BeEmpty())
+				Expect(config.DeployLogRetention).To(Equal(7 * 24 * time.Hour))
+			})
+		})
+
+		Context("when they are specified", func() {
+			It("uses the specified directory and retention", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"deploy_log_directory: /var/log/deploys\ndeploy_log_retention: 720h\n"), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.DeployLogDirectory).To(Equal("/var/log/deploys"))
+				Expect(config.DeployLogRetention).To(Equal(720 * time.Hour))
+			})
+		})
+
+		Context("when the retention is invalid", func() {
+			It("returns an error", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"deploy_log_retention: bork\n"), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, customConfigPath)
+
+				Expect(err).To(MatchError(InvalidDeployLogRetentionError{"bork"}))
+			})
+		})
+	})
+
+	Describe("setting foundation timeouts", func() {
+		var timeoutConfig = func(defaultTimeout, timeout string) string {
+			config := "---\n"
+			if defaultTimeout != "" {
+				config += "default_foundation_timeout: " + defaultTimeout + "\n"
+			}
+			config += `environments:
+- name: production
+  foundations:
+  - api1.example.com
+  domain: example.com
+`
+			if timeout != "" {
+				config += "  timeout: " + timeout + "\n"
+			}
+			config += `- name: staging
+  foundations:
+  - api2.example.com
+  domain: example.com
+`
+			return config
+		}
+
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		Context("when timeout is specified on an environment", func() {
+			It("sets Timeout on the environment", func() {
+				Expect(ioutil.WriteFile(badConfigPath, []byte(timeoutConfig("2m", "90s")), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, badConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Environments["production"].Timeout).To(Equal(90 * time.Second))
+			})
+		})
+
+		Context("when timeout is absent from an environment", func() {
+			It("uses default_foundation_timeout", func() {
+				Expect(ioutil.WriteFile(badConfigPath, []byte(timeoutConfig("2m", "90s")), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, badConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.DefaultFoundationTimeout).To(Equal(2 * time.Minute))
+				Expect(config.Environments["staging"].Timeout).To(Equal(2 * time.Minute))
+			})
+
+			It("leaves Timeout unset when there is no default_foundation_timeout", func() {
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.DefaultFoundationTimeout).To(BeZero())
+				Expect(config.Environments["test"].Timeout).To(BeZero())
+			})
+		})
+
+		Context("when timeout is invalid", func() {
+			It("returns an error", func() {
+				Expect(ioutil.WriteFile(badConfigPath, []byte(timeoutConfig("", "bork")), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, badConfigPath)
+
+				Expect(err).To(MatchError(InvalidTimeoutError{"timeout for environment production", "bork"}))
+			})
+		})
+
+		Context("when default_foundation_timeout is invalid", func() {
+			It("returns an error", func() {
+				Expect(ioutil.WriteFile(badConfigPath, []byte(timeoutConfig("-1m", "")), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, badConfigPath)
+
+				Expect(err).To(MatchError(InvalidTimeoutError{"default_foundation_timeout", "-1m"}))
+			})
+		})
+	})
+
+	Describe("setting login timeouts", func() {
+		var loginTimeoutConfig = func(defaultLoginTimeout, loginTimeout string) string {
+			config := "---\n"
+			if defaultLoginTimeout != "" {
+				config += "default_login_timeout: " + defaultLoginTimeout + "\n"
+			}
+			config += `environments:
+- name: production
+  foundations:
+  - api1.example.com
+  domain: example.com
+  timeout: 10m
+`
+			if loginTimeout != "" {
+				config += "  login_timeout: " + loginTimeout + "\n"
+			}
+			config += `- name: staging
+  foundations:
+  - api2.example.com
+  domain: example.com
+`
+			return config
+		}
+
+		BeforeEach(func() {
+			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+		})
+
+		It("sets LoginTimeout on the environment separately from Timeout", func() {
+			Expect(ioutil.WriteFile(badConfigPath, []byte(loginTimeoutConfig("30s", "15s")), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, badConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.Environments["production"].LoginTimeout).To(Equal(15 * time.Second))
+			Expect(config.Environments["production"].Timeout).To(Equal(10 * time.Minute))
+		})
+
+		It("uses default_login_timeout when login_timeout is absent from an environment", func() {
+			Expect(ioutil.WriteFile(badConfigPath, []byte(loginTimeoutConfig("30s", "15s")), 0644)).To(Succeed())
+
+			config, err := Custom(env.Get, badConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.DefaultLoginTimeout).To(Equal(30 * time.Second))
+			Expect(config.Environments["staging"].LoginTimeout).To(Equal(30 * time.Second))
+		})
+
+		It("leaves LoginTimeout unset when there is no default_login_timeout", func() {
+			config, err := Custom(env.Get, customConfigPath)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(config.DefaultLoginTimeout).To(BeZero())
+			Expect(config.Environments["test"].LoginTimeout).To(BeZero())
+		})
+
+		Context("when login_timeout is invalid", func() {
+			It("returns an error", func() {
+				Expect(ioutil.WriteFile(badConfigPath, []byte(loginTimeoutConfig("", "bork")), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, badConfigPath)
+
+				Expect(err).To(MatchError(InvalidTimeoutError{"login timeout for environment production", "bork"}))
+			})
+		})
+
+		Context("when default_login_timeout is invalid", func() {
+			It("returns an error", func() {
+				Expect(ioutil.WriteFile(badConfigPath, []byte(loginTimeoutConfig("0s", "")), 0644)).To(Succeed())
 
-	AfterEach(func() {
-		Expect(os.RemoveAll(customConfigPath)).To(Succeed())
-		Expect(os.RemoveAll(badConfigPath)).To(Succeed())
+				_, err := Custom(env.Get, badConfigPath)
+
+				Expect(err).To(MatchError(InvalidTimeoutError{"default_login_timeout", "0s"}))
+			})
+		})
 	})
 
-	Context("when all environment variables are present", func() {
-		It("returns a valid config", func() {
+	Describe("setting the result sentinel", func() {
+		BeforeEach(func() {
 			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
 			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
-			env.GetCall.Returns.Values["PORT"] = ""
+		})
 
-			config, err := Custom(env.Get, customConfigPath)
-			Expect(err).ToNot(HaveOccurred())
+		Context("when result_sentinel is not specified", func() {
+			It("defaults to __DEPLOYADACTYL_RESULT__", func() {
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
 
-			Expect(config.Username).To(Equal(cfUsername))
-			Expect(config.Password).To(Equal(cfPassword))
-			Expect(config.Environments).To(Equal(envMap))
-			Expect(config.Port).To(Equal(8080))
+				Expect(config.ResultSentinel).To(Equal("__DEPLOYADACTYL_RESULT__"))
+			})
+		})
+
+		Context("when result_sentinel is specified", func() {
+			It("uses the specified sentinel", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"result_sentinel: __RESULT__\n"), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.ResultSentinel).To(Equal("__RESULT__"))
+			})
 		})
 	})
 
-	Context("when PORT is in the environment", func() {
-		It("uses the value as the port", func() {
+	Describe("setting the basic auth realm", func() {
+		BeforeEach(func() {
 			env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
 			env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
-			env.GetCall.Returns.Values["PORT"] = "42"
+		})
 
-			config, err := Custom(env.Get, customConfigPath)
-			Expect(err).ToNot(HaveOccurred())
+		Context("when auth_realm is not specified", func() {
+			It("defaults to deployadactyl", func() {
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
 
-			Expect(config.Port).To(Equal(42))
+				Expect(config.AuthRealm).To(Equal("deployadactyl"))
+			})
+		})
+
+		Context("when auth_realm is specified", func() {
+			It("uses the specified realm", func() {
+				Expect(ioutil.WriteFile(customConfigPath, []byte(testConfig+"auth_realm: Production Deploys\n"), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.AuthRealm).To(Equal("Production Deploys"))
+			})
 		})
 	})
 
@@ -159,6 +1131,21 @@ environments:
 				Expect(badConfig.Environments).To(BeEmpty())
 			})
 
+			It("returns an error when foundations is empty", func() {
+				testBadConfig := `---
+environments:
+- name: production
+  domain: test.example.com
+  foundations: []
+`
+				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+				badConfig, err := Custom(env.Get, badConfigPath)
+				Expect(err).To(MatchError(MissingParameterError{}))
+
+				Expect(badConfig.Environments).To(BeEmpty())
+			})
+
 			It("returns an error when domain is missing", func() {
 				testBadConfig := `---
 environments:
@@ -174,6 +1161,326 @@ environments:
 			})
 		})
 
+		Context("when disable_rollback is present", func() {
+			It("sets DisableRollback on the environment", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				testBadConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  domain: example.com
+  disable_rollback: true
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(testBadConfig), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, badConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Environments["production"].DisableRollback).To(BeTrue())
+			})
+		})
+
+		Context("when disable_rollback is absent", func() {
+			It("defaults DisableRollback to false", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				config, err := Custom(env.Get, customConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Environments["test"].DisableRollback).To(BeFalse())
+				Expect(config.Environments["prod"].DisableRollback).To(BeFalse())
+			})
+		})
+
+		Context("when retention is present", func() {
+			It("sets Retention on the environment", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(`---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  domain: example.com
+  retention: 3
+`), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, badConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Environments["production"].Retention).To(Equal(3))
+			})
+
+			It("returns an error when retention is negative", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(`---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  domain: example.com
+  retention: -1
+`), 0644)).To(Succeed())
+
+				_, err := Custom(env.Get, badConfigPath)
+
+				Expect(err).To(MatchError(InvalidRetentionError{"production", -1}))
+			})
+		})
+
+		Context("when a client certificate is present", func() {
+			var (
+				certDirectory string
+				certPath      string
+				keyPath       string
+			)
+
+			var writeClientCertConfig = func(clientCert, clientKey string) {
+				Expect(ioutil.WriteFile(badConfigPath, []byte(fmt.Sprintf(`---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  domain: example.com
+  client_cert: %s
+  client_key: %s
+`, clientCert, clientKey)), 0644)).To(Succeed())
+			}
+
+			BeforeEach(func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				var err error
+				certDirectory, err = ioutil.TempDir("", "deployadactyl-client-cert-")
+				Expect(err).ToNot(HaveOccurred())
+
+				certPath = path.Join(certDirectory, "client.crt")
+				keyPath = path.Join(certDirectory, "client.key")
+				writeClientCertificate(certPath, keyPath)
+			})
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(certDirectory)).To(Succeed())
+			})
+
+			It("loads the client certificate of the environment", func() {
+				writeClientCertConfig(certPath, keyPath)
+
+				config, err := Custom(env.Get, badConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Environments["production"].ClientCert).To(Equal(certPath))
+				Expect(config.Environments["production"].ClientKey).To(Equal(keyPath))
+				Expect(config.Environments["production"].ClientCertificate).ToNot(BeNil())
+				Expect(config.Environments["production"].ClientCertificate.Certificate).To(HaveLen(1))
+			})
+
+			It("returns an error when the client key is missing", func() {
+				writeClientCertConfig(certPath, `""`)
+
+				_, err := Custom(env.Get, badConfigPath)
+
+				Expect(err).To(MatchError(MissingClientCertificateError{"production"}))
+			})
+
+			It("returns an error when the client certificate cannot be read", func() {
+				writeClientCertConfig(path.Join(certDirectory, "missing.crt"), keyPath)
+
+				_, err := Custom(env.Get, badConfigPath)
+
+				Expect(err).To(BeAssignableToTypeOf(InvalidClientCertificateError{}))
+				Expect(err.Error()).To(ContainSubstring("production"))
+			})
+		})
+
+		Context("when username and password are present", func() {
+			It("sets Username and Password on the environment and leaves the other environments without them", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				credentialsConfig := `---
+environments:
+- name: prod
+  foundations:
+  - api1.example.com
+  domain: example.com
+  username: prod-username
+  password: prod-password
+- name: dev
+  foundations:
+  - api2.example.com
+  domain: example.com
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(credentialsConfig), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, badConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Environments["prod"].Username).To(Equal("prod-username"))
+				Expect(config.Environments["prod"].Password).To(Equal("prod-password"))
+				Expect(config.Environments["dev"].Username).To(BeEmpty())
+				Expect(config.Environments["dev"].Password).To(BeEmpty())
+				Expect(config.Username).To(Equal(cfUsername))
+			})
+		})
+
+		Context("when required_env_vars is present", func() {
+			It("sets RequiredEnvVars on the environment", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				envVarsConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  domain: example.com
+  required_env_vars:
+  - SPRING_PROFILES_ACTIVE
+  - LOG_LEVEL
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(envVarsConfig), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, badConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Environments["production"].RequiredEnvVars).To(Equal([]string{"SPRING_PROFILES_ACTIVE", "LOG_LEVEL"}))
+			})
+		})
+
+		Context("when custom_domains is present", func() {
+			It("sets CustomDomains on the environment", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				customDomainsConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  domain: example.com
+  custom_domains:
+  - internal.example.com
+  - example.org
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(customDomainsConfig), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, badConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Environments["production"].Domain).To(Equal("example.com"))
+				Expect(config.Environments["production"].CustomDomains).To(Equal([]string{"internal.example.com", "example.org"}))
+			})
+		})
+
+		Context("when max_routes_per_app is present", func() {
+			It("sets MaxRoutesPerApp on the environment", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				maxRoutesConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  domain: example.com
+  max_routes_per_app: 5
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(maxRoutesConfig), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, badConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Environments["production"].MaxRoutesPerApp).To(Equal(5))
+			})
+		})
+
+		Context("when preflight_push is present", func() {
+			It("sets PreflightPush on the environment", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				preflightConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  domain: example.com
+  preflight_push: true
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(preflightConfig), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, badConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Environments["production"].PreflightPush).To(BeTrue())
+			})
+		})
+
+		Context("when webhook_url is present", func() {
+			It("sets WebhookURL on the environment", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				webhookConfig := `---
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  domain: example.com
+  webhook_url: https://hooks.example.com/deploys
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(webhookConfig), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, badConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.Environments["production"].WebhookURL).To(Equal("https://hooks.example.com/deploys"))
+			})
+		})
+
+		Context("when slack_webhook_url is present", func() {
+			It("sets SlackWebhookURL on the environment and in the config", func() {
+				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
+				env.GetCall.Returns.Values["CF_PASSWORD"] = cfPassword
+
+				slackConfig := `---
+slack_webhook_url: https://hooks.slack.com/services/global
+slack_template: "{{.AppName}} {{.Type}}"
+environments:
+- name: production
+  foundations:
+  - api1.example.com
+  domain: example.com
+  slack_webhook_url: https://hooks.slack.com/services/production
+`
+
+				Expect(ioutil.WriteFile(badConfigPath, []byte(slackConfig), 0644)).To(Succeed())
+
+				config, err := Custom(env.Get, badConfigPath)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(config.SlackWebhookURL).To(Equal("https://hooks.slack.com/services/global"))
+				Expect(config.SlackTemplate).To(Equal("{{.AppName}} {{.Type}}"))
+				Expect(config.Environments["production"].SlackWebhookURL).To(Equal("https://hooks.slack.com/services/production"))
+			})
+		})
+
 		Context("when the number of instances is zero", func() {
 			It("sets the number of instances to one", func() {
 				env.GetCall.Returns.Values["CF_USERNAME"] = cfUsername
@@ -200,3 +1507,26 @@ environments:
 		})
 	})
 })
+
+// writeClientCertificate writes a self-signed client certificate and its key as PEM files.
+func writeClientCertificate(certPath, keyPath string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "deployadactyl"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).ToNot(HaveOccurred())
+
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	Expect(err).ToNot(HaveOccurred())
+
+	Expect(ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}), 0600)).To(Succeed())
+	Expect(ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0600)).To(Succeed())
+}
@@ -21,3 +21,163 @@ type ParseYamlError struct {
 func (e ParseYamlError) Error() string {
 	return fmt.Sprintf("cannot parse yaml file: %s", e.Err)
 }
+
+type ParseJSONError struct {
+	Err error
+}
+
+func (e ParseJSONError) Error() string {
+	return fmt.Sprintf("cannot parse json file: %s", e.Err)
+}
+
+type UndefinedVariableError struct {
+	Name string
+}
+
+func (e UndefinedVariableError) Error() string {
+	return fmt.Sprintf("config references undefined variable %s", e.Name)
+}
+
+type DuplicateEnvironmentError struct {
+	Name       string
+	FirstFile  string
+	SecondFile string
+}
+
+func (e DuplicateEnvironmentError) Error() string {
+	return fmt.Sprintf("environment %s is defined in both %s and %s", e.Name, e.FirstFile, e.SecondFile)
+}
+
+type InvalidRetentionError struct {
+	Environment string
+	Retention   int
+}
+
+func (e InvalidRetentionError) Error() string {
+	return fmt.Sprintf("invalid retention for environment %s: %d: must not be negative", e.Environment, e.Retention)
+}
+
+type MissingClientCertificateError struct {
+	Environment string
+}
+
+func (e MissingClientCertificateError) Error() string {
+	return fmt.Sprintf("client_cert and client_key must both be set for environment %s", e.Environment)
+}
+
+type InvalidClientCertificateError struct {
+	Environment string
+	Err         error
+}
+
+func (e InvalidClientCertificateError) Error() string {
+	return fmt.Sprintf("cannot load the client certificate of environment %s: %s", e.Environment, e.Err)
+}
+
+type InvalidTLSVersionError struct {
+	Version string
+}
+
+func (e InvalidTLSVersionError) Error() string {
+	return fmt.Sprintf("invalid min_tls_version: %s: must be one of 1.0, 1.1, 1.2 or 1.3", e.Version)
+}
+
+type InvalidHistorySizeError struct {
+	Size int
+}
+
+func (e InvalidHistorySizeError) Error() string {
+	return fmt.Sprintf("invalid history_size: %d: must be greater than zero", e.Size)
+}
+
+type InvalidArtifactCacheSizeError struct {
+	Size int
+}
+
+func (e InvalidArtifactCacheSizeError) Error() string {
+	return fmt.Sprintf("invalid artifact_cache_size: %d: must be greater than zero", e.Size)
+}
+
+type InvalidMaxRequestBodySizeError struct {
+	Size int
+}
+
+func (e InvalidMaxRequestBodySizeError) Error() string {
+	return fmt.Sprintf("invalid max_request_body_size: %d: must be greater than zero", e.Size)
+}
+
+type InvalidDeployDebounceError struct {
+	Debounce string
+}
+
+func (e InvalidDeployDebounceError) Error() string {
+	return fmt.Sprintf("invalid deploy_debounce: %s: must be a non-negative duration such as 5s", e.Debounce)
+}
+
+type InvalidJobTTLError struct {
+	TTL string
+}
+
+func (e InvalidJobTTLError) Error() string {
+	return fmt.Sprintf("invalid job_ttl: %s: must be a non-negative duration such as 30m or 1h", e.TTL)
+}
+
+type InvalidDeployLogRetentionError struct {
+	Retention string
+}
+
+func (e InvalidDeployLogRetentionError) Error() string {
+	return fmt.Sprintf("invalid deploy_log_retention: %s: must be a non-negative duration such as 24h or 720h", e.Retention)
+}
+
+type InvalidRedeployWindowError struct {
+	Window string
+}
+
+func (e InvalidRedeployWindowError) Error() string {
+	return fmt.Sprintf("invalid redeploy_window: %s: must be a non-negative duration such as 10m", e.Window)
+}
+
+type InvalidDeployLimitError struct {
+	Key   string
+	Limit int
+}
+
+func (e InvalidDeployLimitError) Error() string {
+	return fmt.Sprintf("invalid %s: %d: must not be negative", e.Key, e.Limit)
+}
+
+type InvalidLogFormatError struct {
+	Format string
+}
+
+func (e InvalidLogFormatError) Error() string {
+	return fmt.Sprintf("invalid $LOG_FORMAT: %s: must be text or json", e.Format)
+}
+
+type InvalidS3CredentialSourceError struct {
+	Source string
+}
+
+func (e InvalidS3CredentialSourceError) Error() string {
+	return fmt.Sprintf("invalid s3_credential_source: %s: must be environment or shared_file", e.Source)
+}
+
+type InvalidTimeoutError struct {
+	Key     string
+	Timeout string
+}
+
+func (e InvalidTimeoutError) Error() string {
+	return fmt.Sprintf("invalid %s: %s: must be a positive duration such as 90s", e.Key, e.Timeout)
+}
+
+type CredentialFileError struct {
+	Variable string
+	Filename string
+	Err      error
+}
+
+func (e CredentialFileError) Error() string {
+	return fmt.Sprintf("cannot read $%s: %s: %s", e.Variable, e.Filename, e.Err)
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"bytes"
+	"regexp"
+)
+
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// interpolate replaces every ${VAR} in the config file with the value of VAR from getenv.
+// $$ is written as a literal $, so $${VAR} is left as ${VAR}.
+// A variable that getenv returns an empty value for is undefined.
+func interpolate(getenv func(string) string, data []byte) ([]byte, error) {
+	var out bytes.Buffer
+
+	for i := 0; i < len(data); i++ {
+		if data[i] != '$' || i+1 == len(data) {
+			out.WriteByte(data[i])
+			continue
+		}
+
+		if data[i+1] == '$' {
+			out.WriteByte('$')
+			i++
+			continue
+		}
+
+		if data[i+1] != '{' {
+			out.WriteByte(data[i])
+			continue
+		}
+
+		end := bytes.IndexByte(data[i+2:], '}')
+		if end == -1 || !variableName.Match(data[i+2:i+2+end]) {
+			out.WriteByte(data[i])
+			continue
+		}
+
+		name := string(data[i+2 : i+2+end])
+		value := getenv(name)
+		if value == "" {
+			return nil, UndefinedVariableError{name}
+		}
+
+		out.WriteString(value)
+		i += end + 2
+	}
+
+	return out.Bytes(), nil
+}
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/compozed/deployadactyl/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// limitedBody is a request body that is read through http.MaxBytesReader.
+// It remembers when the client sent more than limit bytes, so that the deploy is rejected
+// with http.StatusRequestEntityTooLarge whichever error it failed with.
+type limitedBody struct {
+	io.ReadCloser
+	limit    int64
+	read     int64
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+
+	if err != nil && err != io.EOF && b.read >= b.limit {
+		b.exceeded = true
+		return n, RequestBodyTooLargeError{b.limit}
+	}
+
+	return n, err
+}
+
+// limitRequestBody limits the request body to the MaxRequestBodySize. A request that says it is larger is rejected straight away.
+// The body is not limited when the MaxRequestBodySize is not positive.
+//
+// Returns false when the request was rejected.
+func (c *Controller) limitRequestBody(g *gin.Context, requestID string) bool {
+	if c.MaxRequestBodySize <= 0 {
+		return true
+	}
+
+	if g.Request.ContentLength > c.MaxRequestBodySize {
+		err := RequestBodyTooLargeError{c.MaxRequestBodySize}
+		logger.WithRequestID(c.Log, requestID).Warningf("%s: %s", "cannot deploy application", err)
+
+		g.Header(requestIDHeader, requestID)
+		g.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return false
+	}
+
+	g.Request.Body = &limitedBody{
+		ReadCloser: http.MaxBytesReader(g.Writer, g.Request.Body, c.MaxRequestBodySize),
+		limit:      c.MaxRequestBodySize,
+	}
+
+	return true
+}
+
+// bodyTooLarge returns true when more than the MaxRequestBodySize was read from the body of the request.
+func (r deployRequest) bodyTooLarge() bool {
+	return r.body != nil && r.body.exceeded
+}
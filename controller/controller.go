@@ -3,45 +3,768 @@ package controller
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/deploylogs"
+	"github.com/compozed/deployadactyl/eventmanager"
+	"github.com/compozed/deployadactyl/eventstream"
 	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/logger"
+	"github.com/compozed/deployadactyl/metrics"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/compozed/deployadactyl/version"
 	"github.com/gin-gonic/gin"
 	"github.com/op/go-logging"
+	"golang.org/x/net/context"
+)
+
+const (
+	defaultHistoryLimit = 20
+	defaultAuthRealm    = "deployadactyl"
+	requestIDHeader     = logger.RequestIDHeader
+	requestIDKey        = "requestID"
+	requestIDLength     = 20
+	ndjsonContentType   = "application/x-ndjson"
 )
 
 // Controller is used to determine the type of request and process it accordingly.
+// Completed deployments are recorded in the History when one is provided, signed by the Signer when one is provided.
+// A deploy that is rejected for missing basic auth has a WWW-Authenticate header with the AuthRealm, or with the default realm when it is empty.
+// When ResultSentinel is set the last line of every plaintext deploy response is the ResultSentinel followed by the DeployResult as JSON.
+// When EventStreams is provided deploys can be streamed as NDJSON events and resumed by their request id.
+// When Jobs is provided deploys can be run asynchronously and polled by their request id.
+// When Debouncer is provided a deploy is superseded by a newer deploy of the same application that arrives within the debounce window.
+// When Limiter is provided only a limited number of deploys run at once and a deploy is rejected when too many are waiting.
+// A waiting deploy whose client closes the connection gives up its place in the queue.
+// A rejected deploy has a Retry-After header that grows with the number of deploys running and waiting and with every deploy rejected in a row.
+// When Metrics is provided they are served in the Prometheus text format.
+// When Readiness is provided it is checked before the server reports that it is ready to serve deploys.
+// When DeployLogs are provided the log of a deploy can be downloaded by its request id.
+// Environments are the configured environments that can be listed. When they are provided a deploy to any other environment
+// is rejected with http.StatusNotFound before it starts.
+// A deploy with a request body larger than the MaxRequestBodySize is rejected with http.StatusRequestEntityTooLarge.
+// The request body is not limited when it is zero.
+// The Deployer and Environments are swapped for the ones of a new config by Reload.
 type Controller struct {
-	Deployer I.Deployer
-	Log      *logging.Logger
+	Deployer       I.Deployer
+	History        I.History
+	EventStreams   I.EventStreams
+	Jobs           I.Jobs
+	Debouncer      I.Debouncer
+	Limiter        I.Limiter
+	Signer         I.Signer
+	Metrics        I.Metrics
+	Readiness      I.Readiness
+	DeployLogs     I.DeployLogs
+	Environments   map[string]config.Environment
+	Randomizer     I.Randomizer
+	ResultSentinel string
+	AuthRealm      string
+	Log            *logging.Logger
+
+	MaxRequestBodySize int64
+
+	mutex    sync.RWMutex
+	overflow overflow
+}
+
+// environmentResponse is an environment as it is listed. It leaves out the foundations and credentials of the environment.
+type environmentResponse struct {
+	Name         string `json:"name"`
+	Domain       string `json:"domain"`
+	Authenticate bool   `json:"authenticate"`
+}
+
+type byName []environmentResponse
+
+func (e byName) Len() int           { return len(e) }
+func (e byName) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e byName) Less(i, j int) bool { return e[i].Name < e[j].Name }
+
+// deployResponse is the body of a deploy response when the client accepts application/json.
+type deployResponse struct {
+	Error     string `json:"error,omitempty"`
+	Status    int    `json:"status"`
+	RequestID string `json:"request_id"`
+	Output    string `json:"output"`
 }
 
 // Deploy checks the request content type and passes it to the Deployer.
+// Clients that accept application/x-ndjson get the deploy output streamed as numbered events.
+// Requests with the async=true query parameter get a job id and the deploy runs in the background.
+// A zip file uploaded in a multipart/form-data request is deployed the same as an application/zip request.
+// Request bodies with a Content-Encoding of gzip are decompressed before they are deployed.
+// A deploy that is neither streamed nor asynchronous is cancelled when the client closes the connection.
+//
+// Every deploy has a request id, taken from the X-Request-Id header or generated when the header is missing or invalid.
+// A request id that already has a deploy log is replaced with a generated one, so that every deploy has its own log.
+// It is stored in the gin context, passed to the Deployer in the X-Request-Id header and returned in the X-Request-Id response header.
 func (c *Controller) Deploy(g *gin.Context) {
-	c.Log.Info("Request originated from: %+v", g.Request.RemoteAddr)
+	startTime := time.Now()
+	requestID := c.setRequestID(g)
+
+	logger.WithRequestID(c.Log, requestID).Infof("Request originated from: %+v", g.Request.RemoteAddr)
+
+	if !c.knownEnvironment(g, requestID) {
+		return
+	}
 
+	if !c.limitRequestBody(g, requestID) {
+		return
+	}
+
+	if c.EventStreams != nil && accepts(g, ndjsonContentType) {
+		c.deployEvents(g, startTime, requestID)
+		return
+	}
+
+	if c.Jobs != nil && g.Query("async") == "true" {
+		c.deployAsync(g, startTime, requestID)
+		return
+	}
+
+	request := newDeployRequest(g, g.Request)
 	response := &bytes.Buffer{}
 
-	defer io.Copy(g.Writer, response)
+	statusCode, err := c.deploy(g.Request.Context(), request, response)
+	if err != nil {
+		g.Error(err)
+	}
+
+	result := newDeployResult(request, startTime, statusCode, err)
+	c.recordResult(result)
+	c.respond(g, response, result, requestID, err)
+}
+
+// GetJobStatus responds with the state and accumulated output of an asynchronous deploy.
+func (c *Controller) GetJobStatus(g *gin.Context) {
+	if c.Jobs == nil {
+		g.JSON(http.StatusNotFound, gin.H{"error": "asynchronous deploys are not enabled"})
+		return
+	}
+
+	status, ok := c.Jobs.Status(g.Param("jobID"))
+	if !ok {
+		g.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("unknown job: %s", g.Param("jobID"))})
+		return
+	}
+
+	g.JSON(http.StatusOK, status)
+}
+
+// GetDeployLog responds with the complete output of a deploy as plain text, found by its request id.
+func (c *Controller) GetDeployLog(g *gin.Context) {
+	if c.DeployLogs == nil {
+		g.JSON(http.StatusNotFound, gin.H{"error": "deploy logs are not enabled"})
+		return
+	}
+
+	deployLog, err := c.DeployLogs.Open(g.Param("requestID"))
+	if err != nil {
+		if _, ok := err.(deploylogs.UnknownLogError); ok {
+			g.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		g.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer deployLog.Close()
+
+	g.Header("Content-Type", "text/plain; charset=utf-8")
+	g.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.log", g.Param("requestID")))
+	g.Status(http.StatusOK)
+	io.Copy(g.Writer, deployLog)
+}
+
+// GetEvents streams the events of a deploy as NDJSON, starting after the sequence number in the since query parameter.
+// The stream ends with an event that has the result of the deploy.
+func (c *Controller) GetEvents(g *gin.Context) {
+	if c.EventStreams == nil {
+		g.JSON(http.StatusNotFound, gin.H{"error": "event streams are not enabled"})
+		return
+	}
+
+	since, err := getQueryInt(g, "since", 0)
+	if err != nil {
+		g.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	g.Header("Content-Type", ndjsonContentType)
+
+	err = c.EventStreams.Follow(g.Param("deployID"), since, writeEvent(g.Writer))
+	if err == nil {
+		return
+	}
+
+	if g.Writer.Written() {
+		c.Log.Warningf("stopped streaming deploy %s: %s", g.Param("deployID"), err)
+		return
+	}
+
+	switch err.(type) {
+	case eventstream.UnknownStreamError:
+		g.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case eventstream.EventsExpiredError:
+		g.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	default:
+		g.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// ListEnvironments responds with the name and domain of every configured environment, sorted by name.
+// Environments that require authentication are only listed for requests with basic auth, the same as deploys to them.
+func (c *Controller) ListEnvironments(g *gin.Context) {
+	_, _, authenticated := g.Request.BasicAuth()
+
+	_, configured := c.current()
+
+	g.JSON(http.StatusOK, listEnvironments(configured, authenticated))
+}
+
+// knownEnvironment responds with http.StatusNotFound and the names of the environments the request can deploy to
+// when the environment of the request is not configured. The Deployer checks the environment again when it deploys.
+func (c *Controller) knownEnvironment(g *gin.Context, requestID string) bool {
+	_, configured := c.current()
+	if configured == nil {
+		return true
+	}
+
+	environment := paramOrQuery(g, "environment")
+	if _, ok := configured[environment]; ok {
+		return true
+	}
+
+	_, _, authenticated := g.Request.BasicAuth()
+
+	names := []string{}
+	for _, e := range listEnvironments(configured, authenticated) {
+		names = append(names, e.Name)
+	}
+
+	err := EnvironmentNotFoundError{environment}
+	logger.WithRequestID(c.Log, requestID).Errorf("%s: %s", "cannot deploy application", err)
+
+	g.Header(requestIDHeader, requestID)
+	g.JSON(http.StatusNotFound, gin.H{"error": err.Error(), "environments": names})
+	return false
+}
+
+func listEnvironments(configured map[string]config.Environment, authenticated bool) []environmentResponse {
+	environments := []environmentResponse{}
+	for _, environment := range configured {
+		if environment.Authenticate && !authenticated {
+			continue
+		}
+
+		environments = append(environments, environmentResponse{
+			Name:         environment.Name,
+			Domain:       environment.Domain,
+			Authenticate: environment.Authenticate,
+		})
+	}
+	sort.Sort(byName(environments))
+
+	return environments
+}
+
+// GetMetrics responds with the deploy metrics in the Prometheus text format.
+func (c *Controller) GetMetrics(g *gin.Context) {
+	if c.Metrics == nil {
+		g.JSON(http.StatusNotFound, gin.H{"error": "metrics are not enabled"})
+		return
+	}
+
+	g.Header("Content-Type", metrics.ContentType)
+	g.Status(http.StatusOK)
+
+	err := c.Metrics.Write(g.Writer)
+	if err != nil {
+		c.Log.Errorf("cannot write metrics: %s", err)
+	}
+}
+
+// GetHealth responds with 200 for as long as the server is running.
+func (c *Controller) GetHealth(g *gin.Context) {
+	g.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// GetVersion responds with the version, git commit and build date of the running binary.
+func (c *Controller) GetVersion(g *gin.Context) {
+	g.JSON(http.StatusOK, version.Get())
+}
+
+// GetReady responds with 200 once the Readiness checks pass and with 503 and the reason until then.
+func (c *Controller) GetReady(g *gin.Context) {
+	if c.Readiness == nil {
+		g.JSON(http.StatusNotFound, gin.H{"error": "readiness checks are not enabled"})
+		return
+	}
+
+	err := c.Readiness.Check()
+	if err != nil {
+		c.Log.Warningf("%s", err)
+		g.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "error": err.Error()})
+		return
+	}
+
+	g.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
+
+// GetHistory responds with the completed deployments in the History, newest first.
+// Results can be filtered with the env, app and status query parameters and paginated with offset and limit.
+func (c *Controller) GetHistory(g *gin.Context) {
+	if c.History == nil {
+		g.JSON(http.StatusNotFound, gin.H{"error": "deploy history is not enabled"})
+		return
+	}
+
+	offset, err := getQueryInt(g, "offset", 0)
+	if err != nil {
+		g.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, err := getQueryInt(g, "limit", defaultHistoryLimit)
+	if err != nil {
+		g.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, total := c.History.Query(S.HistoryQuery{
+		Environment: g.Query("env"),
+		AppName:     g.Query("app"),
+		Status:      g.Query("status"),
+		Offset:      offset,
+		Limit:       limit,
+	})
+
+	g.JSON(http.StatusOK, gin.H{
+		"results": results,
+		"total":   total,
+		"offset":  offset,
+		"limit":   limit,
+	})
+}
+
+// deployRequest holds what the Deployer needs from a request.
+// It is copied out of the gin.Context so that a deploy can outlive the request.
+// target is set to the org and space the Deployer resolved, which can differ from the ones in the URL.
+type deployRequest struct {
+	request     *http.Request
+	requestID   string
+	environment string
+	org         string
+	space       string
+	appName     string
+	contentType string
+	body        *limitedBody
+	target      *S.DeployTarget
+}
+
+func newDeployRequest(g *gin.Context, request *http.Request) deployRequest {
+	body, _ := request.Body.(*limitedBody)
+
+	return deployRequest{
+		body:        body,
+		request:     request,
+		requestID:   request.Header.Get(requestIDHeader),
+		environment: paramOrQuery(g, "environment"),
+		org:         paramOrQuery(g, "org"),
+		space:       paramOrQuery(g, "space"),
+		appName:     paramOrQuery(g, "appName"),
+		contentType: g.Request.Header.Get("Content-Type"),
+		target:      &S.DeployTarget{},
+	}
+}
+
+// paramOrQuery returns the path parameter called name, or the query parameter of the same name
+// when the path does not have it, for the clients that send the application in the query.
+func paramOrQuery(g *gin.Context, name string) string {
+	if value := g.Param(name); value != "" {
+		return value
+	}
+	return g.Query(name)
+}
+
+// key identifies the application being deployed by the org and space of its target.
+func (r deployRequest) key() string {
+	return strings.Join([]string{r.environment, r.target.Org, r.target.Space, r.appName}, "/")
+}
+
+// newDeployResult returns the result of the deploy of the request. The org and space are the ones the Deployer resolved,
+// or the ones in the URL when the deploy did not get as far as the Deployer.
+func newDeployResult(request deployRequest, startTime time.Time, statusCode int, err error) S.DeployResult {
+	org, space := request.org, request.space
+	if request.target.Org != "" || request.target.Space != "" {
+		org, space = request.target.Org, request.target.Space
+	}
+
+	result := S.DeployResult{
+		Environment: request.environment,
+		Org:         org,
+		Space:       space,
+		AppName:     request.appName,
+		RequestID:   request.requestID,
+		Status:      "success",
+		StatusCode:  statusCode,
+		Time:        startTime,
+		Duration:    time.Since(startTime),
+	}
+
+	if user, _, ok := request.request.BasicAuth(); ok {
+		result.User = user
+	}
+	if err != nil || statusCode >= http.StatusBadRequest {
+		result.Status = "failure"
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// deploy runs the deploy of the request.
+// A client error status code from the Deployer is responded with as is. Any other failed deploy is an internal server error.
+// A deploy that fails after more than the MaxRequestBodySize was read from the request body fails with a RequestBodyTooLargeError,
+// as does a gzip compressed request body that decompresses to more than the MaxRequestBodySize.
+func (c *Controller) deploy(ctx context.Context, request deployRequest, response io.Writer) (statusCode int, err error) {
+	log := logger.WithRequestID(c.Log, request.requestID)
 
-	statusCode, err := c.Deployer.Deploy(
-		g.Request,
-		g.Param("environment"),
-		g.Param("org"),
-		g.Param("space"),
-		g.Param("appName"),
-		g.Request.Header.Get("Content-Type"),
+	defer func() {
+		if err != nil && request.bodyTooLarge() {
+			statusCode, err = http.StatusRequestEntityTooLarge, RequestBodyTooLargeError{c.MaxRequestBodySize}
+		}
+	}()
+
+	var cleanUp func()
+
+	if isGzipped(request) {
+		request, cleanUp, err = request.decompressed(c.MaxRequestBodySize)
+		if err != nil {
+			log.Errorf("%s: %s", "cannot deploy application", err)
+			switch err.(type) {
+			case DecompressError:
+				return http.StatusBadRequest, err
+			case RequestBodyTooLargeError:
+				return http.StatusRequestEntityTooLarge, err
+			}
+			return http.StatusInternalServerError, err
+		}
+		defer cleanUp()
+	}
+
+	if isMultipart(request.contentType) {
+		request, cleanUp, err = request.fromMultipartForm()
+		if err != nil {
+			log.Errorf("%s: %s", "cannot deploy application", err)
+			return http.StatusBadRequest, err
+		}
+		defer cleanUp()
+	}
+
+	if c.Debouncer != nil {
+		_, environments := c.current()
+		request, err = request.withTarget(environments)
+		if err != nil {
+			log.Errorf("%s: %s", "cannot deploy application", err)
+			return http.StatusBadRequest, err
+		}
+
+		err = c.Debouncer.Wait(request.key())
+		if err != nil {
+			log.Warningf("%s: %s", "cannot deploy application", err)
+			return http.StatusConflict, err
+		}
+	}
+
+	if c.Limiter != nil {
+		c.overflow.hold()
+		release, err := c.Limiter.Acquire(ctx)
+		if err != nil && ctx.Err() != nil {
+			c.overflow.release()
+			log.Warningf("%s: %s", "cannot deploy application", err)
+			return http.StatusInternalServerError, err
+		}
+		if err != nil {
+			log.Warningf("%s: %s", "cannot deploy application", err)
+			return http.StatusTooManyRequests, OverflowError{err, c.overflow.reject()}
+		}
+		c.overflow.admit()
+		defer func() {
+			release()
+			c.overflow.release()
+		}()
+	}
+
+	deployer, _ := c.current()
+
+	*request.target, statusCode, err = deployer.Deploy(
+		ctx,
+		request.request,
+		request.environment,
+		request.org,
+		request.space,
+		request.appName,
+		request.contentType,
 		response,
 	)
+	if _, ok := err.(eventmanager.VetoError); ok {
+		log.Warningf("%s: %s", "cannot deploy application", err)
+		return statusCode, err
+	}
+	if statusCode >= http.StatusBadRequest && statusCode < http.StatusInternalServerError {
+		log.Warningf("%s: %s", "cannot deploy application", err)
+		return statusCode, err
+	}
 	if err != nil {
-		c.Log.Errorf("%s: %s", "cannot deploy application", err)
-		g.Writer.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprintf(response, "cannot deploy application: %s\n", err)
+		log.Errorf("%s: %s", "cannot deploy application", err)
+		statusCode = http.StatusInternalServerError
+	}
+
+	return statusCode, err
+}
+
+// Reload swaps the Deployer and Environments for the ones of a new config.
+// Deploys that have already started keep the Deployer they started with.
+func (c *Controller) Reload(deployer I.Deployer, environments map[string]config.Environment) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.Deployer = deployer
+	c.Environments = environments
+}
+
+// current returns the Deployer and Environments of the config the controller was last loaded with.
+func (c *Controller) current() (I.Deployer, map[string]config.Environment) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.Deployer, c.Environments
+}
+
+// deployAsync starts the deploy in the background and responds with the job id straight away.
+// The request body is read up front into a temp file because it cannot be read once the request is finished.
+func (c *Controller) deployAsync(g *gin.Context, startTime time.Time, jobID string) {
+	spooledRequest, cleanUp, err := spoolBody(g.Request)
+	if _, ok := err.(RequestBodyTooLargeError); ok {
+		g.Header(requestIDHeader, jobID)
+		g.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		g.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("cannot read request body: %s", err)})
+		return
+	}
+	request := newDeployRequest(g, spooledRequest)
+
+	job := c.Jobs.Start(jobID)
+
+	go func() {
+		defer cleanUp()
+
+		statusCode, err := c.deploy(context.Background(), request, job)
+		if err != nil {
+			fmt.Fprintf(job, "cannot deploy application: %s\n", err)
+		}
+
+		result := newDeployResult(request, startTime, statusCode, err)
+		c.recordResult(result)
+		job.Finish(result)
+	}()
+
+	g.Header(requestIDHeader, jobID)
+	g.JSON(http.StatusAccepted, gin.H{"job_id": jobID})
+}
+
+// spoolBody returns a copy of the request with its body copied into a temp file, so that the body is not held in memory.
+//
+// The returned function closes and removes the temp file.
+func spoolBody(request *http.Request) (*http.Request, func(), error) {
+	file, err := ioutil.TempFile("", "deployadactyl-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanUp := func() {
+		file.Close()
+		os.Remove(file.Name())
+	}
+
+	_, err = io.Copy(file, request.Body)
+	if err == nil {
+		_, err = file.Seek(0, 0)
+	}
+	if err != nil {
+		cleanUp()
+		return nil, nil, err
+	}
+
+	spooledRequest := *request
+	spooledRequest.Body = file
+
+	return &spooledRequest, cleanUp, nil
+}
+
+// deployEvents streams the deploy output as it is written.
+// The deploy runs to completion even if the client disconnects so the client can resume the stream with GetEvents.
+func (c *Controller) deployEvents(g *gin.Context, startTime time.Time, deployID string) {
+	stream := c.EventStreams.Open(deployID)
+
+	g.Header(requestIDHeader, deployID)
+	g.Header("Content-Type", ndjsonContentType)
+	g.Writer.WriteHeader(http.StatusOK)
+
+	followed := make(chan error, 1)
+	go func() {
+		followed <- c.EventStreams.Follow(deployID, 0, writeEvent(g.Writer))
+	}()
+
+	request := newDeployRequest(g, g.Request)
+
+	statusCode, err := c.deploy(context.Background(), request, stream)
+	if err != nil {
+		fmt.Fprintf(stream, "cannot deploy application: %s\n", err)
 		g.Error(err)
+	}
+
+	result := newDeployResult(request, startTime, statusCode, err)
+	c.recordResult(result)
+	stream.Close(result)
+
+	if err := <-followed; err != nil {
+		logger.WithRequestID(c.Log, deployID).Warningf("stopped streaming deploy %s: %s", deployID, err)
+	}
+}
+
+// respond writes the deploy output and the result of the deploy.
+// Clients that accept application/json get a deployResponse, everyone else gets the plaintext output followed by the result trailer.
+func (c *Controller) respond(g *gin.Context, response *bytes.Buffer, result S.DeployResult, requestID string, err error) {
+	g.Header(requestIDHeader, requestID)
+
+	if result.StatusCode == http.StatusUnauthorized {
+		g.Header("WWW-Authenticate", c.authenticateHeader())
+	}
+
+	if overflowErr, ok := err.(OverflowError); ok {
+		g.Header("Retry-After", strconv.Itoa(int((overflowErr.RetryAfter+time.Second-1)/time.Second)))
+	}
+
+	if accepts(g, "application/json") {
+		body := deployResponse{
+			Status:    result.StatusCode,
+			RequestID: requestID,
+			Output:    response.String(),
+		}
+		if err != nil {
+			body.Error = err.Error()
+		}
+
+		g.JSON(result.StatusCode, body)
 		return
 	}
 
-	g.Writer.WriteHeader(statusCode)
+	if err != nil {
+		fmt.Fprintf(response, "cannot deploy application: %s\n", err)
+	}
+	c.writeResultTrailer(response, result)
+
+	g.Writer.WriteHeader(result.StatusCode)
+	io.Copy(g.Writer, response)
+}
+
+// authenticateHeader is the WWW-Authenticate header that makes browsers and other interactive clients prompt for basic auth.
+func (c *Controller) authenticateHeader() string {
+	realm := c.AuthRealm
+	if realm == "" {
+		realm = defaultAuthRealm
+	}
+
+	return fmt.Sprintf("Basic realm=%q", realm)
+}
+
+// setRequestID stores the request id of the request in the gin context and the X-Request-Id header of the request.
+func (c *Controller) setRequestID(g *gin.Context) string {
+	requestID := g.Request.Header.Get(requestIDHeader)
+	if !logger.ValidRequestID(requestID) {
+		requestID = c.Randomizer.StringRunes(requestIDLength)
+	} else if c.DeployLogs != nil && c.DeployLogs.Exists(requestID) {
+		reusedID := requestID
+		requestID = c.Randomizer.StringRunes(requestIDLength)
+		logger.WithRequestID(c.Log, requestID).Infof("request id %s already has a deploy log, deploying as %s", reusedID, requestID)
+	}
+
+	g.Request.Header.Set(requestIDHeader, requestID)
+	g.Set(requestIDKey, requestID)
+
+	return requestID
+}
+
+func accepts(g *gin.Context, contentType string) bool {
+	return strings.Contains(g.Request.Header.Get("Accept"), contentType)
+}
+
+func writeEvent(w gin.ResponseWriter) func(S.StreamEvent) error {
+	encoder := json.NewEncoder(w)
+
+	return func(event S.StreamEvent) error {
+		err := encoder.Encode(event)
+		if err != nil {
+			return err
+		}
+
+		w.Flush()
+		return nil
+	}
+}
+
+func (c *Controller) recordResult(result S.DeployResult) {
+	if c.History == nil {
+		return
+	}
+
+	if c.Signer != nil {
+		result = c.Signer.Sign(result)
+	}
+
+	c.History.Add(result)
+}
+
+func (c *Controller) writeResultTrailer(response io.Writer, result S.DeployResult) {
+	if c.ResultSentinel == "" {
+		return
+	}
+
+	trailer, err := json.Marshal(result)
+	if err != nil {
+		c.Log.Errorf("cannot write the result trailer: %s", err)
+		return
+	}
+
+	fmt.Fprintf(response, "\n%s %s\n", c.ResultSentinel, trailer)
+}
+
+func getQueryInt(g *gin.Context, key string, defaultValue int) (int, error) {
+	value := g.Query(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid %s: %s: must be a non-negative integer", key, value)
+	}
+
+	return number, nil
 }
@@ -2,19 +2,38 @@ package controller_test
 
 import (
 	"bytes"
+	"compress/gzip"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/compozed/deployadactyl/config"
 	. "github.com/compozed/deployadactyl/controller"
+	"github.com/compozed/deployadactyl/deploylogs"
+	"github.com/compozed/deployadactyl/eventmanager"
+	"github.com/compozed/deployadactyl/eventstream"
+	"github.com/compozed/deployadactyl/jobs"
+	"github.com/compozed/deployadactyl/limiter"
 	"github.com/compozed/deployadactyl/logger"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/readiness"
+	"github.com/compozed/deployadactyl/signer"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/compozed/deployadactyl/version"
 	"github.com/gin-gonic/gin"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/op/go-logging"
+	"github.com/spf13/afero"
+	"golang.org/x/net/context"
 )
 
 const (
@@ -24,11 +43,14 @@ const (
 var _ = Describe("Controller", func() {
 
 	var (
-		deployer   *mocks.Deployer
-		controller *Controller
-		router     *gin.Engine
-		resp       *httptest.ResponseRecorder
-		jsonBuffer *bytes.Buffer
+		deployer       *mocks.Deployer
+		history        *mocks.History
+		metrics        *mocks.Metrics
+		randomizerMock *mocks.Randomizer
+		controller     *Controller
+		router         *gin.Engine
+		resp           *httptest.ResponseRecorder
+		jsonBuffer     *bytes.Buffer
 
 		apiURL      string
 		appName     string
@@ -39,10 +61,17 @@ var _ = Describe("Controller", func() {
 
 	BeforeEach(func() {
 		deployer = &mocks.Deployer{}
+		history = &mocks.History{}
+		metrics = &mocks.Metrics{}
+		randomizerMock = &mocks.Randomizer{}
 
 		controller = &Controller{
-			Deployer: deployer,
-			Log:      logger.DefaultLogger(GinkgoWriter, logging.DEBUG, "api_test"),
+			Deployer:       deployer,
+			History:        history,
+			Metrics:        metrics,
+			Randomizer:     randomizerMock,
+			ResultSentinel: "__DEPLOYADACTYL_RESULT__",
+			Log:            logger.DefaultLogger(GinkgoWriter, logging.DEBUG, "api_test", logger.TextFormat),
 		}
 
 		router = gin.New()
@@ -55,6 +84,17 @@ var _ = Describe("Controller", func() {
 		space = "space-" + randomizer.StringRunes(10)
 
 		router.POST("/v1/apps/:environment/:org/:space/:appName", controller.Deploy)
+		router.POST("/v1/apps", controller.Deploy)
+		router.GET("/v1/history", controller.GetHistory)
+		router.GET("/metrics", controller.GetMetrics)
+		router.GET("/health", controller.GetHealth)
+		router.GET("/ready", controller.GetReady)
+		router.GET("/version", controller.GetVersion)
+		router.GET("/environments", controller.ListEnvironments)
+		router.GET("/v1/deploys/:deployID/events", controller.GetEvents)
+		router.GET("/v1/deploy/status/:jobID", controller.GetJobStatus)
+		router.GET("/v1/deploy/logs/:requestID", controller.GetDeployLog)
+		router.GET("/deploy/logs/:requestID", controller.GetDeployLog)
 	})
 
 	Describe("Deploy handler", func() {
@@ -79,6 +119,110 @@ var _ = Describe("Controller", func() {
 				Expect(deployer.DeployCall.Received.Space).To(Equal(space))
 				Expect(deployer.DeployCall.Received.AppName).To(Equal(appName))
 			})
+
+			It("records a successful result in the history", func() {
+				apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+
+				deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+				router.ServeHTTP(resp, req)
+
+				Expect(history.AddCall.Received.Results).To(HaveLen(1))
+
+				result := history.AddCall.Received.Results[0]
+				Expect(result.Environment).To(Equal(environment))
+				Expect(result.Org).To(Equal(org))
+				Expect(result.Space).To(Equal(space))
+				Expect(result.AppName).To(Equal(appName))
+				Expect(result.Status).To(Equal("success"))
+				Expect(result.StatusCode).To(Equal(http.StatusOK))
+				Expect(result.Error).To(BeEmpty())
+				Expect(result.Signature).To(BeEmpty())
+			})
+
+			It("records the org and space the deployer resolved", func() {
+				apiURL = fmt.Sprintf("/v1/apps?environment=%s&appName=%s", environment, appName)
+
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+
+				deployer.DeployCall.Returns.StatusCode = http.StatusOK
+				deployer.DeployCall.Returns.Target = S.DeployTarget{Org: org, Space: space}
+
+				router.ServeHTTP(resp, req)
+
+				Expect(deployer.DeployCall.Received.Org).To(BeEmpty())
+				Expect(deployer.DeployCall.Received.Space).To(BeEmpty())
+
+				Expect(history.AddCall.Received.Results).To(HaveLen(1))
+
+				result := history.AddCall.Received.Results[0]
+				Expect(result.Org).To(Equal(org))
+				Expect(result.Space).To(Equal(space))
+			})
+
+			It("records the user and the request id of the deploy", func() {
+				apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+				req.SetBasicAuth("user-from-basic-auth", "password")
+				req.Header.Set("X-Request-Id", "requestID-from-header")
+
+				deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+				router.ServeHTTP(resp, req)
+
+				Expect(history.AddCall.Received.Results).To(HaveLen(1))
+
+				result := history.AddCall.Received.Results[0]
+				Expect(result.User).To(Equal("user-from-basic-auth"))
+				Expect(result.RequestID).To(Equal("requestID-from-header"))
+			})
+
+			It("signs the recorded result when a signer is provided", func() {
+				resultSigner := signer.New("key-" + randomizer.StringRunes(10))
+				controller.Signer = resultSigner
+
+				apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+
+				deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+				router.ServeHTTP(resp, req)
+
+				Expect(history.AddCall.Received.Results).To(HaveLen(1))
+
+				result := history.AddCall.Received.Results[0]
+				Expect(result.Signature).ToNot(BeEmpty())
+				Expect(resultSigner.Verify(result)).To(Succeed())
+			})
+		})
+
+		Context("when the client closes the connection", func() {
+			It("cancels the context that is passed to the deployer", func() {
+				apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+
+				ctx, cancel := context.WithCancel(context.Background())
+
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+				req = req.WithContext(ctx)
+
+				deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+				router.ServeHTTP(resp, req)
+				Expect(deployer.DeployCall.Received.Context.Err()).To(BeNil())
+
+				cancel()
+
+				Expect(deployer.DeployCall.Received.Context.Err()).To(Equal(context.Canceled))
+			})
 		})
 
 		Context("when deployer fails", func() {
@@ -96,6 +240,1448 @@ var _ = Describe("Controller", func() {
 				Expect(resp.Code).To(Equal(http.StatusInternalServerError))
 				Expect(resp.Body).To(ContainSubstring("bork"))
 			})
+
+			It("records a failed result in the history", func() {
+				apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+
+				deployer.DeployCall.Returns.Error = errors.New("bork")
+				deployer.DeployCall.Returns.StatusCode = http.StatusInternalServerError
+
+				router.ServeHTTP(resp, req)
+
+				Expect(history.AddCall.Received.Results).To(HaveLen(1))
+
+				result := history.AddCall.Received.Results[0]
+				Expect(result.Status).To(Equal("failure"))
+				Expect(result.StatusCode).To(Equal(http.StatusInternalServerError))
+				Expect(result.Error).To(Equal("bork"))
+			})
+		})
+
+		Context("when the deployer rejects the request", func() {
+			It("responds with the status code of the deployer", func() {
+				apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+
+				deployer.DeployCall.Returns.Error = errors.New("unknown strategy")
+				deployer.DeployCall.Returns.StatusCode = http.StatusBadRequest
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+				Expect(resp.Body).To(ContainSubstring("unknown strategy"))
+			})
+
+			It("reports the status code of the deployer in the JSON result", func() {
+				apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Accept", "application/json")
+
+				deployer.DeployCall.Returns.Error = errors.New("unknown strategy")
+				deployer.DeployCall.Returns.StatusCode = http.StatusBadRequest
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+
+				var body map[string]interface{}
+				Expect(json.Unmarshal(resp.Body.Bytes(), &body)).To(Succeed())
+				Expect(body["status"]).To(BeEquivalentTo(http.StatusBadRequest))
+				Expect(history.AddCall.Received.Results[0].StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("when a deploy.start handler vetoes the deploy", func() {
+			It("responds with the status code and message of the veto", func() {
+				apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Accept", "application/json")
+
+				deployer.DeployCall.Returns.Error = eventmanager.VetoError{StatusCode: http.StatusForbidden, Message: "production is frozen until the change window opens"}
+				deployer.DeployCall.Returns.StatusCode = http.StatusForbidden
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusForbidden))
+
+				var body map[string]interface{}
+				Expect(json.Unmarshal(resp.Body.Bytes(), &body)).To(Succeed())
+				Expect(body["error"]).To(Equal("production is frozen until the change window opens"))
+				Expect(body["status"]).To(BeEquivalentTo(http.StatusForbidden))
+			})
+		})
+
+		Context("when the deploy is missing basic auth", func() {
+			It("responds with http.StatusUnauthorized and a WWW-Authenticate header", func() {
+				apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+
+				deployer.DeployCall.Returns.Error = errors.New("basic auth header not found")
+				deployer.DeployCall.Returns.StatusCode = http.StatusUnauthorized
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusUnauthorized))
+				Expect(resp.Header().Get("WWW-Authenticate")).To(Equal(`Basic realm="deployadactyl"`))
+			})
+
+			It("uses the configured realm", func() {
+				controller.AuthRealm = "Production Deploys"
+				apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+
+				deployer.DeployCall.Returns.Error = errors.New("basic auth header not found")
+				deployer.DeployCall.Returns.StatusCode = http.StatusUnauthorized
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Header().Get("WWW-Authenticate")).To(Equal(`Basic realm="Production Deploys"`))
+			})
+		})
+
+		Context("when the deploy succeeds", func() {
+			It("does not set a WWW-Authenticate header", func() {
+				apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+
+				deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Header().Get("WWW-Authenticate")).To(BeEmpty())
+			})
 		})
 	})
-})
+
+	Describe("deploying to an unknown environment", func() {
+		BeforeEach(func() {
+			controller.Environments = map[string]config.Environment{
+				"production": {Name: "production"},
+				"staging":    {Name: "staging"},
+				"secure":     {Name: "secure", Authenticate: true},
+			}
+		})
+
+		It("does not deploy and returns http.StatusNotFound with the environments", func() {
+			req, err := http.NewRequest("POST", fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName), jsonBuffer)
+			Expect(err).ToNot(HaveOccurred())
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusNotFound))
+			Expect(deployer.DeployCall.Received.Environment).To(BeEmpty())
+			Expect(history.AddCall.Received.Results).To(BeEmpty())
+
+			var body map[string]interface{}
+			Expect(json.Unmarshal(resp.Body.Bytes(), &body)).To(Succeed())
+			Expect(body["error"]).To(Equal("environment not found: " + environment))
+			Expect(body["environments"]).To(Equal([]interface{}{"production", "staging"}))
+		})
+
+		It("lists the environments that require authentication when the request has basic auth", func() {
+			req, err := http.NewRequest("POST", fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName), jsonBuffer)
+			Expect(err).ToNot(HaveOccurred())
+			req.SetBasicAuth("username", "password")
+
+			router.ServeHTTP(resp, req)
+
+			var body map[string]interface{}
+			Expect(json.Unmarshal(resp.Body.Bytes(), &body)).To(Succeed())
+			Expect(body["environments"]).To(Equal([]interface{}{"production", "secure", "staging"}))
+		})
+
+		It("deploys to a configured environment", func() {
+			deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+			req, err := http.NewRequest("POST", fmt.Sprintf("/v1/apps/staging/%s/%s/%s", org, space, appName), jsonBuffer)
+			Expect(err).ToNot(HaveOccurred())
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(deployer.DeployCall.Received.Environment).To(Equal("staging"))
+		})
+	})
+
+	Describe("reading the application from the request", func() {
+		BeforeEach(func() {
+			deployer.DeployCall.Returns.StatusCode = http.StatusOK
+		})
+
+		It("reads the application from the path", func() {
+			req, err := http.NewRequest("POST", fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName), jsonBuffer)
+			Expect(err).ToNot(HaveOccurred())
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(deployer.DeployCall.Received.Environment).To(Equal(environment))
+			Expect(deployer.DeployCall.Received.Org).To(Equal(org))
+			Expect(deployer.DeployCall.Received.Space).To(Equal(space))
+			Expect(deployer.DeployCall.Received.AppName).To(Equal(appName))
+		})
+
+		It("reads the application from the query when the path does not have it", func() {
+			req, err := http.NewRequest("POST", fmt.Sprintf("/v1/apps?environment=%s&org=%s&space=%s&appName=%s", environment, org, space, appName), jsonBuffer)
+			Expect(err).ToNot(HaveOccurred())
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(deployer.DeployCall.Received.Environment).To(Equal(environment))
+			Expect(deployer.DeployCall.Received.Org).To(Equal(org))
+			Expect(deployer.DeployCall.Received.Space).To(Equal(space))
+			Expect(deployer.DeployCall.Received.AppName).To(Equal(appName))
+		})
+
+		It("reads the values missing from the path from the query", func() {
+			router.POST("/v1/apps/:environment", controller.Deploy)
+
+			req, err := http.NewRequest("POST", fmt.Sprintf("/v1/apps/%s?org=%s&space=%s&appName=%s", environment, org, space, appName), jsonBuffer)
+			Expect(err).ToNot(HaveOccurred())
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(deployer.DeployCall.Received.Environment).To(Equal(environment))
+			Expect(deployer.DeployCall.Received.Org).To(Equal(org))
+			Expect(deployer.DeployCall.Received.Space).To(Equal(space))
+			Expect(deployer.DeployCall.Received.AppName).To(Equal(appName))
+		})
+
+		It("prefers the path to the query", func() {
+			req, err := http.NewRequest("POST", fmt.Sprintf("/v1/apps/%s/%s/%s/%s?environment=other-environment&org=other-org&space=other-space&appName=other-app", environment, org, space, appName), jsonBuffer)
+			Expect(err).ToNot(HaveOccurred())
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(deployer.DeployCall.Received.Environment).To(Equal(environment))
+			Expect(deployer.DeployCall.Received.Org).To(Equal(org))
+			Expect(deployer.DeployCall.Received.Space).To(Equal(space))
+			Expect(deployer.DeployCall.Received.AppName).To(Equal(appName))
+		})
+	})
+
+	Describe("deploying a multipart form", func() {
+		var (
+			formBuffer *bytes.Buffer
+			form       *multipart.Writer
+		)
+
+		BeforeEach(func() {
+			apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+
+			formBuffer = &bytes.Buffer{}
+			form = multipart.NewWriter(formBuffer)
+
+			deployer.DeployCall.Returns.StatusCode = http.StatusOK
+		})
+
+		It("deploys the uploaded file as a zip", func() {
+			file, err := form.CreateFormFile("artifact", "artifact.zip")
+			Expect(err).ToNot(HaveOccurred())
+			fmt.Fprint(file, "zip file contents")
+			Expect(form.Close()).To(Succeed())
+
+			req, err := http.NewRequest("POST", apiURL, formBuffer)
+			Expect(err).ToNot(HaveOccurred())
+			req.Header.Set("Content-Type", form.FormDataContentType())
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(deployer.DeployCall.Received.ContentType).To(Equal("application/zip"))
+			Expect(string(deployer.DeployCall.Received.Body)).To(Equal("zip file contents"))
+			Expect(deployer.DeployCall.Received.Org).To(Equal(org))
+			Expect(deployer.DeployCall.Received.Space).To(Equal(space))
+		})
+
+		It("uses the org and space fields of the form", func() {
+			Expect(form.WriteField("org", "org-from-form")).To(Succeed())
+			Expect(form.WriteField("space", "space-from-form")).To(Succeed())
+			file, err := form.CreateFormFile("upload", "artifact.zip")
+			Expect(err).ToNot(HaveOccurred())
+			fmt.Fprint(file, "zip file contents")
+			Expect(form.Close()).To(Succeed())
+
+			req, err := http.NewRequest("POST", apiURL, formBuffer)
+			Expect(err).ToNot(HaveOccurred())
+			req.Header.Set("Content-Type", form.FormDataContentType())
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(string(deployer.DeployCall.Received.Body)).To(Equal("zip file contents"))
+			Expect(deployer.DeployCall.Received.Org).To(Equal("org-from-form"))
+			Expect(deployer.DeployCall.Received.Space).To(Equal("space-from-form"))
+		})
+
+		Context("when the form has no file", func() {
+			It("does not deploy and returns http.StatusBadRequest", func() {
+				Expect(form.WriteField("org", "org-from-form")).To(Succeed())
+				Expect(form.Close()).To(Succeed())
+
+				req, err := http.NewRequest("POST", apiURL, formBuffer)
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Content-Type", form.FormDataContentType())
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+				Expect(resp.Body.String()).To(ContainSubstring(MissingArtifactFileError{}.Error()))
+				Expect(deployer.DeployCall.Received.AppName).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("limiting the request body size", func() {
+		BeforeEach(func() {
+			apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+			controller.MaxRequestBodySize = 16
+
+			deployer.DeployCall.Returns.StatusCode = http.StatusOK
+		})
+
+		It("deploys a request body within the limit", func() {
+			req, err := http.NewRequest("POST", apiURL, bytes.NewBufferString("zip contents"))
+			Expect(err).ToNot(HaveOccurred())
+			req.Header.Set("Content-Type", "application/zip")
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(string(deployer.DeployCall.Received.Body)).To(Equal("zip contents"))
+		})
+
+		Context("when the content length is over the limit", func() {
+			It("does not deploy and returns http.StatusRequestEntityTooLarge", func() {
+				req, err := http.NewRequest("POST", apiURL, bytes.NewBufferString("zip contents over the limit"))
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Content-Type", "application/zip")
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusRequestEntityTooLarge))
+				Expect(resp.Body).To(ContainSubstring("payload too large"))
+				Expect(deployer.DeployCall.Received.Request).To(BeNil())
+			})
+		})
+
+		Context("when a request body of unknown length is over the limit", func() {
+			It("fails the deploy with http.StatusRequestEntityTooLarge", func() {
+				req, err := http.NewRequest("POST", apiURL, bytes.NewBufferString(`{"artifact_url": "artifact-url-over-the-limit"}`))
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Content-Type", "application/json")
+				req.ContentLength = -1
+
+				deployer.DeployCall.Returns.Error = errors.New("cannot read request body")
+				deployer.DeployCall.Returns.StatusCode = http.StatusInternalServerError
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusRequestEntityTooLarge))
+				Expect(resp.Body).To(ContainSubstring(RequestBodyTooLargeError{16}.Error()))
+				Expect(deployer.DeployCall.Received.Body).To(HaveLen(16))
+			})
+		})
+	})
+
+	Describe("deploying a gzip compressed request body", func() {
+		var gzipped = func(body string) *bytes.Buffer {
+			buffer := &bytes.Buffer{}
+			writer := gzip.NewWriter(buffer)
+			fmt.Fprint(writer, body)
+			Expect(writer.Close()).To(Succeed())
+
+			return buffer
+		}
+
+		BeforeEach(func() {
+			apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+
+			deployer.DeployCall.Returns.StatusCode = http.StatusOK
+		})
+
+		It("decompresses a json request body", func() {
+			req, err := http.NewRequest("POST", apiURL, gzipped(`{"artifact_url": "artifact-url"}`))
+			Expect(err).ToNot(HaveOccurred())
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Content-Encoding", "gzip")
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(deployer.DeployCall.Received.ContentType).To(Equal("application/json"))
+			Expect(string(deployer.DeployCall.Received.Body)).To(Equal(`{"artifact_url": "artifact-url"}`))
+		})
+
+		It("decompresses a zip request body", func() {
+			req, err := http.NewRequest("POST", apiURL, gzipped("zip file contents"))
+			Expect(err).ToNot(HaveOccurred())
+			req.Header.Set("Content-Type", "application/zip")
+			req.Header.Set("Content-Encoding", "gzip")
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(deployer.DeployCall.Received.ContentType).To(Equal("application/zip"))
+			Expect(string(deployer.DeployCall.Received.Body)).To(Equal("zip file contents"))
+		})
+
+		Context("when the request body is not valid gzip", func() {
+			It("does not deploy and returns http.StatusBadRequest", func() {
+				req, err := http.NewRequest("POST", apiURL, bytes.NewBufferString("not gzip"))
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Content-Type", "application/zip")
+				req.Header.Set("Content-Encoding", "gzip")
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+				Expect(resp.Body.String()).To(ContainSubstring("cannot decompress request body"))
+				Expect(deployer.DeployCall.Received.AppName).To(BeEmpty())
+			})
+		})
+
+		Context("when the decompressed body is over the limit", func() {
+			It("does not deploy and returns http.StatusRequestEntityTooLarge", func() {
+				controller.MaxRequestBodySize = 4096
+				body := gzipped(strings.Repeat("0", 1024*1024))
+				Expect(body.Len()).To(BeNumerically("<", 4096))
+
+				req, err := http.NewRequest("POST", apiURL, body)
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Content-Type", "application/zip")
+				req.Header.Set("Content-Encoding", "gzip")
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusRequestEntityTooLarge))
+				Expect(resp.Body.String()).To(ContainSubstring(RequestBodyTooLargeError{4096}.Error()))
+				Expect(deployer.DeployCall.Received.AppName).To(BeEmpty())
+			})
+		})
+
+		Context("when the gzip compressed body is truncated", func() {
+			It("does not deploy and returns http.StatusBadRequest", func() {
+				body := gzipped("zip file contents").Bytes()
+
+				req, err := http.NewRequest("POST", apiURL, bytes.NewReader(body[:len(body)-4]))
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Content-Type", "application/zip")
+				req.Header.Set("Content-Encoding", "gzip")
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+				Expect(resp.Body.String()).To(ContainSubstring("cannot decompress request body"))
+			})
+		})
+	})
+
+	Describe("debouncing deploys", func() {
+		var debouncer *mocks.Debouncer
+
+		BeforeEach(func() {
+			apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+
+			debouncer = &mocks.Debouncer{}
+			controller.Debouncer = debouncer
+		})
+
+		It("waits on the application being deployed and then deploys", func() {
+			req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+			Expect(err).ToNot(HaveOccurred())
+
+			deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(debouncer.WaitCall.Received.Key).To(Equal(fmt.Sprintf("%s/%s/%s/%s", environment, org, space, appName)))
+			Expect(deployer.DeployCall.Received.AppName).To(Equal(appName))
+		})
+
+		Context("when the org and space are only in the JSON request body", func() {
+			It("waits on the org and space of the body and passes the body on to the deployer", func() {
+				apiURL = fmt.Sprintf("/v1/apps?environment=%s&appName=%s", environment, appName)
+				body := fmt.Sprintf(`{"org": "%s", "space": "%s"}`, org, space)
+
+				req, err := http.NewRequest("POST", apiURL, bytes.NewBufferString(body))
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Content-Type", "application/json")
+
+				deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+				router.ServeHTTP(resp, req)
+
+				Expect(debouncer.WaitCall.Received.Key).To(Equal(fmt.Sprintf("%s/%s/%s/%s", environment, org, space, appName)))
+				Expect(string(deployer.DeployCall.Received.Body)).To(Equal(body))
+			})
+		})
+
+		Context("when the org and space are rendered from the templates of the environment", func() {
+			It("waits on the rendered org and space", func() {
+				controller.Environments = map[string]config.Environment{
+					environment: {Name: environment, OrgTemplate: "{{.Environment}}-org", SpaceTemplate: "{{.AppName}}-space"},
+				}
+				apiURL = fmt.Sprintf("/v1/apps?environment=%s&appName=%s", environment, appName)
+
+				req, err := http.NewRequest("POST", apiURL, bytes.NewBufferString("{}"))
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Content-Type", "application/json")
+
+				deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+				router.ServeHTTP(resp, req)
+
+				Expect(debouncer.WaitCall.Received.Key).To(Equal(fmt.Sprintf("%s/%s-org/%s-space/%s", environment, environment, appName, appName)))
+			})
+		})
+
+		Context("when a newer deploy supersedes the deploy", func() {
+			It("does not deploy and returns http.StatusConflict", func() {
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+
+				debouncer.WaitCall.Returns.Error = errors.New("superseded")
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusConflict))
+				Expect(resp.Body.String()).To(ContainSubstring("cannot deploy application: superseded"))
+				Expect(deployer.DeployCall.Received.AppName).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("limiting concurrent deploys", func() {
+		var blocking *blockingDeployer
+
+		BeforeEach(func() {
+			apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+
+			blocking = &blockingDeployer{started: make(chan struct{}), release: make(chan struct{})}
+			controller.Deployer = blocking
+			controller.History = nil
+			controller.Randomizer = randomizer.Randomizer{}
+		})
+
+		deployConcurrently := func() <-chan *httptest.ResponseRecorder {
+			responses := make(chan *httptest.ResponseRecorder, 1)
+
+			go func() {
+				defer GinkgoRecover()
+
+				req, err := http.NewRequest("POST", apiURL, bytes.NewBufferString("{}"))
+				Expect(err).ToNot(HaveOccurred())
+
+				resp := httptest.NewRecorder()
+				router.ServeHTTP(resp, req)
+				responses <- resp
+			}()
+
+			return responses
+		}
+
+		It("runs the maximum number of deploys and rejects one more with http.StatusTooManyRequests", func() {
+			controller.Limiter = limiter.New(2, 0)
+
+			running := []<-chan *httptest.ResponseRecorder{deployConcurrently(), deployConcurrently()}
+			Eventually(blocking.started).Should(Receive())
+			Eventually(blocking.started).Should(Receive())
+
+			var rejected *httptest.ResponseRecorder
+			Eventually(deployConcurrently()).Should(Receive(&rejected))
+			Expect(rejected.Code).To(Equal(http.StatusTooManyRequests))
+			Expect(rejected.Body.String()).To(ContainSubstring("server busy, retry later"))
+
+			close(blocking.release)
+
+			for _, responses := range running {
+				var resp *httptest.ResponseRecorder
+				Eventually(responses).Should(Receive(&resp))
+				Expect(resp.Code).To(Equal(http.StatusOK))
+			}
+		})
+
+		It("tells rejected deploys to retry later the more deploys are rejected in a row", func() {
+			controller.Limiter = limiter.New(2, 0)
+
+			running := []<-chan *httptest.ResponseRecorder{deployConcurrently(), deployConcurrently()}
+			Eventually(blocking.started).Should(Receive())
+			Eventually(blocking.started).Should(Receive())
+
+			var rejected *httptest.ResponseRecorder
+			Eventually(deployConcurrently()).Should(Receive(&rejected))
+			Expect(rejected.Code).To(Equal(http.StatusTooManyRequests))
+
+			retryAfter, err := strconv.Atoi(rejected.Header().Get("Retry-After"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(retryAfter).To(BeNumerically(">=", 2))
+			Expect(retryAfter).To(BeNumerically("<=", 3))
+
+			Eventually(deployConcurrently()).Should(Receive(&rejected))
+
+			retryAfter, err = strconv.Atoi(rejected.Header().Get("Retry-After"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(retryAfter).To(BeNumerically(">=", 4))
+			Expect(retryAfter).To(BeNumerically("<=", 6))
+
+			close(blocking.release)
+
+			for _, responses := range running {
+				var resp *httptest.ResponseRecorder
+				Eventually(responses).Should(Receive(&resp))
+				Expect(resp.Header().Get("Retry-After")).To(BeEmpty())
+			}
+		})
+
+		Context("when deploys can be queued", func() {
+			It("runs the queued deploy once a running deploy finishes", func() {
+				controller.Limiter = limiter.New(1, 1)
+
+				running := deployConcurrently()
+				Eventually(blocking.started).Should(Receive())
+
+				queued := deployConcurrently()
+				Consistently(blocking.started, 100*time.Millisecond).ShouldNot(Receive())
+
+				var rejected *httptest.ResponseRecorder
+				Eventually(deployConcurrently()).Should(Receive(&rejected))
+				Expect(rejected.Code).To(Equal(http.StatusTooManyRequests))
+
+				blocking.release <- struct{}{}
+				Eventually(running).Should(Receive())
+
+				Eventually(blocking.started).Should(Receive())
+				blocking.release <- struct{}{}
+
+				var resp *httptest.ResponseRecorder
+				Eventually(queued).Should(Receive(&resp))
+				Expect(resp.Code).To(Equal(http.StatusOK))
+			})
+		})
+	})
+
+	Describe("the result trailer", func() {
+		var parseTrailer = func(body string) S.DeployResult {
+			lines := strings.Split(strings.TrimRight(body, "\n"), "\n")
+			lastLine := lines[len(lines)-1]
+
+			Expect(lastLine).To(HavePrefix("__DEPLOYADACTYL_RESULT__ "))
+
+			var result S.DeployResult
+			Expect(json.Unmarshal([]byte(strings.TrimPrefix(lastLine, "__DEPLOYADACTYL_RESULT__ ")), &result)).To(Succeed())
+
+			return result
+		}
+
+		BeforeEach(func() {
+			apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+		})
+
+		It("ends a successful deploy with the result", func() {
+			req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+			Expect(err).ToNot(HaveOccurred())
+
+			deployer.DeployCall.Returns.StatusCode = http.StatusOK
+			deployer.DeployCall.Write.Output = "deploy success"
+
+			router.ServeHTTP(resp, req)
+
+			result := parseTrailer(resp.Body.String())
+			Expect(result.Status).To(Equal("success"))
+			Expect(result.StatusCode).To(Equal(http.StatusOK))
+			Expect(result.Environment).To(Equal(environment))
+			Expect(result.AppName).To(Equal(appName))
+		})
+
+		It("ends a failed deploy with the result", func() {
+			req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+			Expect(err).ToNot(HaveOccurred())
+
+			deployer.DeployCall.Returns.Error = errors.New("bork")
+			deployer.DeployCall.Returns.StatusCode = http.StatusInternalServerError
+
+			router.ServeHTTP(resp, req)
+
+			result := parseTrailer(resp.Body.String())
+			Expect(result.Status).To(Equal("failure"))
+			Expect(result.StatusCode).To(Equal(http.StatusInternalServerError))
+			Expect(result.Error).To(Equal("bork"))
+		})
+
+		Context("when the result sentinel is empty", func() {
+			It("does not write a trailer", func() {
+				controller.ResultSentinel = ""
+
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+
+				deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Body.String()).ToNot(ContainSubstring("__DEPLOYADACTYL_RESULT__"))
+			})
+		})
+	})
+
+	Describe("the response format", func() {
+		var requestID string
+
+		BeforeEach(func() {
+			apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+			requestID = "requestID-" + randomizer.StringRunes(10)
+			randomizerMock.RandomizeCall.Returns.Runes = requestID
+		})
+
+		Context("when the client accepts application/json", func() {
+			var parseBody = func() map[string]interface{} {
+				var body map[string]interface{}
+				Expect(json.Unmarshal(resp.Body.Bytes(), &body)).To(Succeed())
+
+				return body
+			}
+
+			It("responds with the error as json", func() {
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Accept", "application/json")
+
+				deployer.DeployCall.Returns.Error = errors.New("bork")
+				deployer.DeployCall.Returns.StatusCode = http.StatusInternalServerError
+
+				router.ServeHTTP(resp, req)
+
+				body := parseBody()
+				Expect(resp.Code).To(Equal(http.StatusInternalServerError))
+				Expect(body["error"]).To(Equal("bork"))
+				Expect(body["status"]).To(BeEquivalentTo(http.StatusInternalServerError))
+				Expect(body["request_id"]).To(Equal(requestID))
+			})
+
+			It("responds with the output of a successful deploy as json", func() {
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Accept", "application/json")
+
+				deployer.DeployCall.Returns.StatusCode = http.StatusOK
+				deployer.DeployCall.Write.Output = "deploy success"
+
+				router.ServeHTTP(resp, req)
+
+				body := parseBody()
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(body).ToNot(HaveKey("error"))
+				Expect(body["status"]).To(BeEquivalentTo(http.StatusOK))
+				Expect(body["output"]).To(ContainSubstring("deploy success"))
+			})
+
+			It("uses the request id from the X-Request-Id header", func() {
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Accept", "application/json")
+				req.Header.Set("X-Request-Id", "requestID-from-header")
+
+				deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+				router.ServeHTTP(resp, req)
+
+				Expect(parseBody()["request_id"]).To(Equal("requestID-from-header"))
+				Expect(resp.Header().Get("X-Request-Id")).To(Equal("requestID-from-header"))
+			})
+
+			It("generates a request id when the X-Request-Id header is not valid", func() {
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Accept", "application/json")
+				req.Header.Set("X-Request-Id", "bad request id %{level}")
+
+				deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+				router.ServeHTTP(resp, req)
+
+				Expect(parseBody()["request_id"]).To(Equal(requestID))
+				Expect(resp.Header().Get("X-Request-Id")).To(Equal(requestID))
+			})
+
+			It("generates a request id when the X-Request-Id header already has a deploy log", func() {
+				deployLogs, err := deploylogs.New(&afero.Afero{Fs: afero.NewMemMapFs()}, "/logs", time.Hour)
+				Expect(err).ToNot(HaveOccurred())
+				controller.DeployLogs = deployLogs
+
+				deployLog, err := deployLogs.Create("requestID-from-header")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(deployLog.Close()).To(Succeed())
+
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Accept", "application/json")
+				req.Header.Set("X-Request-Id", "requestID-from-header")
+
+				deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Header().Get("X-Request-Id")).To(Equal(requestID))
+				Expect(deployer.DeployCall.Received.Request.Header.Get("X-Request-Id")).To(Equal(requestID))
+			})
+
+			It("passes the request id to the deployer in the X-Request-Id header", func() {
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Accept", "application/json")
+
+				deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+				router.ServeHTTP(resp, req)
+
+				Expect(deployer.DeployCall.Received.Request.Header.Get("X-Request-Id")).To(Equal(requestID))
+			})
+		})
+
+		Context("when the client does not accept application/json", func() {
+			It("responds with the error as plaintext", func() {
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+
+				deployer.DeployCall.Returns.Error = errors.New("bork")
+				deployer.DeployCall.Returns.StatusCode = http.StatusInternalServerError
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Body.String()).To(HavePrefix("cannot deploy application: bork\n"))
+				Expect(resp.Header().Get("X-Request-Id")).To(Equal(requestID))
+			})
+		})
+	})
+
+	Describe("event streams", func() {
+		var (
+			requestID   string
+			parseEvents func() []S.StreamEvent
+		)
+
+		BeforeEach(func() {
+			apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s", environment, org, space, appName)
+			requestID = "requestID-" + randomizer.StringRunes(10)
+			randomizerMock.RandomizeCall.Returns.Runes = requestID
+
+			controller.EventStreams = eventstream.New(10, 100)
+
+			parseEvents = func() []S.StreamEvent {
+				var events []S.StreamEvent
+
+				decoder := json.NewDecoder(resp.Body)
+				for decoder.More() {
+					var event S.StreamEvent
+					Expect(decoder.Decode(&event)).To(Succeed())
+					events = append(events, event)
+				}
+
+				return events
+			}
+		})
+
+		Context("when the client accepts application/x-ndjson", func() {
+			It("streams the deploy output as numbered events ending with the result", func() {
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Accept", "application/x-ndjson")
+
+				deployer.DeployCall.Returns.StatusCode = http.StatusOK
+				deployer.DeployCall.Write.Output = "deploy success\n"
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(resp.Header().Get("Content-Type")).To(Equal("application/x-ndjson"))
+				Expect(resp.Header().Get("X-Request-Id")).To(Equal(requestID))
+
+				events := parseEvents()
+				Expect(events).To(HaveLen(2))
+				Expect(events[0]).To(Equal(S.StreamEvent{Seq: 1, Line: "deploy success"}))
+				Expect(events[1].Seq).To(Equal(2))
+				Expect(events[1].Result.Status).To(Equal("success"))
+				Expect(events[1].Result.AppName).To(Equal(appName))
+			})
+
+			It("streams the error of a failed deploy", func() {
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Accept", "application/x-ndjson")
+
+				deployer.DeployCall.Returns.Error = errors.New("bork")
+				deployer.DeployCall.Returns.StatusCode = http.StatusInternalServerError
+
+				router.ServeHTTP(resp, req)
+
+				events := parseEvents()
+				Expect(events).To(HaveLen(2))
+				Expect(events[0].Line).To(Equal("cannot deploy application: bork"))
+				Expect(events[1].Result.Status).To(Equal("failure"))
+				Expect(events[1].Result.Error).To(Equal("bork"))
+			})
+		})
+
+		Describe("GetEvents handler", func() {
+			It("resumes the stream of a deploy after since", func() {
+				req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Accept", "application/x-ndjson")
+
+				deployer.DeployCall.Returns.StatusCode = http.StatusOK
+				deployer.DeployCall.Write.Output = "first\nsecond\n"
+
+				router.ServeHTTP(resp, req)
+
+				resp = httptest.NewRecorder()
+				req, err = http.NewRequest("GET", "/v1/deploys/"+requestID+"/events?since=1", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusOK))
+
+				events := parseEvents()
+				Expect(events).To(HaveLen(2))
+				Expect(events[0]).To(Equal(S.StreamEvent{Seq: 2, Line: "second"}))
+				Expect(events[1].Seq).To(Equal(3))
+				Expect(events[1].Result).ToNot(BeNil())
+			})
+
+			Context("when the deploy is unknown", func() {
+				It("returns http.StatusNotFound", func() {
+					req, err := http.NewRequest("GET", "/v1/deploys/"+requestID+"/events", nil)
+					Expect(err).ToNot(HaveOccurred())
+
+					router.ServeHTTP(resp, req)
+
+					Expect(resp.Code).To(Equal(http.StatusNotFound))
+					Expect(resp.Body.String()).To(ContainSubstring("unknown deploy"))
+				})
+			})
+
+			Context("when since is invalid", func() {
+				It("returns http.StatusBadRequest", func() {
+					req, err := http.NewRequest("GET", "/v1/deploys/"+requestID+"/events?since=bork", nil)
+					Expect(err).ToNot(HaveOccurred())
+
+					router.ServeHTTP(resp, req)
+
+					Expect(resp.Code).To(Equal(http.StatusBadRequest))
+				})
+			})
+		})
+	})
+
+	Describe("asynchronous deploys", func() {
+		var (
+			jobID     string
+			getStatus func() S.JobStatus
+		)
+
+		BeforeEach(func() {
+			apiURL = fmt.Sprintf("/v1/apps/%s/%s/%s/%s?async=true", environment, org, space, appName)
+			jobID = "jobID-" + randomizer.StringRunes(10)
+			randomizerMock.RandomizeCall.Returns.Runes = jobID
+
+			controller.Jobs = jobs.New(time.Hour)
+
+			getStatus = func() S.JobStatus {
+				resp := httptest.NewRecorder()
+
+				req, err := http.NewRequest("GET", "/v1/deploy/status/"+jobID, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				router.ServeHTTP(resp, req)
+				Expect(resp.Code).To(Equal(http.StatusOK))
+
+				var status S.JobStatus
+				Expect(json.Unmarshal(resp.Body.Bytes(), &status)).To(Succeed())
+
+				return status
+			}
+		})
+
+		It("responds with http.StatusAccepted and the job id", func() {
+			req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+			Expect(err).ToNot(HaveOccurred())
+
+			deployer.DeployCall.Returns.StatusCode = http.StatusOK
+
+			router.ServeHTTP(resp, req)
+
+			var body map[string]interface{}
+			Expect(json.Unmarshal(resp.Body.Bytes(), &body)).To(Succeed())
+
+			Expect(resp.Code).To(Equal(http.StatusAccepted))
+			Expect(body["job_id"]).To(Equal(jobID))
+		})
+
+		It("reports the output of a succeeded deploy", func() {
+			req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+			Expect(err).ToNot(HaveOccurred())
+
+			deployer.DeployCall.Returns.StatusCode = http.StatusOK
+			deployer.DeployCall.Write.Output = "deploy success"
+
+			router.ServeHTTP(resp, req)
+
+			Eventually(func() string { return getStatus().State }).Should(Equal(jobs.Succeeded))
+
+			status := getStatus()
+			Expect(status.ID).To(Equal(jobID))
+			Expect(status.Output).To(ContainSubstring("deploy success"))
+			Expect(status.Result.AppName).To(Equal(appName))
+		})
+
+		It("reports the error of a failed deploy", func() {
+			req, err := http.NewRequest("POST", apiURL, jsonBuffer)
+			Expect(err).ToNot(HaveOccurred())
+
+			deployer.DeployCall.Returns.Error = errors.New("bork")
+			deployer.DeployCall.Returns.StatusCode = http.StatusInternalServerError
+
+			router.ServeHTTP(resp, req)
+
+			Eventually(func() string { return getStatus().State }).Should(Equal(jobs.Failed))
+
+			status := getStatus()
+			Expect(status.Output).To(ContainSubstring("cannot deploy application: bork"))
+			Expect(status.Result.Error).To(Equal("bork"))
+		})
+
+		Context("when the job is unknown", func() {
+			It("returns http.StatusNotFound", func() {
+				req, err := http.NewRequest("GET", "/v1/deploy/status/"+jobID, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("reloading the config", func() {
+		var (
+			reloadedDeployer *mocks.Deployer
+			newEnvironment   string
+		)
+
+		BeforeEach(func() {
+			controller.Environments = map[string]config.Environment{
+				environment: {Name: environment, Domain: "old.example.com"},
+			}
+
+			reloadedDeployer = &mocks.Deployer{}
+			newEnvironment = "environment-" + randomizer.StringRunes(10)
+		})
+
+		It("deploys a new environment with the deployer of the new config", func() {
+			deployer.DeployCall.Returns.StatusCode = http.StatusInternalServerError
+			deployer.DeployCall.Returns.Error = errors.New("environment not found")
+			reloadedDeployer.DeployCall.Returns.StatusCode = http.StatusOK
+			reloadedDeployer.DeployCall.Write.Output = "deploy success"
+
+			controller.Reload(reloadedDeployer, map[string]config.Environment{
+				environment:    {Name: environment, Domain: "old.example.com"},
+				newEnvironment: {Name: newEnvironment, Domain: "new.example.com"},
+			})
+
+			req, err := http.NewRequest("POST", fmt.Sprintf("/v1/apps/%s/%s/%s/%s", newEnvironment, org, space, appName), jsonBuffer)
+			Expect(err).ToNot(HaveOccurred())
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Body).To(ContainSubstring("deploy success"))
+			Expect(reloadedDeployer.DeployCall.Received.Environment).To(Equal(newEnvironment))
+			Expect(deployer.DeployCall.Received.Environment).To(BeEmpty())
+		})
+
+		It("lists the environments of the new config", func() {
+			controller.Reload(reloadedDeployer, map[string]config.Environment{
+				newEnvironment: {Name: newEnvironment, Domain: "new.example.com"},
+			})
+
+			req, err := http.NewRequest("GET", "/environments", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			router.ServeHTTP(resp, req)
+
+			var body []map[string]interface{}
+			Expect(json.Unmarshal(resp.Body.Bytes(), &body)).To(Succeed())
+			Expect(body).To(Equal([]map[string]interface{}{
+				{"name": newEnvironment, "domain": "new.example.com", "authenticate": false},
+			}))
+		})
+	})
+
+	Describe("ListEnvironments handler", func() {
+		var listEnvironments = func(authenticated bool) []map[string]interface{} {
+			req, err := http.NewRequest("GET", "/environments", nil)
+			Expect(err).ToNot(HaveOccurred())
+			if authenticated {
+				req.SetBasicAuth("username", "password")
+			}
+
+			router.ServeHTTP(resp, req)
+			Expect(resp.Code).To(Equal(http.StatusOK))
+
+			var body []map[string]interface{}
+			Expect(json.Unmarshal(resp.Body.Bytes(), &body)).To(Succeed())
+
+			return body
+		}
+
+		BeforeEach(func() {
+			controller.Environments = map[string]config.Environment{
+				"production": {
+					Name:         "production",
+					Domain:       "production.example.com",
+					Foundations:  []string{"https://api.production.example.com"},
+					Authenticate: true,
+					Username:     "productionUsername",
+					Password:     "productionPassword",
+				},
+				"preproduction": {
+					Name:         "preproduction",
+					Domain:       "preproduction.example.com",
+					Foundations:  []string{"https://api.preproduction.example.com"},
+					ClientID:     "preproductionClientID",
+					ClientSecret: "preproductionClientSecret",
+				},
+			}
+		})
+
+		It("lists the name and domain of every environment sorted by name", func() {
+			body := listEnvironments(true)
+
+			Expect(body).To(Equal([]map[string]interface{}{
+				{"name": "preproduction", "domain": "preproduction.example.com", "authenticate": false},
+				{"name": "production", "domain": "production.example.com", "authenticate": true},
+			}))
+		})
+
+		It("never includes the foundations or credentials of an environment", func() {
+			listEnvironments(true)
+
+			for _, secret := range []string{"api.production", "api.preproduction", "productionUsername", "productionPassword", "preproductionClientID", "preproductionClientSecret"} {
+				Expect(resp.Body.String()).ToNot(ContainSubstring(secret))
+			}
+		})
+
+		Context("when the request has no basic auth", func() {
+			It("does not list the environments that require authentication", func() {
+				body := listEnvironments(false)
+
+				Expect(body).To(HaveLen(1))
+				Expect(body[0]["name"]).To(Equal("preproduction"))
+			})
+		})
+
+		Context("when no environments are configured", func() {
+			It("responds with an empty list", func() {
+				controller.Environments = nil
+
+				Expect(listEnvironments(false)).To(BeEmpty())
+				Expect(resp.Body.String()).To(Equal("[]"))
+			})
+		})
+	})
+
+	Describe("GetMetrics handler", func() {
+		It("responds with the metrics in the prometheus text format", func() {
+			metrics.WriteCall.Returns.Output = "deploys_total 1\n"
+
+			req, err := http.NewRequest("GET", "/metrics", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Header().Get("Content-Type")).To(Equal("text/plain; version=0.0.4"))
+			Expect(resp.Body.String()).To(Equal("deploys_total 1\n"))
+		})
+
+		Context("when metrics are not enabled", func() {
+			It("returns http.StatusNotFound", func() {
+				controller.Metrics = nil
+
+				req, err := http.NewRequest("GET", "/metrics", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("GetDeployLog handler", func() {
+		var requestID string
+
+		BeforeEach(func() {
+			deployLogs, err := deploylogs.New(&afero.Afero{Fs: afero.NewMemMapFs()}, "/logs", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			controller.DeployLogs = deployLogs
+
+			requestID = "requestID-" + randomizer.StringRunes(10)
+
+			deployLog, err := deployLogs.Create(requestID)
+			Expect(err).ToNot(HaveOccurred())
+			fmt.Fprint(deployLog, "deploy output")
+			Expect(deployLog.Close()).To(Succeed())
+		})
+
+		It("responds with the log of the deploy as a download", func() {
+			req, err := http.NewRequest("GET", "/v1/deploy/logs/"+requestID, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Header().Get("Content-Type")).To(Equal("text/plain; charset=utf-8"))
+			Expect(resp.Header().Get("Content-Disposition")).To(Equal("attachment; filename=" + requestID + ".log"))
+			Expect(resp.Body.String()).To(Equal("deploy output"))
+		})
+
+		It("responds with the log of the deploy under /deploy", func() {
+			req, err := http.NewRequest("GET", "/deploy/logs/"+requestID, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Body.String()).To(Equal("deploy output"))
+		})
+
+		Context("when the deploy has no log", func() {
+			It("returns http.StatusNotFound", func() {
+				req, err := http.NewRequest("GET", "/v1/deploy/logs/unknown-"+requestID, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusNotFound))
+				Expect(resp.Body.String()).To(ContainSubstring("unknown deploy log"))
+			})
+		})
+
+		Context("when deploy logs are not enabled", func() {
+			It("returns http.StatusNotFound", func() {
+				controller.DeployLogs = nil
+
+				req, err := http.NewRequest("GET", "/v1/deploy/logs/"+requestID, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusNotFound))
+				Expect(resp.Body.String()).To(ContainSubstring("deploy logs are not enabled"))
+			})
+		})
+	})
+
+	Describe("GetHealth handler", func() {
+		It("returns http.StatusOK", func() {
+			req, err := http.NewRequest("GET", "/health", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Body.String()).To(ContainSubstring(`"status":"ok"`))
+		})
+	})
+
+	Describe("GetVersion handler", func() {
+		It("responds with the version, commit and build date", func() {
+			req, err := http.NewRequest("GET", "/version", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+
+			var body map[string]string
+			Expect(json.Unmarshal(resp.Body.Bytes(), &body)).To(Succeed())
+			Expect(body).To(HaveKeyWithValue("version", version.Unknown))
+			Expect(body).To(HaveKeyWithValue("commit", version.Unknown))
+			Expect(body).To(HaveKeyWithValue("build_date", version.Unknown))
+		})
+	})
+
+	Describe("GetReady handler", func() {
+		var (
+			courier    *mocks.Courier
+			prechecker *mocks.Prechecker
+		)
+
+		BeforeEach(func() {
+			courier = &mocks.Courier{}
+			prechecker = &mocks.Prechecker{}
+
+			controller.Readiness = readiness.New(courier, prechecker, map[string]config.Environment{
+				environment: {Name: environment, Foundations: []string{"https://api.example.com"}},
+			})
+		})
+
+		Context("when the cf CLI is present and the foundations are up", func() {
+			It("returns http.StatusOK", func() {
+				courier.VersionCall.Returns.Output = []byte("cf version 6.32.0")
+
+				req, err := http.NewRequest("GET", "/ready", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusOK))
+				Expect(resp.Body.String()).To(ContainSubstring(`"status":"ready"`))
+			})
+		})
+
+		Context("when the cf CLI is absent", func() {
+			It("returns http.StatusServiceUnavailable with the reason", func() {
+				courier.VersionCall.Returns.Error = errors.New("exec: \"cf\": executable file not found in $PATH")
+
+				req, err := http.NewRequest("GET", "/ready", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+				Expect(resp.Body.String()).To(ContainSubstring("cannot run the cf CLI"))
+			})
+		})
+
+		Context("when a foundation is down", func() {
+			It("returns http.StatusServiceUnavailable", func() {
+				prechecker.AssertAllFoundationsUpCall.Returns.Error = errors.New("foundation down")
+
+				req, err := http.NewRequest("GET", "/ready", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+				Expect(resp.Body.String()).To(ContainSubstring("foundation down"))
+			})
+		})
+
+		Context("when readiness checks are not enabled", func() {
+			It("returns http.StatusNotFound", func() {
+				controller.Readiness = nil
+
+				req, err := http.NewRequest("GET", "/ready", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("GetHistory handler", func() {
+		It("passes the filters and pagination to the history", func() {
+			apiURL = fmt.Sprintf("/v1/history?env=%s&app=%s&status=failure&offset=2&limit=5", environment, appName)
+
+			req, err := http.NewRequest("GET", apiURL, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			router.ServeHTTP(resp, req)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(history.QueryCall.Received.Query).To(Equal(S.HistoryQuery{
+				Environment: environment,
+				AppName:     appName,
+				Status:      "failure",
+				Offset:      2,
+				Limit:       5,
+			}))
+		})
+
+		It("responds with the results and the total", func() {
+			history.QueryCall.Returns.Results = []S.DeployResult{{Environment: environment, AppName: appName, Status: "success"}}
+			history.QueryCall.Returns.Total = 3
+
+			req, err := http.NewRequest("GET", "/v1/history", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			router.ServeHTTP(resp, req)
+
+			var body struct {
+				Results []S.DeployResult `json:"results"`
+				Total   int              `json:"total"`
+				Offset  int              `json:"offset"`
+				Limit   int              `json:"limit"`
+			}
+			Expect(json.Unmarshal(resp.Body.Bytes(), &body)).To(Succeed())
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(body.Results).To(HaveLen(1))
+			Expect(body.Results[0].AppName).To(Equal(appName))
+			Expect(body.Total).To(Equal(3))
+			Expect(body.Offset).To(Equal(0))
+			Expect(body.Limit).To(Equal(20))
+		})
+
+		Context("when the limit is invalid", func() {
+			It("returns http.StatusBadRequest", func() {
+				req, err := http.NewRequest("GET", "/v1/history?limit=bork", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+				Expect(resp.Body).To(ContainSubstring("invalid limit: bork"))
+			})
+		})
+
+		Context("when the offset is negative", func() {
+			It("returns http.StatusBadRequest", func() {
+				req, err := http.NewRequest("GET", "/v1/history?offset=-1", nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				router.ServeHTTP(resp, req)
+
+				Expect(resp.Code).To(Equal(http.StatusBadRequest))
+				Expect(resp.Body).To(ContainSubstring("invalid offset: -1"))
+			})
+		})
+	})
+})
+
+// blockingDeployer holds every deploy until it is released so that concurrent deploys can be tested.
+type blockingDeployer struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (d *blockingDeployer) Deploy(ctx context.Context, req *http.Request, environment, org, space, appName, contentType string, out io.Writer) (int, error) {
+	d.started <- struct{}{}
+	<-d.release
+
+	return http.StatusOK, nil
+}
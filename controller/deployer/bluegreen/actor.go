@@ -1,14 +1,18 @@
 package bluegreen
 
-import I "github.com/compozed/deployadactyl/interfaces"
+import (
+	"io"
 
-func newActor(pusher I.Pusher, foundationURL string) actor {
+	I "github.com/compozed/deployadactyl/interfaces"
+)
+
+func newActor(pusher I.Pusher, foundationURL string, response io.Writer) actor {
 	commands := make(chan actorCommand)
 	errs := make(chan error)
 
 	go func() {
 		for command := range commands {
-			errs <- command(pusher, foundationURL)
+			errs <- command(pusher, foundationURL, response)
 		}
 		close(errs)
 	}()
@@ -24,4 +28,4 @@ type actor struct {
 	errs     <-chan error
 }
 
-type actorCommand func(pusher I.Pusher, foundationURL string) error
+type actorCommand func(pusher I.Pusher, foundationURL string, response io.Writer) error
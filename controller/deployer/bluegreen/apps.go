@@ -0,0 +1,176 @@
+package bluegreen
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/controller/deployer/manifestro"
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+	"golang.org/x/net/context"
+)
+
+// appPush is the push of one of the applications of a manifest that declares more than one.
+// Every application has its own pushers because a pusher keeps the state of the application it pushed
+// until it is rolled back or its venerable application is deleted.
+type appPush struct {
+	blueGreen      BlueGreen
+	deploymentInfo S.DeploymentInfo
+	pushErrs       []error
+	stop           func()
+}
+
+// pushApps blue greens the applications of the manifest one after the other, the same as Push does with one.
+// The venerable applications are only deleted once every application has been pushed, so when an application fails
+// the applications pushed before it are rolled back with it. The smoke test of the deploy runs once every application
+// has been pushed and unmapped from its venerable application, and when it fails every application is rolled back.
+//
+// Returns a map of the S.AppGUIDKey of every foundation URL and application name to the guid of the pushed application.
+func (bg BlueGreen) pushApps(ctx context.Context, environment config.Environment, appPath string, deploymentInfo S.DeploymentInfo, appNames []string, response io.Writer) (map[string]string, error) {
+	var pushes []*appPush
+	defer func() {
+		for i := len(pushes) - 1; i >= 0; i-- {
+			pushes[i].stop()
+		}
+	}()
+
+	for _, appName := range appNames {
+		push := &appPush{blueGreen: bg, deploymentInfo: appDeploymentInfo(environment, deploymentInfo, appName)}
+
+		bg.Log.Infof("pushing application %s of the manifest", appName)
+		fmt.Fprintf(response, "\nPushing application %s\n", appName)
+
+		stop, err := push.blueGreen.startActors(environment.Foundations, response)
+		if err != nil {
+			return nil, bg.failApps(ctx, environment, pushes, []error{AppPushError{appName, err}}, err)
+		}
+		push.stop = stop
+		pushes = append(pushes, push)
+
+		errs, err := push.run(ctx, appPath, environment.Foundations, response)
+		if err != nil {
+			return nil, bg.failApps(ctx, environment, pushes, []error{AppPushError{appName, err}}, err)
+		}
+		if len(errs) > 0 {
+			failed := make([]error, len(errs))
+			for i, err := range errs {
+				failed[i] = AppPushError{appName, err}
+			}
+			return nil, bg.failApps(ctx, environment, pushes, failed, nil)
+		}
+	}
+
+	var err error
+	for _, push := range pushes {
+		if err == nil {
+			err = push.blueGreen.unmapVenerableAll(ctx, push.deploymentInfo)
+		}
+	}
+	if err == nil {
+		err = bg.smokeTest(deploymentInfo, response)
+	}
+	if err != nil {
+		if environment.DisableRollback {
+			bg.Log.Errorf("rollback is disabled for %s: leaving the pushed applications as they are", environment.Name)
+			return nil, SmokeTestFailRollbackDisabledError{err}
+		}
+		bg.rollbackApps(ctx, environment, pushes)
+		return nil, SmokeTestFailRollbackError{err}
+	}
+
+	appGUIDs := map[string]string{}
+	for _, push := range pushes {
+		push.blueGreen.finishPushAll(ctx, push.deploymentInfo)
+
+		for foundationURL, appGUID := range push.blueGreen.appGUIDAll(environment.Foundations) {
+			appGUIDs[S.AppGUIDKey(foundationURL, push.deploymentInfo.AppName)] = appGUID
+		}
+	}
+
+	return appGUIDs, nil
+}
+
+// run logs in to every foundation and pushes the application without deleting its venerable application.
+//
+// Returns the error of every foundation the push failed on, or an error when nothing could be pushed.
+func (p *appPush) run(ctx context.Context, appPath string, foundations []string, response io.Writer) ([]error, error) {
+	defer p.blueGreen.finishOutput(response)
+
+	err := p.blueGreen.loginAll(ctx, p.deploymentInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	p.blueGreen.cleanUpAll(ctx, p.deploymentInfo)
+
+	p.blueGreen.existsAll(ctx, p.deploymentInfo)
+
+	p.pushErrs = p.blueGreen.pushAll(foundations, p.deploymentInfo, func(pusher I.Pusher, response io.Writer) error {
+		return pusher.Push(ctx, appPath, p.deploymentInfo, response)
+	})
+
+	return p.blueGreen.logErrors(p.pushErrs), nil
+}
+
+// failApps rolls back every application that was pushed, unless rollback is disabled for the environment.
+// When nothing was pushed yet err is returned as it is, the same as a single application that cannot be pushed.
+func (bg BlueGreen) failApps(ctx context.Context, environment config.Environment, pushes []*appPush, errs []error, err error) error {
+	if len(pushedApps(pushes)) == 0 && err != nil {
+		return err
+	}
+
+	if environment.DisableRollback {
+		bg.Log.Errorf("rollback is disabled for %s: leaving the pushed applications as they are", environment.Name)
+		return PushFailRollbackDisabledError{errs}
+	}
+	if environment.DisableFirstDeployRollback {
+		return PushFailNoRollbackError{errs}
+	}
+
+	bg.rollbackApps(ctx, environment, pushes)
+
+	return PushFailRollbackError{errs}
+}
+
+// rollbackApps rolls back every application that was pushed, in the reverse order they were pushed in.
+func (bg BlueGreen) rollbackApps(ctx context.Context, environment config.Environment, pushes []*appPush) {
+	pushed := pushedApps(pushes)
+
+	var appNames []string
+	for i := len(pushed) - 1; i >= 0; i-- {
+		pushed[i].blueGreen.rollbackAll(ctx, environment.Foundations, pushed[i].deploymentInfo, pushed[i].pushErrs)
+		appNames = append(appNames, pushed[i].deploymentInfo.AppName)
+	}
+	bg.Log.Errorf("rolled back %s", strings.Join(appNames, ", "))
+}
+
+// pushedApps returns the pushes that got as far as pushing their application to the foundations.
+func pushedApps(pushes []*appPush) []*appPush {
+	var pushed []*appPush
+	for _, push := range pushes {
+		if push.pushErrs != nil {
+			pushed = append(pushed, push)
+		}
+	}
+	return pushed
+}
+
+// appDeploymentInfo returns the deploymentInfo of the application called appName in the manifest of the deploy.
+// The application gets its instances from the manifest, or from the environment when the manifest does not set them.
+func appDeploymentInfo(environment config.Environment, deploymentInfo S.DeploymentInfo, appName string) S.DeploymentInfo {
+	deploymentInfo.AppName = appName
+	deploymentInfo.NoRoute = deploymentInfo.NoRoute || manifestro.GetAppNoRoute(deploymentInfo.Manifest, appName)
+
+	deploymentInfo.Instances = environment.Instances
+	if instances := manifestro.GetAppInstances(deploymentInfo.Manifest, appName); instances != nil {
+		deploymentInfo.Instances = *instances
+	}
+
+	return deploymentInfo
+}
@@ -2,165 +2,384 @@
 package bluegreen
 
 import (
-	"bytes"
-	"errors"
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 
 	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/controller/deployer/manifestro"
+	"github.com/compozed/deployadactyl/failureinjection"
 	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/logger"
 	S "github.com/compozed/deployadactyl/structs"
 	"github.com/op/go-logging"
+	"golang.org/x/net/context"
 )
 
 // BlueGreen has a PusherCreator to creater pushers for blue green deployments.
+// The EventManager is used to emit a deploy.rollback event when a deploy is rolled back.
 type BlueGreen struct {
 	PusherCreator I.PusherFactory
+	EventManager  I.EventManager
 	Log           *logging.Logger
 	actors        []actor
-	buffers       []*bytes.Buffer
+	writers       []*prefixWriter
 }
 
 // Push will login to all the Cloud Foundry instances provided in the Config and then push the application to all the instances concurrently.
 // If the application fails to start in any of the instances it handles rolling back the application in every instance, unless this is the first deploy and disable rollback is enabled.
-func (bg BlueGreen) Push(environment config.Environment, appPath string, deploymentInfo S.DeploymentInfo, response io.Writer) error {
-	bg.actors = make([]actor, len(environment.Foundations))
-	bg.buffers = make([]*bytes.Buffer, len(environment.Foundations))
+// If rollback is disabled for the environment the foundations are left as they are for debugging.
+// Push does not return until every foundation has finished, and the returned error lists each foundation that failed.
+// An environment without foundations is an error rather than an empty successful deploy.
+// When ctx is done the in-flight cf commands are killed, including the ones that roll the foundations back.
+// When the manifest declares more than one application every one of them is pushed, and they are rolled back together.
+//
+// Returns a map of foundation URL to the guid of the pushed application.
+func (bg BlueGreen) Push(ctx context.Context, environment config.Environment, appPath string, deploymentInfo S.DeploymentInfo, response io.Writer) (map[string]string, error) {
+	bg.Log = logger.WithRequestID(bg.Log, deploymentInfo.RequestID)
 
-	for i, foundationURL := range environment.Foundations {
-		pusher, err := bg.PusherCreator.CreatePusher()
-		if err != nil {
-			return err
-		}
-		defer pusher.CleanUp()
-
-		bg.actors[i] = newActor(pusher, foundationURL)
-		defer close(bg.actors[i].commands)
+	if len(environment.Foundations) == 0 {
+		return nil, NoFoundationsError{environment.Name}
+	}
 
-		bg.buffers[i] = &bytes.Buffer{}
+	if appNames := manifestro.GetAppNames(deploymentInfo.Manifest); len(appNames) > 1 {
+		return bg.pushApps(ctx, environment, appPath, deploymentInfo, appNames, response)
 	}
 
-	defer func() {
-		for _, buffer := range bg.buffers {
-			fmt.Fprintf(response, "\n%s Cloud Foundry Output %s\n", strings.Repeat("-", 19), strings.Repeat("-", 19))
+	stop, err := bg.startActors(environment.Foundations, response)
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+	defer bg.finishOutput(response)
 
-			buffer.WriteTo(response)
-		}
-		fmt.Fprintf(response, "\n%s End Cloud Foundry Output %s\n", strings.Repeat("-", 17), strings.Repeat("-", 17))
-	}()
+	err = bg.loginAll(ctx, deploymentInfo)
+	if err != nil {
+		return nil, err
+	}
 
-	failed := bg.loginAll(deploymentInfo)
-	if failed {
-		return errors.New("push failed: login failed")
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
 	}
 
-	bg.cleanUpAll(deploymentInfo)
+	bg.cleanUpAll(ctx, deploymentInfo)
 
-	bg.existsAll(deploymentInfo)
+	bg.existsAll(ctx, deploymentInfo)
 
-	failed = bg.pushAll(appPath, deploymentInfo)
-	if failed {
+	pushErrs := bg.pushAll(environment.Foundations, deploymentInfo, func(pusher I.Pusher, response io.Writer) error {
+		return pusher.Push(ctx, appPath, deploymentInfo, response)
+	})
+	errs := bg.logErrors(pushErrs)
+	if len(errs) > 0 {
+		if environment.DisableRollback {
+			bg.Log.Errorf("rollback is disabled for %s: leaving the pushed foundations as they are", environment.Name)
+			return nil, PushFailRollbackDisabledError{errs}
+		}
 		if !environment.DisableFirstDeployRollback {
-			bg.rollbackAll(deploymentInfo)
-			return PushFailRollbackError{}
+			bg.rollbackAll(ctx, environment.Foundations, deploymentInfo, pushErrs)
+			return nil, PushFailRollbackError{errs}
 		}
-		return PushFailNoRollbackError{}
+		return nil, PushFailNoRollbackError{errs}
 	}
 
-	bg.finishPushAll(deploymentInfo)
+	err = bg.unmapVenerableAll(ctx, deploymentInfo)
+	if err == nil {
+		err = bg.smokeTest(deploymentInfo, response)
+	}
+	if err != nil {
+		if environment.DisableRollback {
+			bg.Log.Errorf("rollback is disabled for %s: leaving the pushed foundations as they are", environment.Name)
+			return nil, SmokeTestFailRollbackDisabledError{err}
+		}
+		bg.rollbackAll(ctx, environment.Foundations, deploymentInfo, pushErrs)
+		return nil, SmokeTestFailRollbackError{err}
+	}
+
+	bg.finishPushAll(ctx, deploymentInfo)
+
+	return bg.appGUIDAll(environment.Foundations), nil
+}
+
+// Preflight will login to all the Cloud Foundry instances provided in the Config and then push a minimal application
+// that is never started from probePath to all the instances concurrently, deleting it straight away.
+// It checks that the deploy has write access to every foundation without touching the application being deployed.
+// The cf commands are killed when ctx is done.
+func (bg BlueGreen) Preflight(ctx context.Context, environment config.Environment, probePath string, deploymentInfo S.DeploymentInfo, response io.Writer) error {
+	bg.Log = logger.WithRequestID(bg.Log, deploymentInfo.RequestID)
+
+	if len(environment.Foundations) == 0 {
+		return NoFoundationsError{environment.Name}
+	}
+
+	stop, err := bg.startActors(environment.Foundations, response)
+	if err != nil {
+		return err
+	}
+	defer stop()
+	defer bg.finishOutput(response)
+
+	err = bg.loginAll(ctx, deploymentInfo)
+	if err != nil {
+		return err
+	}
+
+	errs := bg.logErrors(bg.runAll(func(pusher I.Pusher, foundationURL string, response io.Writer) error {
+		err := pusher.CanPush(ctx, probePath, deploymentInfo, response)
+		if err != nil {
+			return FoundationPushError{foundationURL, err}
+		}
+		return nil
+	}))
+	if len(errs) > 0 {
+		return PreflightFailError{errs}
+	}
 
 	return nil
 }
 
-func (bg BlueGreen) loginAll(deploymentInfo S.DeploymentInfo) bool {
-	failed := false
+// startActors creates a pusher and an actor for every foundation.
+// The Cloud Foundry output of every foundation is written to the response as it is produced.
+//
+// Returns a function that stops every actor and cleans up its pusher.
+func (bg *BlueGreen) startActors(foundations []string, response io.Writer) (func(), error) {
+	bg.actors = make([]actor, 0, len(foundations))
+	bg.writers = make([]*prefixWriter, 0, len(foundations))
+	pushers := make([]I.Pusher, 0, len(foundations))
 
-	for i, a := range bg.actors {
-		buffer := bg.buffers[i]
-		a.commands <- func(pusher I.Pusher, foundationURL string) error {
-			return pusher.Login(foundationURL, deploymentInfo, buffer)
+	stop := func() {
+		for i := len(bg.actors) - 1; i >= 0; i-- {
+			close(bg.actors[i].commands)
+			pushers[i].CleanUp()
 		}
 	}
-	for _, a := range bg.actors {
-		if err := <-a.errs; err != nil {
-			bg.Log.Error(err.Error())
-			failed = true
+
+	output := &syncWriter{writer: response}
+
+	for _, foundationURL := range foundations {
+		pusher, err := bg.PusherCreator.CreatePusher()
+		if err != nil {
+			stop()
+			return nil, err
 		}
+
+		writer := newPrefixWriter(output, foundationURL)
+
+		pushers = append(pushers, pusher)
+		bg.writers = append(bg.writers, writer)
+		bg.actors = append(bg.actors, newActor(pusher, foundationURL, writer))
 	}
 
-	return failed
+	fmt.Fprintf(response, "\n%s Cloud Foundry Output %s\n", strings.Repeat("-", 19), strings.Repeat("-", 19))
+
+	return stop, nil
 }
 
-func (bg BlueGreen) cleanUpAll(deploymentInfo S.DeploymentInfo) {
-	for _, a := range bg.actors {
-		a.commands <- func(pusher I.Pusher, foundationURL string) error {
-			pusher.Exists(deploymentInfo.AppName + "-venerable")
-			return pusher.DeleteVenerable(deploymentInfo)
-		}
+// finishOutput writes the unfinished last line of every foundation to the response and ends the Cloud Foundry output.
+func (bg BlueGreen) finishOutput(response io.Writer) {
+	for _, writer := range bg.writers {
+		writer.flush()
 	}
-	for _, a := range bg.actors {
-		if err := <-a.errs; err != nil {
+	fmt.Fprintf(response, "\n%s End Cloud Foundry Output %s\n", strings.Repeat("-", 17), strings.Repeat("-", 17))
+}
+
+// runAll sends a command to every actor at the same time and waits for all of them to finish.
+//
+// Returns the error of each actor in the same order as the foundations.
+func (bg BlueGreen) runAll(command actorCommand) []error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(bg.actors))
+
+	for i, a := range bg.actors {
+		wg.Add(1)
+		go func(i int, a actor) {
+			defer wg.Done()
+
+			a.commands <- command
+			errs[i] = <-a.errs
+		}(i, a)
+	}
+
+	wg.Wait()
+
+	return errs
+}
+
+// logErrors logs every error returned by runAll.
+//
+// Returns only the errors that are not nil.
+func (bg BlueGreen) logErrors(errs []error) []error {
+	var failed []error
+
+	for _, err := range errs {
+		if err != nil {
 			bg.Log.Error(err.Error())
+			failed = append(failed, err)
 		}
 	}
+
+	return failed
 }
 
-func (bg BlueGreen) existsAll(deploymentInfo S.DeploymentInfo) (exists bool) {
-	for _, a := range bg.actors {
-		a.commands <- func(pusher I.Pusher, foundationURL string) error {
-			pusher.Exists(deploymentInfo.AppName)
-			return nil
-		}
+// loginAll logs in to every foundation.
+// Returns a LoginUnavailableFailError when every failed login was temporary and a LoginFailError otherwise.
+func (bg BlueGreen) loginAll(ctx context.Context, deploymentInfo S.DeploymentInfo) error {
+	errs := bg.logErrors(bg.runAll(func(pusher I.Pusher, foundationURL string, response io.Writer) error {
+		return pusher.Login(ctx, foundationURL, deploymentInfo, response)
+	}))
+	if len(errs) == 0 {
+		return nil
 	}
-	for _, a := range bg.actors {
-		if err := <-a.errs; err != nil {
+
+	for _, err := range errs {
+		if temporary, ok := err.(interface {
+			Temporary() bool
+		}); !ok || !temporary.Temporary() {
+			return LoginFailError{errs}
 		}
 	}
-	return
+
+	return LoginUnavailableFailError{errs}
 }
 
-func (bg BlueGreen) pushAll(appPath string, deploymentInfo S.DeploymentInfo) (failed bool) {
-	for i, a := range bg.actors {
-		buffer := bg.buffers[i]
-		a.commands <- func(pusher I.Pusher, foundationURL string) error {
-			return pusher.Push(appPath, deploymentInfo, buffer)
+func (bg BlueGreen) cleanUpAll(ctx context.Context, deploymentInfo S.DeploymentInfo) {
+	bg.logErrors(bg.runAll(func(pusher I.Pusher, foundationURL string, response io.Writer) error {
+		pusher.Exists(ctx, deploymentInfo.AppName+"-venerable")
+		return pusher.DeleteVenerable(ctx, deploymentInfo)
+	}))
+}
+
+func (bg BlueGreen) existsAll(ctx context.Context, deploymentInfo S.DeploymentInfo) {
+	bg.runAll(func(pusher I.Pusher, foundationURL string, response io.Writer) error {
+		pusher.Exists(ctx, deploymentInfo.AppName)
+		return nil
+	})
+}
+
+// pushAll pushes to every foundation with push and emits a deploy.progress event as each foundation finishes.
+func (bg BlueGreen) pushAll(foundations []string, deploymentInfo S.DeploymentInfo, push func(pusher I.Pusher, response io.Writer) error) []error {
+	var (
+		mutex     sync.Mutex
+		completed int
+	)
+
+	return bg.runAll(func(pusher I.Pusher, foundationURL string, response io.Writer) error {
+		defer func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			completed++
+			bg.emitProgress(foundations, foundationURL, completed, deploymentInfo)
+		}()
+
+		if deploymentInfo.InjectFailure == failureinjection.Push {
+			return FoundationPushError{foundationURL, failureinjection.InjectedFailureError{Stage: failureinjection.Push}}
 		}
+
+		err := push(pusher, response)
+		if err != nil {
+			return FoundationPushError{foundationURL, err}
+		}
+		return nil
+	})
+}
+
+func (bg BlueGreen) emitProgress(foundations []string, foundationURL string, completed int, deploymentInfo S.DeploymentInfo) {
+	progress := &S.DeployProgress{
+		FoundationURL: foundationURL,
+		Completed:     completed,
+		Total:         len(foundations),
+		Percent:       completed * 100 / len(foundations),
 	}
-	for _, a := range bg.actors {
-		if err := <-a.errs; err != nil {
-			bg.Log.Error(err.Error())
-			failed = true
+	for i, foundation := range foundations {
+		if foundation == foundationURL {
+			progress.FoundationIndex = i
+			break
 		}
 	}
 
-	return
+	bg.Log.Debugf("emitting a deploy.progress event: %d%%", progress.Percent)
+	err := bg.EventManager.Emit(S.Event{Type: "deploy.progress", Data: S.DeployEventData{DeploymentInfo: &deploymentInfo, Progress: progress}})
+	if err != nil {
+		bg.Log.Error(EventError{"deploy.progress", err}.Error())
+	}
 }
 
-func (bg BlueGreen) rollbackAll(deploymentInfo S.DeploymentInfo) {
-	for _, a := range bg.actors {
-		a.commands <- func(pusher I.Pusher, foundationURL string) error {
-			return pusher.Rollback(deploymentInfo)
-		}
+// smokeTest emits a deploy.smoketest event once every foundation has been pushed and before the venerable application
+// is deleted, so that a failed smoke test can still be rolled back. There is no smoke test when the SmokeTestURL is empty.
+// The route has to be unmapped from the venerable application first with unmapVenerableAll.
+func (bg BlueGreen) smokeTest(deploymentInfo S.DeploymentInfo, response io.Writer) error {
+	if deploymentInfo.SmokeTestURL == "" {
+		return nil
 	}
 
-	for _, a := range bg.actors {
-		if err := <-a.errs; err != nil {
-			bg.Log.Error(err.Error())
+	bg.Log.Debug("emitting a deploy.smoketest event")
+	return bg.EventManager.Emit(S.Event{Type: "deploy.smoketest", Data: S.DeployEventData{Writer: response, DeploymentInfo: &deploymentInfo}})
+}
+
+// unmapVenerableAll unmaps the route from the venerable application on every foundation before the smoke test,
+// so that the smoke test only reaches the pushed application. Nothing is unmapped when the deploy has no smoke test.
+func (bg BlueGreen) unmapVenerableAll(ctx context.Context, deploymentInfo S.DeploymentInfo) error {
+	if deploymentInfo.SmokeTestURL == "" {
+		return nil
+	}
+
+	errs := bg.logErrors(bg.runAll(func(pusher I.Pusher, foundationURL string, response io.Writer) error {
+		err := pusher.UnmapVenerable(ctx, deploymentInfo)
+		if err != nil {
+			return FoundationPushError{foundationURL, err}
 		}
+		return nil
+	}))
+	if len(errs) > 0 {
+		return UnmapVenerableFailError{errs}
 	}
+
+	return nil
 }
 
-func (bg BlueGreen) finishPushAll(deploymentInfo S.DeploymentInfo) {
-	for _, a := range bg.actors {
-		a.commands <- func(pusher I.Pusher, foundationURL string) error {
-			return pusher.DeleteVenerable(deploymentInfo)
+// rollbackAll rolls back every foundation so that the deploy is atomic. Foundations where the push failed
+// are rolled back as well because the live application may already have been renamed to venerable.
+// The foundations that were successfully pushed and the ones that failed are emitted in a deploy.rollback event.
+func (bg BlueGreen) rollbackAll(ctx context.Context, foundations []string, deploymentInfo S.DeploymentInfo, pushErrs []error) {
+	rollbackEventData := S.RollbackEventData{DeploymentInfo: &deploymentInfo}
+
+	for i, foundationURL := range foundations {
+		if pushErrs[i] == nil {
+			rollbackEventData.PushedFoundations = append(rollbackEventData.PushedFoundations, foundationURL)
+		} else {
+			rollbackEventData.FailedFoundations = append(rollbackEventData.FailedFoundations, foundationURL)
 		}
 	}
 
-	for _, a := range bg.actors {
-		if err := <-a.errs; err != nil {
-			bg.Log.Error(err.Error())
-		}
+	bg.logErrors(bg.runAll(func(pusher I.Pusher, foundationURL string, response io.Writer) error {
+		return pusher.Rollback(ctx, deploymentInfo)
+	}))
+
+	bg.Log.Debug("emitting a deploy.rollback event")
+	err := bg.EventManager.Emit(S.Event{Type: "deploy.rollback", Data: rollbackEventData})
+	if err != nil {
+		bg.Log.Error(EventError{"deploy.rollback", err}.Error())
 	}
 }
+
+func (bg BlueGreen) finishPushAll(ctx context.Context, deploymentInfo S.DeploymentInfo) {
+	bg.logErrors(bg.runAll(func(pusher I.Pusher, foundationURL string, response io.Writer) error {
+		return pusher.DeleteVenerable(ctx, deploymentInfo)
+	}))
+}
+
+func (bg BlueGreen) appGUIDAll(foundations []string) map[string]string {
+	appGUIDs := make(map[string]string, len(foundations))
+	var mutex sync.Mutex
+
+	bg.runAll(func(pusher I.Pusher, foundationURL string, response io.Writer) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		appGUIDs[foundationURL] = pusher.AppGUID()
+		return nil
+	})
+
+	return appGUIDs
+}
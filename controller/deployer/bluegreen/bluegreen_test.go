@@ -2,14 +2,18 @@ package bluegreen_test
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/compozed/deployadactyl/config"
 	. "github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	pusherpkg "github.com/compozed/deployadactyl/controller/deployer/bluegreen/pusher"
+	"github.com/compozed/deployadactyl/failureinjection"
 	"github.com/compozed/deployadactyl/logger"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
 	S "github.com/compozed/deployadactyl/structs"
 	"github.com/op/go-logging"
+	"golang.org/x/net/context"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -29,6 +33,7 @@ var _ = Describe("Bluegreen", func() {
 		username        string
 		password        string
 		pusherFactory   *mocks.PusherCreator
+		eventManager    *mocks.EventManager
 		pushers         []*mocks.Pusher
 		log             *logging.Logger
 		blueGreen       BlueGreen
@@ -36,6 +41,8 @@ var _ = Describe("Bluegreen", func() {
 		deploymentInfo  S.DeploymentInfo
 		response        *Buffer
 		logBuffer       *Buffer
+		eventTypes      func() []string
+		ctx             context.Context
 	)
 
 	BeforeEach(func() {
@@ -50,13 +57,25 @@ var _ = Describe("Bluegreen", func() {
 		password = "password-" + randomizer.StringRunes(10)
 		response = NewBuffer()
 		logBuffer = NewBuffer()
+		ctx = context.Background()
 
 		pusherFactory = &mocks.PusherCreator{}
 		pushers = nil
 
-		log = logger.DefaultLogger(logBuffer, logging.DEBUG, "test")
+		log = logger.DefaultLogger(logBuffer, logging.DEBUG, "test", logger.TextFormat)
 
-		blueGreen = BlueGreen{PusherCreator: pusherFactory, Log: log}
+		eventManager = &mocks.EventManager{}
+		eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, nil)
+
+		blueGreen = BlueGreen{PusherCreator: pusherFactory, EventManager: eventManager, Log: log}
+
+		eventTypes = func() []string {
+			var types []string
+			for _, event := range eventManager.EmitCall.Received.Events {
+				types = append(types, event.Type)
+			}
+			return types
+		}
 
 		environment = config.Environment{Name: environmentName}
 		environment.Foundations = []string{randomizer.StringRunes(10), randomizer.StringRunes(10)}
@@ -70,6 +89,18 @@ var _ = Describe("Bluegreen", func() {
 		}
 	})
 
+	Context("when the environment has no foundations", func() {
+		It("returns an error without creating any pushers", func() {
+			environment.Foundations = []string{}
+
+			appGUIDs, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+
+			Expect(err).To(MatchError(NoFoundationsError{environmentName}))
+			Expect(appGUIDs).To(BeNil())
+			Expect(pusherFactory.CreatePusherCall.TimesCalled).To(Equal(0))
+		})
+	})
+
 	Context("when pusher factory fails", func() {
 		It("returns an error", func() {
 			for i := range environment.Foundations {
@@ -82,7 +113,7 @@ var _ = Describe("Bluegreen", func() {
 				}
 			}
 
-			err := blueGreen.Push(environment, appPath, deploymentInfo, response)
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
 
 			Expect(err).To(MatchError("push creator failed"))
 		})
@@ -108,7 +139,8 @@ var _ = Describe("Bluegreen", func() {
 				pusher.CleanUpCall.Returns.Error = nil
 			}
 
-			Expect(blueGreen.Push(environment, appPath, deploymentInfo, response)).ToNot(Succeed())
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).To(HaveOccurred())
 
 			for i, pusher := range pushers {
 				Expect(pusher.LoginCall.Received.FoundationURL).To(Equal(environment.Foundations[i]))
@@ -120,6 +152,92 @@ var _ = Describe("Bluegreen", func() {
 		})
 	})
 
+	Context("when every failed login was temporary", func() {
+		It("returns a LoginUnavailableFailError instead of a LoginFailError", func() {
+			for range environment.Foundations {
+				pusher := &mocks.Pusher{}
+				pushers = append(pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Pushers = append(pusherFactory.CreatePusherCall.Returns.Pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Error = append(pusherFactory.CreatePusherCall.Returns.Error, nil)
+
+				pusher.LoginCall.Returns.Error = pusherpkg.LoginUnavailableError{FoundationURL: "foundationURL", Err: errors.New("bork")}
+			}
+
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+
+			Expect(err).To(BeAssignableToTypeOf(LoginUnavailableFailError{}))
+			Expect(err.Error()).ToNot(ContainSubstring("login failed"))
+
+			for _, pusher := range pushers {
+				Expect(pusher.PushCall.Received.AppPath).To(BeEmpty())
+			}
+		})
+
+		It("returns a LoginFailError when a login was rejected", func() {
+			for index := range environment.Foundations {
+				pusher := &mocks.Pusher{}
+				pushers = append(pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Pushers = append(pusherFactory.CreatePusherCall.Returns.Pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Error = append(pusherFactory.CreatePusherCall.Returns.Error, nil)
+
+				if index == 0 {
+					pusher.LoginCall.Returns.Error = errors.New("bork")
+				} else {
+					pusher.LoginCall.Returns.Error = pusherpkg.LoginUnavailableError{FoundationURL: "foundationURL", Err: errors.New("bork")}
+				}
+			}
+
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+
+			Expect(err).To(BeAssignableToTypeOf(LoginFailError{}))
+			Expect(err.Error()).To(ContainSubstring("push failed: login failed"))
+		})
+	})
+
+	Context("when the deploy is cancelled", func() {
+		It("logs in but does not push to any foundation", func() {
+			for range environment.Foundations {
+				pusher := &mocks.Pusher{}
+				pushers = append(pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Pushers = append(pusherFactory.CreatePusherCall.Returns.Pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Error = append(pusherFactory.CreatePusherCall.Returns.Error, nil)
+			}
+
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithCancel(ctx)
+			cancel()
+
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+
+			Expect(err).To(Equal(context.Canceled))
+
+			for i, pusher := range pushers {
+				Expect(pusher.LoginCall.Received.FoundationURL).To(Equal(environment.Foundations[i]))
+				Expect(pusher.PushCall.Received.AppPath).To(BeEmpty())
+			}
+		})
+
+		It("passes the context to every pusher", func() {
+			for range environment.Foundations {
+				pusher := &mocks.Pusher{}
+				pushers = append(pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Pushers = append(pusherFactory.CreatePusherCall.Returns.Pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Error = append(pusherFactory.CreatePusherCall.Returns.Error, nil)
+			}
+
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithCancel(ctx)
+			defer cancel()
+
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, pusher := range pushers {
+				Expect(pusher.PushCall.Received.Context).To(Equal(ctx))
+			}
+		})
+	})
+
 	Context("when all push commands are successful", func() {
 		It("can push an app to a single foundation", func() {
 			By("setting a single foundation")
@@ -138,7 +256,8 @@ var _ = Describe("Bluegreen", func() {
 			pusher.DeleteVenerableCall.Returns.Error = nil
 			pusher.CleanUpCall.Returns.Error = nil
 
-			Expect(blueGreen.Push(environment, appPath, deploymentInfo, response)).To(Succeed())
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).ToNot(HaveOccurred())
 
 			Expect(pusher.LoginCall.Received.FoundationURL).To(Equal(foundationURL))
 			Expect(pusher.LoginCall.Received.DeploymentInfo).To(Equal(deploymentInfo))
@@ -169,7 +288,8 @@ var _ = Describe("Bluegreen", func() {
 				pusher.CleanUpCall.Returns.Error = nil
 			}
 
-			Expect(blueGreen.Push(environment, appPath, deploymentInfo, response)).To(Succeed())
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).ToNot(HaveOccurred())
 
 			for i, pusher := range pushers {
 				foundationURL := environment.Foundations[i]
@@ -201,13 +321,273 @@ var _ = Describe("Bluegreen", func() {
 
 				pusher.DeleteVenerableCall.Returns.Error = errors.New("delete venerable failed")
 
-				Expect(blueGreen.Push(environment, appPath, deploymentInfo, response)).To(Succeed())
+				_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+				Expect(err).ToNot(HaveOccurred())
 
 				Eventually(logBuffer).Should(Say("delete venerable failed"))
 			})
 		})
 	})
 
+	Context("when the manifest declares more than one application", func() {
+		var appPushers func(appIndex int) []*mocks.Pusher
+
+		BeforeEach(func() {
+			deploymentInfo.Manifest = `---
+applications:
+- name: example-api
+  instances: 3
+- name: example-worker
+`
+			environment.Instances = 1
+
+			for i := 0; i < 2*len(environment.Foundations); i++ {
+				pusher := &mocks.Pusher{}
+				pushers = append(pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Pushers = append(pusherFactory.CreatePusherCall.Returns.Pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Error = append(pusherFactory.CreatePusherCall.Returns.Error, nil)
+			}
+
+			appPushers = func(appIndex int) []*mocks.Pusher {
+				return pushers[appIndex*len(environment.Foundations) : (appIndex+1)*len(environment.Foundations)]
+			}
+		})
+
+		It("blue greens every application with its own pushers", func() {
+			appGUIDs, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, pusher := range appPushers(0) {
+				Expect(pusher.PushCall.Received.DeploymentInfo.AppName).To(Equal("example-api"))
+				Expect(pusher.PushCall.Received.DeploymentInfo.Instances).To(Equal(uint16(3)))
+				Expect(pusher.DeleteVenerableCall.Received.DeploymentInfo.AppName).To(Equal("example-api"))
+			}
+			for _, pusher := range appPushers(1) {
+				Expect(pusher.PushCall.Received.DeploymentInfo.AppName).To(Equal("example-worker"))
+				Expect(pusher.PushCall.Received.DeploymentInfo.Instances).To(Equal(uint16(1)))
+				Expect(pusher.DeleteVenerableCall.Received.DeploymentInfo.AppName).To(Equal("example-worker"))
+			}
+
+			Expect(appGUIDs).To(HaveLen(4))
+			Expect(appGUIDs).To(HaveKey(S.AppGUIDKey(environment.Foundations[0], "example-api")))
+			Expect(appGUIDs).To(HaveKey(S.AppGUIDKey(environment.Foundations[1], "example-worker")))
+
+			Expect(response).To(Say("Pushing application example-api"))
+			Expect(response).To(Say("Pushing application example-worker"))
+		})
+
+		Context("when the second application fails", func() {
+			BeforeEach(func() {
+				appPushers(1)[1].PushCall.Returns.Error = errors.New("bork")
+			})
+
+			It("rolls back the first application with it", func() {
+				_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+				Expect(err).To(MatchError(PushFailRollbackError{[]error{
+					AppPushError{"example-worker", FoundationPushError{environment.Foundations[1], errors.New("bork")}},
+				}}))
+
+				for _, pusher := range appPushers(0) {
+					Expect(pusher.RollbackCall.Received.DeploymentInfo.AppName).To(Equal("example-api"))
+					Expect(pusher.DeleteVenerableCall.Received.DeploymentInfo.AppName).To(BeEmpty())
+				}
+				for _, pusher := range appPushers(1) {
+					Expect(pusher.RollbackCall.Received.DeploymentInfo.AppName).To(Equal("example-worker"))
+					Expect(pusher.DeleteVenerableCall.Received.DeploymentInfo.AppName).To(BeEmpty())
+				}
+			})
+
+			Context("when rollback is disabled for the environment", func() {
+				It("leaves every application as it is", func() {
+					environment.DisableRollback = true
+
+					_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+					Expect(err).To(BeAssignableToTypeOf(PushFailRollbackDisabledError{}))
+
+					for _, pusher := range pushers {
+						Expect(pusher.RollbackCall.Received.DeploymentInfo.AppName).To(BeEmpty())
+					}
+				})
+			})
+		})
+
+		Context("when the deploy has a smoke test", func() {
+			BeforeEach(func() {
+				deploymentInfo.SmokeTestURL = "https://example-api.example.com/health"
+			})
+
+			It("emits a deploy.smoketest event once every application has been pushed", func() {
+				_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(eventTypes()).To(Equal([]string{"deploy.progress", "deploy.progress", "deploy.progress", "deploy.progress", "deploy.smoketest"}))
+
+				smokeTestEventData := eventManager.EmitCall.Received.Events[4].Data.(S.DeployEventData)
+				Expect(*smokeTestEventData.DeploymentInfo).To(Equal(deploymentInfo))
+
+				for _, pusher := range pushers {
+					Expect(pusher.UnmapVenerableCall.Received.DeploymentInfo.AppName).To(Equal(pusher.PushCall.Received.DeploymentInfo.AppName))
+					Expect(pusher.DeleteVenerableCall.Received.DeploymentInfo.AppName).ToNot(BeEmpty())
+				}
+			})
+
+			Context("when the smoke test fails", func() {
+				BeforeEach(func() {
+					eventManager.EmitCall.Returns.Error = []error{nil, nil, nil, nil, errors.New("smoke test error")}
+				})
+
+				It("rolls back every application instead of deleting the venerable applications", func() {
+					_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+					Expect(err).To(MatchError(SmokeTestFailRollbackError{errors.New("smoke test error")}))
+
+					for _, pusher := range appPushers(0) {
+						Expect(pusher.RollbackCall.Received.DeploymentInfo.AppName).To(Equal("example-api"))
+						Expect(pusher.DeleteVenerableCall.Received.DeploymentInfo.AppName).To(BeEmpty())
+					}
+					for _, pusher := range appPushers(1) {
+						Expect(pusher.RollbackCall.Received.DeploymentInfo.AppName).To(Equal("example-worker"))
+						Expect(pusher.DeleteVenerableCall.Received.DeploymentInfo.AppName).To(BeEmpty())
+					}
+				})
+
+				It("does not roll back when rollback is disabled for the environment", func() {
+					environment.DisableRollback = true
+
+					_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+					Expect(err).To(MatchError(SmokeTestFailRollbackDisabledError{errors.New("smoke test error")}))
+
+					for _, pusher := range pushers {
+						Expect(pusher.RollbackCall.Received.DeploymentInfo.AppName).To(BeEmpty())
+					}
+				})
+			})
+		})
+
+		Context("when logging in for the second application fails", func() {
+			It("rolls back the first application", func() {
+				appPushers(1)[0].LoginCall.Returns.Error = errors.New("bork")
+
+				_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+				Expect(err).To(BeAssignableToTypeOf(PushFailRollbackError{}))
+				Expect(err.Error()).To(ContainSubstring("cannot push example-worker: push failed: login failed"))
+
+				for _, pusher := range appPushers(0) {
+					Expect(pusher.RollbackCall.Received.DeploymentInfo.AppName).To(Equal("example-api"))
+				}
+				for _, pusher := range appPushers(1) {
+					Expect(pusher.PushCall.Received.AppPath).To(BeEmpty())
+					Expect(pusher.RollbackCall.Received.DeploymentInfo.AppName).To(BeEmpty())
+				}
+			})
+		})
+	})
+
+	Context("when writing the Cloud Foundry output", func() {
+		It("prefixes each line with the foundation URL", func() {
+			for range environment.Foundations {
+				pusher := &mocks.Pusher{}
+				pushers = append(pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Pushers = append(pusherFactory.CreatePusherCall.Returns.Pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Error = append(pusherFactory.CreatePusherCall.Returns.Error, nil)
+
+				pusher.LoginCall.Write.Output = loginOutput + "\n"
+				pusher.PushCall.Write.Output = pushOutput + "\n"
+			}
+
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, foundationURL := range environment.Foundations {
+				Expect(response).To(Say(fmt.Sprintf(`\[%s\] %s`, foundationURL, loginOutput)))
+				Expect(response).To(Say(fmt.Sprintf(`\[%s\] %s`, foundationURL, pushOutput)))
+			}
+		})
+	})
+
+	Context("when streaming the Cloud Foundry output", func() {
+		var foundationURL string
+
+		BeforeEach(func() {
+			foundationURL = "foundationURL-" + randomizer.StringRunes(10)
+			environment.Foundations = []string{foundationURL}
+
+			pusher := &mocks.Pusher{}
+			pushers = append(pushers, pusher)
+			pusherFactory.CreatePusherCall.Returns.Pushers = append(pusherFactory.CreatePusherCall.Returns.Pushers, pusher)
+			pusherFactory.CreatePusherCall.Returns.Error = append(pusherFactory.CreatePusherCall.Returns.Error, nil)
+		})
+
+		It("writes each line of a foundation straight to the response", func() {
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			fmt.Fprint(pushers[0].PushCall.Received.Out, "staging app\n")
+
+			Expect(response).To(Say(fmt.Sprintf(`\[%s\] staging app\n`, foundationURL)))
+		})
+
+		It("holds back a line until it has ended", func() {
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			fmt.Fprint(pushers[0].PushCall.Received.Out, "staging ")
+			Expect(response.Contents()).ToNot(ContainSubstring("staging"))
+
+			fmt.Fprint(pushers[0].PushCall.Received.Out, "app\n")
+			Expect(response).To(Say(fmt.Sprintf(`\[%s\] staging app\n`, foundationURL)))
+		})
+
+		It("writes the last line of a foundation when it has not ended", func() {
+			pushers[0].PushCall.Write.Output = pushOutput
+
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(response).To(Say(fmt.Sprintf(`\[%s\] %s`, foundationURL, pushOutput)))
+			Expect(response).To(Say("End Cloud Foundry Output"))
+		})
+	})
+
+	Context("when the pushes have app guids", func() {
+		It("returns the app guid for each foundation", func() {
+			environment.Foundations = []string{randomizer.StringRunes(10), randomizer.StringRunes(10)}
+
+			for i := range environment.Foundations {
+				pusher := &mocks.Pusher{}
+				pushers = append(pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Pushers = append(pusherFactory.CreatePusherCall.Returns.Pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Error = append(pusherFactory.CreatePusherCall.Returns.Error, nil)
+
+				pusher.AppGUIDCall.Returns.AppGUID = fmt.Sprintf("guid-%d", i)
+			}
+
+			appGUIDs, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(appGUIDs).To(Equal(map[string]string{
+				environment.Foundations[0]: "guid-0",
+				environment.Foundations[1]: "guid-1",
+			}))
+		})
+
+		It("does not return app guids when the push fails", func() {
+			for range environment.Foundations {
+				pusher := &mocks.Pusher{}
+				pushers = append(pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Pushers = append(pusherFactory.CreatePusherCall.Returns.Pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Error = append(pusherFactory.CreatePusherCall.Returns.Error, nil)
+
+				pusher.PushCall.Returns.Error = errors.New("bork")
+				pusher.AppGUIDCall.Returns.AppGUID = "guid-" + randomizer.StringRunes(10)
+			}
+
+			appGUIDs, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).To(HaveOccurred())
+
+			Expect(appGUIDs).To(BeNil())
+		})
+	})
+
 	Context("when pushing to multiple foundations", func() {
 		It("checks if the app exists on each foundation", func() {
 			environment.Foundations = []string{randomizer.StringRunes(10), randomizer.StringRunes(10), randomizer.StringRunes(10), randomizer.StringRunes(10)}
@@ -219,7 +599,8 @@ var _ = Describe("Bluegreen", func() {
 				pusherFactory.CreatePusherCall.Returns.Error = append(pusherFactory.CreatePusherCall.Returns.Error, nil)
 			}
 
-			Expect(blueGreen.Push(environment, appPath, deploymentInfo, response)).To(Succeed())
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).ToNot(HaveOccurred())
 
 			for i := range environment.Foundations {
 				Expect(pushers[i].ExistsCall.Received.AppName).To(Equal(appName))
@@ -238,7 +619,8 @@ var _ = Describe("Bluegreen", func() {
 				pusher.DeleteVenerableCall.Returns.Error = nil
 			}
 
-			Expect(blueGreen.Push(environment, appPath, deploymentInfo, response)).To(Succeed())
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).ToNot(HaveOccurred())
 
 			for _, pusher := range pushers {
 				Expect(pusher.DeleteVenerableCall.Received.DeploymentInfo).To(Equal(deploymentInfo))
@@ -257,7 +639,8 @@ var _ = Describe("Bluegreen", func() {
 					pusher.DeleteVenerableCall.Returns.Error = errors.New("delete failed")
 				}
 
-				Expect(blueGreen.Push(environment, appPath, deploymentInfo, response)).To(Succeed())
+				_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+				Expect(err).ToNot(HaveOccurred())
 
 				Eventually(logBuffer).Should(Say("delete failed"))
 			})
@@ -288,7 +671,8 @@ var _ = Describe("Bluegreen", func() {
 				pusher.CleanUpCall.Returns.Error = nil
 			}
 
-			Expect(blueGreen.Push(environment, appPath, deploymentInfo, response)).To(MatchError(PushFailRollbackError{}))
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).To(MatchError(PushFailRollbackError{[]error{FoundationPushError{environment.Foundations[1], errors.New("bork")}}}))
 
 			for i, pusher := range pushers {
 				foundationURL := environment.Foundations[i]
@@ -307,6 +691,76 @@ var _ = Describe("Bluegreen", func() {
 			Expect(response).To(Say(pushOutput))
 		})
 
+		It("reports every foundation that failed after all pushes have finished", func() {
+			environment.Foundations = []string{randomizer.StringRunes(10), randomizer.StringRunes(10), randomizer.StringRunes(10)}
+
+			for index := range environment.Foundations {
+				pusher := &mocks.Pusher{}
+				pushers = append(pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Pushers = append(pusherFactory.CreatePusherCall.Returns.Pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Error = append(pusherFactory.CreatePusherCall.Returns.Error, nil)
+
+				if index != 1 {
+					pusher.PushCall.Returns.Error = fmt.Errorf("bork-%d", index)
+				}
+			}
+
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).To(MatchError(PushFailRollbackError{[]error{
+				FoundationPushError{environment.Foundations[0], errors.New("bork-0")},
+				FoundationPushError{environment.Foundations[2], errors.New("bork-2")},
+			}}))
+
+			for _, pusher := range pushers {
+				Expect(pusher.PushCall.Received.AppPath).To(Equal(appPath))
+			}
+		})
+
+		It("emits a deploy.rollback event with the pushed and failed foundations", func() {
+			for index := range environment.Foundations {
+				pusher := &mocks.Pusher{}
+				pushers = append(pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Pushers = append(pusherFactory.CreatePusherCall.Returns.Pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Error = append(pusherFactory.CreatePusherCall.Returns.Error, nil)
+
+				if index == 1 {
+					pusher.PushCall.Returns.Error = errors.New("bork")
+				}
+			}
+
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).To(HaveOccurred())
+
+			Expect(eventTypes()).To(Equal([]string{"deploy.progress", "deploy.progress", "deploy.rollback"}))
+
+			rollbackEventData := eventManager.EmitCall.Received.Events[2].Data.(S.RollbackEventData)
+			Expect(*rollbackEventData.DeploymentInfo).To(Equal(deploymentInfo))
+			Expect(rollbackEventData.PushedFoundations).To(Equal([]string{environment.Foundations[0]}))
+			Expect(rollbackEventData.FailedFoundations).To(Equal([]string{environment.Foundations[1]}))
+		})
+
+		Context("when emitting the deploy.rollback event fails", func() {
+			It("logs an error", func() {
+				eventManager.EmitCall.Returns.Error = []error{nil, nil, errors.New("rollback event error")}
+
+				for index := range environment.Foundations {
+					pusher := &mocks.Pusher{}
+					pushers = append(pushers, pusher)
+					pusherFactory.CreatePusherCall.Returns.Pushers = append(pusherFactory.CreatePusherCall.Returns.Pushers, pusher)
+					pusherFactory.CreatePusherCall.Returns.Error = append(pusherFactory.CreatePusherCall.Returns.Error, nil)
+
+					if index == 0 {
+						pusher.PushCall.Returns.Error = errors.New("bork")
+					}
+				}
+
+				_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+				Expect(err).To(HaveOccurred())
+
+				Eventually(logBuffer).Should(Say("rollback event error"))
+			})
+		})
+
 		Context("when rollback fails", func() {
 			It("logs an error", func() {
 				for index := range environment.Foundations {
@@ -321,12 +775,63 @@ var _ = Describe("Bluegreen", func() {
 					}
 				}
 
-				Expect(blueGreen.Push(environment, appPath, deploymentInfo, response)).ToNot(Succeed())
+				_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+				Expect(err).To(HaveOccurred())
 
 				Eventually(logBuffer).Should(Say("rollback error"))
 			})
 		})
 
+		It("rolls back every foundation when a push failure is injected", func() {
+			deploymentInfo.InjectFailure = failureinjection.Push
+
+			for range environment.Foundations {
+				pusher := &mocks.Pusher{}
+				pushers = append(pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Pushers = append(pusherFactory.CreatePusherCall.Returns.Pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Error = append(pusherFactory.CreatePusherCall.Returns.Error, nil)
+			}
+
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).To(MatchError(PushFailRollbackError{[]error{
+				FoundationPushError{environment.Foundations[0], failureinjection.InjectedFailureError{Stage: failureinjection.Push}},
+				FoundationPushError{environment.Foundations[1], failureinjection.InjectedFailureError{Stage: failureinjection.Push}},
+			}}))
+
+			for _, pusher := range pushers {
+				Expect(pusher.PushCall.Received.AppPath).To(BeEmpty())
+				Expect(pusher.RollbackCall.Received.DeploymentInfo).To(Equal(deploymentInfo))
+			}
+
+			Expect(eventTypes()).To(ContainElement("deploy.rollback"))
+		})
+
+		It("should not rollback any pushes when rollback is disabled for the environment", func() {
+			environment.DisableRollback = true
+
+			for index := range environment.Foundations {
+				pusher := &mocks.Pusher{}
+				pushers = append(pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Pushers = append(pusherFactory.CreatePusherCall.Returns.Pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Error = append(pusherFactory.CreatePusherCall.Returns.Error, nil)
+
+				if index == 1 {
+					pusher.PushCall.Returns.Error = errors.New("bork")
+				}
+			}
+
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).To(MatchError(PushFailRollbackDisabledError{[]error{FoundationPushError{environment.Foundations[1], errors.New("bork")}}}))
+
+			for _, pusher := range pushers {
+				Expect(pusher.PushCall.Received.DeploymentInfo).To(Equal(deploymentInfo))
+				Expect(pusher.RollbackCall.Received.DeploymentInfo).ToNot(Equal(deploymentInfo))
+			}
+
+			Expect(eventTypes()).ToNot(ContainElement("deploy.rollback"))
+			Eventually(logBuffer).Should(Say("rollback is disabled"))
+		})
+
 		It("should not rollback any pushes on the first deploy when first deploy rollback is disabled", func() {
 			environment.DisableFirstDeployRollback = true
 
@@ -351,7 +856,8 @@ var _ = Describe("Bluegreen", func() {
 				pusher.CleanUpCall.Returns.Error = nil
 			}
 
-			Expect(blueGreen.Push(environment, appPath, deploymentInfo, response)).To(MatchError(PushFailNoRollbackError{}))
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).To(MatchError(PushFailNoRollbackError{[]error{FoundationPushError{environment.Foundations[1], errors.New("bork")}}}))
 
 			for i, pusher := range pushers {
 				foundationURL := environment.Foundations[i]
@@ -364,10 +870,211 @@ var _ = Describe("Bluegreen", func() {
 				Expect(pusher.RollbackCall.Received.DeploymentInfo).ToNot(Equal(deploymentInfo))
 			}
 
+			Expect(eventTypes()).ToNot(ContainElement("deploy.rollback"))
+
 			Expect(response).To(Say(loginOutput))
 			Expect(response).To(Say(pushOutput))
 			Expect(response).To(Say(loginOutput))
 			Expect(response).To(Say(pushOutput))
 		})
 	})
+
+	Context("when the deploy has a smoke test", func() {
+		BeforeEach(func() {
+			deploymentInfo.SmokeTestURL = "https://" + appName + ".example.com/health"
+
+			for range environment.Foundations {
+				pusher := &mocks.Pusher{}
+				pushers = append(pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Pushers = append(pusherFactory.CreatePusherCall.Returns.Pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Error = append(pusherFactory.CreatePusherCall.Returns.Error, nil)
+			}
+		})
+
+		It("emits a deploy.smoketest event after pushing and before deleting the venerable application", func() {
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(eventTypes()).To(Equal([]string{"deploy.progress", "deploy.progress", "deploy.smoketest"}))
+
+			smokeTestEventData := eventManager.EmitCall.Received.Events[2].Data.(S.DeployEventData)
+			Expect(*smokeTestEventData.DeploymentInfo).To(Equal(deploymentInfo))
+			Expect(smokeTestEventData.Writer).To(Equal(response))
+
+			for _, pusher := range pushers {
+				Expect(pusher.DeleteVenerableCall.Received.DeploymentInfo).To(Equal(deploymentInfo))
+				Expect(pusher.RollbackCall.Received.DeploymentInfo).ToNot(Equal(deploymentInfo))
+			}
+		})
+
+		It("unmaps the route from the venerable application before the smoke test", func() {
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, pusher := range pushers {
+				Expect(pusher.UnmapVenerableCall.Received.Context).To(Equal(ctx))
+				Expect(pusher.UnmapVenerableCall.Received.DeploymentInfo).To(Equal(deploymentInfo))
+			}
+		})
+
+		Context("when the route cannot be unmapped from the venerable application", func() {
+			It("rolls back every foundation without a smoke test", func() {
+				pushers[1].UnmapVenerableCall.Returns.Error = errors.New("unmap error")
+
+				_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+				Expect(err).To(BeAssignableToTypeOf(SmokeTestFailRollbackError{}))
+				Expect(err.Error()).To(ContainSubstring("unmap error"))
+
+				for _, pusher := range pushers {
+					Expect(pusher.RollbackCall.Received.DeploymentInfo).To(Equal(deploymentInfo))
+				}
+
+				Expect(eventTypes()).To(Equal([]string{"deploy.progress", "deploy.progress", "deploy.rollback"}))
+			})
+		})
+
+		Context("when the smoke test fails", func() {
+			BeforeEach(func() {
+				eventManager.EmitCall.Returns.Error = []error{nil, nil, errors.New("smoke test error")}
+			})
+
+			It("rolls back every foundation instead of deleting the venerable application", func() {
+				_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+				Expect(err).To(MatchError(SmokeTestFailRollbackError{errors.New("smoke test error")}))
+
+				for _, pusher := range pushers {
+					Expect(pusher.RollbackCall.Received.DeploymentInfo).To(Equal(deploymentInfo))
+					Expect(pusher.DeleteVenerableCall.Received.DeploymentInfo).ToNot(Equal(deploymentInfo))
+				}
+
+				Expect(eventTypes()).To(Equal([]string{"deploy.progress", "deploy.progress", "deploy.smoketest", "deploy.rollback"}))
+			})
+
+			It("does not roll back when rollback is disabled for the environment", func() {
+				environment.DisableRollback = true
+
+				_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+				Expect(err).To(MatchError(SmokeTestFailRollbackDisabledError{errors.New("smoke test error")}))
+
+				for _, pusher := range pushers {
+					Expect(pusher.RollbackCall.Received.DeploymentInfo).ToNot(Equal(deploymentInfo))
+				}
+
+				Expect(eventTypes()).ToNot(ContainElement("deploy.rollback"))
+			})
+		})
+	})
+
+	Context("when each foundation finishes pushing", func() {
+		BeforeEach(func() {
+			for range environment.Foundations {
+				pusher := &mocks.Pusher{}
+				pushers = append(pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Pushers = append(pusherFactory.CreatePusherCall.Returns.Pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Error = append(pusherFactory.CreatePusherCall.Returns.Error, nil)
+			}
+		})
+
+		It("emits a deploy.progress event for every foundation", func() {
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(eventTypes()).To(Equal([]string{"deploy.progress", "deploy.progress"}))
+
+			var percents []int
+			for _, event := range eventManager.EmitCall.Received.Events {
+				progress := event.Data.(S.DeployEventData).Progress
+				Expect(*event.Data.(S.DeployEventData).DeploymentInfo).To(Equal(deploymentInfo))
+				Expect(progress.Total).To(Equal(2))
+				Expect(environment.Foundations[progress.FoundationIndex]).To(Equal(progress.FoundationURL))
+				percents = append(percents, progress.Percent)
+			}
+			Expect(percents).To(Equal([]int{50, 100}))
+		})
+
+		It("counts a foundation whose push failed as finished", func() {
+			pushers[0].PushCall.Returns.Error = errors.New("bork")
+
+			_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).To(HaveOccurred())
+
+			Expect(eventTypes()).To(Equal([]string{"deploy.progress", "deploy.progress", "deploy.rollback"}))
+		})
+
+		Context("when emitting a deploy.progress event fails", func() {
+			It("logs an error and keeps deploying", func() {
+				eventManager.EmitCall.Returns.Error = []error{errors.New("progress event error")}
+
+				_, err := blueGreen.Push(ctx, environment, appPath, deploymentInfo, response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Eventually(logBuffer).Should(Say("progress event error"))
+			})
+		})
+	})
+
+	Describe("running a preflight push", func() {
+		var probePath string
+
+		BeforeEach(func() {
+			probePath = "probePath-" + randomizer.StringRunes(10)
+
+			for range environment.Foundations {
+				pusher := &mocks.Pusher{}
+				pushers = append(pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Pushers = append(pusherFactory.CreatePusherCall.Returns.Pushers, pusher)
+				pusherFactory.CreatePusherCall.Returns.Error = append(pusherFactory.CreatePusherCall.Returns.Error, nil)
+
+				pusher.LoginCall.Write.Output = loginOutput
+				pusher.CanPushCall.Write.Output = pushOutput
+			}
+		})
+
+		It("logs in and checks that the probe can be pushed to every foundation", func() {
+			Expect(blueGreen.Preflight(ctx, environment, probePath, deploymentInfo, response)).To(Succeed())
+
+			for i, pusher := range pushers {
+				Expect(pusher.LoginCall.Received.FoundationURL).To(Equal(environment.Foundations[i]))
+				Expect(pusher.CanPushCall.Received.ProbePath).To(Equal(probePath))
+				Expect(pusher.CanPushCall.Received.DeploymentInfo).To(Equal(deploymentInfo))
+				Expect(pusher.PushCall.Received.AppPath).To(BeEmpty())
+			}
+
+			Expect(response).To(Say(loginOutput))
+			Expect(response).To(Say(pushOutput))
+		})
+
+		Context("when the environment has no foundations", func() {
+			It("returns an error without creating any pushers", func() {
+				environment.Foundations = []string{}
+
+				err := blueGreen.Preflight(ctx, environment, probePath, deploymentInfo, response)
+
+				Expect(err).To(MatchError(NoFoundationsError{environmentName}))
+				Expect(pusherFactory.CreatePusherCall.TimesCalled).To(Equal(0))
+			})
+		})
+
+		Context("when a login command fails", func() {
+			It("returns a login fail error without pushing the probe", func() {
+				pushers[0].LoginCall.Returns.Error = errors.New("bork")
+
+				err := blueGreen.Preflight(ctx, environment, probePath, deploymentInfo, response)
+				Expect(err).To(MatchError(LoginFailError{[]error{errors.New("bork")}}))
+
+				for _, pusher := range pushers {
+					Expect(pusher.CanPushCall.Received.ProbePath).To(BeEmpty())
+				}
+			})
+		})
+
+		Context("when the probe cannot be pushed to a foundation", func() {
+			It("returns a preflight fail error for that foundation", func() {
+				pushers[1].CanPushCall.Returns.Error = errors.New("not authorized")
+
+				err := blueGreen.Preflight(ctx, environment, probePath, deploymentInfo, response)
+				Expect(err).To(MatchError(PreflightFailError{[]error{FoundationPushError{environment.Foundations[1], errors.New("not authorized")}}}))
+			})
+		})
+	})
 })
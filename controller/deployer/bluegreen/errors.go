@@ -1,13 +1,137 @@
 package bluegreen
 
-type PushFailRollbackError struct{}
+import (
+	"fmt"
+	"strings"
+)
+
+type NoFoundationsError struct {
+	Environment string
+}
+
+func (e NoFoundationsError) Error() string {
+	return fmt.Sprintf("push failed: environment %s has no foundations", e.Environment)
+}
+
+type LoginFailError struct {
+	Errs []error
+}
+
+func (e LoginFailError) Error() string {
+	return fmt.Sprintf("push failed: login failed: %s", joinErrors(e.Errs))
+}
+
+type LoginUnavailableFailError struct {
+	Errs []error
+}
+
+func (e LoginUnavailableFailError) Error() string {
+	return fmt.Sprintf("push failed: cloud foundry is unavailable: %s", joinErrors(e.Errs))
+}
+
+type RollingPushFailError struct {
+	Errs []error
+}
+
+func (e RollingPushFailError) Error() string {
+	return fmt.Sprintf("rolling push failed: the failed foundations keep their previous version: %s", joinErrors(e.Errs))
+}
+
+type RollingSmokeTestFailError struct {
+	Err error
+}
+
+func (e RollingSmokeTestFailError) Error() string {
+	return fmt.Sprintf("smoke test failed: rolling deploys cannot be rolled back: every foundation keeps the pushed version: %s", e.Err)
+}
+
+type PreflightFailError struct {
+	Errs []error
+}
+
+func (e PreflightFailError) Error() string {
+	return fmt.Sprintf("preflight failed: cannot push to the space: %s", joinErrors(e.Errs))
+}
+
+type FoundationPushError struct {
+	FoundationURL string
+	Err           error
+}
+
+func (e FoundationPushError) Error() string {
+	return fmt.Sprintf("push failed on %s: %s", e.FoundationURL, e.Err)
+}
+
+type AppPushError struct {
+	AppName string
+	Err     error
+}
+
+func (e AppPushError) Error() string {
+	return fmt.Sprintf("cannot push %s: %s", e.AppName, e.Err)
+}
+
+type PushFailRollbackError struct {
+	Errs []error
+}
 
 func (e PushFailRollbackError) Error() string {
-	return "push failed: rollback triggered"
+	return fmt.Sprintf("push failed: rollback triggered: the deploy was rolled back on every foundation: %s", joinErrors(e.Errs))
 }
 
-type PushFailNoRollbackError struct{}
+type PushFailNoRollbackError struct {
+	Errs []error
+}
 
 func (e PushFailNoRollbackError) Error() string {
-	return "push failed: first deploy, rollback not enabled"
+	return fmt.Sprintf("push failed: first deploy, rollback not enabled: %s", joinErrors(e.Errs))
+}
+
+type PushFailRollbackDisabledError struct {
+	Errs []error
+}
+
+func (e PushFailRollbackDisabledError) Error() string {
+	return fmt.Sprintf("push failed: rollback disabled for this environment: %s", joinErrors(e.Errs))
+}
+
+type UnmapVenerableFailError struct {
+	Errs []error
+}
+
+func (e UnmapVenerableFailError) Error() string {
+	return fmt.Sprintf("cannot route the smoke test to the pushed application: %s", joinErrors(e.Errs))
+}
+
+type SmokeTestFailRollbackError struct {
+	Err error
+}
+
+func (e SmokeTestFailRollbackError) Error() string {
+	return fmt.Sprintf("smoke test failed: rollback triggered: the deploy was rolled back on every foundation: %s", e.Err)
+}
+
+type SmokeTestFailRollbackDisabledError struct {
+	Err error
+}
+
+func (e SmokeTestFailRollbackDisabledError) Error() string {
+	return fmt.Sprintf("smoke test failed: rollback disabled for this environment: %s", e.Err)
+}
+
+type EventError struct {
+	Type string
+	Err  error
+}
+
+func (e EventError) Error() string {
+	return fmt.Sprintf("an error occurred in the %s event: %s", e.Type, e.Err)
+}
+
+func joinErrors(errs []error) string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, ", ")
 }
@@ -0,0 +1,74 @@
+package bluegreen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// prefixWriter prefixes every line written to it with a foundation URL so that
+// output from concurrent pushes stays readable.
+// Only whole lines are written to the underlying writer so the lines of different foundations are not mixed together.
+type prefixWriter struct {
+	writer io.Writer
+	prefix string
+	line   bytes.Buffer
+}
+
+func newPrefixWriter(writer io.Writer, foundationURL string) *prefixWriter {
+	return &prefixWriter{
+		writer: writer,
+		prefix: fmt.Sprintf("[%s] ", foundationURL),
+	}
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	var prefixed bytes.Buffer
+
+	for _, c := range b {
+		if p.line.Len() == 0 {
+			p.line.WriteString(p.prefix)
+		}
+
+		p.line.WriteByte(c)
+
+		if c == '\n' {
+			p.line.WriteTo(&prefixed)
+		}
+	}
+
+	if prefixed.Len() == 0 {
+		return len(b), nil
+	}
+
+	_, err := p.writer.Write(prefixed.Bytes())
+	if err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+// flush writes the last line even though it has not ended yet.
+func (p *prefixWriter) flush() error {
+	if p.line.Len() == 0 {
+		return nil
+	}
+
+	_, err := p.line.WriteTo(p.writer)
+	return err
+}
+
+// syncWriter lets the foundations write to the same response at the same time.
+type syncWriter struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+func (s *syncWriter) Write(b []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.writer.Write(b)
+}
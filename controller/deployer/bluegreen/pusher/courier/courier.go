@@ -2,12 +2,19 @@
 package courier
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
 
 	I "github.com/compozed/deployadactyl/interfaces"
+	"golang.org/x/net/context"
 )
 
 // Courier has an Executor to execute Cloud Foundry commands.
+// Commands that take a context are killed when the context is done.
 type Courier struct {
 	Executor I.Executor
 }
@@ -15,48 +22,198 @@ type Courier struct {
 // Login runs the Cloud Foundry login command.
 //
 // Returns the combined standard output and standard error.
-func (c Courier) Login(api, username, password, org, space string, skipSSL bool) ([]byte, error) {
+func (c Courier) Login(ctx context.Context, api, username, password, org, space string, skipSSL bool) ([]byte, error) {
 	var s string
 	if skipSSL {
 		s = "--skip-ssl-validation"
 	}
 
-	return c.Executor.Execute("login", "-a", api, "-u", username, "-p", password, "-o", org, "-s", space, s)
+	return c.Executor.Execute(ctx, "login", "-a", api, "-u", username, "-p", password, "-o", org, "-s", space, s)
+}
+
+// Auth targets the api and authenticates with a bearer token instead of a username and password,
+// then targets the org and space.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) Auth(ctx context.Context, api, token, org, space string, skipSSL bool) ([]byte, error) {
+	apiArgs := []string{"api", api}
+	if skipSSL {
+		apiArgs = append(apiArgs, "--skip-ssl-validation")
+	}
+
+	output, err := c.Executor.Execute(ctx, apiArgs...)
+	if err != nil {
+		return output, err
+	}
+
+	err = c.Executor.SetAccessToken(token)
+	if err != nil {
+		return output, err
+	}
+
+	targetOutput, err := c.Executor.Execute(ctx, "target", "-o", org, "-s", space)
+	return append(output, targetOutput...), err
 }
 
 // Delete runs the Cloud Foundry delete command.
 //
 // Returns the combined standard output and standard error.
-func (c Courier) Delete(appName string) ([]byte, error) {
-	return c.Executor.Execute("delete", appName, "-f")
+func (c Courier) Delete(ctx context.Context, appName string) ([]byte, error) {
+	return c.Executor.Execute(ctx, "delete", appName, "-f")
 }
 
 // Push runs the Cloud Foundry push command.
+// The application gets an http health check on healthCheckPath when it is not empty, and startupTimeout seconds to start when it is positive.
+// With noRoute the application is pushed without the default route Cloud Foundry would otherwise map to it.
+// The output is written to out while the application is staged and started.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) Push(ctx context.Context, appName, appLocation string, instances uint16, healthCheckPath string, startupTimeout int, noRoute bool, out io.Writer) ([]byte, error) {
+	args := []string{"push", appName, "-i", fmt.Sprint(instances)}
+	if healthCheckPath != "" {
+		args = append(args, "-u", "http", "--endpoint", healthCheckPath)
+	}
+	if startupTimeout > 0 {
+		args = append(args, "-t", fmt.Sprint(startupTimeout))
+	}
+	if noRoute {
+		args = append(args, "--no-route")
+	}
+
+	return c.Executor.StreamInDirectory(ctx, appLocation, out, args...)
+}
+
+// PushDocker runs the Cloud Foundry push command with the docker image instead of the files in appLocation.
+// The manifest in appLocation is still used. The application gets an http health check on healthCheckPath when it is not empty,
+// and startupTimeout seconds to start when it is positive. With noRoute no default route is mapped to it.
+// The output is written to out while the application is staged and started.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) PushDocker(ctx context.Context, appName, appLocation, dockerImage string, instances uint16, healthCheckPath string, startupTimeout int, noRoute bool, out io.Writer) ([]byte, error) {
+	args := []string{"push", appName, "--docker-image", dockerImage, "-i", fmt.Sprint(instances)}
+	if healthCheckPath != "" {
+		args = append(args, "-u", "http", "--endpoint", healthCheckPath)
+	}
+	if startupTimeout > 0 {
+		args = append(args, "-t", fmt.Sprint(startupTimeout))
+	}
+	if noRoute {
+		args = append(args, "--no-route")
+	}
+
+	return c.Executor.StreamInDirectory(ctx, appLocation, out, args...)
+}
+
+// PushRolling runs the Cloud Foundry push command with the rolling strategy, replacing the instances of the application in place.
+// The docker image is pushed instead of the files in appLocation when it is not empty.
+// The application gets an http health check on healthCheckPath when it is not empty, and startupTimeout seconds to start when it is positive.
+// With noRoute no default route is mapped to it.
+// The output is written to out while the application is staged and started.
 //
 // Returns the combined standard output and standard error.
-func (c Courier) Push(appName, appLocation string, instances uint16) ([]byte, error) {
-	return c.Executor.ExecuteInDirectory(appLocation, "push", appName, "-i", fmt.Sprint(instances))
+func (c Courier) PushRolling(ctx context.Context, appName, appLocation, dockerImage string, instances uint16, healthCheckPath string, startupTimeout int, noRoute bool, out io.Writer) ([]byte, error) {
+	args := []string{"push", appName, "--strategy", "rolling"}
+	if dockerImage != "" {
+		args = append(args, "--docker-image", dockerImage)
+	}
+	args = append(args, "-i", fmt.Sprint(instances))
+	if healthCheckPath != "" {
+		args = append(args, "-u", "http", "--endpoint", healthCheckPath)
+	}
+	if startupTimeout > 0 {
+		args = append(args, "-t", fmt.Sprint(startupTimeout))
+	}
+	if noRoute {
+		args = append(args, "--no-route")
+	}
+
+	return c.Executor.StreamInDirectory(ctx, appLocation, out, args...)
+}
+
+// Healthy runs the Cloud Foundry curl command to get the state of every instance of the application.
+//
+// Returns true when the application has instances and all of them are running.
+func (c Courier) Healthy(ctx context.Context, appName string) (bool, error) {
+	guid, err := c.Executor.Execute(ctx, "app", appName, "--guid")
+	if err != nil {
+		return false, err
+	}
+
+	output, err := c.Executor.Execute(ctx, "curl", fmt.Sprintf("/v2/apps/%s/instances", strings.TrimSpace(string(guid))))
+	if err != nil {
+		return false, err
+	}
+
+	var instances map[string]struct {
+		State string `json:"state"`
+	}
+	err = json.Unmarshal(output, &instances)
+	if err != nil {
+		return false, err
+	}
+
+	if len(instances) == 0 {
+		return false, nil
+	}
+	for _, instance := range instances {
+		if instance.State != "RUNNING" {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// CanPush pushes the application in appLocation without starting it or mapping a route and deletes it again.
+// It is used to check that the logged in user is allowed to push to the targeted space.
+// The application is deleted even when the push fails, since a push can fail after the application was created.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) CanPush(ctx context.Context, appName, appLocation string) ([]byte, error) {
+	output, pushErr := c.Executor.ExecuteInDirectory(ctx, appLocation, "push", appName, "--no-start", "--no-route")
+
+	deleteOutput, err := c.Executor.Execute(ctx, "delete", appName, "-f")
+	output = append(output, deleteOutput...)
+	if pushErr != nil {
+		return output, pushErr
+	}
+
+	return output, err
 }
 
 // Rename runs the Cloud Foundry rename command.
 //
 // Returns the combined standard output and standard error.
-func (c Courier) Rename(appName, newAppName string) ([]byte, error) {
-	return c.Executor.Execute("rename", appName, newAppName)
+func (c Courier) Rename(ctx context.Context, appName, newAppName string) ([]byte, error) {
+	return c.Executor.Execute(ctx, "rename", appName, newAppName)
+}
+
+// MapRoute runs the Cloud Foundry map-route command to map hostname.domain to appName.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) MapRoute(ctx context.Context, appName, hostname, domain string) ([]byte, error) {
+	return c.Executor.Execute(ctx, "map-route", appName, domain, "-n", hostname)
+}
+
+// UnmapRoute runs the Cloud Foundry unmap-route command to unmap hostname.domain from appName.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) UnmapRoute(ctx context.Context, appName, hostname, domain string) ([]byte, error) {
+	return c.Executor.Execute(ctx, "unmap-route", appName, domain, "-n", hostname)
 }
 
-// MapRoute runs the Cloud Foundry map-route command.
+// DeleteRoute runs the Cloud Foundry delete-route command.
 //
 // Returns the combined standard output and standard error.
-func (c Courier) MapRoute(appName, domain string) ([]byte, error) {
-	return c.Executor.Execute("map-route", appName, domain, "-n", appName)
+func (c Courier) DeleteRoute(ctx context.Context, hostname, domain string) ([]byte, error) {
+	return c.Executor.Execute(ctx, "delete-route", domain, "-n", hostname, "-f")
 }
 
 // Logs runs the Cloud Foundry logs command.
 //
 // Returns the combined standard output and standard error.
-func (c Courier) Logs(appName string) ([]byte, error) {
-	logs, err := c.Executor.Execute("logs", appName, "--recent")
+func (c Courier) Logs(ctx context.Context, appName string) ([]byte, error) {
+	logs, err := c.Executor.Execute(ctx, "logs", appName, "--recent")
 	return logs, err
 }
 
@@ -64,23 +221,116 @@ func (c Courier) Logs(appName string) ([]byte, error) {
 // services.
 //
 // Returns the combined standard output and standard error.
-func (c Courier) Cups(appName string, body string) ([]byte, error) {
-	return c.Executor.Execute("cups", appName, "-p", body)
+func (c Courier) Cups(ctx context.Context, appName string, body string) ([]byte, error) {
+	return c.Executor.Execute(ctx, "cups", appName, "-p", body)
 }
 
 // Uups runs the Cloud Foundry UUPS command to update a user provided serivce
-func (c Courier) Uups(appName string, body string) ([]byte, error) {
-	return c.Executor.Execute("uups", appName, "-p", body)
+func (c Courier) Uups(ctx context.Context, appName string, body string) ([]byte, error) {
+	return c.Executor.Execute(ctx, "uups", appName, "-p", body)
 }
 
 // Exists checks to see whether the application name exists already.
 //
 // Returns true if the application exists.
-func (c Courier) Exists(appName string) bool {
-	_, err := c.Executor.Execute("app", appName)
+func (c Courier) Exists(ctx context.Context, appName string) bool {
+	_, err := c.Executor.Execute(ctx, "app", appName)
 	return err == nil
 }
 
+// List runs the Cloud Foundry apps command.
+//
+// Returns the names of the applications in the targeted space that start with prefix.
+func (c Courier) List(ctx context.Context, prefix string) ([]string, error) {
+	apps, err := c.apps(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var appNames []string
+	for _, fields := range apps {
+		if strings.HasPrefix(fields[0], prefix) {
+			appNames = append(appNames, fields[0])
+		}
+	}
+
+	return appNames, nil
+}
+
+// RoutedApps runs the Cloud Foundry apps command.
+//
+// Returns the names of the applications in the targeted space that hostname.domain is mapped to.
+func (c Courier) RoutedApps(ctx context.Context, hostname, domain string) ([]string, error) {
+	apps, err := c.apps(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	route := hostname + "." + domain
+
+	var appNames []string
+	for _, fields := range apps {
+		for _, url := range fields[1:] {
+			if strings.TrimSuffix(url, ",") == route {
+				appNames = append(appNames, fields[0])
+				break
+			}
+		}
+	}
+
+	return appNames, nil
+}
+
+// apps returns the fields of each row of the table printed by the Cloud Foundry apps command.
+func (c Courier) apps(ctx context.Context) ([][]string, error) {
+	output, err := c.Executor.Execute(ctx, "apps")
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		apps        [][]string
+		inTable     bool
+		outputLines = bufio.NewScanner(bytes.NewReader(output))
+	)
+	for outputLines.Scan() {
+		fields := strings.Fields(outputLines.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if !inTable {
+			inTable = fields[0] == "name"
+			continue
+		}
+
+		apps = append(apps, fields)
+	}
+
+	return apps, nil
+}
+
+// Stop runs the Cloud Foundry stop command.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) Stop(ctx context.Context, appName string) ([]byte, error) {
+	return c.Executor.Execute(ctx, "stop", appName)
+}
+
+// AppGUID runs the Cloud Foundry app command with the guid flag.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) AppGUID(ctx context.Context, appName string) ([]byte, error) {
+	return c.Executor.Execute(ctx, "app", appName, "--guid")
+}
+
+// Version runs the Cloud Foundry CLI with the version flag.
+//
+// Returns the combined standard output and standard error.
+func (c Courier) Version() ([]byte, error) {
+	return c.Executor.Execute(context.Background(), "--version")
+}
+
 // CleanUp removes the temporary directory created by the Executor.
 func (c Courier) CleanUp() error {
 	return c.Executor.CleanUp()
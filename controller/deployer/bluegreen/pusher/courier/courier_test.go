@@ -1,14 +1,18 @@
 package courier_test
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"math/rand"
+	"time"
 
 	. "github.com/compozed/deployadactyl/controller/deployer/bluegreen/pusher/courier"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
 )
 
 var _ = Describe("Courier", func() {
@@ -17,12 +21,16 @@ var _ = Describe("Courier", func() {
 		output   string
 		courier  Courier
 		executor *mocks.Executor
+		ctx      context.Context
+		stream   *bytes.Buffer
 	)
 
 	BeforeEach(func() {
 		appName = "appName-" + randomizer.StringRunes(10)
 		output = "output-" + randomizer.StringRunes(10)
 		executor = &mocks.Executor{}
+		ctx = context.Background()
+		stream = &bytes.Buffer{}
 		courier = Courier{
 			Executor: executor,
 		}
@@ -43,7 +51,7 @@ var _ = Describe("Courier", func() {
 			executor.ExecuteCall.Returns.Output = []byte(output)
 			executor.ExecuteCall.Returns.Error = nil
 
-			out, err := courier.Login(api, user, password, org, space, skipSSL)
+			out, err := courier.Login(ctx, api, user, password, org, space, skipSSL)
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
@@ -64,7 +72,7 @@ var _ = Describe("Courier", func() {
 			executor.ExecuteCall.Returns.Output = []byte(output)
 			executor.ExecuteCall.Returns.Error = nil
 
-			out, err := courier.Login(api, user, password, org, space, skipSSL)
+			out, err := courier.Login(ctx, api, user, password, org, space, skipSSL)
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
@@ -72,6 +80,63 @@ var _ = Describe("Courier", func() {
 		})
 	})
 
+	Describe("authenticating with client credentials", func() {
+		var (
+			api   string
+			token string
+			org   string
+			space string
+		)
+
+		BeforeEach(func() {
+			api = "api-" + randomizer.StringRunes(10)
+			token = "token-" + randomizer.StringRunes(10)
+			org = "org-" + randomizer.StringRunes(10)
+			space = "space-" + randomizer.StringRunes(10)
+		})
+
+		It("should get valid Cloud Foundry api and target commands", func() {
+			executor.ExecuteCall.Returns.Output = []byte(output)
+			executor.ExecuteCall.Returns.Error = nil
+
+			out, err := courier.Auth(ctx, api, token, org, space, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.AllArgs).To(Equal([][]string{
+				{"api", api, "--skip-ssl-validation"},
+				{"target", "-o", org, "-s", space},
+			}))
+			Expect(string(out)).To(Equal(output + output))
+		})
+
+		It("authenticates with the token", func() {
+			_, err := courier.Auth(ctx, api, token, org, space, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.SetAccessTokenCall.Received.Token).To(Equal(token))
+		})
+
+		It("stops at the first command that fails", func() {
+			executor.ExecuteCall.Returns.Output = []byte(output)
+			executor.ExecuteCall.Returns.Error = errors.New("bork")
+
+			_, err := courier.Auth(ctx, api, token, org, space, false)
+			Expect(err).To(MatchError("bork"))
+
+			Expect(executor.ExecuteCall.Received.AllArgs).To(Equal([][]string{{"api", api}}))
+			Expect(executor.SetAccessTokenCall.Received.Token).To(BeEmpty())
+		})
+
+		It("does not target the org and space when the token cannot be set", func() {
+			executor.SetAccessTokenCall.Returns.Error = errors.New("bork")
+
+			_, err := courier.Auth(ctx, api, token, org, space, false)
+			Expect(err).To(MatchError("bork"))
+
+			Expect(executor.ExecuteCall.Received.AllArgs).To(Equal([][]string{{"api", api}}))
+		})
+	})
+
 	Describe("deleting an app", func() {
 		It("should get a valid Cloud Foundry delete command", func() {
 			expectedArgs := []string{"delete", appName, "-f"}
@@ -79,9 +144,10 @@ var _ = Describe("Courier", func() {
 			executor.ExecuteCall.Returns.Output = []byte(output)
 			executor.ExecuteCall.Returns.Error = nil
 
-			out, err := courier.Delete(appName)
+			out, err := courier.Delete(ctx, appName)
 			Expect(err).ToNot(HaveOccurred())
 
+			Expect(executor.ExecuteCall.Received.Context).To(Equal(ctx))
 			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
 			Expect(string(out)).To(Equal(output))
 		})
@@ -95,15 +161,202 @@ var _ = Describe("Courier", func() {
 				expectedArgs = []string{"push", appName, "-i", fmt.Sprint(instances)}
 			)
 
-			executor.ExecuteInDirectoryCall.Returns.Output = []byte(output)
-			executor.ExecuteInDirectoryCall.Returns.Error = nil
+			executor.StreamInDirectoryCall.Returns.Output = []byte(output)
+			executor.StreamInDirectoryCall.Returns.Error = nil
+
+			out, err := courier.Push(ctx, appName, appLocation, instances, "", 0, false, stream)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.StreamInDirectoryCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output))
+		})
+
+		It("streams the output of the push to the writer", func() {
+			executor.StreamInDirectoryCall.Returns.Output = []byte(output)
+
+			out, err := courier.Push(ctx, appName, "appLocation", 1, "", 0, false, stream)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.StreamInDirectoryCall.Received.Out).To(Equal(stream))
+			Expect(stream.String()).To(Equal(output))
+			Expect(string(out)).To(Equal(output))
+		})
+
+		It("runs the push with the given context", func() {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Minute)
+			defer cancel()
+
+			_, err := courier.Push(ctx, appName, "appLocation", 1, "", 0, false, stream)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.StreamInDirectoryCall.Received.Context).To(Equal(ctx))
+		})
+
+		It("pushes with an http health check on the health check path", func() {
+			_, err := courier.Push(ctx, appName, "appLocation", 1, "/health", 0, false, stream)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.StreamInDirectoryCall.Received.Args).To(Equal([]string{"push", appName, "-i", "1", "-u", "http", "--endpoint", "/health"}))
+		})
+
+		It("pushes with the startup timeout when it is given", func() {
+			_, err := courier.Push(ctx, appName, "appLocation", 1, "", 180, false, stream)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.StreamInDirectoryCall.Received.Args).To(Equal([]string{"push", appName, "-i", "1", "-t", "180"}))
+		})
 
-			out, err := courier.Push(appName, appLocation, instances)
+		It("pushes without a route when there should be none", func() {
+			_, err := courier.Push(ctx, appName, "appLocation", 1, "", 0, true, stream)
 			Expect(err).ToNot(HaveOccurred())
 
-			Expect(executor.ExecuteInDirectoryCall.Received.Args).To(Equal(expectedArgs))
+			Expect(executor.StreamInDirectoryCall.Received.Args).To(Equal([]string{"push", appName, "-i", "1", "--no-route"}))
+		})
+	})
+
+	Describe("pushing a docker image", func() {
+		It("pushes the image with the manifest in the app location", func() {
+			var (
+				appLocation  = "appLocation-" + randomizer.StringRunes(10)
+				dockerImage  = "dockerImage-" + randomizer.StringRunes(10)
+				expectedArgs = []string{"push", appName, "--docker-image", dockerImage, "-i", "2"}
+			)
+
+			executor.StreamInDirectoryCall.Returns.Output = []byte(output)
+
+			out, err := courier.PushDocker(ctx, appName, appLocation, dockerImage, 2, "", 0, false, stream)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.StreamInDirectoryCall.Received.AppLocation).To(Equal(appLocation))
+			Expect(executor.StreamInDirectoryCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output))
+		})
+
+		It("pushes with an http health check on the health check path", func() {
+			_, err := courier.PushDocker(ctx, appName, "appLocation", "dockerImage", 1, "/health", 0, false, stream)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.StreamInDirectoryCall.Received.Args).To(Equal([]string{"push", appName, "--docker-image", "dockerImage", "-i", "1", "-u", "http", "--endpoint", "/health"}))
+		})
+
+		It("pushes without a route when there should be none", func() {
+			_, err := courier.PushDocker(ctx, appName, "appLocation", "dockerImage", 1, "", 0, true, stream)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.StreamInDirectoryCall.Received.Args).To(Equal([]string{"push", appName, "--docker-image", "dockerImage", "-i", "1", "--no-route"}))
+		})
+	})
+
+	Describe("pushing an app with a rolling deployment", func() {
+		It("pushes the app with the rolling strategy", func() {
+			appLocation := "appLocation-" + randomizer.StringRunes(10)
+			executor.StreamInDirectoryCall.Returns.Output = []byte(output)
+
+			out, err := courier.PushRolling(ctx, appName, appLocation, "", 2, "", 0, false, stream)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.StreamInDirectoryCall.Received.AppLocation).To(Equal(appLocation))
+			Expect(executor.StreamInDirectoryCall.Received.Args).To(Equal([]string{"push", appName, "--strategy", "rolling", "-i", "2"}))
 			Expect(string(out)).To(Equal(output))
 		})
+
+		It("pushes the docker image with an http health check when they are given", func() {
+			_, err := courier.PushRolling(ctx, appName, "appLocation", "dockerImage", 1, "/health", 0, false, stream)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.StreamInDirectoryCall.Received.Args).To(Equal([]string{"push", appName, "--strategy", "rolling", "--docker-image", "dockerImage", "-i", "1", "-u", "http", "--endpoint", "/health"}))
+		})
+
+		It("pushes with the startup timeout when it is given", func() {
+			_, err := courier.PushRolling(ctx, appName, "appLocation", "", 1, "", 30, false, stream)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.StreamInDirectoryCall.Received.Args).To(Equal([]string{"push", appName, "--strategy", "rolling", "-i", "1", "-t", "30"}))
+		})
+
+		It("pushes without a route when there should be none", func() {
+			_, err := courier.PushRolling(ctx, appName, "appLocation", "", 1, "", 0, true, stream)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.StreamInDirectoryCall.Received.Args).To(Equal([]string{"push", appName, "--strategy", "rolling", "-i", "1", "--no-route"}))
+		})
+	})
+
+	Describe("checking the health of an app", func() {
+		It("gets the state of the instances of the app", func() {
+			executor.ExecuteCall.Returns.Output = []byte(`{"0": {"state": "RUNNING"}, "1": {"state": "RUNNING"}}`)
+
+			healthy, err := courier.Healthy(ctx, appName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(healthy).To(BeTrue())
+			Expect(executor.ExecuteCall.Received.AllArgs[0]).To(Equal([]string{"app", appName, "--guid"}))
+			Expect(executor.ExecuteCall.Received.AllArgs[1][0]).To(Equal("curl"))
+			Expect(executor.ExecuteCall.Received.Context).To(Equal(ctx))
+		})
+
+		It("is not healthy when an instance is not running", func() {
+			executor.ExecuteCall.Returns.Output = []byte(`{"0": {"state": "RUNNING"}, "1": {"state": "CRASHED"}}`)
+
+			healthy, err := courier.Healthy(ctx, appName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(healthy).To(BeFalse())
+		})
+
+		It("is not healthy when there are no instances", func() {
+			executor.ExecuteCall.Returns.Output = []byte(`{}`)
+
+			healthy, err := courier.Healthy(ctx, appName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(healthy).To(BeFalse())
+		})
+
+		It("returns an error when the state cannot be read", func() {
+			executor.ExecuteCall.Returns.Output = []byte("not json")
+
+			_, err := courier.Healthy(ctx, appName)
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("checking whether an application can be pushed", func() {
+		It("should push without starting and then delete the application", func() {
+			appLocation := "appLocation-" + randomizer.StringRunes(10)
+
+			executor.ExecuteInDirectoryCall.Returns.Output = []byte(output)
+			executor.ExecuteCall.Returns.Output = []byte("deleted")
+
+			out, err := courier.CanPush(ctx, appName, appLocation)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteInDirectoryCall.Received.AppLocation).To(Equal(appLocation))
+			Expect(executor.ExecuteInDirectoryCall.Received.Args).To(Equal([]string{"push", appName, "--no-start", "--no-route"}))
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"delete", appName, "-f"}))
+			Expect(string(out)).To(Equal(output + "deleted"))
+		})
+
+		It("still deletes the application when the push fails", func() {
+			executor.ExecuteInDirectoryCall.Returns.Output = []byte(output)
+			executor.ExecuteInDirectoryCall.Returns.Error = errors.New("not authorized")
+			executor.ExecuteCall.Returns.Output = []byte("deleted")
+
+			out, err := courier.CanPush(ctx, appName, "appLocation")
+			Expect(err).To(MatchError("not authorized"))
+
+			Expect(executor.ExecuteCall.Received.AllArgs).To(Equal([][]string{{"delete", appName, "-f"}}))
+			Expect(string(out)).To(Equal(output + "deleted"))
+		})
+
+		It("returns an error when the application cannot be deleted", func() {
+			executor.ExecuteCall.Returns.Error = errors.New("delete failed")
+
+			_, err := courier.CanPush(ctx, appName, "appLocation")
+			Expect(err).To(MatchError("delete failed"))
+		})
 	})
 
 	Describe("renaming an app", func() {
@@ -116,7 +369,7 @@ var _ = Describe("Courier", func() {
 			executor.ExecuteCall.Returns.Output = []byte(output)
 			executor.ExecuteCall.Returns.Error = nil
 
-			out, err := courier.Rename(appName, newAppName)
+			out, err := courier.Rename(ctx, appName, newAppName)
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
@@ -128,13 +381,47 @@ var _ = Describe("Courier", func() {
 		It("should get a valid Cloud Foundry map-route command", func() {
 			var (
 				domain       = "domain-" + randomizer.StringRunes(10)
-				expectedArgs = []string{"map-route", appName, domain, "-n", appName}
+				hostname     = "hostname-" + randomizer.StringRunes(10)
+				expectedArgs = []string{"map-route", appName, domain, "-n", hostname}
 			)
 
 			executor.ExecuteCall.Returns.Output = []byte(output)
 			executor.ExecuteCall.Returns.Error = nil
 
-			out, err := courier.MapRoute(appName, domain)
+			out, err := courier.MapRoute(ctx, appName, hostname, domain)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output))
+		})
+	})
+
+	Describe("unmapping a route", func() {
+		It("should get a valid Cloud Foundry unmap-route command", func() {
+			domain := "domain-" + randomizer.StringRunes(10)
+			hostname := "hostname-" + randomizer.StringRunes(10)
+			expectedArgs := []string{"unmap-route", appName, domain, "-n", hostname}
+
+			executor.ExecuteCall.Returns.Output = []byte(output)
+			executor.ExecuteCall.Returns.Error = nil
+
+			out, err := courier.UnmapRoute(ctx, appName, hostname, domain)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output))
+		})
+	})
+
+	Describe("deleting a route", func() {
+		It("should get a valid Cloud Foundry delete-route command", func() {
+			domain := "domain-" + randomizer.StringRunes(10)
+			expectedArgs := []string{"delete-route", domain, "-n", appName, "-f"}
+
+			executor.ExecuteCall.Returns.Output = []byte(output)
+			executor.ExecuteCall.Returns.Error = nil
+
+			out, err := courier.DeleteRoute(ctx, appName, domain)
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
@@ -149,7 +436,7 @@ var _ = Describe("Courier", func() {
 			executor.ExecuteCall.Returns.Output = []byte(output)
 			executor.ExecuteCall.Returns.Error = nil
 
-			out, err := courier.Logs(appName)
+			out, err := courier.Logs(ctx, appName)
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
@@ -164,9 +451,102 @@ var _ = Describe("Courier", func() {
 			executor.ExecuteCall.Returns.Output = []byte(output)
 			executor.ExecuteCall.Returns.Error = nil
 
-			Expect(courier.Exists(appName)).To(BeTrue())
+			Expect(courier.Exists(ctx, appName)).To(BeTrue())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+		})
+	})
+
+	Describe("listing apps", func() {
+		It("returns the names of the apps that start with the prefix", func() {
+			executor.ExecuteCall.Returns.Output = []byte(`Getting apps in org org / space space as user...
+OK
+
+name                           requested state   instances   memory   disk   urls
+` + appName + `-venerable-1500000000   stopped           0/1         1G       1G
+` + appName + `                        started           1/1         1G       1G     ` + appName + `.example.com
+other-app                      started           1/1         1G       1G     other-app.example.com
+`)
+
+			appNames, err := courier.List(ctx, appName+"-venerable-")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"apps"}))
+			Expect(appNames).To(Equal([]string{appName + "-venerable-1500000000"}))
+		})
+
+		It("returns an error when the apps command fails", func() {
+			executor.ExecuteCall.Returns.Error = errors.New("apps error")
+
+			_, err := courier.List(ctx, appName)
+
+			Expect(err).To(MatchError("apps error"))
+		})
+	})
+
+	Describe("listing the apps a route is mapped to", func() {
+		It("returns the names of the apps with the route", func() {
+			executor.ExecuteCall.Returns.Output = []byte(`Getting apps in org org / space space as user...
+OK
+
+name                           requested state   instances   memory   disk   urls
+` + appName + `-build-2          started           1/1         1G       1G     ` + appName + `-build-2.example.com, ` + appName + `.example.com
+` + appName + `-venerable        stopped           0/1         1G       1G
+` + appName + `-admin            started           1/1         1G       1G     ` + appName + `-admin.example.com
+`)
+
+			appNames, err := courier.RoutedApps(ctx, appName, "example.com")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"apps"}))
+			Expect(appNames).To(Equal([]string{appName + "-build-2"}))
+		})
+
+		It("returns an error when the apps command fails", func() {
+			executor.ExecuteCall.Returns.Error = errors.New("apps error")
+
+			_, err := courier.RoutedApps(ctx, appName, "example.com")
+
+			Expect(err).To(MatchError("apps error"))
+		})
+	})
+
+	Describe("getting the version of the cf CLI", func() {
+		It("should get a valid Cloud Foundry version command", func() {
+			executor.ExecuteCall.Returns.Output = []byte(output)
+
+			out, err := courier.Version()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"--version"}))
+			Expect(string(out)).To(Equal(output))
+		})
+	})
+
+	Describe("stopping an app", func() {
+		It("should get a valid Cloud Foundry stop command", func() {
+			executor.ExecuteCall.Returns.Output = []byte(output)
+
+			out, err := courier.Stop(ctx, appName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(executor.ExecuteCall.Received.Args).To(Equal([]string{"stop", appName}))
+			Expect(string(out)).To(Equal(output))
+		})
+	})
+
+	Describe("getting the guid of an app", func() {
+		It("should get a valid Cloud Foundry app guid command", func() {
+			expectedArgs := []string{"app", appName, "--guid"}
+
+			executor.ExecuteCall.Returns.Output = []byte(output)
+			executor.ExecuteCall.Returns.Error = nil
+
+			out, err := courier.AppGUID(ctx, appName)
+			Expect(err).ToNot(HaveOccurred())
 
 			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
+			Expect(string(out)).To(Equal(output))
 		})
 	})
 
@@ -182,7 +562,7 @@ var _ = Describe("Courier", func() {
 			executor.ExecuteCall.Returns.Output = []byte(output)
 			executor.ExecuteCall.Returns.Error = nil
 
-			out, err := courier.Cups(appName, body)
+			out, err := courier.Cups(ctx, appName, body)
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
@@ -201,7 +581,7 @@ var _ = Describe("Courier", func() {
 			executor.ExecuteCall.Returns.Output = []byte(output)
 			executor.ExecuteCall.Returns.Error = nil
 
-			out, err := courier.Uups(appName, body)
+			out, err := courier.Uups(ctx, appName, body)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(executor.ExecuteCall.Received.Args).To(Equal(expectedArgs))
 			Expect(string(out)).To(Equal(output))
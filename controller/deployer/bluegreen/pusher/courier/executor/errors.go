@@ -0,0 +1,27 @@
+package executor
+
+import "fmt"
+
+type TimeoutError struct {
+	Command string
+}
+
+func (e TimeoutError) Error() string {
+	return fmt.Sprintf("cf command timed out: %s", e.Command)
+}
+
+type CancelledError struct {
+	Command string
+}
+
+func (e CancelledError) Error() string {
+	return fmt.Sprintf("cf command was cancelled: %s", e.Command)
+}
+
+type AccessTokenError struct {
+	Err error
+}
+
+func (e AccessTokenError) Error() string {
+	return fmt.Sprintf("cannot set the access token of the cf CLI: %s", e.Err)
+}
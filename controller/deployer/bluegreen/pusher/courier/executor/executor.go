@@ -2,11 +2,17 @@
 package executor
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
 	"os"
 	"os/exec"
+	"path"
 	"strings"
+	"syscall"
 
 	"github.com/spf13/afero"
+	"golang.org/x/net/context"
 )
 
 // New returns a new Executor struct.
@@ -29,22 +35,66 @@ type Executor struct {
 }
 
 // Execute takes a slice of string args and runs them together against the cf command on the Cloud Foundry binary.
+// If ctx is done before the command finishes, the process group of the command is killed and a TimeoutError is returned,
+// or a CancelledError when ctx was cancelled.
 //
-// Returns the combined standard output and standard error.
-func (e Executor) Execute(args ...string) ([]byte, error) {
+// Returns the combined standard output and standard error, including the output written before a timeout.
+func (e Executor) Execute(ctx context.Context, args ...string) ([]byte, error) {
 	command := exec.Command("cf", args...)
 	command.Env = setEnv(os.Environ(), "CF_HOME", e.tempDir)
-	return command.CombinedOutput()
+	return run(ctx, command, nil)
 }
 
 // ExecuteInDirectory does the same thing as Execute does, but does it in a specific directory.
 //
-// Returns the combined standard output and standard error.
-func (e Executor) ExecuteInDirectory(directory string, args ...string) ([]byte, error) {
+// Returns the combined standard output and standard error, including the output written before a timeout.
+func (e Executor) ExecuteInDirectory(ctx context.Context, directory string, args ...string) ([]byte, error) {
 	command := exec.Command("cf", args...)
 	command.Env = setEnv(os.Environ(), "CF_HOME", e.tempDir)
 	command.Dir = directory
-	return command.CombinedOutput()
+	return run(ctx, command, nil)
+}
+
+// StreamInDirectory does the same thing as ExecuteInDirectory does, but also writes the output to out as the command produces it.
+//
+// Returns the combined standard output and standard error, including the output written before a timeout.
+func (e Executor) StreamInDirectory(ctx context.Context, directory string, out io.Writer, args ...string) ([]byte, error) {
+	command := exec.Command("cf", args...)
+	command.Env = setEnv(os.Environ(), "CF_HOME", e.tempDir)
+	command.Dir = directory
+	return run(ctx, command, out)
+}
+
+// SetAccessToken writes the token into the config of the Cloud Foundry CLI as the access token the commands after it authenticate with.
+// The api has to be targeted first so that the config exists.
+func (e Executor) SetAccessToken(token string) error {
+	configPath := path.Join(e.tempDir, ".cf", "config.json")
+
+	contents, err := e.fileSystem.ReadFile(configPath)
+	if err != nil {
+		return AccessTokenError{err}
+	}
+
+	config := map[string]interface{}{}
+	err = json.Unmarshal(contents, &config)
+	if err != nil {
+		return AccessTokenError{err}
+	}
+
+	config["AccessToken"] = "bearer " + token
+	config["RefreshToken"] = ""
+
+	contents, err = json.Marshal(config)
+	if err != nil {
+		return AccessTokenError{err}
+	}
+
+	err = e.fileSystem.WriteFile(configPath, contents, 0600)
+	if err != nil {
+		return AccessTokenError{err}
+	}
+
+	return nil
 }
 
 // CleanUp removes the temporary directory of the Executor.
@@ -52,6 +102,47 @@ func (e Executor) CleanUp() error {
 	return e.fileSystem.RemoveAll(e.tempDir)
 }
 
+// run starts the command in its own process group so that the cf CLI and anything it started can be killed together
+// when ctx is done. The output is written to out as well when it is not nil.
+func run(ctx context.Context, command *exec.Cmd, out io.Writer) ([]byte, error) {
+	var output bytes.Buffer
+	var writer io.Writer = &output
+	if out != nil {
+		writer = io.MultiWriter(&output, out)
+	}
+	command.Stdout = writer
+	command.Stderr = writer
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	err := command.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- command.Wait()
+	}()
+
+	select {
+	case err = <-done:
+		return output.Bytes(), err
+	case <-ctx.Done():
+		syscall.Kill(-command.Process.Pid, syscall.SIGKILL)
+		<-done
+
+		// Only the subcommand is named because the arguments can contain credentials.
+		subcommand := ""
+		if len(command.Args) > 1 {
+			subcommand = command.Args[1]
+		}
+		if ctx.Err() == context.Canceled {
+			return output.Bytes(), CancelledError{subcommand}
+		}
+		return output.Bytes(), TimeoutError{subcommand}
+	}
+}
+
 func setEnv(env []string, key, value string) []string {
 	keyValuePair := key + "=" + value
 
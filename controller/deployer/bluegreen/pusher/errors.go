@@ -1,6 +1,9 @@
 package pusher
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type RenameFailError struct {
 	Err error
@@ -28,6 +31,62 @@ func (e DeleteVenerableError) Error() string {
 	return fmt.Sprintf("cannot delete %s: %s", e.VenerableName, e.Err)
 }
 
+type RetainVenerableError struct {
+	VenerableName string
+	Err           error
+}
+
+func (e RetainVenerableError) Error() string {
+	return fmt.Sprintf("cannot keep %s as a previous version: %s", e.VenerableName, e.Err)
+}
+
+type ListVersionsError struct {
+	AppName string
+	Err     error
+}
+
+func (e ListVersionsError) Error() string {
+	return fmt.Sprintf("cannot list the previous versions of %s: %s", e.AppName, e.Err)
+}
+
+type ListRoutedAppsError struct {
+	AppName string
+	Err     error
+}
+
+func (e ListRoutedAppsError) Error() string {
+	return fmt.Sprintf("cannot find the application the %s route is mapped to: %s", e.AppName, e.Err)
+}
+
+type RouteTakenError struct {
+	Hostname string
+	Domain   string
+	AppName  string
+}
+
+func (e RouteTakenError) Error() string {
+	return fmt.Sprintf("route %s.%s is already mapped to %s", e.Hostname, e.Domain, e.AppName)
+}
+
+type UnmapVenerableError struct {
+	Hostname string
+	Domain   string
+	Err      error
+}
+
+func (e UnmapVenerableError) Error() string {
+	return fmt.Sprintf("cannot unmap route %s.%s from the venerable app: %s", e.Hostname, e.Domain, e.Err)
+}
+
+type UnhealthyAppError struct {
+	AppName string
+	Timeout time.Duration
+}
+
+func (e UnhealthyAppError) Error() string {
+	return fmt.Sprintf("application %s did not become healthy within %s", e.AppName, e.Timeout)
+}
+
 type LoginError struct {
 	FoundationURL string
 	Err           error
@@ -36,3 +95,39 @@ type LoginError struct {
 func (e LoginError) Error() string {
 	return fmt.Sprintf("cannot login to %s: %s", e.FoundationURL, e.Err)
 }
+
+type LoginUnavailableError struct {
+	FoundationURL string
+	Err           error
+}
+
+func (e LoginUnavailableError) Error() string {
+	return fmt.Sprintf("cannot login to %s: cloud foundry is unavailable: %s", e.FoundationURL, e.Err)
+}
+
+func (e LoginUnavailableError) Temporary() bool {
+	return true
+}
+
+type LoginTimeoutError struct {
+	FoundationURL string
+	Timeout       time.Duration
+}
+
+func (e LoginTimeoutError) Error() string {
+	return fmt.Sprintf("cannot login to %s: cloud foundry did not respond within %s", e.FoundationURL, e.Timeout)
+}
+
+func (e LoginTimeoutError) Temporary() bool {
+	return true
+}
+
+type PushPermissionError struct {
+	Org   string
+	Space string
+	Err   error
+}
+
+func (e PushPermissionError) Error() string {
+	return fmt.Sprintf("cannot push to %s/%s: %s", e.Org, e.Space, e.Err)
+}
@@ -4,44 +4,171 @@ package pusher
 import (
 	"fmt"
 	"io"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/compozed/deployadactyl/clock"
 	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/logger"
+	"github.com/compozed/deployadactyl/randomizer"
 	S "github.com/compozed/deployadactyl/structs"
 	"github.com/op/go-logging"
+	"golang.org/x/net/context"
+)
+
+// DefaultTimeout is used for each Cloud Foundry command when neither the deployment nor the Pusher has a Timeout.
+const DefaultTimeout = 5 * time.Minute
+
+// transientLoginFailure matches the output of a login that failed because Cloud Foundry or UAA had a server error.
+var transientLoginFailure = regexp.MustCompile(`(?i)\b50[234]\b|bad gateway|service unavailable|gateway time-?out`)
+
+// DefaultHealthCheckTimeout is how long a pushed application has to become healthy when the deployment does not say.
+// DefaultHealthCheckInterval is how often its health is checked when the Pusher has no HealthCheckInterval.
+const (
+	DefaultHealthCheckTimeout  = 2 * time.Minute
+	DefaultHealthCheckInterval = 2 * time.Second
 )
 
 // Pusher has a courier used to push applications to Cloud Foundry.
+// The TokenFetcher is used to get a token for environments that log in with client credentials.
+// Timeout limits how long a single login, push, rename or map-route command can run before it is killed.
+// The Timeout of the environment being deployed to takes precedence.
+// LoginTimeout limits how long logging in can take instead, so that an unreachable foundation fails fast. The Timeout is used when it is zero.
+// LoginRetries is the number of times a login that failed with a server error is retried, waiting LoginRetryDelay before the first retry.
+// Clock is used to wait between health checks and login retries and to name the versions kept of an application.
+// The time package is used when it is nil.
 type Pusher struct {
-	Courier   I.Courier
-	Log       *logging.Logger
-	appExists bool
+	Courier             I.Courier
+	TokenFetcher        I.TokenFetcher
+	Log                 *logging.Logger
+	Timeout             time.Duration
+	LoginTimeout        time.Duration
+	HealthCheckInterval time.Duration
+	LoginRetries        int
+	LoginRetryDelay     time.Duration
+	Clock               I.Clock
+	appExists           bool
+	venerableUnmapped   bool
+	liveAppName         string
+	appGUID             string
 }
 
 // Push pushes a single application to a Clound Foundry instance using blue green deployment.
 // Blue green is done by renaming the current application to appName-venerable.
 // Pushes the new application to the existing appName route with an included load balanced domain if provided.
+// The route is also mapped on every custom domain of the deployment, and the push fails when any of them cannot be mapped.
+// A deployment with NoRoute is renamed and cleaned up the same way but no route is mapped to it.
+// A deployment with a Hostname is pushed without the default route and has the route of that host mapped instead.
+// It fails before renaming the current application when the route is already mapped to another application.
+// When the deployment has a health check the route is only mapped once the new application is healthy.
+// A deployment with a DockerImage pushes the image with the manifest in appPath.
+//
+// When the deployment has an AppNamePrefix or AppNameSuffix the current application is the one the appName route
+// is mapped to, since it was pushed with the name of a previous version.
+//
+// The cf commands are killed when ctx is done.
 //
 // Returns Cloud Foundry logs if there is an error.
-func (p Pusher) Push(appPath string, deploymentInfo S.DeploymentInfo, response io.Writer) error {
+func (p *Pusher) Push(ctx context.Context, appPath string, deploymentInfo S.DeploymentInfo, response io.Writer) error {
+	log := logger.WithRequestID(p.Log, deploymentInfo.RequestID)
+
+	venerableName := deploymentInfo.AppName + "-venerable"
+
+	p.liveAppName = deploymentInfo.AppName
+	if deploymentInfo.PushedAppName() != deploymentInfo.AppName {
+		err := p.findLiveApp(ctx, deploymentInfo)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := p.checkRoutesAvailable(ctx, deploymentInfo)
+	if err != nil {
+		return err
+	}
+
 	if p.appExists {
-		_, err := p.Courier.Rename(deploymentInfo.AppName, deploymentInfo.AppName+"-venerable")
+		commandCtx, cancel := p.newContext(ctx, deploymentInfo)
+		renameOutput, err := p.Courier.Rename(commandCtx, p.liveAppName, venerableName)
+		cancel()
 		if err != nil {
+			fmt.Fprint(response, string(renameOutput))
 			return RenameFailError{err}
 		}
 
-		p.Log.Infof("renamed app from %s to %s", deploymentInfo.AppName, deploymentInfo.AppName+"-venerable")
+		log.Infof("renamed app from %s to %s", p.liveAppName, venerableName)
 	} else {
-		p.Log.Infof("new app detected")
+		log.Infof("new app detected")
+	}
+
+	return p.push(ctx, appPath, deploymentInfo, response, false)
+}
+
+// findLiveApp looks up the application the appName route is mapped to and keeps it as the current application.
+func (p *Pusher) findLiveApp(ctx context.Context, deploymentInfo S.DeploymentInfo) error {
+	appNames, err := p.Courier.RoutedApps(ctx, deploymentInfo.RouteHostname(), deploymentInfo.Domain)
+	if err != nil {
+		return ListRoutedAppsError{deploymentInfo.RouteHostname(), err}
+	}
+
+	p.appExists = false
+	for _, appName := range appNames {
+		if appName != deploymentInfo.AppName+"-venerable" {
+			p.liveAppName = appName
+			p.appExists = true
+			break
+		}
+	}
+
+	return nil
+}
+
+// PushInPlace pushes a single application to a Cloud Foundry instance with a rolling deployment.
+// The instances of the current application are replaced in place, so nothing is renamed and there is nothing to roll back.
+// The route is mapped the same as it is by Push.
+//
+// Returns Cloud Foundry logs if there is an error.
+func (p *Pusher) PushInPlace(ctx context.Context, appPath string, deploymentInfo S.DeploymentInfo, response io.Writer) error {
+	err := p.checkRoutesAvailable(ctx, deploymentInfo)
+	if err != nil {
+		return err
 	}
 
-	p.Log.Debugf("pushing app %s to %s", deploymentInfo.AppName, deploymentInfo.Domain)
-	p.Log.Debugf("tempdir for app %s: %s", deploymentInfo.AppName, appPath)
+	return p.push(ctx, appPath, deploymentInfo, response, true)
+}
+
+// push pushes the application, waits for it to become healthy and maps the route to it.
+// The rolling strategy of cf push is used when rolling is set.
+// The output of cf push is written to the response while the application is staged and started.
+func (p *Pusher) push(ctx context.Context, appPath string, deploymentInfo S.DeploymentInfo, response io.Writer, rolling bool) error {
+	log := logger.WithRequestID(p.Log, deploymentInfo.RequestID)
+
+	appName := deploymentInfo.PushedAppName()
+
+	log.Debugf("pushing app %s to %s", appName, deploymentInfo.Domain)
+	log.Debugf("tempdir for app %s: %s", appName, appPath)
 
-	pushOutput, err := p.Courier.Push(deploymentInfo.AppName, appPath, deploymentInfo.Instances)
-	fmt.Fprint(response, string(pushOutput))
+	// The route of an overridden Hostname is mapped once the application is pushed, so that cf push does not map the app name.
+	noRoute := deploymentInfo.NoRoute || deploymentInfo.Hostname != ""
+
+	commandCtx, cancel := p.newContext(ctx, deploymentInfo)
+	var (
+		pushOutput []byte
+		err        error
+	)
+	if rolling {
+		pushOutput, err = p.Courier.PushRolling(commandCtx, appName, appPath, deploymentInfo.DockerImage, deploymentInfo.Instances, deploymentInfo.HealthCheckPath, deploymentInfo.StartupTimeout, noRoute, response)
+	} else if deploymentInfo.DockerImage != "" {
+		pushOutput, err = p.Courier.PushDocker(commandCtx, appName, appPath, deploymentInfo.DockerImage, deploymentInfo.Instances, deploymentInfo.HealthCheckPath, deploymentInfo.StartupTimeout, noRoute, response)
+	} else {
+		pushOutput, err = p.Courier.Push(commandCtx, appName, appPath, deploymentInfo.Instances, deploymentInfo.HealthCheckPath, deploymentInfo.StartupTimeout, noRoute, response)
+	}
+	cancel()
 	if err != nil {
-		logs, newErr := p.Courier.Logs(deploymentInfo.AppName)
+		logs, newErr := p.Courier.Logs(ctx, appName)
 		fmt.Fprintf(response, "\n%s", string(logs))
 		if newErr != nil {
 			return CloudFoundryGetLogsError{err, newErr}
@@ -49,59 +176,341 @@ func (p Pusher) Push(appPath string, deploymentInfo S.DeploymentInfo, response i
 		return err
 	}
 
-	p.Log.Infof(fmt.Sprintf("output from Cloud Foundry:\n%s\n%s\n%s", strings.Repeat("-", 60), string(pushOutput), strings.Repeat("-", 60)))
-	p.Log.Debugf("mapping route for %s to %s", deploymentInfo.AppName, deploymentInfo.Domain)
+	log.Infof(fmt.Sprintf("output from Cloud Foundry:\n%s\n%s\n%s", strings.Repeat("-", 60), string(pushOutput), strings.Repeat("-", 60)))
+
+	if deploymentInfo.HealthCheckPath != "" || deploymentInfo.HealthCheckTimeout != "" {
+		err = p.waitUntilHealthy(ctx, deploymentInfo)
+		if err != nil {
+			logs, newErr := p.Courier.Logs(ctx, appName)
+			fmt.Fprintf(response, "\n%s", string(logs))
+			if newErr != nil {
+				return CloudFoundryGetLogsError{err, newErr}
+			}
+			return err
+		}
+	}
+
+	if deploymentInfo.NoRoute {
+		log.Infof("not mapping a route to %s because it has no route", appName)
+	} else {
+		for _, domain := range append([]string{deploymentInfo.Domain}, deploymentInfo.CustomDomains...) {
+			err = p.mapRoute(ctx, appName, domain, deploymentInfo, response)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	guidOutput, err := p.Courier.AppGUID(ctx, appName)
+	if err != nil {
+		log.Warningf("unable to get the guid of %s: %s", appName, err)
+		p.appGUID = ""
+	} else {
+		p.appGUID = strings.TrimSpace(string(guidOutput))
+		log.Infof("application %s has guid %s", appName, p.appGUID)
+	}
 
-	mapRouteOutput, err := p.Courier.MapRoute(deploymentInfo.AppName, deploymentInfo.Domain)
+	return nil
+}
+
+// waitUntilHealthy checks the health of the pushed application every HealthCheckInterval
+// until it is healthy, the HealthCheckTimeout of the deployment has passed or ctx is done.
+func (p Pusher) waitUntilHealthy(ctx context.Context, deploymentInfo S.DeploymentInfo) error {
+	log := logger.WithRequestID(p.Log, deploymentInfo.RequestID)
+
+	appName := deploymentInfo.PushedAppName()
+
+	timeout, err := time.ParseDuration(deploymentInfo.HealthCheckTimeout)
+	if err != nil || timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+
+	interval := p.HealthCheckInterval
+	if interval <= 0 {
+		interval = DefaultHealthCheckInterval
+	}
+
+	healthCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	log.Debugf("waiting up to %s for %s to become healthy", timeout, appName)
+
+	for {
+		healthy, err := p.Courier.Healthy(healthCtx, appName)
+		if err != nil {
+			log.Debugf("unable to check the health of %s: %s", appName, err)
+		}
+		if healthy {
+			log.Infof("application %s is healthy", appName)
+			return nil
+		}
+
+		select {
+		case <-healthCtx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return UnhealthyAppError{appName, timeout}
+		case <-p.clock().After(interval):
+		}
+	}
+}
+
+// checkRoutesAvailable makes sure the route of an overridden Hostname is available on every domain of the deployment
+// before anything is renamed or pushed.
+func (p *Pusher) checkRoutesAvailable(ctx context.Context, deploymentInfo S.DeploymentInfo) error {
+	if deploymentInfo.Hostname == "" || deploymentInfo.NoRoute {
+		return nil
+	}
+
+	for _, domain := range append([]string{deploymentInfo.Domain}, deploymentInfo.CustomDomains...) {
+		err := p.checkRouteAvailable(ctx, domain, deploymentInfo)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkRouteAvailable makes sure the route of the overridden Hostname on domain is not mapped to an application
+// other than the one being deployed, its venerable application or the current application.
+func (p *Pusher) checkRouteAvailable(ctx context.Context, domain string, deploymentInfo S.DeploymentInfo) error {
+	hostname := deploymentInfo.RouteHostname()
+
+	routedApps, err := p.Courier.RoutedApps(ctx, hostname, domain)
+	if err != nil {
+		return ListRoutedAppsError{hostname, err}
+	}
+
+	for _, routedApp := range routedApps {
+		switch routedApp {
+		case deploymentInfo.AppName, deploymentInfo.AppName + "-venerable", deploymentInfo.PushedAppName(), p.liveAppName:
+		default:
+			return RouteTakenError{hostname, domain, routedApp}
+		}
+	}
+
+	return nil
+}
+
+// mapRoute maps the route of the application on domain to the pushed appName.
+func (p *Pusher) mapRoute(ctx context.Context, appName, domain string, deploymentInfo S.DeploymentInfo, response io.Writer) error {
+	log := logger.WithRequestID(p.Log, deploymentInfo.RequestID)
+
+	hostname := deploymentInfo.RouteHostname()
+
+	log.Debugf("mapping route %s.%s to %s", hostname, domain, appName)
+
+	commandCtx, cancel := p.newContext(ctx, deploymentInfo)
+	mapRouteOutput, err := p.Courier.MapRoute(commandCtx, appName, hostname, domain)
+	cancel()
 	fmt.Fprint(response, string(mapRouteOutput))
 	if err != nil {
-		logs, newErr := p.Courier.Logs(deploymentInfo.AppName)
+		logs, newErr := p.Courier.Logs(ctx, appName)
 		fmt.Fprintf(response, "\n%s", string(logs))
 		if newErr != nil {
 			return CloudFoundryGetLogsError{err, newErr}
 		}
 		return err
 	}
-	p.Log.Debugf(string(mapRouteOutput))
-	p.Log.Infof("application route created at %s.%s", deploymentInfo.AppName, deploymentInfo.Domain)
+	log.Debugf(string(mapRouteOutput))
+	log.Infof("application route created at %s.%s", hostname, domain)
+
+	return nil
+}
+
+// UnmapVenerable unmaps the route of the application from appName-venerable on every domain of the deployment,
+// so that the route only reaches the pushed application while it is smoke tested.
+// There is nothing to unmap on the first deploy or when the deployment has NoRoute.
+// The route is mapped back to the venerable application when the deploy is rolled back.
+func (p *Pusher) UnmapVenerable(ctx context.Context, deploymentInfo S.DeploymentInfo) error {
+	log := logger.WithRequestID(p.Log, deploymentInfo.RequestID)
+
+	if !p.appExists || deploymentInfo.NoRoute {
+		return nil
+	}
+
+	venerableName := deploymentInfo.AppName + "-venerable"
+	hostname := deploymentInfo.RouteHostname()
+
+	p.venerableUnmapped = true
+	for _, domain := range append([]string{deploymentInfo.Domain}, deploymentInfo.CustomDomains...) {
+		commandCtx, cancel := p.newContext(ctx, deploymentInfo)
+		_, err := p.Courier.UnmapRoute(commandCtx, venerableName, hostname, domain)
+		cancel()
+		if err != nil {
+			return UnmapVenerableError{hostname, domain, err}
+		}
+		log.Infof("unmapped route %s.%s from %s", hostname, domain, venerableName)
+	}
+
+	return nil
+}
+
+// CanPush pushes the probe in probePath as appName-preflight with a random suffix without starting it and deletes it again.
+// The suffix keeps concurrent deploys of the same application from pushing the same probe.
+// It checks that the logged in user is allowed to push to the space before the deploy starts.
+func (p Pusher) CanPush(ctx context.Context, probePath string, deploymentInfo S.DeploymentInfo, response io.Writer) error {
+	log := logger.WithRequestID(p.Log, deploymentInfo.RequestID)
+
+	probeName := deploymentInfo.AppName + "-preflight-" + strings.ToLower(randomizer.StringRunes(8))
+
+	log.Debugf("pushing preflight probe %s to %s/%s", probeName, deploymentInfo.Org, deploymentInfo.Space)
+
+	output, err := p.Courier.CanPush(ctx, probeName, probePath)
+	fmt.Fprint(response, string(output))
+	if err != nil {
+		return PushPermissionError{deploymentInfo.Org, deploymentInfo.Space, err}
+	}
+
+	log.Infof("preflight probe %s was pushed and deleted", probeName)
 
 	return nil
 }
 
 // DeleteVenerable will delete the venerable instance of your application.
-func (p Pusher) DeleteVenerable(deploymentInfo S.DeploymentInfo) error {
+// When the deployment has a Retention, the venerable instance is stopped and kept as appName-venerable-<unix time> instead
+// and only the previous versions that are not among the Retention most recent ones are deleted.
+// The cf commands are killed when ctx is done.
+func (p Pusher) DeleteVenerable(ctx context.Context, deploymentInfo S.DeploymentInfo) error {
+	if deploymentInfo.Retention > 0 {
+		return p.retainVenerable(ctx, deploymentInfo)
+	}
+
+	log := logger.WithRequestID(p.Log, deploymentInfo.RequestID)
+
 	venerableName := deploymentInfo.AppName + "-venerable"
 
-	_, err := p.Courier.Delete(deploymentInfo.AppName + "-venerable")
+	_, err := p.Courier.Delete(ctx, deploymentInfo.AppName+"-venerable")
 	if err != nil {
 		return DeleteVenerableError{venerableName, err}
 	}
 
-	p.Log.Infof("deleted %s", venerableName)
+	log.Infof("deleted %s", venerableName)
+
+	return nil
+}
+
+func (p Pusher) retainVenerable(ctx context.Context, deploymentInfo S.DeploymentInfo) error {
+	log := logger.WithRequestID(p.Log, deploymentInfo.RequestID)
+
+	venerableName := deploymentInfo.AppName + "-venerable"
+
+	if p.appExists {
+		_, err := p.Courier.Stop(ctx, venerableName)
+		if err != nil {
+			return RetainVenerableError{venerableName, err}
+		}
+
+		versionName := fmt.Sprintf("%s-%d", venerableName, p.clock().Now().Unix())
+
+		commandCtx, cancel := p.newContext(ctx, deploymentInfo)
+		_, err = p.Courier.Rename(commandCtx, venerableName, versionName)
+		cancel()
+		if err != nil {
+			return RetainVenerableError{venerableName, err}
+		}
+
+		log.Infof("stopped %s and kept it as %s", venerableName, versionName)
+	}
+
+	versions, err := p.previousVersions(ctx, venerableName)
+	if err != nil {
+		return ListVersionsError{deploymentInfo.AppName, err}
+	}
+
+	for i := deploymentInfo.Retention; i < len(versions); i++ {
+		_, err = p.Courier.Delete(ctx, versions[i].name)
+		if err != nil {
+			return DeleteVenerableError{versions[i].name, err}
+		}
+
+		log.Infof("deleted %s", versions[i].name)
+	}
 
 	return nil
 }
 
+// previousVersions returns the versions of an application kept by retainVenerable, the most recent first.
+func (p Pusher) previousVersions(ctx context.Context, venerableName string) ([]version, error) {
+	appNames, err := p.Courier.List(ctx, venerableName+"-")
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []version
+	for _, appName := range appNames {
+		keptAt, err := strconv.ParseInt(strings.TrimPrefix(appName, venerableName+"-"), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		versions = append(versions, version{appName, keptAt})
+	}
+	sort.Sort(byMostRecent(versions))
+
+	return versions, nil
+}
+
+// version is a previous version of an application and the unix time it was kept at.
+type version struct {
+	name   string
+	keptAt int64
+}
+
+type byMostRecent []version
+
+func (v byMostRecent) Len() int           { return len(v) }
+func (v byMostRecent) Less(i, j int) bool { return v[i].keptAt > v[j].keptAt }
+func (v byMostRecent) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
+
 // Rollback will rollback Push.
+// Maps the route back to appName-venerable when it was unmapped for the smoke test.
 // Deletes the new application.
-// Renames appName-venerable back to appName if this is not the first deploy.
-func (p Pusher) Rollback(deploymentInfo S.DeploymentInfo) error {
-	p.Log.Errorf("rolling back deploy of %s", deploymentInfo.AppName)
+// Renames appName-venerable back to the name of the current application if this is not the first deploy.
+// The cf commands are killed when ctx is done, so a rollback of a cancelled deploy is left unfinished.
+func (p Pusher) Rollback(ctx context.Context, deploymentInfo S.DeploymentInfo) error {
+	log := logger.WithRequestID(p.Log, deploymentInfo.RequestID)
+
+	appName := deploymentInfo.PushedAppName()
+	liveAppName := p.liveAppName
+	if liveAppName == "" {
+		liveAppName = deploymentInfo.AppName
+	}
+
+	log.Errorf("rolling back deploy of %s", appName)
 	venerableName := deploymentInfo.AppName + "-venerable"
 
-	_, err := p.Courier.Delete(deploymentInfo.AppName)
+	if p.appExists && p.venerableUnmapped {
+		hostname := deploymentInfo.RouteHostname()
+		for _, domain := range append([]string{deploymentInfo.Domain}, deploymentInfo.CustomDomains...) {
+			commandCtx, cancel := p.newContext(ctx, deploymentInfo)
+			_, err := p.Courier.MapRoute(commandCtx, venerableName, hostname, domain)
+			cancel()
+			if err != nil {
+				log.Infof("unable to map route %s.%s back to %s: %s", hostname, domain, venerableName, err)
+			} else {
+				log.Infof("mapped route %s.%s back to %s", hostname, domain, venerableName)
+			}
+		}
+	}
+
+	_, err := p.Courier.Delete(ctx, appName)
 	if err != nil {
-		p.Log.Infof("unable to delete %s: %s", deploymentInfo.AppName, err)
+		log.Infof("unable to delete %s: %s", appName, err)
 	} else {
-		p.Log.Infof("deleted %s", deploymentInfo.AppName)
+		log.Infof("deleted %s", appName)
 	}
 
 	if p.appExists {
-		_, err = p.Courier.Rename(venerableName, deploymentInfo.AppName)
+		commandCtx, cancel := p.newContext(ctx, deploymentInfo)
+		_, err = p.Courier.Rename(commandCtx, venerableName, liveAppName)
+		cancel()
 		if err != nil {
-			p.Log.Infof("unable to rename venerable app %s: %s", venerableName, err)
+			log.Infof("unable to rename venerable app %s: %s", venerableName, err)
 		} else {
-			p.Log.Infof("renamed app from %s to %s", venerableName, deploymentInfo.AppName)
+			log.Infof("renamed app from %s to %s", venerableName, liveAppName)
 		}
 	}
 
@@ -109,8 +518,17 @@ func (p Pusher) Rollback(deploymentInfo S.DeploymentInfo) error {
 }
 
 // Login will login to a Cloud Foundry instance.
-func (p Pusher) Login(foundationURL string, deploymentInfo S.DeploymentInfo, response io.Writer) error {
-	p.Log.Debugf(
+// If the deployment has a token URL the cf CLI authenticates with a token fetched with the client credentials
+// instead of the username and password.
+// The login is killed when ctx is done.
+func (p Pusher) Login(ctx context.Context, foundationURL string, deploymentInfo S.DeploymentInfo, response io.Writer) error {
+	if deploymentInfo.TokenURL != "" {
+		return p.auth(ctx, foundationURL, deploymentInfo, response)
+	}
+
+	log := logger.WithRequestID(p.Log, deploymentInfo.RequestID)
+
+	log.Debugf(
 		`logging into cloud foundry with parameters:
 		foundation URL: %+v
 		username: %+v
@@ -119,29 +537,155 @@ func (p Pusher) Login(foundationURL string, deploymentInfo S.DeploymentInfo, res
 		foundationURL, deploymentInfo.Username, deploymentInfo.Org, deploymentInfo.Space,
 	)
 
-	loginOutput, err := p.Courier.Login(
-		foundationURL,
-		deploymentInfo.Username,
-		deploymentInfo.Password,
-		deploymentInfo.Org,
-		deploymentInfo.Space,
-		deploymentInfo.SkipSSL,
+	err := p.retryLogin(ctx, foundationURL, deploymentInfo, response, func(ctx context.Context) ([]byte, error) {
+		return p.Courier.Login(
+			ctx,
+			foundationURL,
+			deploymentInfo.Username,
+			deploymentInfo.Password,
+			deploymentInfo.Org,
+			deploymentInfo.Space,
+			deploymentInfo.SkipSSL,
+		)
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("logged into cloud foundry %s", foundationURL)
+
+	return nil
+}
+
+// auth authenticates with the token the TokenFetcher has for the client credentials of the deployment,
+// so a cached token saves a round trip to the token endpoint.
+func (p Pusher) auth(ctx context.Context, foundationURL string, deploymentInfo S.DeploymentInfo, response io.Writer) error {
+	log := logger.WithRequestID(p.Log, deploymentInfo.RequestID)
+
+	log.Debugf(
+		`authenticating with cloud foundry with parameters:
+		foundation URL: %+v
+		token URL: %+v
+		client id: %+v
+		org: %+v
+		space: %+v`,
+		foundationURL, deploymentInfo.TokenURL, deploymentInfo.ClientID, deploymentInfo.Org, deploymentInfo.Space,
 	)
-	response.Write(loginOutput)
+
+	token, err := p.TokenFetcher.Token(deploymentInfo.TokenURL, deploymentInfo.ClientID, deploymentInfo.ClientSecret)
 	if err != nil {
 		return LoginError{foundationURL, err}
 	}
-	p.Log.Infof("logged into cloud foundry %s", foundationURL)
+
+	err = p.retryLogin(ctx, foundationURL, deploymentInfo, response, func(ctx context.Context) ([]byte, error) {
+		return p.Courier.Auth(
+			ctx,
+			foundationURL,
+			token,
+			deploymentInfo.Org,
+			deploymentInfo.Space,
+			deploymentInfo.SkipSSL,
+		)
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("authenticated with cloud foundry %s as %s", foundationURL, deploymentInfo.ClientID)
 
 	return nil
 }
 
+// retryLogin runs login and writes its output to the response.
+// A login that fails with a server error, such as a 502 from UAA, is retried up to LoginRetries times,
+// waiting LoginRetryDelay before the first retry and doubling the wait before each one after that.
+// A login that does not finish within the login timeout is returned as a LoginTimeoutError without retrying.
+// Any other failure, such as rejected credentials, or a login killed because ctx is done is returned as a LoginError without retrying.
+func (p Pusher) retryLogin(ctx context.Context, foundationURL string, deploymentInfo S.DeploymentInfo, response io.Writer, login func(ctx context.Context) ([]byte, error)) error {
+	log := logger.WithRequestID(p.Log, deploymentInfo.RequestID)
+	delay := p.LoginRetryDelay
+
+	for attempt := 1; ; attempt++ {
+		timeout := p.loginTimeout(deploymentInfo)
+		loginCtx, cancel := context.WithTimeout(ctx, timeout)
+		output, err := login(loginCtx)
+		timedOut := loginCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+		cancel()
+		response.Write(output)
+		if err == nil {
+			return nil
+		}
+
+		if timedOut {
+			return LoginTimeoutError{foundationURL, timeout}
+		}
+
+		if ctx.Err() != nil {
+			return LoginError{foundationURL, err}
+		}
+
+		if !transientLoginFailure.MatchString(string(output) + err.Error()) {
+			return LoginError{foundationURL, err}
+		}
+
+		if attempt > p.LoginRetries {
+			return LoginUnavailableError{foundationURL, err}
+		}
+
+		log.Debugf("retrying login %d of %d to %s in %s: %s", attempt, p.LoginRetries, foundationURL, delay, err)
+		p.clock().Sleep(delay)
+		delay *= 2
+	}
+}
+
+// newContext returns a context that is done once parent is done or the Timeout of the deployment has passed.
+// The Timeout of the Pusher is used when the deployment has none.
+func (p Pusher) newContext(parent context.Context, deploymentInfo S.DeploymentInfo) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, p.timeout(deploymentInfo))
+}
+
+func (p Pusher) timeout(deploymentInfo S.DeploymentInfo) time.Duration {
+	timeout := deploymentInfo.Timeout
+	if timeout <= 0 {
+		timeout = p.Timeout
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return timeout
+}
+
+// loginTimeout returns the LoginTimeout of the deployment, or of the Pusher when the deployment has none.
+// The command timeout is used when neither has a LoginTimeout.
+func (p Pusher) loginTimeout(deploymentInfo S.DeploymentInfo) time.Duration {
+	if deploymentInfo.LoginTimeout > 0 {
+		return deploymentInfo.LoginTimeout
+	}
+	if p.LoginTimeout > 0 {
+		return p.LoginTimeout
+	}
+
+	return p.timeout(deploymentInfo)
+}
+
+func (p Pusher) clock() I.Clock {
+	if p.Clock == nil {
+		return clock.Clock{}
+	}
+	return p.Clock
+}
+
 // CleanUp removes the temporary directory created by the Executor.
 func (p Pusher) CleanUp() error {
 	return p.Courier.CleanUp()
 }
 
+// AppGUID returns the guid of the application captured after a successful Push.
+// It is empty if the guid could not be retrieved.
+func (p *Pusher) AppGUID() string {
+	return p.appGUID
+}
+
 // Exists uses the courier to check if the application exists.
-func (p *Pusher) Exists(appName string) {
-	p.appExists = p.Courier.Exists(appName)
+func (p *Pusher) Exists(ctx context.Context, appName string) {
+	p.appExists = p.Courier.Exists(ctx, appName)
 }
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"strings"
+	"time"
 
 	. "github.com/compozed/deployadactyl/controller/deployer/bluegreen/pusher"
 	"github.com/compozed/deployadactyl/logger"
@@ -15,12 +17,15 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
+	"golang.org/x/net/context"
 )
 
 var _ = Describe("Pusher", func() {
 	var (
-		courier *mocks.Courier
-		pusher  Pusher
+		courier      *mocks.Courier
+		tokenFetcher *mocks.TokenFetcher
+		pusher       Pusher
+		ctx          context.Context
 
 		foundationURL    string
 		username         string
@@ -40,6 +45,7 @@ var _ = Describe("Pusher", func() {
 
 	BeforeEach(func() {
 		courier = &mocks.Courier{}
+		tokenFetcher = &mocks.TokenFetcher{}
 
 		foundationURL = "foundationURL-" + randomizer.StringRunes(10)
 		username = "username-" + randomizer.StringRunes(10)
@@ -54,10 +60,12 @@ var _ = Describe("Pusher", func() {
 
 		response = gbytes.NewBuffer()
 		logBuffer = gbytes.NewBuffer()
+		ctx = context.Background()
 
 		pusher = Pusher{
-			Courier: courier,
-			Log:     logger.DefaultLogger(logBuffer, logging.DEBUG, "extractor_test"),
+			Courier:      courier,
+			TokenFetcher: tokenFetcher,
+			Log:          logger.DefaultLogger(logBuffer, logging.DEBUG, "extractor_test", logger.TextFormat),
 		}
 
 		deploymentInfo = S.DeploymentInfo{
@@ -76,7 +84,7 @@ var _ = Describe("Pusher", func() {
 		Context("when login succeeds", func() {
 			It("gives the correct info to the courier", func() {
 
-				Expect(pusher.Login(foundationURL, deploymentInfo, response)).To(Succeed())
+				Expect(pusher.Login(ctx, foundationURL, deploymentInfo, response)).To(Succeed())
 
 				Expect(courier.LoginCall.Received.FoundationURL).To(Equal(foundationURL))
 				Expect(courier.LoginCall.Received.Username).To(Equal(username))
@@ -89,7 +97,7 @@ var _ = Describe("Pusher", func() {
 			It("writes the output of the courier to the response", func() {
 				courier.LoginCall.Returns.Output = []byte("login succeeded")
 
-				Expect(pusher.Login(foundationURL, deploymentInfo, response)).To(Succeed())
+				Expect(pusher.Login(ctx, foundationURL, deploymentInfo, response)).To(Succeed())
 
 				Eventually(response).Should(gbytes.Say("login succeeded"))
 			})
@@ -100,12 +108,99 @@ var _ = Describe("Pusher", func() {
 				courier.LoginCall.Returns.Output = []byte("login failed")
 				courier.LoginCall.Returns.Error = errors.New("bork")
 
-				err := pusher.Login(foundationURL, deploymentInfo, response)
+				err := pusher.Login(ctx, foundationURL, deploymentInfo, response)
 				Expect(err).To(MatchError(LoginError{foundationURL, errors.New("bork")}))
 
 				Eventually(response).Should(gbytes.Say("login failed"))
 			})
 		})
+
+		Context("when login fails with a server error", func() {
+			var clock *mocks.Clock
+
+			BeforeEach(func() {
+				clock = &mocks.Clock{}
+
+				pusher.LoginRetries = 2
+				pusher.LoginRetryDelay = time.Second
+				pusher.Clock = clock
+
+				courier.LoginCall.Returns.Output = []byte("Server error, status code: 502, error code: 0, message: Bad Gateway")
+				courier.LoginCall.Returns.Error = errors.New("exit status 1")
+			})
+
+			It("retries the login and returns a temporary error once the retries are used up", func() {
+				err := pusher.Login(ctx, foundationURL, deploymentInfo, response)
+				Expect(err).To(MatchError(LoginUnavailableError{foundationURL, errors.New("exit status 1")}))
+				Expect(err.(LoginUnavailableError).Temporary()).To(BeTrue())
+
+				Expect(courier.LoginCall.TimesCalled).To(Equal(3))
+				Eventually(logBuffer).Should(gbytes.Say(fmt.Sprintf("retrying login 1 of 2 to %s", foundationURL)))
+			})
+
+			It("doubles the wait before each retry", func() {
+				pusher.Login(ctx, foundationURL, deploymentInfo, response)
+
+				Expect(clock.SleepCall.Received.Durations).To(Equal([]time.Duration{time.Second, 2 * time.Second}))
+			})
+
+			It("does not retry when the credentials are rejected", func() {
+				courier.LoginCall.Returns.Output = []byte("Credentials were rejected, please try again.")
+
+				err := pusher.Login(ctx, foundationURL, deploymentInfo, response)
+				Expect(err).To(MatchError(LoginError{foundationURL, errors.New("exit status 1")}))
+
+				Expect(courier.LoginCall.TimesCalled).To(Equal(1))
+			})
+
+			It("does not retry when the deploy was cancelled", func() {
+				cancelledCtx, cancel := context.WithCancel(ctx)
+				cancel()
+
+				err := pusher.Login(cancelledCtx, foundationURL, deploymentInfo, response)
+				Expect(err).To(MatchError(LoginError{foundationURL, errors.New("exit status 1")}))
+
+				Expect(courier.LoginCall.TimesCalled).To(Equal(1))
+			})
+		})
+
+		Context("when the deployment has a token URL", func() {
+			BeforeEach(func() {
+				deploymentInfo.TokenURL = "tokenURL-" + randomizer.StringRunes(10)
+				deploymentInfo.ClientID = "clientID-" + randomizer.StringRunes(10)
+				deploymentInfo.ClientSecret = "clientSecret-" + randomizer.StringRunes(10)
+			})
+
+			It("fetches a token and authenticates with it instead of logging in", func() {
+				courier.AuthCall.Returns.Output = []byte("auth succeeded")
+				tokenFetcher.TokenCall.Returns.Token = "token-" + randomizer.StringRunes(10)
+
+				Expect(pusher.Login(ctx, foundationURL, deploymentInfo, response)).To(Succeed())
+
+				Expect(tokenFetcher.TokenCall.Received.TokenURL).To(Equal(deploymentInfo.TokenURL))
+				Expect(tokenFetcher.TokenCall.Received.ClientID).To(Equal(deploymentInfo.ClientID))
+				Expect(tokenFetcher.TokenCall.Received.ClientSecret).To(Equal(deploymentInfo.ClientSecret))
+
+				Expect(courier.AuthCall.Received.FoundationURL).To(Equal(foundationURL))
+				Expect(courier.AuthCall.Received.Token).To(Equal(tokenFetcher.TokenCall.Returns.Token))
+				Expect(courier.AuthCall.Received.Org).To(Equal(org))
+				Expect(courier.AuthCall.Received.Space).To(Equal(space))
+				Expect(courier.LoginCall.Received.FoundationURL).To(BeEmpty())
+
+				Eventually(response).Should(gbytes.Say("auth succeeded"))
+			})
+
+			Context("when the token cannot be fetched", func() {
+				It("returns an error without authenticating", func() {
+					tokenFetcher.TokenCall.Returns.Error = errors.New("bork")
+
+					err := pusher.Login(ctx, foundationURL, deploymentInfo, response)
+					Expect(err).To(MatchError(LoginError{foundationURL, errors.New("bork")}))
+
+					Expect(courier.AuthCall.Received.FoundationURL).To(BeEmpty())
+				})
+			})
+		})
 	})
 
 	Describe("pushing an app", func() {
@@ -114,9 +209,9 @@ var _ = Describe("Pusher", func() {
 				courier.ExistsCall.Returns.Bool = true
 				courier.RenameCall.Returns.Error = nil
 
-				pusher.Exists(appName)
+				pusher.Exists(ctx, appName)
 
-				Expect(pusher.Push(appPath, deploymentInfo, response)).To(Succeed())
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
 
 				Expect(courier.RenameCall.Received.AppName).To(Equal(appName))
 				Expect(courier.RenameCall.Received.AppNameVenerable).To(Equal(appNameVenerable))
@@ -129,9 +224,9 @@ var _ = Describe("Pusher", func() {
 					courier.ExistsCall.Returns.Bool = true
 					courier.RenameCall.Returns.Error = errors.New("bork")
 
-					pusher.Exists(appName)
+					pusher.Exists(ctx, appName)
 
-					err := pusher.Push(appPath, deploymentInfo, response)
+					err := pusher.Push(ctx, appPath, deploymentInfo, response)
 					Expect(err).To(MatchError(RenameFailError{errors.New("bork")}))
 
 					Expect(courier.RenameCall.Received.AppName).To(Equal(appName))
@@ -142,7 +237,7 @@ var _ = Describe("Pusher", func() {
 
 		Context("when no app with the same name exists", func() {
 			It("reports that the app is new", func() {
-				Expect(pusher.Push(appPath, deploymentInfo, response)).To(Succeed())
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
 
 				Eventually(logBuffer).Should(gbytes.Say("new app detected"))
 			})
@@ -151,7 +246,7 @@ var _ = Describe("Pusher", func() {
 		It("pushes the new app", func() {
 			courier.PushCall.Returns.Output = []byte("push succeeded")
 
-			Expect(pusher.Push(appPath, deploymentInfo, response)).To(Succeed())
+			Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
 
 			Expect(courier.PushCall.Received.AppName).To(Equal(appName))
 			Expect(courier.PushCall.Received.AppPath).To(Equal(appPath))
@@ -164,35 +259,632 @@ var _ = Describe("Pusher", func() {
 			Eventually(logBuffer).Should(gbytes.Say(fmt.Sprintf("push succeeded")))
 		})
 
+		It("streams the output of the push to the response only once", func() {
+			courier.PushCall.Returns.Output = []byte("push succeeded")
+
+			Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+			Expect(courier.PushCall.Received.Out).To(Equal(response))
+			Expect(strings.Count(string(response.Contents()), "push succeeded")).To(Equal(1))
+		})
+
+		Context("when the deployment has a docker image", func() {
+			It("pushes the docker image instead of the app path", func() {
+				deploymentInfo.DockerImage = "dockerImage-" + randomizer.StringRunes(10)
+				courier.PushDockerCall.Returns.Output = []byte("push succeeded")
+
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+				Expect(courier.PushDockerCall.Received.AppName).To(Equal(appName))
+				Expect(courier.PushDockerCall.Received.AppPath).To(Equal(appPath))
+				Expect(courier.PushDockerCall.Received.DockerImage).To(Equal(deploymentInfo.DockerImage))
+				Expect(courier.PushDockerCall.Received.Instances).To(Equal(instances))
+				Expect(courier.PushCall.Received.AppName).To(BeEmpty())
+
+				Eventually(response).Should(gbytes.Say("push succeeded"))
+			})
+		})
+
 		It("maps the route to the app", func() {
 			courier.MapRouteCall.Returns.Output = []byte("mapped route")
 			courier.MapRouteCall.Returns.Error = nil
 
-			Expect(pusher.Push(appPath, deploymentInfo, response)).To(Succeed())
+			Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
 
+			Expect(courier.PushCall.Received.NoRoute).To(BeFalse())
 			Expect(courier.MapRouteCall.Received.AppName).To(Equal(appName))
+			Expect(courier.MapRouteCall.Received.Hostname).To(Equal(appName))
 			Expect(courier.MapRouteCall.Received.Domain).To(Equal(domain))
 
 			Eventually(response).Should(gbytes.Say("mapped route"))
 
-			Eventually(logBuffer).Should(gbytes.Say(fmt.Sprintf("mapping route for %s to %s", appName, domain)))
+			Eventually(logBuffer).Should(gbytes.Say(fmt.Sprintf("mapping route %s.%s to %s", appName, domain, appName)))
+		})
+
+		It("does not look up the apps the route is mapped to", func() {
+			Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+			Expect(courier.RoutedAppsCall.Received.Hostname).To(BeEmpty())
+		})
+
+		Context("when the deployment has a hostname", func() {
+			var hostname string
+
+			BeforeEach(func() {
+				hostname = "hostname-" + randomizer.StringRunes(10)
+				deploymentInfo.Hostname = hostname
+			})
+
+			It("pushes the app without the default route of the app name", func() {
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+				Expect(courier.PushCall.Received.AppName).To(Equal(appName))
+				Expect(courier.PushCall.Received.NoRoute).To(BeTrue())
+			})
+
+			It("maps the route of the hostname to the app", func() {
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+				Expect(courier.MapRouteCall.Received.AppName).To(Equal(appName))
+				Expect(courier.MapRouteCall.Received.Hostname).To(Equal(hostname))
+				Expect(courier.MapRouteCall.Received.Domain).To(Equal(domain))
+
+				Eventually(logBuffer).Should(gbytes.Say(fmt.Sprintf("mapping route %s.%s to %s", hostname, domain, appName)))
+			})
+
+			It("checks that the route of the hostname is available", func() {
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+				Expect(courier.RoutedAppsCall.Received.Hostname).To(Equal(hostname))
+				Expect(courier.RoutedAppsCall.Received.Domain).To(Equal(domain))
+			})
+
+			It("maps the route when it is already mapped to the app", func() {
+				courier.RoutedAppsCall.Returns.AppNames = []string{appName}
+
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+				Expect(courier.MapRouteCall.Received.Hostname).To(Equal(hostname))
+			})
+
+			Context("when the route is mapped to another app", func() {
+				It("returns an error without pushing", func() {
+					courier.RoutedAppsCall.Returns.AppNames = []string{"other-app"}
+
+					err := pusher.Push(ctx, appPath, deploymentInfo, response)
+					Expect(err).To(MatchError(RouteTakenError{hostname, domain, "other-app"}))
+
+					Expect(courier.PushCall.Received.AppName).To(BeEmpty())
+					Expect(courier.MapRouteCall.Received.AppName).To(BeEmpty())
+				})
+
+				It("does not rename the current app", func() {
+					courier.ExistsCall.Returns.Bool = true
+					courier.RoutedAppsCall.Returns.AppNames = []string{"other-app"}
+
+					pusher.Exists(ctx, appName)
+					err := pusher.Push(ctx, appPath, deploymentInfo, response)
+					Expect(err).To(MatchError(RouteTakenError{hostname, domain, "other-app"}))
+
+					Expect(courier.RenameCall.Received.AppName).To(BeEmpty())
+				})
+
+				It("does not push in place", func() {
+					courier.RoutedAppsCall.Returns.AppNames = []string{"other-app"}
+
+					err := pusher.PushInPlace(ctx, appPath, deploymentInfo, response)
+					Expect(err).To(MatchError(RouteTakenError{hostname, domain, "other-app"}))
+
+					Expect(courier.PushRollingCall.Received.AppName).To(BeEmpty())
+				})
+			})
+
+			Context("when the apps the route is mapped to cannot be listed", func() {
+				It("returns an error without pushing", func() {
+					courier.RoutedAppsCall.Returns.Error = errors.New("apps error")
+
+					err := pusher.Push(ctx, appPath, deploymentInfo, response)
+					Expect(err).To(MatchError(ListRoutedAppsError{hostname, errors.New("apps error")}))
+
+					Expect(courier.PushCall.Received.AppName).To(BeEmpty())
+				})
+			})
+		})
+
+		Context("when the deployment has no route", func() {
+			BeforeEach(func() {
+				deploymentInfo.NoRoute = true
+				deploymentInfo.CustomDomains = []string{"internal-" + domain}
+			})
+
+			It("pushes the app without mapping a route", func() {
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+				Expect(courier.PushCall.Received.AppName).To(Equal(appName))
+				Expect(courier.PushCall.Received.NoRoute).To(BeTrue())
+				Expect(courier.MapRouteCall.Received.Domains).To(BeEmpty())
+
+				Eventually(logBuffer).Should(gbytes.Say(fmt.Sprintf("not mapping a route to %s because it has no route", appName)))
+			})
+
+			It("still renames the current app to the venerable name", func() {
+				courier.ExistsCall.Returns.Bool = true
+				pusher.Exists(ctx, appName)
+
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+				Expect(courier.RenameCall.Received.AppName).To(Equal(appName))
+				Expect(courier.RenameCall.Received.AppNameVenerable).To(Equal(appNameVenerable))
+				Expect(courier.MapRouteCall.Received.Domains).To(BeEmpty())
+			})
+		})
+
+		Context("when the environment has custom domains", func() {
+			BeforeEach(func() {
+				deploymentInfo.CustomDomains = []string{"internal-" + domain, "external-" + domain}
+			})
+
+			It("maps the route on the domain and on every custom domain", func() {
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+				Expect(courier.MapRouteCall.Received.Domains).To(Equal([]string{domain, "internal-" + domain, "external-" + domain}))
+				Expect(courier.MapRouteCall.Received.Hostname).To(Equal(appName))
+
+				Eventually(logBuffer).Should(gbytes.Say(fmt.Sprintf("application route created at %s.external-%s", appName, domain)))
+			})
+
+			It("fails the push when the route cannot be mapped on a custom domain", func() {
+				courier.MapRouteCall.Returns.DomainErrors = map[string]error{"internal-" + domain: errors.New("map route failed")}
+				courier.LogsCall.Returns.Output = []byte("cf logs")
+
+				err := pusher.Push(ctx, appPath, deploymentInfo, response)
+				Expect(err).To(MatchError("map route failed"))
+
+				Expect(courier.MapRouteCall.Received.Domains).To(Equal([]string{domain, "internal-" + domain}))
+				Expect(courier.AppGUIDCall.Received.AppName).To(BeEmpty())
+				Eventually(response).Should(gbytes.Say("cf logs"))
+			})
+		})
+
+		It("captures the guid of the pushed app", func() {
+			courier.AppGUIDCall.Returns.Output = []byte("app-guid\n")
+			courier.AppGUIDCall.Returns.Error = nil
+
+			Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+			Expect(courier.AppGUIDCall.Received.AppName).To(Equal(appName))
+			Expect(pusher.AppGUID()).To(Equal("app-guid"))
+
+			Eventually(logBuffer).Should(gbytes.Say(fmt.Sprintf("application %s has guid app-guid", appName)))
+		})
+
+		Context("when the guid cannot be retrieved", func() {
+			It("logs a warning and leaves the guid empty", func() {
+				courier.AppGUIDCall.Returns.Error = errors.New("guid error")
+
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+				Expect(pusher.AppGUID()).To(BeEmpty())
+
+				Eventually(logBuffer).Should(gbytes.Say(fmt.Sprintf("unable to get the guid of %s: guid error", appName)))
+			})
 		})
 
 		Context("when the push fails", func() {
 			It("returns an error", func() {
 				courier.PushCall.Returns.Error = errors.New("push error")
 
-				err := pusher.Push(appPath, deploymentInfo, response)
+				err := pusher.Push(ctx, appPath, deploymentInfo, response)
 
 				Expect(err).To(MatchError("push error"))
 
 			})
+
+			It("does not get the guid", func() {
+				courier.PushCall.Returns.Error = errors.New("push error")
+
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).ToNot(Succeed())
+
+				Expect(courier.AppGUIDCall.Received.AppName).To(BeEmpty())
+				Expect(pusher.AppGUID()).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("pushing an app with a versioned name", func() {
+		Context("when the deployment has an app name prefix", func() {
+			It("pushes the app with the prefix and maps the route of the app name to it", func() {
+				deploymentInfo.AppNamePrefix = "v2-"
+
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+				Expect(courier.PushCall.Received.AppName).To(Equal("v2-" + appName))
+				Expect(courier.MapRouteCall.Received.AppName).To(Equal("v2-" + appName))
+				Expect(courier.MapRouteCall.Received.Hostname).To(Equal(appName))
+				Expect(courier.AppGUIDCall.Received.AppName).To(Equal("v2-" + appName))
+			})
+		})
+
+		Context("when the deployment has an app name suffix", func() {
+			It("pushes the app with the suffix and maps the route of the app name to it", func() {
+				deploymentInfo.AppNameSuffix = "-build-1234"
+
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+				Expect(courier.PushCall.Received.AppName).To(Equal(appName + "-build-1234"))
+				Expect(courier.MapRouteCall.Received.AppName).To(Equal(appName + "-build-1234"))
+				Expect(courier.MapRouteCall.Received.Hostname).To(Equal(appName))
+				Expect(courier.AppGUIDCall.Received.AppName).To(Equal(appName + "-build-1234"))
+			})
+		})
+
+		Context("when the deployment has an app name prefix and suffix", func() {
+			BeforeEach(func() {
+				deploymentInfo.AppNamePrefix = "v2-"
+				deploymentInfo.AppNameSuffix = "-build-1234"
+			})
+
+			It("pushes the app with both and maps the route of the app name to it", func() {
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+				Expect(courier.PushCall.Received.AppName).To(Equal("v2-" + appName + "-build-1234"))
+				Expect(courier.MapRouteCall.Received.AppName).To(Equal("v2-" + appName + "-build-1234"))
+				Expect(courier.MapRouteCall.Received.Hostname).To(Equal(appName))
+				Expect(courier.MapRouteCall.Received.Domain).To(Equal(domain))
+			})
+
+			It("checks the health of the versioned app", func() {
+				deploymentInfo.HealthCheckPath = "/health"
+				courier.HealthyCall.Returns.Healthy = true
+
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+				Expect(courier.HealthyCall.Received.AppName).To(Equal("v2-" + appName + "-build-1234"))
+			})
+		})
+
+		Context("when the route is mapped to a previous version", func() {
+			BeforeEach(func() {
+				deploymentInfo.AppNameSuffix = "-build-1234"
+
+				courier.RoutedAppsCall.Returns.AppNames = []string{appName + "-build-1233"}
+			})
+
+			It("renames the previous version to the venerable name of the app", func() {
+				pusher.Exists(ctx, appName)
+
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+				Expect(courier.RoutedAppsCall.Received.Hostname).To(Equal(appName))
+				Expect(courier.RoutedAppsCall.Received.Domain).To(Equal(domain))
+				Expect(courier.RenameCall.Received.AppName).To(Equal(appName + "-build-1233"))
+				Expect(courier.RenameCall.Received.AppNameVenerable).To(Equal(appNameVenerable))
+
+				Eventually(logBuffer).Should(gbytes.Say(fmt.Sprintf("renamed app from %s-build-1233 to %s", appName, appNameVenerable)))
+			})
+
+			It("deletes the venerable app of the app name once the deploy completes", func() {
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+				Expect(pusher.DeleteVenerable(ctx, deploymentInfo)).To(Succeed())
+
+				Expect(courier.DeleteCall.Received.AppName).To(Equal(appNameVenerable))
+			})
+
+			It("deletes the versioned app and renames the previous version back on rollback", func() {
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+				Expect(pusher.Rollback(ctx, deploymentInfo)).To(Succeed())
+
+				Expect(courier.DeleteCall.Received.AppName).To(Equal(appName + "-build-1234"))
+				Expect(courier.RenameCall.Received.AppName).To(Equal(appNameVenerable))
+				Expect(courier.RenameCall.Received.AppNameVenerable).To(Equal(appName + "-build-1233"))
+			})
+		})
+
+		Context("when the route is not mapped to any app", func() {
+			It("reports that the app is new without renaming anything", func() {
+				deploymentInfo.AppNameSuffix = "-build-1234"
+
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+				Expect(courier.RenameCall.Received.AppName).To(BeEmpty())
+				Eventually(logBuffer).Should(gbytes.Say("new app detected"))
+			})
+		})
+
+		Context("when the apps the route is mapped to cannot be listed", func() {
+			It("returns an error without pushing", func() {
+				deploymentInfo.AppNameSuffix = "-build-1234"
+				courier.RoutedAppsCall.Returns.Error = errors.New("apps error")
+
+				err := pusher.Push(ctx, appPath, deploymentInfo, response)
+				Expect(err).To(MatchError(ListRoutedAppsError{appName, errors.New("apps error")}))
+
+				Expect(courier.PushCall.Received.AppName).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("pushing an app in place", func() {
+		It("pushes the app with a rolling deployment without renaming the existing app", func() {
+			courier.ExistsCall.Returns.Bool = true
+			courier.PushRollingCall.Returns.Output = []byte("rolling push succeeded")
+
+			pusher.Exists(ctx, appName)
+
+			Expect(pusher.PushInPlace(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+			Expect(courier.PushRollingCall.Received.AppName).To(Equal(appName))
+			Expect(courier.PushRollingCall.Received.AppPath).To(Equal(appPath))
+			Expect(courier.PushRollingCall.Received.Instances).To(Equal(instances))
+			Expect(courier.RenameCall.Received.AppName).To(BeEmpty())
+			Expect(courier.PushCall.Received.AppName).To(BeEmpty())
+
+			Eventually(response).Should(gbytes.Say("rolling push succeeded"))
+		})
+
+		It("maps the route to the app", func() {
+			Expect(pusher.PushInPlace(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+			Expect(courier.MapRouteCall.Received.AppName).To(Equal(appName))
+			Expect(courier.MapRouteCall.Received.Domain).To(Equal(domain))
+		})
+
+		Context("when the deployment has a docker image", func() {
+			It("pushes the docker image with a rolling deployment", func() {
+				deploymentInfo.DockerImage = "dockerImage-" + randomizer.StringRunes(10)
+
+				Expect(pusher.PushInPlace(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+				Expect(courier.PushRollingCall.Received.DockerImage).To(Equal(deploymentInfo.DockerImage))
+				Expect(courier.PushDockerCall.Received.AppName).To(BeEmpty())
+			})
+		})
+
+		Context("when the push fails", func() {
+			It("returns an error", func() {
+				courier.PushRollingCall.Returns.Error = errors.New("push error")
+
+				Expect(pusher.PushInPlace(ctx, appPath, deploymentInfo, response)).ToNot(Succeed())
+			})
+		})
+	})
+
+	Describe("passing the startup timeout", func() {
+		It("pushes with the startup timeout of the deploy", func() {
+			deploymentInfo.StartupTimeout = 180
+
+			Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+			Expect(courier.PushCall.Received.StartupTimeout).To(Equal(180))
+		})
+
+		It("leaves the startup timeout to Cloud Foundry when it is not given", func() {
+			Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+			Expect(courier.PushCall.Received.StartupTimeout).To(Equal(0))
+		})
+	})
+
+	Describe("waiting for the app to become healthy", func() {
+		BeforeEach(func() {
+			pusher.HealthCheckInterval = time.Millisecond
+			deploymentInfo.HealthCheckPath = "/health"
+			deploymentInfo.HealthCheckTimeout = "50ms"
+		})
+
+		It("pushes with the health check path", func() {
+			courier.HealthyCall.Returns.Healthy = true
+
+			Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+			Expect(courier.PushCall.Received.HealthCheckPath).To(Equal("/health"))
+		})
+
+		It("maps the route once the app is healthy", func() {
+			courier.HealthyCall.Returns.Healthy = true
+
+			Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+			Expect(courier.HealthyCall.Received.AppName).To(Equal(appName))
+			Expect(courier.MapRouteCall.Received.AppName).To(Equal(appName))
+		})
+
+		Context("when the app never becomes healthy", func() {
+			It("returns an error without mapping the route", func() {
+				courier.HealthyCall.Returns.Healthy = false
+				courier.LogsCall.Returns.Output = []byte("crash logs")
+
+				err := pusher.Push(ctx, appPath, deploymentInfo, response)
+
+				Expect(err).To(MatchError(UnhealthyAppError{appName, 50 * time.Millisecond}))
+				Expect(courier.HealthyCall.TimesCalled).To(BeNumerically(">", 1))
+				Expect(courier.MapRouteCall.Received.AppName).To(BeEmpty())
+				Eventually(response).Should(gbytes.Say("crash logs"))
+			})
+		})
+
+		Context("when the deploy is cancelled while waiting", func() {
+			It("stops waiting and returns the error of the context", func() {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithCancel(ctx)
+				cancel()
+
+				deploymentInfo.HealthCheckTimeout = "1m"
+				courier.HealthyCall.Returns.Healthy = false
+
+				err := pusher.Push(ctx, appPath, deploymentInfo, response)
+
+				Expect(err).To(Equal(context.Canceled))
+				Expect(courier.MapRouteCall.Received.AppName).To(BeEmpty())
+			})
+		})
+
+		Context("when the deployment has no health check", func() {
+			It("maps the route without checking the health of the app", func() {
+				deploymentInfo.HealthCheckPath = ""
+				deploymentInfo.HealthCheckTimeout = ""
+
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+				Expect(courier.HealthyCall.TimesCalled).To(BeZero())
+				Expect(courier.MapRouteCall.Received.AppName).To(Equal(appName))
+			})
+		})
+	})
+
+	Describe("timing out cf commands", func() {
+		It("runs each command with the default timeout when none is set", func() {
+			before := time.Now()
+
+			Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+			deadline, ok := courier.PushCall.Received.Context.Deadline()
+			Expect(ok).To(BeTrue())
+			Expect(deadline).To(BeTemporally(">=", before.Add(DefaultTimeout)))
+			Expect(deadline).To(BeTemporally("<", before.Add(DefaultTimeout+time.Minute)))
+		})
+
+		It("runs login, rename, push and map-route with the timeout of the pusher", func() {
+			pusher.Timeout = 30 * time.Second
+			courier.ExistsCall.Returns.Bool = true
+			pusher.Exists(ctx, appName)
+			before := time.Now()
+
+			Expect(pusher.Login(ctx, foundationURL, deploymentInfo, response)).To(Succeed())
+			Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+			for _, ctx := range []context.Context{
+				courier.LoginCall.Received.Context,
+				courier.RenameCall.Received.Context,
+				courier.PushCall.Received.Context,
+				courier.MapRouteCall.Received.Context,
+			} {
+				deadline, ok := ctx.Deadline()
+				Expect(ok).To(BeTrue())
+				Expect(deadline).To(BeTemporally("~", before.Add(30*time.Second), 5*time.Second))
+			}
+		})
+
+		It("logs in with the login timeout instead of the timeout when it is set", func() {
+			pusher.Timeout = 5 * time.Minute
+			deploymentInfo.LoginTimeout = 20 * time.Second
+			before := time.Now()
+
+			Expect(pusher.Login(ctx, foundationURL, deploymentInfo, response)).To(Succeed())
+
+			deadline, ok := courier.LoginCall.Received.Context.Deadline()
+			Expect(ok).To(BeTrue())
+			Expect(deadline).To(BeTemporally("~", before.Add(20*time.Second), 5*time.Second))
+		})
+
+		It("logs in with the login timeout of the pusher when the environment has none", func() {
+			pusher.Timeout = 5 * time.Minute
+			pusher.LoginTimeout = 10 * time.Second
+			before := time.Now()
+
+			Expect(pusher.Login(ctx, foundationURL, deploymentInfo, response)).To(Succeed())
+
+			deadline, ok := courier.LoginCall.Received.Context.Deadline()
+			Expect(ok).To(BeTrue())
+			Expect(deadline).To(BeTemporally("~", before.Add(10*time.Second), 5*time.Second))
+		})
+
+		Context("when login takes longer than the login timeout", func() {
+			BeforeEach(func() {
+				pusher.Timeout = 5 * time.Minute
+				pusher.LoginRetries = 2
+				pusher.LoginRetryDelay = time.Millisecond
+				deploymentInfo.LoginTimeout = 20 * time.Millisecond
+				courier.LoginCall.Delay = time.Minute
+			})
+
+			It("fails without waiting for the timeout and does not retry", func() {
+				before := time.Now()
+
+				err := pusher.Login(ctx, foundationURL, deploymentInfo, response)
+				Expect(err).To(MatchError(LoginTimeoutError{foundationURL, 20 * time.Millisecond}))
+				Expect(err.(LoginTimeoutError).Temporary()).To(BeTrue())
+
+				Expect(time.Since(before)).To(BeNumerically("<", time.Second))
+				Expect(courier.LoginCall.TimesCalled).To(Equal(1))
+			})
+		})
+
+		It("prefers the timeout of the environment being deployed to", func() {
+			pusher.Timeout = 30 * time.Second
+			deploymentInfo.Timeout = 90 * time.Second
+			before := time.Now()
+
+			Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+			deadline, ok := courier.PushCall.Received.Context.Deadline()
+			Expect(ok).To(BeTrue())
+			Expect(deadline).To(BeTemporally("~", before.Add(90*time.Second), 5*time.Second))
+		})
+
+		Context("when the push times out", func() {
+			It("writes the output so far before returning the timeout error", func() {
+				courier.PushCall.Returns.Output = []byte("uploading app")
+				courier.PushCall.Returns.Error = errors.New("cf command timed out: push")
+
+				err := pusher.Push(ctx, appPath, deploymentInfo, response)
+				Expect(err).To(MatchError(ContainSubstring("cf command timed out")))
+
+				Eventually(response).Should(gbytes.Say("uploading app"))
+			})
+		})
+
+		Context("when renaming the existing app times out", func() {
+			It("writes the output so far before returning the error", func() {
+				courier.ExistsCall.Returns.Bool = true
+				courier.RenameCall.Returns.Output = []byte("renaming app")
+				courier.RenameCall.Returns.Error = errors.New("cf command timed out: rename")
+				pusher.Exists(ctx, appName)
+
+				err := pusher.Push(ctx, appPath, deploymentInfo, response)
+				Expect(err).To(MatchError(RenameFailError{errors.New("cf command timed out: rename")}))
+
+				Eventually(response).Should(gbytes.Say("renaming app"))
+			})
+		})
+	})
+
+	Describe("checking whether the app can be pushed", func() {
+		It("pushes and deletes a preflight probe", func() {
+			courier.CanPushCall.Returns.Output = []byte("probe pushed")
+
+			Expect(pusher.CanPush(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+			Expect(courier.CanPushCall.Received.AppName).To(MatchRegexp("^%s-preflight-[a-z]{8}$", appName))
+			Expect(courier.CanPushCall.Received.AppPath).To(Equal(appPath))
+			Eventually(response).Should(gbytes.Say("probe pushed"))
+		})
+
+		It("gives every probe a different name so that concurrent deploys of the app do not collide", func() {
+			Expect(pusher.CanPush(ctx, appPath, deploymentInfo, response)).To(Succeed())
+			firstProbe := courier.CanPushCall.Received.AppName
+
+			Expect(pusher.CanPush(ctx, appPath, deploymentInfo, response)).To(Succeed())
+
+			Expect(courier.CanPushCall.Received.AppName).ToNot(Equal(firstProbe))
+		})
+
+		Context("when the probe cannot be pushed", func() {
+			It("returns a push permission error", func() {
+				courier.CanPushCall.Returns.Output = []byte("not authorized")
+				courier.CanPushCall.Returns.Error = errors.New("bork")
+
+				err := pusher.CanPush(ctx, appPath, deploymentInfo, response)
+				Expect(err).To(MatchError(PushPermissionError{org, space, errors.New("bork")}))
+
+				Eventually(response).Should(gbytes.Say("not authorized"))
+			})
 		})
 	})
 
 	Describe("rolling back a deployment", func() {
 		It("deletes the app that was pushed", func() {
-			Expect(pusher.Rollback(deploymentInfo)).To(Succeed())
+			Expect(pusher.Rollback(ctx, deploymentInfo)).To(Succeed())
 
 			Expect(courier.DeleteCall.Received.AppName).To(Equal(appName))
 
@@ -204,7 +896,7 @@ var _ = Describe("Pusher", func() {
 			It("writes a message to the info log", func() {
 				courier.DeleteCall.Returns.Error = errors.New("delete error")
 
-				Expect(pusher.Rollback(deploymentInfo)).To(Succeed())
+				Expect(pusher.Rollback(ctx, deploymentInfo)).To(Succeed())
 
 				Eventually(logBuffer).Should(gbytes.Say(fmt.Sprintf("unable to delete %s: %s", deploymentInfo.AppName, "delete error")))
 			})
@@ -213,8 +905,8 @@ var _ = Describe("Pusher", func() {
 		It("renames the venerable app", func() {
 			courier.ExistsCall.Returns.Bool = true
 
-			pusher.Exists(appName)
-			Expect(pusher.Rollback(deploymentInfo)).To(Succeed())
+			pusher.Exists(ctx, appName)
+			Expect(pusher.Rollback(ctx, deploymentInfo)).To(Succeed())
 
 			Expect(courier.RenameCall.Received.AppName).To(Equal(appNameVenerable))
 			Expect(courier.RenameCall.Received.AppNameVenerable).To(Equal(appName))
@@ -227,21 +919,111 @@ var _ = Describe("Pusher", func() {
 				courier.ExistsCall.Returns.Bool = true
 				courier.RenameCall.Returns.Error = errors.New("rename error")
 
-				pusher.Exists(appName)
+				pusher.Exists(ctx, appName)
 
-				Expect(pusher.Rollback(deploymentInfo)).To(Succeed())
+				Expect(pusher.Rollback(ctx, deploymentInfo)).To(Succeed())
 
 				Eventually(logBuffer).Should(gbytes.Say(fmt.Sprintf("unable to rename venerable app %s: %s", appNameVenerable, "rename error")))
 			})
 		})
 
+		It("does not map the route back when it was not unmapped", func() {
+			courier.ExistsCall.Returns.Bool = true
+
+			pusher.Exists(ctx, appName)
+			Expect(pusher.Rollback(ctx, deploymentInfo)).To(Succeed())
+
+			Expect(courier.MapRouteCall.Received.Domains).To(BeEmpty())
+		})
+
+		It("maps the route back to the venerable app when it was unmapped for the smoke test", func() {
+			courier.ExistsCall.Returns.Bool = true
+			deploymentInfo.CustomDomains = []string{"custom-" + domain}
+
+			pusher.Exists(ctx, appName)
+			Expect(pusher.UnmapVenerable(ctx, deploymentInfo)).To(Succeed())
+			Expect(pusher.Rollback(ctx, deploymentInfo)).To(Succeed())
+
+			Expect(courier.MapRouteCall.Received.AppName).To(Equal(appNameVenerable))
+			Expect(courier.MapRouteCall.Received.Hostname).To(Equal(appName))
+			Expect(courier.MapRouteCall.Received.Domains).To(Equal([]string{domain, "custom-" + domain}))
+
+			Eventually(logBuffer).Should(gbytes.Say("mapped route %s.%s back to %s", appName, domain, appNameVenerable))
+		})
+
+		It("runs the cf commands with the context of the deploy", func() {
+			courier.ExistsCall.Returns.Bool = true
+			cancelledCtx, cancel := context.WithCancel(ctx)
+			cancel()
+
+			pusher.Exists(ctx, appName)
+			Expect(pusher.Rollback(cancelledCtx, deploymentInfo)).To(Succeed())
+
+			Expect(courier.DeleteCall.Received.Context).To(Equal(cancelledCtx))
+			Expect(courier.RenameCall.Received.Context.Err()).To(Equal(context.Canceled))
+		})
+	})
+
+	Describe("unmapping the venerable app before the smoke test", func() {
+		It("unmaps the route from the venerable app on every domain", func() {
+			courier.ExistsCall.Returns.Bool = true
+			deploymentInfo.CustomDomains = []string{"custom-" + domain}
+
+			pusher.Exists(ctx, appName)
+			Expect(pusher.UnmapVenerable(ctx, deploymentInfo)).To(Succeed())
+
+			Expect(courier.UnmapRouteCall.Received.AppName).To(Equal(appNameVenerable))
+			Expect(courier.UnmapRouteCall.Received.Hostname).To(Equal(appName))
+			Expect(courier.UnmapRouteCall.Received.Domains).To(Equal([]string{domain, "custom-" + domain}))
+
+			Eventually(logBuffer).Should(gbytes.Say("unmapped route %s.%s from %s", appName, domain, appNameVenerable))
+		})
+
+		It("unmaps the overridden hostname", func() {
+			courier.ExistsCall.Returns.Bool = true
+			deploymentInfo.Hostname = "hostname-" + randomizer.StringRunes(10)
+
+			pusher.Exists(ctx, appName)
+			Expect(pusher.UnmapVenerable(ctx, deploymentInfo)).To(Succeed())
+
+			Expect(courier.UnmapRouteCall.Received.Hostname).To(Equal(deploymentInfo.Hostname))
+		})
+
+		It("does nothing on the first deploy", func() {
+			pusher.Exists(ctx, appName)
+			Expect(pusher.UnmapVenerable(ctx, deploymentInfo)).To(Succeed())
+
+			Expect(courier.UnmapRouteCall.Received.Domains).To(BeEmpty())
+		})
+
+		It("does nothing when the app has no route", func() {
+			courier.ExistsCall.Returns.Bool = true
+			deploymentInfo.NoRoute = true
+
+			pusher.Exists(ctx, appName)
+			Expect(pusher.UnmapVenerable(ctx, deploymentInfo)).To(Succeed())
+
+			Expect(courier.UnmapRouteCall.Received.Domains).To(BeEmpty())
+		})
+
+		Context("when unmapping fails", func() {
+			It("returns an error", func() {
+				courier.ExistsCall.Returns.Bool = true
+				courier.UnmapRouteCall.Returns.Error = errors.New("unmap error")
+
+				pusher.Exists(ctx, appName)
+				err := pusher.UnmapVenerable(ctx, deploymentInfo)
+
+				Expect(err).To(MatchError(UnmapVenerableError{appName, domain, errors.New("unmap error")}))
+			})
+		})
 	})
 
 	Describe("completing a deployment", func() {
 		It("deletes venerable", func() {
 			courier.DeleteCall.Returns.Error = nil
 
-			Expect(pusher.DeleteVenerable(deploymentInfo)).To(Succeed())
+			Expect(pusher.DeleteVenerable(ctx, deploymentInfo)).To(Succeed())
 
 			Expect(courier.DeleteCall.Received.AppName).To(Equal(appNameVenerable))
 
@@ -252,7 +1034,96 @@ var _ = Describe("Pusher", func() {
 			It("returns an error", func() {
 				courier.DeleteCall.Returns.Error = errors.New("delete error")
 
-				Expect(pusher.DeleteVenerable(deploymentInfo)).To(MatchError(DeleteVenerableError{appNameVenerable, errors.New("delete error")}))
+				Expect(pusher.DeleteVenerable(ctx, deploymentInfo)).To(MatchError(DeleteVenerableError{appNameVenerable, errors.New("delete error")}))
+			})
+		})
+
+		Context("when the environment keeps previous versions", func() {
+			BeforeEach(func() {
+				deploymentInfo.Retention = 2
+
+				courier.ExistsCall.Returns.Bool = true
+				pusher.Exists(ctx, appName)
+
+				courier.ListCall.Returns.AppNames = []string{
+					appNameVenerable + "-1400000000",
+					appNameVenerable + "-1500000000",
+					appNameVenerable + "-1300000000",
+					appNameVenerable + "-1600000000",
+				}
+			})
+
+			It("stops the venerable app and keeps it under a versioned name", func() {
+				Expect(pusher.DeleteVenerable(ctx, deploymentInfo)).To(Succeed())
+
+				Expect(courier.StopCall.Received.AppName).To(Equal(appNameVenerable))
+				Expect(courier.RenameCall.Received.AppName).To(Equal(appNameVenerable))
+				Expect(courier.RenameCall.Received.AppNameVenerable).To(MatchRegexp("^%s-[0-9]+$", appNameVenerable))
+			})
+
+			It("names the version after the time of the Clock", func() {
+				clock := &mocks.Clock{}
+				clock.NowCall.Returns.Time = time.Unix(1700000000, 0)
+				pusher.Clock = clock
+
+				Expect(pusher.DeleteVenerable(ctx, deploymentInfo)).To(Succeed())
+
+				Expect(courier.RenameCall.Received.AppNameVenerable).To(Equal(appNameVenerable + "-1700000000"))
+			})
+
+			It("deletes every version but the most recent ones", func() {
+				Expect(pusher.DeleteVenerable(ctx, deploymentInfo)).To(Succeed())
+
+				Expect(courier.ListCall.Received.Prefix).To(Equal(appNameVenerable + "-"))
+				Expect(courier.DeleteCall.Received.AppNames).To(Equal([]string{
+					appNameVenerable + "-1400000000",
+					appNameVenerable + "-1300000000",
+				}))
+
+				Eventually(logBuffer).Should(gbytes.Say(fmt.Sprintf("deleted %s-1400000000", appNameVenerable)))
+			})
+
+			It("ignores apps that are not versions of the app", func() {
+				courier.ListCall.Returns.AppNames = []string{appNameVenerable + "-blue", appNameVenerable + "-1600000000"}
+
+				Expect(pusher.DeleteVenerable(ctx, deploymentInfo)).To(Succeed())
+
+				Expect(courier.DeleteCall.Received.AppNames).To(BeEmpty())
+			})
+
+			It("keeps nothing new on the first deploy", func() {
+				courier.ExistsCall.Returns.Bool = false
+				pusher.Exists(ctx, appName)
+
+				Expect(pusher.DeleteVenerable(ctx, deploymentInfo)).To(Succeed())
+
+				Expect(courier.StopCall.Received.AppName).To(BeEmpty())
+				Expect(courier.RenameCall.Received.AppName).To(BeEmpty())
+			})
+
+			Context("when the venerable app cannot be stopped", func() {
+				It("returns an error", func() {
+					courier.StopCall.Returns.Error = errors.New("stop error")
+
+					Expect(pusher.DeleteVenerable(ctx, deploymentInfo)).To(MatchError(RetainVenerableError{appNameVenerable, errors.New("stop error")}))
+					Expect(courier.RenameCall.Received.AppName).To(BeEmpty())
+				})
+			})
+
+			Context("when the versions cannot be listed", func() {
+				It("returns an error", func() {
+					courier.ListCall.Returns.Error = errors.New("list error")
+
+					Expect(pusher.DeleteVenerable(ctx, deploymentInfo)).To(MatchError(ListVersionsError{appName, errors.New("list error")}))
+				})
+			})
+
+			Context("when an old version cannot be deleted", func() {
+				It("returns an error", func() {
+					courier.DeleteCall.Returns.Error = errors.New("delete error")
+
+					Expect(pusher.DeleteVenerable(ctx, deploymentInfo)).To(MatchError(DeleteVenerableError{appNameVenerable + "-1400000000", errors.New("delete error")}))
+				})
 			})
 		})
 	})
@@ -263,7 +1134,7 @@ var _ = Describe("Pusher", func() {
 				courier.PushCall.Returns.Error = errors.New("push error")
 				courier.LogsCall.Returns.Output = []byte("cf logs")
 
-				Expect(pusher.Push(appPath, deploymentInfo, response)).ToNot(Succeed())
+				Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).ToNot(Succeed())
 
 				Eventually(response).Should(gbytes.Say(("cf logs")))
 			})
@@ -276,7 +1147,7 @@ var _ = Describe("Pusher", func() {
 					courier.PushCall.Returns.Error = pushErr
 					courier.LogsCall.Returns.Error = logsErr
 
-					err := pusher.Push(appPath, deploymentInfo, response)
+					err := pusher.Push(ctx, appPath, deploymentInfo, response)
 
 					Expect(err).To(MatchError(CloudFoundryGetLogsError{pushErr, logsErr}))
 				})
@@ -288,7 +1159,7 @@ var _ = Describe("Pusher", func() {
 				courier.MapRouteCall.Returns.Error = errors.New("map route failed")
 				courier.LogsCall.Returns.Output = []byte("cf logs")
 
-				err := pusher.Push(appPath, deploymentInfo, response)
+				err := pusher.Push(ctx, appPath, deploymentInfo, response)
 
 				Expect(err).To(MatchError("map route failed"))
 
@@ -303,7 +1174,7 @@ var _ = Describe("Pusher", func() {
 					courier.MapRouteCall.Returns.Error = mapRouteErr
 					courier.LogsCall.Returns.Error = logsErr
 
-					Expect(pusher.Push(appPath, deploymentInfo, response)).To(MatchError(CloudFoundryGetLogsError{mapRouteErr, logsErr}))
+					Expect(pusher.Push(ctx, appPath, deploymentInfo, response)).To(MatchError(CloudFoundryGetLogsError{mapRouteErr, logsErr}))
 				})
 			})
 		})
@@ -321,7 +1192,7 @@ var _ = Describe("Pusher", func() {
 		It("it is successful", func() {
 			courier.ExistsCall.Returns.Bool = true
 
-			pusher.Exists(appName)
+			pusher.Exists(ctx, appName)
 
 			Expect(courier.ExistsCall.Received.AppName).To(Equal(appName))
 		})
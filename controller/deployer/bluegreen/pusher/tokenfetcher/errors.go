@@ -0,0 +1,38 @@
+package tokenfetcher
+
+import "fmt"
+
+type TokenRequestError struct {
+	TokenURL string
+	Err      error
+}
+
+func (e TokenRequestError) Error() string {
+	return fmt.Sprintf("cannot request token: %s: %s", e.TokenURL, e.Err)
+}
+
+type TokenStatusError struct {
+	TokenURL string
+	Status   string
+}
+
+func (e TokenStatusError) Error() string {
+	return fmt.Sprintf("cannot get token: %s: %s", e.TokenURL, e.Status)
+}
+
+type TokenDecodeError struct {
+	TokenURL string
+	Err      error
+}
+
+func (e TokenDecodeError) Error() string {
+	return fmt.Sprintf("cannot decode token response: %s: %s", e.TokenURL, e.Err)
+}
+
+type MissingTokenError struct {
+	TokenURL string
+}
+
+func (e MissingTokenError) Error() string {
+	return fmt.Sprintf("token response has no access_token: %s", e.TokenURL)
+}
@@ -0,0 +1,118 @@
+// Package tokenfetcher fetches OAuth tokens for service accounts that log in with client credentials.
+package tokenfetcher
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/op/go-logging"
+)
+
+const defaultExpiryMargin = time.Minute
+
+// TokenFetcher fetches tokens from a token endpoint with the client credentials grant.
+// Tokens are cached until ExpiryMargin before they expire, so one TokenFetcher should be shared by every Pusher.
+type TokenFetcher struct {
+	Client       *http.Client
+	Log          *logging.Logger
+	ExpiryMargin time.Duration
+	Now          func() time.Time
+	mutex        sync.Mutex
+	tokens       map[string]token
+}
+
+type token struct {
+	accessToken string
+	expiresAt   time.Time
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// New returns a TokenFetcher with a client that uses minTLSVersion.
+func New(minTLSVersion uint16, log *logging.Logger) *TokenFetcher {
+	return &TokenFetcher{
+		Client: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{MinVersion: minTLSVersion},
+			},
+		},
+		Log:          log,
+		ExpiryMargin: defaultExpiryMargin,
+		Now:          time.Now,
+	}
+}
+
+// Token returns a cached token for the client if it is not near expiry, otherwise it fetches a new one from tokenURL.
+//
+// Returns the access token and an error.
+func (t *TokenFetcher) Token(tokenURL, clientID, clientSecret string) (string, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	key := tokenURL + " " + clientID
+
+	cached, ok := t.tokens[key]
+	if ok && t.Now().Add(t.ExpiryMargin).Before(cached.expiresAt) {
+		t.Log.Debugf("using cached token for %s", clientID)
+		return cached.accessToken, nil
+	}
+
+	t.Log.Debugf("fetching token for %s from %s", clientID, tokenURL)
+
+	fetched, err := t.fetch(tokenURL, clientID, clientSecret)
+	if err != nil {
+		return "", err
+	}
+
+	if t.tokens == nil {
+		t.tokens = map[string]token{}
+	}
+	t.tokens[key] = fetched
+
+	return fetched.accessToken, nil
+}
+
+func (t *TokenFetcher) fetch(tokenURL, clientID, clientSecret string) (token, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+
+	req, err := http.NewRequest("POST", tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return token{}, TokenRequestError{tokenURL, err}
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(clientID, clientSecret)
+
+	response, err := t.Client.Do(req)
+	if err != nil {
+		return token{}, TokenRequestError{tokenURL, err}
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return token{}, TokenStatusError{tokenURL, response.Status}
+	}
+
+	var body tokenResponse
+	err = json.NewDecoder(response.Body).Decode(&body)
+	if err != nil {
+		return token{}, TokenDecodeError{tokenURL, err}
+	}
+	if body.AccessToken == "" {
+		return token{}, MissingTokenError{tokenURL}
+	}
+
+	return token{
+		accessToken: body.AccessToken,
+		expiresAt:   t.Now().Add(time.Duration(body.ExpiresIn) * time.Second),
+	}, nil
+}
@@ -0,0 +1,13 @@
+package tokenfetcher_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestTokenfetcher(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Tokenfetcher Suite")
+}
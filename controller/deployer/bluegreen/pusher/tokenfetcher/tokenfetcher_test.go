@@ -0,0 +1,95 @@
+package tokenfetcher_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/compozed/deployadactyl/controller/deployer/bluegreen/pusher/tokenfetcher"
+	"github.com/compozed/deployadactyl/logger"
+	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/op/go-logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TokenFetcher", func() {
+	var (
+		tokenFetcher *TokenFetcher
+		testserver   *httptest.Server
+		now          time.Time
+		requests     int
+		clientID     string
+		clientSecret string
+	)
+
+	BeforeEach(func() {
+		now = time.Now()
+		requests = 0
+		clientID = "clientID-" + randomizer.StringRunes(10)
+		clientSecret = "clientSecret-" + randomizer.StringRunes(10)
+
+		testserver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+
+			id, secret, ok := r.BasicAuth()
+			if !ok || id != clientID || secret != clientSecret || r.FormValue("grant_type") != "client_credentials" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "bearer", "expires_in": 600}`, requests)
+		}))
+
+		tokenFetcher = New(0, logger.DefaultLogger(GinkgoWriter, logging.DEBUG, "tokenfetcher_test", logger.TextFormat))
+		tokenFetcher.Now = func() time.Time { return now }
+	})
+
+	AfterEach(func() {
+		testserver.Close()
+	})
+
+	It("fetches a token with the client credentials", func() {
+		token, err := tokenFetcher.Token(testserver.URL, clientID, clientSecret)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(token).To(Equal("token-1"))
+		Expect(requests).To(Equal(1))
+	})
+
+	It("caches the token until it is near expiry", func() {
+		_, err := tokenFetcher.Token(testserver.URL, clientID, clientSecret)
+		Expect(err).ToNot(HaveOccurred())
+
+		now = now.Add(5 * time.Minute)
+
+		token, err := tokenFetcher.Token(testserver.URL, clientID, clientSecret)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(token).To(Equal("token-1"))
+		Expect(requests).To(Equal(1))
+	})
+
+	It("refreshes the token when it is near expiry", func() {
+		_, err := tokenFetcher.Token(testserver.URL, clientID, clientSecret)
+		Expect(err).ToNot(HaveOccurred())
+
+		now = now.Add(9*time.Minute + 30*time.Second)
+
+		token, err := tokenFetcher.Token(testserver.URL, clientID, clientSecret)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(token).To(Equal("token-2"))
+		Expect(requests).To(Equal(2))
+	})
+
+	Context("when the token endpoint rejects the credentials", func() {
+		It("returns an error", func() {
+			_, err := tokenFetcher.Token(testserver.URL, clientID, "bork")
+
+			Expect(err).To(MatchError(TokenStatusError{testserver.URL, "401 Unauthorized"}))
+		})
+	})
+})
@@ -0,0 +1,63 @@
+package bluegreen
+
+import (
+	"io"
+
+	"github.com/compozed/deployadactyl/config"
+	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/logger"
+	S "github.com/compozed/deployadactyl/structs"
+	"golang.org/x/net/context"
+)
+
+// RollingGreener pushes an application to multiple Cloud Foundry instances with a rolling deployment instead of blue green.
+// The application is replaced in place on every foundation, so there is no venerable application and nothing is rolled back.
+// It logs in, reports progress and writes output the same as the BlueGreen it embeds.
+type RollingGreener struct {
+	BlueGreen
+}
+
+// Push will login to all the Cloud Foundry instances provided in the Config and then push the application in place to all the instances concurrently.
+// A foundation whose push fails keeps running the application it had before, but the other foundations are not rolled back.
+// The smoke test of the deploy runs once every foundation has been pushed. When it fails the deploy fails, but as there is
+// no venerable application the foundations keep the version that was pushed.
+// When ctx is done the in-flight cf commands are killed.
+//
+// Returns a map of foundation URL to the guid of the pushed application.
+func (r RollingGreener) Push(ctx context.Context, environment config.Environment, appPath string, deploymentInfo S.DeploymentInfo, response io.Writer) (map[string]string, error) {
+	r.Log = logger.WithRequestID(r.Log, deploymentInfo.RequestID)
+
+	if len(environment.Foundations) == 0 {
+		return nil, NoFoundationsError{environment.Name}
+	}
+
+	stop, err := r.startActors(environment.Foundations, response)
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+	defer r.finishOutput(response)
+
+	err = r.loginAll(ctx, deploymentInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	errs := r.logErrors(r.pushAll(environment.Foundations, deploymentInfo, func(pusher I.Pusher, response io.Writer) error {
+		return pusher.PushInPlace(ctx, appPath, deploymentInfo, response)
+	}))
+	if len(errs) > 0 {
+		return nil, RollingPushFailError{errs}
+	}
+
+	err = r.smokeTest(deploymentInfo, response)
+	if err != nil {
+		return nil, RollingSmokeTestFailError{err}
+	}
+
+	return r.appGUIDAll(environment.Foundations), nil
+}
@@ -0,0 +1,159 @@
+package bluegreen_test
+
+import (
+	"errors"
+
+	"github.com/compozed/deployadactyl/config"
+	. "github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	"github.com/compozed/deployadactyl/logger"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/randomizer"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/op/go-logging"
+	"golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("RollingGreener", func() {
+
+	var (
+		appPath        string
+		pushOutput     string
+		pusherFactory  *mocks.PusherCreator
+		eventManager   *mocks.EventManager
+		pushers        []*mocks.Pusher
+		rollingGreener RollingGreener
+		environment    config.Environment
+		deploymentInfo S.DeploymentInfo
+		response       *Buffer
+		ctx            context.Context
+	)
+
+	BeforeEach(func() {
+		appPath = "appPath-" + randomizer.StringRunes(10)
+		pushOutput = "pushOutput-" + randomizer.StringRunes(10)
+		response = NewBuffer()
+		ctx = context.Background()
+
+		pusherFactory = &mocks.PusherCreator{}
+		pushers = nil
+
+		eventManager = &mocks.EventManager{}
+		eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, nil)
+
+		rollingGreener = RollingGreener{BlueGreen{
+			PusherCreator: pusherFactory,
+			EventManager:  eventManager,
+			Log:           logger.DefaultLogger(NewBuffer(), logging.DEBUG, "test", logger.TextFormat),
+		}}
+
+		environment = config.Environment{Name: "environmentName-" + randomizer.StringRunes(10)}
+		environment.Foundations = []string{randomizer.StringRunes(10), randomizer.StringRunes(10)}
+
+		deploymentInfo = S.DeploymentInfo{
+			AppName:  "appName-" + randomizer.StringRunes(10),
+			Strategy: "rolling",
+		}
+
+		for range environment.Foundations {
+			pusher := &mocks.Pusher{}
+			pusher.PushInPlaceCall.Write.Output = pushOutput
+			pushers = append(pushers, pusher)
+			pusherFactory.CreatePusherCall.Returns.Pushers = append(pusherFactory.CreatePusherCall.Returns.Pushers, pusher)
+			pusherFactory.CreatePusherCall.Returns.Error = append(pusherFactory.CreatePusherCall.Returns.Error, nil)
+		}
+	})
+
+	Context("when the environment has no foundations", func() {
+		It("returns an error without creating any pushers", func() {
+			environment.Foundations = nil
+
+			_, err := rollingGreener.Push(ctx, environment, appPath, deploymentInfo, response)
+
+			Expect(err).To(MatchError(NoFoundationsError{environment.Name}))
+			Expect(pusherFactory.CreatePusherCall.TimesCalled).To(Equal(0))
+		})
+	})
+
+	Context("when every foundation pushes in place", func() {
+		It("pushes in place to every foundation without a venerable app", func() {
+			_, err := rollingGreener.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, pusher := range pushers {
+				Expect(pusher.PushInPlaceCall.Received.AppPath).To(Equal(appPath))
+				Expect(pusher.PushInPlaceCall.Received.DeploymentInfo).To(Equal(deploymentInfo))
+				Expect(pusher.PushCall.Received.AppPath).To(BeEmpty())
+				Expect(pusher.DeleteVenerableCall.Received.DeploymentInfo).To(Equal(S.DeploymentInfo{}))
+			}
+
+			Expect(response).To(Say(pushOutput))
+		})
+
+		It("emits a deploy.progress event for every foundation", func() {
+			rollingGreener.Push(ctx, environment, appPath, deploymentInfo, response)
+
+			var types []string
+			for _, event := range eventManager.EmitCall.Received.Events {
+				types = append(types, event.Type)
+			}
+			Expect(types).To(Equal([]string{"deploy.progress", "deploy.progress"}))
+		})
+	})
+
+	Context("when the deploy has a smoke test", func() {
+		BeforeEach(func() {
+			deploymentInfo.SmokeTestURL = "https://" + deploymentInfo.AppName + ".example.com/health"
+		})
+
+		It("emits a deploy.smoketest event after pushing in place to every foundation", func() {
+			_, err := rollingGreener.Push(ctx, environment, appPath, deploymentInfo, response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(eventManager.EmitCall.Received.Events).To(HaveLen(3))
+			Expect(eventManager.EmitCall.Received.Events[2].Type).To(Equal("deploy.smoketest"))
+		})
+
+		It("fails the deploy when the smoke test fails", func() {
+			eventManager.EmitCall.Returns.Error = []error{nil, nil, errors.New("smoke test error")}
+
+			_, err := rollingGreener.Push(ctx, environment, appPath, deploymentInfo, response)
+
+			Expect(err).To(MatchError(RollingSmokeTestFailError{errors.New("smoke test error")}))
+		})
+	})
+
+	Context("when the deploy is cancelled", func() {
+		It("does not push in place to any foundation", func() {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithCancel(ctx)
+			cancel()
+
+			_, err := rollingGreener.Push(ctx, environment, appPath, deploymentInfo, response)
+
+			Expect(err).To(Equal(context.Canceled))
+
+			for _, pusher := range pushers {
+				Expect(pusher.PushInPlaceCall.Received.AppPath).To(BeEmpty())
+			}
+		})
+	})
+
+	Context("when a foundation fails to push in place", func() {
+		It("returns an error without rolling back any foundation", func() {
+			pushers[0].PushInPlaceCall.Returns.Error = errors.New("push in place failed")
+
+			_, err := rollingGreener.Push(ctx, environment, appPath, deploymentInfo, response)
+
+			Expect(err).To(BeAssignableToTypeOf(RollingPushFailError{}))
+			Expect(err.Error()).To(ContainSubstring("push in place failed"))
+
+			for _, pusher := range pushers {
+				Expect(pusher.RollbackCall.Received.DeploymentInfo).To(Equal(S.DeploymentInfo{}))
+			}
+		})
+	})
+})
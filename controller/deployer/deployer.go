@@ -2,20 +2,41 @@
 package deployer
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"path"
 	"regexp"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/compozed/deployadactyl/artifetcher"
+	"github.com/compozed/deployadactyl/clock"
 	"github.com/compozed/deployadactyl/config"
 	"github.com/compozed/deployadactyl/controller/deployer/manifestro"
+	"github.com/compozed/deployadactyl/controller/deployer/orgspace"
+	"github.com/compozed/deployadactyl/eventmanager"
+	"github.com/compozed/deployadactyl/failureinjection"
 	"github.com/compozed/deployadactyl/geterrors"
 	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/logger"
 	S "github.com/compozed/deployadactyl/structs"
 	"github.com/op/go-logging"
 	"github.com/spf13/afero"
+	"golang.org/x/net/context"
+)
+
+// The strategies a deploy can be pushed with.
+const (
+	BlueGreenStrategy = "bluegreen"
+	RollingStrategy   = "rolling"
 )
 
 const (
@@ -24,6 +45,14 @@ If you experience any problems after this point, check that you can manually pus
 It is likely that it is an error with your application and not with Deployadactyl.
 Thanks for using Deployadactyl! Please push down pull up on your lap bar and exit to your left.`
 
+	preflightProbe = "deployadactyl preflight"
+
+	defaultManifestPath = "manifest.yml"
+
+	successfulDryRun = "Your dry run passed! The foundations are up, the artifact was fetched and the manifest is valid. Nothing was pushed."
+
+	alreadyDeployed = "Your application is already deployed, skipping. An identical deploy completed recently. Set force to deploy it again."
+
 	deploymentOutput = `Deployment Parameters:
 Artifact URL: %s,
 Username:     %s,
@@ -33,35 +62,78 @@ Space:        %s,
 AppName:      %s`
 )
 
+// validHostname matches a single DNS label, the host of a route.
+var validHostname = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
 // Deployer contains the bluegreener for deployments, environment variables, a fetcher for artifacts, a prechecker and event manager.
+// Every deploy is recorded in the Metrics when they are provided.
+// The RollingGreener deploys the requests with the rolling strategy.
+// When Fingerprints are provided a deploy identical to one that completed recently is skipped.
+// When DeployLogs are provided the output of every deploy is also written to its deploy log.
+// The Clock times every deploy. The time package is used when it is nil.
 type Deployer struct {
-	Config       config.Config
-	BlueGreener  I.BlueGreener
-	Fetcher      I.Fetcher
-	Prechecker   I.Prechecker
-	EventManager I.EventManager
-	Randomizer   I.Randomizer
-	Log          *logging.Logger
-	FileSystem   *afero.Afero
+	Config         config.Config
+	BlueGreener    I.BlueGreener
+	Fetcher        I.Fetcher
+	Prechecker     I.Prechecker
+	EventManager   I.EventManager
+	Randomizer     I.Randomizer
+	Log            *logging.Logger
+	FileSystem     *afero.Afero
+	Metrics        I.Metrics
+	RollingGreener I.BlueGreener
+	Fingerprints   I.Fingerprints
+	DeployLogs     I.DeployLogs
+	Clock          I.Clock
 }
 
 // Deploy takes the deployment information, checks the foundations, fetches the artifact and deploys the application.
-func (d Deployer) Deploy(req *http.Request, environment, org, space, appName, contentType string, response io.Writer) (statusCode int, err error) {
+// An org or space that is empty in the URL is taken from the JSON request body. If it is still empty it is rendered from the templates of the environment.
+// A dry run stops before pushing the application.
+// When ctx is done during the push the in-flight cf commands are killed and a deploy.cancelled event is emitted.
+// Log lines are prefixed with the request id in the X-Request-Id header of the request.
+//
+// Returns the org and space the deploy was resolved to, which are the ones in the URL until they have been resolved.
+func (d Deployer) Deploy(ctx context.Context, req *http.Request, environment, org, space, appName, contentType string, response io.Writer) (S.DeployTarget, int, error) {
+	target := S.DeployTarget{Org: org, Space: space}
+	statusCode, err := d.deploy(ctx, req, environment, org, space, appName, contentType, response, &target)
+	return target, statusCode, err
+}
+
+// deploy runs the deploy of Deploy and sets target to the org and space once they have been resolved.
+func (d Deployer) deploy(ctx context.Context, req *http.Request, environment, org, space, appName, contentType string, response io.Writer, target *S.DeployTarget) (statusCode int, err error) {
 	var (
 		deploymentInfo         = S.DeploymentInfo{}
 		environments           = d.Config.Environments
 		authenticationRequired = environments[environment].Authenticate
 		deployEventData        = S.DeployEventData{}
+		requestID              = req.Header.Get(logger.RequestIDHeader)
 		manifest               []byte
 		appPath                string
 	)
 	defer func() { d.FileSystem.RemoveAll(appPath) }()
 
-	d.Log.Debug("prechecking the foundations")
-	err = d.Prechecker.AssertAllFoundationsUp(environments[environment])
+	startTime := d.clock().Now()
+	defer func() { d.recordMetrics(environment, startTime, err) }()
+
+	// requestedApp identifies the application in the deploy.error events emitted before deploymentInfo is filled in.
+	requestedApp := S.DeploymentInfo{Environment: environment, Org: org, Space: space, AppName: appName}
+
+	d.Log = logger.WithRequestID(d.Log, requestID)
+
+	if d.DeployLogs != nil {
+		var closeLog func()
+		response, closeLog = d.logOutput(requestID, response)
+		defer closeLog()
+	}
+
+	injectFailure, err := failureinjection.Stage(req, d.Config.EnableFailureInjection)
 	if err != nil {
 		fmt.Fprintln(response, err)
-		return http.StatusInternalServerError, err
+		return http.StatusBadRequest, err
+	}
+	if injectFailure != "" {
+		d.Log.Warningf("injecting a failure into the %s stage", injectFailure)
 	}
 
 	d.Log.Debug("checking for basic auth")
@@ -70,8 +142,7 @@ func (d Deployer) Deploy(req *http.Request, environment, org, space, appName, co
 		if authenticationRequired {
 			return http.StatusUnauthorized, BasicAuthError{}
 		}
-		username = d.Config.Username
-		password = d.Config.Password
+		username, password = getCredentials(d.Config, environments[environment])
 	}
 
 	if isJSON(contentType) {
@@ -80,9 +151,43 @@ func (d Deployer) Deploy(req *http.Request, environment, org, space, appName, co
 		deploymentInfo, err = getDeploymentInfo(req.Body)
 		if err != nil {
 			fmt.Fprintln(response, err)
+			switch err.(type) {
+			case DockerImageSourceError, RawManifestSourceError:
+				return http.StatusBadRequest, err
+			}
 			return http.StatusInternalServerError, err
 		}
 
+		err = checkHealthCheck(deploymentInfo.HealthCheckPath, deploymentInfo.HealthCheckTimeout)
+		if err != nil {
+			fmt.Fprintln(response, err)
+			return http.StatusBadRequest, err
+		}
+
+		if deploymentInfo.StartupTimeout < 0 {
+			err = InvalidStartupTimeoutError{deploymentInfo.StartupTimeout}
+			fmt.Fprintln(response, err)
+			return http.StatusBadRequest, err
+		}
+
+		if deploymentInfo.Strategy != "" && deploymentInfo.Strategy != BlueGreenStrategy && deploymentInfo.Strategy != RollingStrategy {
+			err = InvalidStrategyError{deploymentInfo.Strategy}
+			fmt.Fprintln(response, err)
+			return http.StatusBadRequest, err
+		}
+
+		if deploymentInfo.Strategy == RollingStrategy && (deploymentInfo.AppNamePrefix != "" || deploymentInfo.AppNameSuffix != "") {
+			err = VersionedRollingError{}
+			fmt.Fprintln(response, err)
+			return http.StatusBadRequest, err
+		}
+
+		if (deploymentInfo.Manifest != "" || deploymentInfo.ManifestRaw != "") && deploymentInfo.ManifestURL != "" {
+			err = ManifestSourceError{}
+			fmt.Fprintln(response, err)
+			return http.StatusBadRequest, err
+		}
+
 		if deploymentInfo.Manifest != "" {
 			manifest, err = base64.StdEncoding.DecodeString(deploymentInfo.Manifest)
 			if err != nil {
@@ -91,85 +196,564 @@ func (d Deployer) Deploy(req *http.Request, environment, org, space, appName, co
 			}
 		}
 
-		appPath, err = d.Fetcher.Fetch(deploymentInfo.ArtifactURL, string(manifest))
-		if err != nil {
+		if deploymentInfo.ManifestRaw != "" {
+			manifest = []byte(deploymentInfo.ManifestRaw)
+		}
+
+		if deploymentInfo.ManifestURL != "" {
+			d.Log.Debug("fetching the manifest separately from the artifact")
+			var fetchedManifest string
+			fetchedManifest, err = d.Fetcher.FetchManifest(deploymentInfo.ManifestURL)
+			if err != nil {
+				fmt.Fprintln(response, err)
+				emitDeployError(d, requestedApp, S.ManifestStage, err, response)
+				return http.StatusInternalServerError, err
+			}
+			manifest = []byte(fetchedManifest)
+		}
+
+		if len(manifest) == 0 && deploymentInfo.OverrideMemory != "" {
+			err = MemoryOverrideError{}
 			fmt.Fprintln(response, err)
-			return http.StatusInternalServerError, err
+			return http.StatusBadRequest, err
 		}
 
-	} else if isZip(contentType) {
-		d.Log.Debug("deploying from zip request")
-		appPath, err = d.Fetcher.FetchZipFromRequest(req)
-		if err != nil {
-			return http.StatusInternalServerError, err
+		if len(manifest) == 0 && len(deploymentInfo.EnvironmentVariables) > 0 {
+			err = EnvironmentVariablesError{}
+			fmt.Fprintln(response, err)
+			return http.StatusBadRequest, err
 		}
 
-		manifest, _ = d.FileSystem.ReadFile(appPath + "/manifest.yml")
+		manifest, err = prepareManifest(manifest, deploymentInfo.OverrideInstances, deploymentInfo.OverrideMemory, deploymentInfo.EnvironmentVariables)
+		if err != nil {
+			fmt.Fprintln(response, err)
+			return http.StatusBadRequest, err
+		}
 
-		deploymentInfo.ArtifactURL = appPath
+	} else if isZip(contentType) {
+		d.Log.Debug("deploying from zip request")
+		deploymentInfo.ManifestPath = req.URL.Query().Get("manifest_path")
+		deploymentInfo.AppNamePrefix = req.URL.Query().Get("app_name_prefix")
+		deploymentInfo.AppNameSuffix = req.URL.Query().Get("app_name_suffix")
+		deploymentInfo.Hostname = req.URL.Query().Get("hostname")
 	} else {
 		return http.StatusBadRequest, InvalidContentTypeError{}
 	}
 
+	if deploymentInfo.Hostname != "" && !validHostname.MatchString(deploymentInfo.Hostname) {
+		err = InvalidHostnameError{deploymentInfo.Hostname}
+		fmt.Fprintln(response, err)
+		return http.StatusBadRequest, err
+	}
+
+	deploymentInfo.DryRun = deploymentInfo.DryRun || isDryRun(req)
+	deploymentInfo.Force = deploymentInfo.Force || isForced(req)
 	deploymentInfo.Username = username
 	deploymentInfo.Password = password
 	deploymentInfo.Environment = environment
+	org = d.fromPathOrBody("org", org, deploymentInfo.Org)
+	space = d.fromPathOrBody("space", space, deploymentInfo.Space)
+
 	deploymentInfo.Org = org
 	deploymentInfo.Space = space
 	deploymentInfo.AppName = appName
-	deploymentInfo.UUID = d.Randomizer.StringRunes(128)
+	deploymentInfo.UUID = d.Randomizer.UUID()
+	deploymentInfo.RequestID = requestID
 	deploymentInfo.SkipSSL = environments[environment].SkipSSL
-	deploymentInfo.Manifest = string(manifest)
+	if deploymentInfo.SkipSSLOverride != nil {
+		deploymentInfo.SkipSSL = *deploymentInfo.SkipSSLOverride
+	}
 	deploymentInfo.Domain = environments[environment].Domain
+	deploymentInfo.CustomDomains = environments[environment].CustomDomains
+	deploymentInfo.InjectFailure = injectFailure
+	deploymentInfo.TokenURL = environments[environment].TokenURL
+	deploymentInfo.ClientID = environments[environment].ClientID
+	deploymentInfo.ClientSecret = environments[environment].ClientSecret
+	deploymentInfo.Timeout = environments[environment].Timeout
+	deploymentInfo.LoginTimeout = environments[environment].LoginTimeout
+	deploymentInfo.Retention = environments[environment].Retention
+	if deploymentInfo.SmokeTestURL == "" {
+		deploymentInfo.SmokeTestURL = environments[environment].SmokeTestURL
+	}
+	if deploymentInfo.SmokeTestStatus == 0 {
+		deploymentInfo.SmokeTestStatus = environments[environment].SmokeTestStatus
+	}
+
+	e, found := environments[deploymentInfo.Environment]
+	if !found {
+		err = fmt.Errorf("environment not found: %s", deploymentInfo.Environment)
+		emitDeployError(d, deploymentInfo, S.EnvironmentStage, err, response)
+		fmt.Fprintln(response, err)
+		return http.StatusInternalServerError, err
+	}
+
+	deploymentInfo.Org, deploymentInfo.Space, err = orgspace.Resolve(e, environment, org, space, appName)
+	if err != nil {
+		fmt.Fprintln(response, err)
+		return http.StatusBadRequest, err
+	}
+	*target = S.DeployTarget{Org: deploymentInfo.Org, Space: deploymentInfo.Space}
+
+	if deploymentInfo.Foundations != nil {
+		e.Foundations, err = selectFoundations(e, deploymentInfo.Foundations)
+		if err != nil {
+			fmt.Fprintln(response, err)
+			return http.StatusBadRequest, err
+		}
+	}
+
+	d.Log.Debug("prechecking the foundations")
+	if injectFailure == failureinjection.Precheck {
+		err = failureinjection.InjectedFailureError{Stage: injectFailure}
+	} else {
+		err = d.Prechecker.AssertAllFoundationsUp(e)
+	}
+	if err != nil {
+		fmt.Fprintln(response, err)
+		emitDeployError(d, deploymentInfo, S.PrecheckStage, err, response)
+		return http.StatusInternalServerError, err
+	}
+
+	if e.PreflightPush && !deploymentInfo.DryRun {
+		statusCode, err = d.preflight(ctx, e, deploymentInfo, response)
+		if err != nil {
+			emitDeployError(d, deploymentInfo, S.PreflightStage, err, response)
+			return statusCode, err
+		}
+	}
+
+	if isJSON(contentType) {
+		if injectFailure == failureinjection.Fetch {
+			err = failureinjection.InjectedFailureError{Stage: injectFailure}
+		} else if deploymentInfo.DockerImage != "" {
+			d.Log.Debugf("deploying docker image %s without fetching an artifact", deploymentInfo.DockerImage)
+			appPath, err = d.writeManifest(manifest)
+		} else {
+			appPath, err = d.Fetcher.Fetch(deploymentInfo, string(manifest), response)
+		}
+		if err != nil {
+			fmt.Fprintln(response, err)
+			emitDeployError(d, deploymentInfo, S.FetchStage, err, response)
+			if _, ok := err.(artifetcher.ChecksumMismatchError); ok {
+				return http.StatusBadRequest, err
+			}
+			return http.StatusInternalServerError, err
+		}
+	} else {
+		if injectFailure == failureinjection.Fetch {
+			err = failureinjection.InjectedFailureError{Stage: injectFailure}
+		} else {
+			appPath, err = d.Fetcher.FetchFromZip(req.Body)
+		}
+		if err != nil {
+			emitDeployError(d, deploymentInfo, S.FetchStage, err, response)
+			return http.StatusInternalServerError, err
+		}
+
+		var manifestPath string
+		manifest, manifestPath, err = d.readManifest(appPath, deploymentInfo.ManifestPath)
+		if err != nil {
+			fmt.Fprintln(response, err)
+			return http.StatusBadRequest, err
+		}
+
+		var preparedManifest []byte
+		preparedManifest, err = prepareManifest(manifest, nil, "", nil)
+		if err != nil {
+			fmt.Fprintln(response, err)
+			return http.StatusBadRequest, err
+		}
+
+		if !bytes.Equal(preparedManifest, manifest) || manifestPath != defaultManifestPath {
+			err = d.FileSystem.WriteFile(path.Join(appPath, defaultManifestPath), preparedManifest, 0644)
+			if err != nil {
+				fmt.Fprintln(response, err)
+				emitDeployError(d, deploymentInfo, S.ManifestStage, err, response)
+				return http.StatusInternalServerError, err
+			}
+			manifest = preparedManifest
+		}
+
+		deploymentInfo.ArtifactURL = appPath
+	}
+
+	deploymentInfo.Manifest = string(manifest)
 
 	instances := manifestro.GetInstances(deploymentInfo.Manifest)
 	if instances != nil {
 		deploymentInfo.Instances = *instances
+	} else if deploymentInfo.OverrideInstances != nil {
+		deploymentInfo.Instances = *deploymentInfo.OverrideInstances
 	} else {
 		deploymentInfo.Instances = environments[environment].Instances
 	}
 
-	e, found := environments[deploymentInfo.Environment]
-	if !found {
-		err = d.EventManager.Emit(S.Event{Type: "deploy.error", Data: deployEventData})
-		if err != nil {
+	missingEnvVars := getMissingEnvVars(e.RequiredEnvVars, deploymentInfo.Manifest)
+	if len(missingEnvVars) > 0 {
+		err = MissingEnvVarsError{missingEnvVars}
+		fmt.Fprintln(response, err)
+		return http.StatusBadRequest, err
+	}
+
+	appNames := manifestro.GetAppNames(deploymentInfo.Manifest)
+	if len(appNames) > 1 && deploymentInfo.Strategy == RollingStrategy {
+		err = MultipleAppsRollingError{}
+		fmt.Fprintln(response, err)
+		return http.StatusBadRequest, err
+	}
+	if len(appNames) > 1 && deploymentInfo.Hostname != "" {
+		err = MultipleAppsHostnameError{}
+		fmt.Fprintln(response, err)
+		return http.StatusBadRequest, err
+	}
+
+	if len(appNames) <= 1 && manifestro.GetNoRoute(deploymentInfo.Manifest) {
+		deploymentInfo.NoRoute = true
+	}
+
+	if e.MaxRoutesPerApp > 0 {
+		routes := getRoutes(deploymentInfo)
+		if len(routes) > e.MaxRoutesPerApp {
+			err = TooManyRoutesError{len(routes), e.MaxRoutesPerApp}
 			fmt.Fprintln(response, err)
+			return http.StatusBadRequest, err
 		}
+	}
 
-		err = fmt.Errorf("environment not found: %s", deploymentInfo.Environment)
-		fmt.Fprintln(response, err)
-		return http.StatusInternalServerError, err
+	artifact := deploymentInfo.ArtifactURL
+	if deploymentInfo.DockerImage != "" {
+		artifact = deploymentInfo.DockerImage
 	}
 
-	deploymentMessage := fmt.Sprintf(deploymentOutput, deploymentInfo.ArtifactURL, deploymentInfo.Username, deploymentInfo.Environment, deploymentInfo.Org, deploymentInfo.Space, deploymentInfo.AppName)
+	deploymentMessage := fmt.Sprintf(deploymentOutput, artifact, deploymentInfo.Username, deploymentInfo.Environment, deploymentInfo.Org, deploymentInfo.Space, deploymentInfo.AppName)
 	d.Log.Info(deploymentMessage)
 	fmt.Fprintln(response, deploymentMessage)
 
+	if len(appNames) > 1 {
+		appsMessage := fmt.Sprintf("Applications: %s", strings.Join(appNames, ", "))
+		d.Log.Info(appsMessage)
+		fmt.Fprintln(response, appsMessage)
+	}
+
+	if len(deploymentInfo.EnvironmentVariables) > 0 {
+		envVarsMessage := fmt.Sprintf("Environment Variables: %s", strings.Join(getEnvVarNames(deploymentInfo.EnvironmentVariables), ", "))
+		d.Log.Info(envVarsMessage)
+		fmt.Fprintln(response, envVarsMessage)
+	}
+
+	if deploymentInfo.Foundations != nil {
+		foundationsMessage := fmt.Sprintf("Foundations:  %s", strings.Join(e.Foundations, ", "))
+		d.Log.Info(foundationsMessage)
+		fmt.Fprintln(response, foundationsMessage)
+	}
+
+	if deploymentInfo.SkipSSLOverride != nil {
+		skipSSLMessage := fmt.Sprintf("Skip SSL:     %t, overriding %t of the environment for this deploy", deploymentInfo.SkipSSL, e.SkipSSL)
+		d.Log.Info(skipSSLMessage)
+		fmt.Fprintln(response, skipSSLMessage)
+	}
+
 	deployEventData = S.DeployEventData{Writer: response, DeploymentInfo: &deploymentInfo, RequestBody: req.Body}
 
+	if deploymentInfo.DryRun {
+		return d.dryRun(deployEventData, response)
+	}
+
+	var fingerprint string
+	if d.Fingerprints != nil {
+		fingerprint, err = d.fingerprint(appPath, deploymentInfo, e.Foundations)
+		if err != nil {
+			fmt.Fprintln(response, err)
+			return http.StatusInternalServerError, err
+		}
+
+		if !deploymentInfo.Force && d.Fingerprints.Seen(fingerprint) {
+			d.Log.Infof("skipping deploy of %s: an identical deploy completed recently", deploymentInfo.AppName)
+			fmt.Fprintf(response, "\n%s\n", alreadyDeployed)
+			return http.StatusOK, nil
+		}
+	}
+
 	defer emitDeployFinish(d, deployEventData, response, &err, &statusCode)
 
 	d.Log.Debug("emitting a deploy.start event")
 	err = d.EventManager.Emit(S.Event{Type: "deploy.start", Data: deployEventData})
 	if err != nil {
+		if veto, ok := eventmanager.FindVeto(err); ok {
+			d.Log.Infof("a deploy.start handler vetoed the deploy with %d: %s", veto.StatusCode, veto.Message)
+			fmt.Fprintln(response, veto.Message)
+			return veto.StatusCode, veto
+		}
+
 		fmt.Fprintln(response, err)
 		return http.StatusInternalServerError, EventError{"deploy.start", err}
 	}
 
-	defer emitDeploySuccess(d, deployEventData, response, &err, &statusCode)
+	defer emitDeploySuccess(d, &deployEventData, response, &err, &statusCode)
+
+	blueGreener := d.BlueGreener
+	if deploymentInfo.Strategy == RollingStrategy {
+		d.Log.Debug("deploying with the rolling strategy")
+		blueGreener = d.RollingGreener
+	}
 
-	err = d.BlueGreener.Push(e, appPath, deploymentInfo, response)
+	appGUIDs, err := blueGreener.Push(ctx, e, appPath, deploymentInfo, response)
 	if err != nil {
+		if ctx.Err() != nil {
+			err = DeployCancelledError{err}
+			emitDeployCancelled(d, deployEventData, response)
+			return http.StatusInternalServerError, err
+		}
 		if matched, _ := regexp.MatchString("login failed", err.Error()); matched {
 			return http.StatusBadRequest, err
 		}
 		return http.StatusInternalServerError, err
 	}
 
+	deployEventData.AppGUIDs = appGUIDs
+	printAppGUIDs(response, e.Foundations, appNames, appGUIDs)
+	if len(appNames) <= 1 {
+		printRouteURLs(response, deploymentInfo)
+	}
+
+	if d.Fingerprints != nil {
+		d.Fingerprints.Add(fingerprint)
+	}
+
 	fmt.Fprintf(response, "\n%s", successfulDeploy)
 	return http.StatusOK, err
 }
 
+// preflight pushes a minimal application that is never started to every foundation of the environment to make sure
+// the deploy has write access to the space before the real artifact is fetched. A dry run does not push the probe.
+func (d Deployer) preflight(ctx context.Context, environment config.Environment, deploymentInfo S.DeploymentInfo, response io.Writer) (int, error) {
+	d.Log.Debug("checking write access to the foundations")
+
+	probePath, err := d.FileSystem.TempDir("", "deployadactyl-preflight-")
+	if err != nil {
+		fmt.Fprintln(response, err)
+		return http.StatusInternalServerError, err
+	}
+	defer d.FileSystem.RemoveAll(probePath)
+
+	err = d.FileSystem.WriteFile(probePath+"/index.html", []byte(preflightProbe), 0644)
+	if err != nil {
+		fmt.Fprintln(response, err)
+		return http.StatusInternalServerError, err
+	}
+
+	err = d.BlueGreener.Preflight(ctx, environment, probePath, deploymentInfo, response)
+	if err != nil {
+		fmt.Fprintln(response, err)
+		if matched, _ := regexp.MatchString("login failed", err.Error()); matched {
+			return http.StatusBadRequest, err
+		}
+		return http.StatusForbidden, err
+	}
+
+	return http.StatusOK, nil
+}
+
+// logOutput starts the deploy log of the request id.
+// A deploy that cannot be logged is deployed without a log.
+//
+// Returns a writer to both response and the log and a function that closes the log.
+func (d Deployer) logOutput(requestID string, response io.Writer) (io.Writer, func()) {
+	deployLog, err := d.DeployLogs.Create(requestID)
+	if err != nil {
+		d.Log.Errorf("deploying without a deploy log: %s", err)
+		return response, func() {}
+	}
+
+	closeLog := func() {
+		err := deployLog.Close()
+		if err != nil {
+			d.Log.Errorf("the deploy log is incomplete: %s", err)
+		}
+	}
+
+	return io.MultiWriter(response, deployLog), closeLog
+}
+
+// recordMetrics records the result and the duration of a deploy that started at startTime.
+func (d Deployer) recordMetrics(environment string, startTime time.Time, err error) {
+	if d.Metrics == nil {
+		return
+	}
+
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+
+	d.Metrics.RecordDeploy(environment, result, d.clock().Now().Sub(startTime))
+}
+
+func (d Deployer) clock() I.Clock {
+	if d.Clock == nil {
+		return clock.Clock{}
+	}
+	return d.Clock
+}
+
+// writeManifest writes the manifest into a new temporary directory that a docker image is pushed from.
+// The directory is left empty when there is no manifest.
+func (d Deployer) writeManifest(manifest []byte) (string, error) {
+	appPath, err := d.FileSystem.TempDir("", "deployadactyl-docker-")
+	if err != nil {
+		return "", err
+	}
+
+	if len(manifest) > 0 {
+		err = d.FileSystem.WriteFile(appPath+"/manifest.yml", manifest, 0644)
+		if err != nil {
+			d.FileSystem.RemoveAll(appPath)
+			return "", err
+		}
+	}
+
+	return appPath, nil
+}
+
+// readManifest reads the manifest at manifestPath in the extracted zip at appPath, or manifest.yml when manifestPath is empty.
+// A zip without a manifest.yml is deployed without a manifest, but a manifestPath that is given must exist.
+//
+// Returns the manifest and the cleaned path it was read from.
+func (d Deployer) readManifest(appPath, manifestPath string) ([]byte, string, error) {
+	if manifestPath == "" {
+		manifest, _ := d.FileSystem.ReadFile(path.Join(appPath, defaultManifestPath))
+		return manifest, defaultManifestPath, nil
+	}
+
+	cleanPath := path.Clean(manifestPath)
+	if path.IsAbs(cleanPath) || cleanPath == ".." || strings.HasPrefix(cleanPath, "../") {
+		return nil, "", InvalidManifestPathError{manifestPath}
+	}
+
+	manifest, err := d.FileSystem.ReadFile(path.Join(appPath, cleanPath))
+	if err != nil {
+		return nil, "", ManifestNotFoundError{cleanPath}
+	}
+
+	return manifest, cleanPath, nil
+}
+
+// selectFoundations returns the foundations of the environment that are in selected, in the order they are configured.
+// Returns an error when nothing is selected or a selected foundation is not one of the environment.
+func selectFoundations(environment config.Environment, selected []string) ([]string, error) {
+	if len(selected) == 0 {
+		return nil, NoFoundationsSelectedError{}
+	}
+
+	configured := make(map[string]bool, len(environment.Foundations))
+	for _, foundation := range environment.Foundations {
+		configured[foundation] = true
+	}
+
+	wanted := make(map[string]bool, len(selected))
+	for _, foundation := range selected {
+		if !configured[foundation] {
+			return nil, UnknownFoundationError{foundation, environment.Name}
+		}
+		wanted[foundation] = true
+	}
+
+	var foundations []string
+	for _, foundation := range environment.Foundations {
+		if wanted[foundation] {
+			foundations = append(foundations, foundation)
+		}
+	}
+
+	return foundations, nil
+}
+
+// fingerprint returns a checksum of the environment, org, space and application name of the deploy,
+// the foundations it is pushed to, its strategy, its docker image and every file that is pushed from appPath, including the manifest.
+func (d Deployer) fingerprint(appPath string, deploymentInfo S.DeploymentInfo, foundations []string) (string, error) {
+	strategy := deploymentInfo.Strategy
+	if strategy == "" {
+		strategy = BlueGreenStrategy
+	}
+
+	sortedFoundations := append([]string{}, foundations...)
+	sort.Strings(sortedFoundations)
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%s\x00%s\x00%s\x00", deploymentInfo.Environment, deploymentInfo.Org, deploymentInfo.Space, deploymentInfo.PushedAppName(), deploymentInfo.DockerImage)
+	fmt.Fprintf(hash, "%s\x00%s\x00", strings.Join(sortedFoundations, "\x01"), strategy)
+
+	err := d.FileSystem.Walk(appPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		contents, err := d.FileSystem.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(hash, "%s\x00%d\x00", strings.TrimPrefix(filePath, appPath), len(contents))
+		hash.Write(contents)
+		return nil
+	})
+	if err != nil {
+		return "", FingerprintError{err}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (d Deployer) dryRun(deployEventData S.DeployEventData, response io.Writer) (int, error) {
+	d.Log.Debug("emitting a deploy.dryrun event")
+	err := d.EventManager.Emit(S.Event{Type: "deploy.dryrun", Data: deployEventData})
+	if err != nil {
+		fmt.Fprintln(response, err)
+		return http.StatusInternalServerError, EventError{"deploy.dryrun", err}
+	}
+
+	fmt.Fprintf(response, "\n%s\n", successfulDryRun)
+	return http.StatusOK, nil
+}
+
+// getCredentials returns the credentials of the environment when it has its own and the global credentials when it does not.
+func getCredentials(c config.Config, environment config.Environment) (string, string) {
+	if environment.Username != "" {
+		return environment.Username, environment.Password
+	}
+	return c.Username, c.Password
+}
+
+// checkHealthCheck returns an error when the health check path is not absolute or the timeout is not a positive duration.
+func checkHealthCheck(path, timeout string) error {
+	if path != "" && !strings.HasPrefix(path, "/") {
+		return InvalidHealthCheckPathError{path}
+	}
+
+	if timeout != "" {
+		duration, err := time.ParseDuration(timeout)
+		if err != nil || duration <= 0 {
+			return InvalidHealthCheckTimeoutError{timeout}
+		}
+	}
+
+	return nil
+}
+
+// fromPathOrBody returns the org or space of the URL, or the one in the request body when the URL has none.
+// The URL wins when they conflict, which is logged as a warning.
+func (d Deployer) fromPathOrBody(name, path, body string) string {
+	if path == "" {
+		return body
+	}
+
+	if body != "" && body != path {
+		d.Log.Warningf("the %s %s in the request body conflicts with the %s %s in the URL, using %s", name, body, name, path, path)
+	}
+
+	return path
+}
+
 func getDeploymentInfo(reader io.Reader) (S.DeploymentInfo, error) {
 	deploymentInfo := S.DeploymentInfo{}
 	err := json.NewDecoder(reader).Decode(&deploymentInfo)
@@ -177,6 +761,17 @@ func getDeploymentInfo(reader io.Reader) (S.DeploymentInfo, error) {
 		return deploymentInfo, err
 	}
 
+	if deploymentInfo.Manifest != "" && deploymentInfo.ManifestRaw != "" {
+		return S.DeploymentInfo{}, RawManifestSourceError{}
+	}
+
+	if deploymentInfo.DockerImage != "" {
+		if deploymentInfo.ArtifactURL != "" {
+			return S.DeploymentInfo{}, DockerImageSourceError{}
+		}
+		return deploymentInfo, nil
+	}
+
 	getter := geterrors.WrapFunc(func(key string) string {
 		if key == "artifact_url" {
 			return deploymentInfo.ArtifactURL
@@ -193,6 +788,154 @@ func getDeploymentInfo(reader io.Reader) (S.DeploymentInfo, error) {
 	return deploymentInfo, nil
 }
 
+// prepareManifest checks a manifest when one is given, applies the instances and memory overrides to every
+// application, merges in the env vars of the request and normalizes the units of its memory and disk_quota.
+// A manifest is optional.
+func prepareManifest(manifest []byte, instances *uint16, memory string, envVars map[string]string) ([]byte, error) {
+	if len(manifest) == 0 {
+		return manifest, nil
+	}
+
+	err := manifestro.Validate(string(manifest))
+	if err != nil {
+		return nil, InvalidManifestError{err}
+	}
+
+	overridden, err := manifestro.Override(string(manifest), instances, memory)
+	if err != nil {
+		return nil, InvalidManifestError{err}
+	}
+
+	overridden, err = manifestro.SetEnvVars(overridden, envVars)
+	if err != nil {
+		return nil, InvalidManifestError{err}
+	}
+
+	normalized, err := manifestro.NormalizeUnits(overridden)
+	if err != nil {
+		return nil, InvalidManifestError{err}
+	}
+
+	return []byte(normalized), nil
+}
+
+// getEnvVarNames returns the sorted names of envVars so they can be shown without their values.
+func getEnvVarNames(envVars map[string]string) []string {
+	names := make([]string, 0, len(envVars))
+	for name := range envVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func getMissingEnvVars(required []string, manifest string) []string {
+	if len(required) == 0 {
+		return nil
+	}
+
+	envVars := manifestro.GetEnvVars(manifest)
+
+	var missing []string
+	for _, name := range required {
+		if _, ok := envVars[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+
+	return missing
+}
+
+// getRoutes returns the unique routes the application will have after it is pushed.
+// These are the routes declared in the manifest and the routes that are always mapped to the domain and custom domains of the environment.
+// An application without a route has none. The routes of the manifest are not mapped when the deployment has a Hostname.
+func getRoutes(deploymentInfo S.DeploymentInfo) []string {
+	routes := []string{}
+	if deploymentInfo.NoRoute {
+		return routes
+	}
+
+	seen := map[string]bool{}
+
+	mappedRoutes := []string{}
+	if deploymentInfo.Hostname == "" {
+		mappedRoutes = manifestro.GetRoutes(deploymentInfo.Manifest)
+	}
+	for _, domain := range append([]string{deploymentInfo.Domain}, deploymentInfo.CustomDomains...) {
+		mappedRoutes = append(mappedRoutes, deploymentInfo.RouteHostname()+"."+domain)
+	}
+
+	for _, route := range mappedRoutes {
+		if !seen[route] {
+			seen[route] = true
+			routes = append(routes, route)
+		}
+	}
+
+	return routes
+}
+
+func printAppGUIDs(response io.Writer, foundations, appNames []string, appGUIDs map[string]string) {
+	if len(appGUIDs) == 0 {
+		return
+	}
+
+	fmt.Fprintln(response, "\nApplication GUIDs:")
+	if len(appNames) > 1 {
+		for _, appName := range appNames {
+			for _, foundationURL := range foundations {
+				fmt.Fprintf(response, "%s on %s: %s\n", appName, foundationURL, appGUIDs[S.AppGUIDKey(foundationURL, appName)])
+			}
+		}
+		return
+	}
+
+	for _, foundationURL := range foundations {
+		fmt.Fprintf(response, "%s: %s\n", foundationURL, appGUIDs[foundationURL])
+	}
+}
+
+// printRouteURLs writes the URLs the deployed application can be reached on, so that the user can check it.
+// They are the routes declared in the manifest. When there are none the host of the manifest, or the app name when it has no host,
+// is used on the domain and custom domains of the environment.
+// A deployment with a Hostname is only reachable on that host, since it is pushed without the routes of the manifest.
+func printRouteURLs(response io.Writer, deploymentInfo S.DeploymentInfo) {
+	if deploymentInfo.NoRoute {
+		return
+	}
+
+	var routes []string
+	if deploymentInfo.Hostname == "" {
+		routes = manifestro.GetRoutes(deploymentInfo.Manifest)
+	}
+
+	if len(routes) == 0 {
+		host := deploymentInfo.Hostname
+		if host == "" {
+			host = manifestro.GetHost(deploymentInfo.Manifest)
+		}
+		if host == "" {
+			host = deploymentInfo.AppName
+		}
+
+		for _, domain := range append([]string{deploymentInfo.Domain}, deploymentInfo.CustomDomains...) {
+			if domain != "" {
+				routes = append(routes, host+"."+domain)
+			}
+		}
+	}
+
+	if len(routes) == 0 {
+		return
+	}
+
+	fmt.Fprintln(response, "\nApplication URLs:")
+	for _, route := range routes {
+		fmt.Fprintf(response, "https://%s\n", route)
+	}
+}
+
 func isZip(contentType string) bool {
 	return contentType == "application/zip"
 }
@@ -201,7 +944,21 @@ func isJSON(contentType string) bool {
 	return contentType == "application/json"
 }
 
+func isDryRun(req *http.Request) bool {
+	return req.URL.Query().Get("dry_run") == "true"
+}
+
+func isForced(req *http.Request) bool {
+	return req.URL.Query().Get("force") == "true"
+}
+
+// emitDeployFinish emits a deploy.finish event with the status code and error the deploy returns.
 func emitDeployFinish(d Deployer, deployEventData S.DeployEventData, response io.Writer, err *error, statusCode *int) {
+	deployEventData.StatusCode = *statusCode
+	if *err != nil {
+		deployEventData.Error = (*err).Error()
+	}
+
 	d.Log.Debug("emitting a deploy.finish event")
 
 	finishErr := d.EventManager.Emit(S.Event{Type: "deploy.finish", Data: deployEventData})
@@ -213,10 +970,37 @@ func emitDeployFinish(d Deployer, deployEventData S.DeployEventData, response io
 	}
 }
 
-func emitDeploySuccess(d Deployer, deployEventData S.DeployEventData, response io.Writer, err *error, statusCode *int) {
-	deployEvent := S.Event{Type: "deploy.success", Data: deployEventData}
+func emitDeployCancelled(d Deployer, deployEventData S.DeployEventData, response io.Writer) {
+	d.Log.Debug("emitting a deploy.cancelled event")
+	err := d.EventManager.Emit(S.Event{Type: "deploy.cancelled", Data: deployEventData})
+	if err != nil {
+		fmt.Fprintln(response, err)
+	}
+}
+
+// emitDeployError emits a deploy.error event for a deploy that failed in the stage before anything was pushed.
+func emitDeployError(d Deployer, deploymentInfo S.DeploymentInfo, stage string, deployErr error, response io.Writer) {
+	deployEventData := S.DeployEventData{
+		Writer:         response,
+		DeploymentInfo: &deploymentInfo,
+		Error:          deployErr.Error(),
+		Stage:          stage,
+	}
+
+	d.Log.Debugf("emitting a deploy.error event for the %s stage", stage)
+	err := d.EventManager.Emit(S.Event{Type: "deploy.error", Data: deployEventData})
+	if err != nil {
+		fmt.Fprintln(response, err)
+	}
+}
+
+func emitDeploySuccess(d Deployer, deployEventData *S.DeployEventData, response io.Writer, err *error, statusCode *int) {
+	deployEvent := S.Event{Type: "deploy.success", Data: *deployEventData}
 	if *err != nil {
-		deployEvent.Type = "deploy.failure"
+		failedEventData := *deployEventData
+		failedEventData.Error = (*err).Error()
+		failedEventData.Stage = S.PushStage
+		deployEvent = S.Event{Type: "deploy.failure", Data: failedEventData}
 	}
 
 	d.Log.Debug(fmt.Sprintf("emitting a %s event", deployEvent.Type))
@@ -5,11 +5,21 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/compozed/deployadactyl/artifetcher"
 	"github.com/compozed/deployadactyl/config"
 	. "github.com/compozed/deployadactyl/controller/deployer"
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen"
+	"github.com/compozed/deployadactyl/controller/deployer/manifestro"
+	"github.com/compozed/deployadactyl/deploylogs"
+	"github.com/compozed/deployadactyl/eventmanager"
+	"github.com/compozed/deployadactyl/failureinjection"
+	"github.com/compozed/deployadactyl/fingerprints"
 	"github.com/compozed/deployadactyl/logger"
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
@@ -19,6 +29,7 @@ import (
 	. "github.com/onsi/gomega/gbytes"
 	"github.com/op/go-logging"
 	"github.com/spf13/afero"
+	"golang.org/x/net/context"
 )
 
 const (
@@ -37,10 +48,12 @@ var _ = Describe("Deployer", func() {
 
 		c              config.Config
 		blueGreener    *mocks.BlueGreener
+		rollingGreener *mocks.BlueGreener
 		fetcher        *mocks.Fetcher
 		prechecker     *mocks.Prechecker
 		eventManager   *mocks.EventManager
 		randomizerMock *mocks.Randomizer
+		metrics        *mocks.Metrics
 
 		req                  *http.Request
 		requestBody          *bytes.Buffer
@@ -63,14 +76,17 @@ var _ = Describe("Deployer", func() {
 		deploymentInfo S.DeploymentInfo
 		foundations    []string
 		environments   = map[string]config.Environment{}
-		log            = logger.DefaultLogger(logBuffer, logging.DEBUG, "deployer tests")
+		log            = logger.DefaultLogger(logBuffer, logging.DEBUG, "deployer tests", logger.TextFormat)
 		af             *afero.Afero
+		ctx            context.Context
 	)
 
 	BeforeEach(func() {
 		blueGreener = &mocks.BlueGreener{}
+		rollingGreener = &mocks.BlueGreener{}
 		fetcher = &mocks.Fetcher{}
 		prechecker = &mocks.Prechecker{}
+		metrics = &mocks.Metrics{}
 		eventManager = &mocks.EventManager{}
 		randomizerMock = &mocks.Randomizer{}
 
@@ -84,12 +100,12 @@ var _ = Describe("Deployer", func() {
 		space = "space-" + randomizer.StringRunes(10)
 		username = "username-" + randomizer.StringRunes(10)
 		uuid = "uuid-" + randomizer.StringRunes(10)
-		manifest = "manifest-" + randomizer.StringRunes(10)
+		manifest = fmt.Sprintf("---\napplications:\n- name: manifest-%s\n", randomizer.StringRunes(10))
 		instances = uint16(rand.Uint32())
 
 		base64Manifest := base64.StdEncoding.EncodeToString([]byte(manifest))
 
-		randomizerMock.RandomizeCall.Returns.Runes = uuid
+		randomizerMock.UUIDCall.Returns.UUID = uuid
 		eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, nil)
 		eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, nil)
 		eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, nil)
@@ -121,6 +137,7 @@ var _ = Describe("Deployer", func() {
 
 		foundations = []string{randomizer.StringRunes(10)}
 		response = &bytes.Buffer{}
+		ctx = context.Background()
 
 		environments = map[string]config.Environment{}
 		environments[environment] = config.Environment{
@@ -149,6 +166,11 @@ var _ = Describe("Deployer", func() {
 			randomizerMock,
 			log,
 			af,
+			metrics,
+			rollingGreener,
+			nil,
+			nil,
+			nil,
 		}
 	})
 
@@ -157,12 +179,213 @@ var _ = Describe("Deployer", func() {
 			It("rejects the request with a http.StatusInternalServerError", func() {
 				prechecker.AssertAllFoundationsUpCall.Returns.Error = errors.New("prechecker failed")
 
-				statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
 				Expect(err).To(MatchError("prechecker failed"))
 
 				Expect(statusCode).To(Equal(http.StatusInternalServerError))
 				Expect(prechecker.AssertAllFoundationsUpCall.Received.Environment).To(Equal(environments[environment]))
 			})
+
+			It("emits a deploy.error event for the precheck stage", func() {
+				prechecker.AssertAllFoundationsUpCall.Returns.Error = errors.New("prechecker failed")
+
+				deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+
+				Expect(eventManager.EmitCall.Received.Events).To(HaveLen(1))
+				Expect(eventManager.EmitCall.Received.Events[0].Type).To(Equal("deploy.error"))
+
+				eventData := eventManager.EmitCall.Received.Events[0].Data.(S.DeployEventData)
+				Expect(eventData.Stage).To(Equal(S.PrecheckStage))
+				Expect(eventData.Error).To(Equal("prechecker failed"))
+				Expect(eventData.DeploymentInfo.Environment).To(Equal(environment))
+				Expect(eventData.DeploymentInfo.AppName).To(Equal(appName))
+			})
+		})
+	})
+
+	Describe("logging the deploy output", func() {
+		var (
+			deployLogs *deploylogs.DeployLogs
+			requestID  string
+		)
+
+		BeforeEach(func() {
+			var err error
+			deployLogs, err = deploylogs.New(af, "/logs", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			deployer.DeployLogs = deployLogs
+
+			requestID = "requestID-" + randomizer.StringRunes(10)
+			req.Header.Set("X-Request-Id", requestID)
+		})
+
+		It("writes the output of the deploy to the log of the request id", func() {
+			_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			deployLog, err := deployLogs.Open(requestID)
+			Expect(err).ToNot(HaveOccurred())
+			output, err := ioutil.ReadAll(deployLog)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(string(output)).To(Equal(response.String()))
+			Expect(string(output)).To(ContainSubstring(appName))
+			Expect(string(output)).To(ContainSubstring("deploy was successful"))
+		})
+
+		It("logs the output of a failed deploy", func() {
+			prechecker.AssertAllFoundationsUpCall.Returns.Error = errors.New("prechecker failed")
+
+			deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+
+			deployLog, err := deployLogs.Open(requestID)
+			Expect(err).ToNot(HaveOccurred())
+			output, err := ioutil.ReadAll(deployLog)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(string(output)).To(ContainSubstring("prechecker failed"))
+		})
+
+		It("deploys without a log when the log cannot be created", func() {
+			req.Header.Set("X-Request-Id", "")
+
+			_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statusCode).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("recording metrics", func() {
+		It("records a successful deploy to the environment", func() {
+			_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(metrics.RecordDeployCall.TimesCalled).To(Equal(1))
+			Expect(metrics.RecordDeployCall.Received.Environment).To(Equal(environment))
+			Expect(metrics.RecordDeployCall.Received.Result).To(Equal("success"))
+		})
+
+		It("records the duration of a failed deploy", func() {
+			prechecker.AssertAllFoundationsUpCall.Returns.Error = errors.New("prechecker failed")
+
+			_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).To(HaveOccurred())
+
+			Expect(metrics.RecordDeployCall.TimesCalled).To(Equal(1))
+			Expect(metrics.RecordDeployCall.Received.Result).To(Equal("failure"))
+			Expect(metrics.RecordDeployCall.Received.Duration).To(BeNumerically(">", 0))
+		})
+
+		It("times the deploy with the clock", func() {
+			clock := &mocks.Clock{}
+			clock.NowCall.Returns.Time = time.Now()
+			clock.NowCall.Returns.Step = 3 * time.Second
+			deployer.Clock = clock
+
+			_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(clock.NowCall.TimesCalled).To(Equal(2))
+			Expect(metrics.RecordDeployCall.Received.Duration).To(Equal(3 * time.Second))
+		})
+	})
+
+	Describe("passing on the request id", func() {
+		It("adds the request id to the deployment info and the log lines", func() {
+			requestID := "requestID-" + randomizer.StringRunes(10)
+			req.Header.Set("X-Request-Id", requestID)
+
+			_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.RequestID).To(Equal(requestID))
+			Eventually(logBuffer).Should(Say(`\[%s\] prechecking the foundations`, requestID))
+		})
+	})
+
+	Describe("passing on the retention", func() {
+		It("adds the retention of the environment to the deployment info", func() {
+			e := deployer.Config.Environments[environment]
+			e.Retention = 3
+			deployer.Config.Environments[environment] = e
+
+			_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.Retention).To(Equal(3))
+		})
+	})
+
+	Describe("injecting failures", func() {
+		BeforeEach(func() {
+			deployer.Config.EnableFailureInjection = true
+		})
+
+		Context("when the precheck stage is injected", func() {
+			It("fails the precheck without checking the foundations", func() {
+				req.Header.Set("X-Inject-Failure", "precheck")
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(failureinjection.InjectedFailureError{Stage: "precheck"}))
+
+				Expect(statusCode).To(Equal(http.StatusInternalServerError))
+				Expect(prechecker.AssertAllFoundationsUpCall.Received.Environment).To(Equal(config.Environment{}))
+				Expect(blueGreener.PushCall.Received.AppPath).To(BeEmpty())
+			})
+		})
+
+		Context("when the fetch stage is injected", func() {
+			It("fails the fetch without downloading the artifact", func() {
+				req.Header.Set("X-Inject-Failure", "fetch")
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(failureinjection.InjectedFailureError{Stage: "fetch"}))
+
+				Expect(statusCode).To(Equal(http.StatusInternalServerError))
+				Expect(fetcher.FetchCall.Received.DeploymentInfo.ArtifactURL).To(BeEmpty())
+				Expect(blueGreener.PushCall.Received.AppPath).To(BeEmpty())
+			})
+		})
+
+		Context("when the push stage is injected", func() {
+			It("passes the injected stage to the BlueGreener and emits a deploy.failure event", func() {
+				req.Header.Set("X-Inject-Failure", "push")
+
+				pushErr := bluegreen.PushFailRollbackError{Errs: []error{failureinjection.InjectedFailureError{Stage: "push"}}}
+				blueGreener.PushCall.Returns.Error = pushErr
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(pushErr))
+
+				Expect(statusCode).To(Equal(http.StatusInternalServerError))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.InjectFailure).To(Equal("push"))
+				Expect(eventManager.EmitCall.Received.Events[1].Type).To(Equal("deploy.failure"))
+			})
+		})
+
+		Context("when the injected stage is unknown", func() {
+			It("returns an error and http.StatusBadRequest", func() {
+				req.Header.Set("X-Inject-Failure", "bork")
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(failureinjection.InvalidStageError{Stage: "bork"}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("when failure injection is disabled", func() {
+			It("ignores the header and deploys", func() {
+				deployer.Config.EnableFailureInjection = false
+				req.Header.Set("X-Inject-Failure", "push")
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.InjectFailure).To(BeEmpty())
+			})
 		})
 	})
 
@@ -175,7 +398,7 @@ var _ = Describe("Deployer", func() {
 
 					By("not setting basic auth")
 
-					statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+					_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
 					Expect(err).ToNot(HaveOccurred())
 					Expect(statusCode).To(Equal(http.StatusOK))
 
@@ -185,13 +408,34 @@ var _ = Describe("Deployer", func() {
 				})
 			})
 
+			Context("when the environment has its own username and password", func() {
+				It("uses the environment username and password instead of the config ones", func() {
+					deployer.Config.Environments["prod"] = config.Environment{Name: "prod", Username: "prod-username", Password: "prod-password"}
+					deployer.Config.Environments["dev"] = config.Environment{Name: "dev"}
+
+					_, _, err := deployer.Deploy(ctx, req, "prod", org, space, appName, "application/json", response)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(blueGreener.PushCall.Received.DeploymentInfo.Username).To(Equal("prod-username"))
+					Expect(blueGreener.PushCall.Received.DeploymentInfo.Password).To(Equal("prod-password"))
+
+					req, _ = http.NewRequest("POST", "", bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s"}`, artifactURL)))
+
+					_, _, err = deployer.Deploy(ctx, req, "dev", org, space, appName, "application/json", response)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(blueGreener.PushCall.Received.DeploymentInfo.Username).To(Equal(username))
+					Expect(blueGreener.PushCall.Received.DeploymentInfo.Password).To(Equal(password))
+				})
+			})
+
 			Context("when authenticate in the config is true", func() {
 				It("rejects the request with a http.StatusUnauthorized", func() {
 					deployer.Config.Environments[environment] = config.Environment{Authenticate: true}
 
 					By("not setting basic auth")
 
-					statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+					_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
 					Expect(err).To(MatchError("basic auth header not found"))
 
 					Expect(statusCode).To(Equal(http.StatusUnauthorized))
@@ -209,7 +453,7 @@ var _ = Describe("Deployer", func() {
 
 				req, _ = http.NewRequest("POST", "", requestBody)
 
-				statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
 				Expect(err).To(MatchError("The following properties are missing: artifact_url"))
 
 				Expect(statusCode).To(Equal(http.StatusInternalServerError))
@@ -219,7 +463,7 @@ var _ = Describe("Deployer", func() {
 		Context("when manifest is given in the request body", func() {
 			Context("if the provided manifest is base64 encoded", func() {
 				It("decodes the manifest, does not return an error and returns http.StatusOK", func() {
-					deploymentInfo.Manifest = "manifest-" + randomizer.StringRunes(10)
+					deploymentInfo.Manifest = fmt.Sprintf("---\napplications:\n- name: manifest-%s\n", randomizer.StringRunes(10))
 
 					By("base64 encoding the manifest")
 					base64Manifest := base64.StdEncoding.EncodeToString([]byte(deploymentInfo.Manifest))
@@ -232,7 +476,7 @@ var _ = Describe("Deployer", func() {
 
 					req, _ = http.NewRequest("POST", "", requestBody)
 
-					statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+					_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
 					Expect(err).ToNot(HaveOccurred())
 
 					Expect(statusCode).To(Equal(http.StatusOK))
@@ -252,131 +496,1495 @@ var _ = Describe("Deployer", func() {
 						deploymentInfo.Manifest,
 					))
 
-					req, _ = http.NewRequest("POST", "", requestBody)
+					req, _ = http.NewRequest("POST", "", requestBody)
+
+					_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+					Expect(err.Error()).To(ContainSubstring("base64 encoded manifest could not be decoded"))
+
+					Expect(statusCode).To(Equal(http.StatusBadRequest))
+				})
+			})
+		})
+
+		Context("when a raw manifest is given in the request body", func() {
+			It("uses the manifest without decoding it", func() {
+				manifestAppName := "manifest-" + randomizer.StringRunes(10)
+				rawManifest := fmt.Sprintf("---\napplications:\n- name: %s\n", manifestAppName)
+
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest_raw": %q}`,
+					artifactURL,
+					rawManifest,
+				))
+
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(fetcher.FetchCall.Received.Manifest).To(ContainSubstring("name: " + manifestAppName))
+			})
+
+			Context("when a base64 encoded manifest is also given", func() {
+				It("returns an error and http.StatusBadRequest", func() {
+					rawManifest := fmt.Sprintf("---\napplications:\n- name: manifest-%s\n", randomizer.StringRunes(10))
+
+					requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest": "%s", "manifest_raw": %q}`,
+						artifactURL,
+						base64.StdEncoding.EncodeToString([]byte(rawManifest)),
+						rawManifest,
+					))
+
+					req, _ = http.NewRequest("POST", "", requestBody)
+
+					_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+					Expect(err).To(MatchError(RawManifestSourceError{}))
+
+					Expect(statusCode).To(Equal(http.StatusBadRequest))
+					Expect(fetcher.FetchCall.Received.DeploymentInfo.ArtifactURL).To(BeEmpty())
+				})
+			})
+		})
+
+		Context("when a manifest url is given in the request body", func() {
+			var manifestURL string
+
+			BeforeEach(func() {
+				manifestURL = "https://example.com/manifests/" + randomizer.StringRunes(10)
+
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest_url": "%s"}`,
+					artifactURL,
+					manifestURL,
+				))
+
+				req, _ = http.NewRequest("POST", "", requestBody)
+			})
+
+			It("pushes the artifact with the externally supplied manifest", func() {
+				fetcher.FetchManifestCall.Returns.Manifest = manifest
+				fetcher.FetchCall.Returns.AppPath = testManifestLocation
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(fetcher.FetchManifestCall.Received.ManifestURL).To(Equal(manifestURL))
+				Expect(fetcher.FetchCall.Received.DeploymentInfo.ArtifactURL).To(Equal(artifactURL))
+				Expect(fetcher.FetchCall.Received.Manifest).To(Equal(manifest))
+				Expect(blueGreener.PushCall.Received.AppPath).To(Equal(testManifestLocation))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.Manifest).To(Equal(manifest))
+			})
+
+			Context("when the manifest cannot be fetched", func() {
+				It("returns an error and http.StatusInternalServerError", func() {
+					fetcher.FetchManifestCall.Returns.Error = errors.New("fetch manifest error")
+
+					_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+					Expect(err).To(MatchError("fetch manifest error"))
+
+					Expect(statusCode).To(Equal(http.StatusInternalServerError))
+					Expect(fetcher.FetchCall.Received.DeploymentInfo.ArtifactURL).To(BeEmpty())
+				})
+			})
+
+			Context("when an inline manifest is also given", func() {
+				It("returns an error and http.StatusBadRequest", func() {
+					requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest": "%s", "manifest_url": "%s"}`,
+						artifactURL,
+						base64.StdEncoding.EncodeToString([]byte(manifest)),
+						manifestURL,
+					))
+
+					req, _ = http.NewRequest("POST", "", requestBody)
+
+					_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+					Expect(err).To(MatchError(ManifestSourceError{}))
+
+					Expect(statusCode).To(Equal(http.StatusBadRequest))
+				})
+			})
+		})
+
+		Context("when an artifact token is given in the request body", func() {
+			It("passes the token to the fetcher without writing it to the response", func() {
+				artifactToken := "artifactToken-" + randomizer.StringRunes(10)
+
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "artifact_token": "%s"}`,
+					artifactURL,
+					artifactToken,
+				))
+
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(fetcher.FetchCall.Received.DeploymentInfo.ArtifactToken).To(Equal(artifactToken))
+				Expect(response.String()).ToNot(ContainSubstring(artifactToken))
+				Expect(logBuffer).ToNot(Say(artifactToken))
+			})
+		})
+
+		Context("when a health check is given in the request body", func() {
+			It("passes the health check path and timeout to the blue greener", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "health_check_path": "/health", "health_check_timeout": "90s"}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.HealthCheckPath).To(Equal("/health"))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.HealthCheckTimeout).To(Equal("90s"))
+			})
+
+			It("rejects a health check path that does not start with /", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "health_check_path": "health"}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(InvalidHealthCheckPathError{"health"}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+			})
+
+			It("rejects a health check timeout that is not a positive duration", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "health_check_timeout": "-1s"}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(InvalidHealthCheckTimeoutError{"-1s"}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.AppName).To(BeEmpty())
+			})
+		})
+
+		Context("when a startup timeout is given in the request body", func() {
+			It("passes the startup timeout to the blue greener", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "startup_timeout": 180}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.StartupTimeout).To(Equal(180))
+			})
+
+			It("rejects a startup timeout that is not positive", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "startup_timeout": -1}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(InvalidStartupTimeoutError{-1}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.AppName).To(BeEmpty())
+			})
+		})
+
+		Context("when a hostname is given in the request body", func() {
+			It("passes the hostname to the blue greener", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "hostname": "example-host"}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.Hostname).To(Equal("example-host"))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.AppName).To(Equal(appName))
+			})
+
+			It("rejects a hostname that is not a valid host", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "hostname": "example.host"}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(InvalidHostnameError{"example.host"}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.AppName).To(BeEmpty())
+			})
+		})
+
+		Context("when a strategy is given in the request body", func() {
+			It("pushes with the blue greener by default", func() {
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.AppName).To(Equal(appName))
+				Expect(rollingGreener.PushCall.Received.DeploymentInfo.AppName).To(BeEmpty())
+			})
+
+			It("pushes with the blue greener for the bluegreen strategy", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "strategy": "bluegreen"}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.Strategy).To(Equal(BlueGreenStrategy))
+				Expect(rollingGreener.PushCall.Received.DeploymentInfo.AppName).To(BeEmpty())
+			})
+
+			It("pushes with the rolling greener for the rolling strategy", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "strategy": "rolling"}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(rollingGreener.PushCall.Received.DeploymentInfo.Strategy).To(Equal(RollingStrategy))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.AppName).To(BeEmpty())
+			})
+
+			It("rejects an unknown strategy", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "strategy": "canary"}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(InvalidStrategyError{"canary"}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.AppName).To(BeEmpty())
+				Expect(rollingGreener.PushCall.Received.DeploymentInfo.AppName).To(BeEmpty())
+			})
+		})
+
+		Context("when an app name prefix or suffix is given", func() {
+			It("passes them to the blue greener", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "app_name_prefix": "v2-", "app_name_suffix": "-build-1234"}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.AppName).To(Equal(appName))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.PushedAppName()).To(Equal("v2-" + appName + "-build-1234"))
+			})
+
+			It("rejects them with the rolling strategy", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "strategy": "rolling", "app_name_suffix": "-build-1234"}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(VersionedRollingError{}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(rollingGreener.PushCall.Received.DeploymentInfo.AppName).To(BeEmpty())
+			})
+		})
+
+		Context("when the manifest declares more than one application", func() {
+			var manifest = base64.StdEncoding.EncodeToString([]byte("---\napplications:\n- name: example-api\n- name: example-worker\n"))
+
+			It("passes the manifest to the blue greener and lists the applications", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest": "%s"}`, artifactURL, manifest))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.Manifest).To(ContainSubstring("example-worker"))
+				Expect(response.String()).To(ContainSubstring("Applications: example-api, example-worker"))
+			})
+
+			It("rejects the rolling strategy", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest": "%s", "strategy": "rolling"}`, artifactURL, manifest))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(MultipleAppsRollingError{}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(rollingGreener.PushCall.Received.DeploymentInfo.AppName).To(BeEmpty())
+			})
+
+			It("rejects a hostname", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest": "%s", "hostname": "example-preview"}`, artifactURL, manifest))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(MultipleAppsHostnameError{}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.AppName).To(BeEmpty())
+			})
+		})
+
+		Context("when an org and space are given in the request body", func() {
+			var (
+				bodyOrg   string
+				bodySpace string
+			)
+
+			BeforeEach(func() {
+				bodyOrg = "bodyOrg-" + randomizer.StringRunes(10)
+				bodySpace = "bodySpace-" + randomizer.StringRunes(10)
+
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "org": "%s", "space": "%s"}`, artifactURL, bodyOrg, bodySpace))
+				req, _ = http.NewRequest("POST", "", requestBody)
+			})
+
+			It("uses the org and space of the body when the URL has none", func() {
+				target, statusCode, err := deployer.Deploy(ctx, req, environment, "", "", appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.Org).To(Equal(bodyOrg))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.Space).To(Equal(bodySpace))
+				Expect(target).To(Equal(S.DeployTarget{Org: bodyOrg, Space: bodySpace}))
+			})
+
+			It("uses the org and space of the URL and logs a warning when they conflict", func() {
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.Org).To(Equal(org))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.Space).To(Equal(space))
+
+				Eventually(logBuffer).Should(Say(fmt.Sprintf("the org %s in the request body conflicts with the org %s in the URL, using %s", bodyOrg, org, org)))
+				Eventually(logBuffer).Should(Say(fmt.Sprintf("the space %s in the request body conflicts with the space %s in the URL, using %s", bodySpace, space, space)))
+			})
+		})
+
+		Context("when an org and space are only given in the URL", func() {
+			It("uses the org and space of the URL without a warning", func() {
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.Org).To(Equal(org))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.Space).To(Equal(space))
+				Expect(logBuffer).ToNot(Say("conflicts with the"))
+			})
+		})
+
+		Context("when a docker image is given in the request body", func() {
+			It("pushes the image without fetching an artifact", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"docker_image": "nginx:1.13", "manifest": "%s"}`, base64.StdEncoding.EncodeToString([]byte(manifest))))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(fetcher.FetchCall.Received.DeploymentInfo.ArtifactURL).To(BeEmpty())
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.DockerImage).To(Equal("nginx:1.13"))
+				Expect(blueGreener.PushCall.Received.AppPath).ToNot(BeEmpty())
+			})
+
+			It("rejects a request that also has an artifact url", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"docker_image": "nginx:1.13", "artifact_url": "%s"}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(DockerImageSourceError{}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.AppName).To(BeEmpty())
+			})
+		})
+
+		Context("when an artifact checksum is given in the request body", func() {
+			var artifactSHA256 string
+
+			BeforeEach(func() {
+				artifactSHA256 = "artifactSHA256-" + randomizer.StringRunes(10)
+
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "artifact_sha256": "%s"}`,
+					artifactURL,
+					artifactSHA256,
+				))
+
+				req, _ = http.NewRequest("POST", "", requestBody)
+			})
+
+			It("passes the checksum to the fetcher", func() {
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(fetcher.FetchCall.Received.DeploymentInfo.ArtifactSHA256).To(Equal(artifactSHA256))
+			})
+
+			Context("when the checksum does not match", func() {
+				It("returns an error and http.StatusBadRequest", func() {
+					checksumErr := artifetcher.ChecksumMismatchError{Expected: artifactSHA256, Actual: "actual"}
+					fetcher.FetchCall.Returns.Error = checksumErr
+
+					_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+					Expect(err).To(MatchError(checksumErr))
+
+					Expect(statusCode).To(Equal(http.StatusBadRequest))
+					Expect(response.String()).To(ContainSubstring("artifact checksum mismatch"))
+				})
+			})
+		})
+
+		Context("when no artifact checksum is given in the request body", func() {
+			It("does not pass a checksum to the fetcher", func() {
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(fetcher.FetchCall.Received.DeploymentInfo.ArtifactSHA256).To(BeEmpty())
+			})
+		})
+
+		Describe("fetching an artifact from an artifact url", func() {
+			It("writes the download progress to the response", func() {
+				fetcher.FetchCall.Returns.AppPath = testManifestLocation
+
+				_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fetcher.FetchCall.Received.Out).To(Equal(response))
+			})
+
+			Context("when Fetcher fails", func() {
+				It("returns an error and http.StatusInternalServerError", func() {
+					fetcher.FetchCall.Returns.AppPath = ""
+					fetcher.FetchCall.Returns.Error = errors.New("fetcher error")
+
+					_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+					Expect(err).To(MatchError("fetcher error"))
+
+					Expect(statusCode).To(Equal(http.StatusInternalServerError))
+					Expect(fetcher.FetchCall.Received.DeploymentInfo.ArtifactURL).To(Equal(artifactURL))
+					Expect(fetcher.FetchCall.Received.Manifest).To(Equal(manifest))
+				})
+
+				It("emits a deploy.error event for the fetch stage", func() {
+					fetcher.FetchCall.Returns.Error = errors.New("fetcher error")
+
+					deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+
+					Expect(eventManager.EmitCall.Received.Events).To(HaveLen(1))
+					Expect(eventManager.EmitCall.Received.Events[0].Type).To(Equal("deploy.error"))
+					Expect(eventManager.EmitCall.Received.Events[0].Data.(S.DeployEventData).Stage).To(Equal(S.FetchStage))
+				})
+			})
+		})
+	})
+
+	Describe("deploying with a zip file in the request body", func() {
+		Context("when manifest file cannot be found in the extracted zip", func() {
+			It("deploys successfully and returns http.StatusOK because manifest is optional", func() {
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/zip", response)
+				Expect(err).To(BeNil())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(response.String()).To(ContainSubstring("deploy was successful"))
+			})
+		})
+
+		Describe("fetching an artifact from the request body", func() {
+			Context("when Fetcher fails", func() {
+				It("returns an error and http.StatusInternalServerError", func() {
+					fetcher.FetchFromZipCall.Returns.AppPath = ""
+					fetcher.FetchFromZipCall.Returns.Error = errors.New("fetcher error")
+
+					_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/zip", response)
+					Expect(err).To(MatchError("fetcher error"))
+
+					Expect(statusCode).To(Equal(http.StatusInternalServerError))
+				})
+			})
+		})
+	})
+
+	Describe("deploying with an unknown request type", func() {
+		It("returns an http.StatusBadRequest and an error", func() {
+
+			_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/bork", response)
+			Expect(err).To(MatchError(InvalidContentTypeError{}))
+
+			Expect(statusCode).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("validating the manifest", func() {
+		Context("when the manifest in the request body is malformed yaml", func() {
+			It("returns an error and http.StatusBadRequest before fetching the artifact", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest": "%s"}`,
+					artifactURL,
+					base64.StdEncoding.EncodeToString([]byte("applications:\n- name: [bork")),
+				))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(BeAssignableToTypeOf(InvalidManifestError{}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(response.String()).To(ContainSubstring("invalid manifest"))
+				Expect(fetcher.FetchCall.Received.DeploymentInfo.ArtifactURL).To(BeEmpty())
+				Expect(blueGreener.PushCall.Received.AppPath).To(BeEmpty())
+			})
+		})
+
+		Context("when the manifest in the request body has no applications", func() {
+			It("returns an error and http.StatusBadRequest", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest": "%s"}`,
+					artifactURL,
+					base64.StdEncoding.EncodeToString([]byte("---\nenv:\n  LOG_LEVEL: debug\n")),
+				))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(InvalidManifestError{manifestro.NoApplicationsError{}}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(fetcher.FetchCall.Received.DeploymentInfo.ArtifactURL).To(BeEmpty())
+			})
+		})
+
+		Context("when the manifest has a malformed memory unit", func() {
+			It("returns an error naming the field and http.StatusBadRequest", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest": "%s"}`,
+					artifactURL,
+					base64.StdEncoding.EncodeToString([]byte("---\napplications:\n- name: example\n  memory: 256\n")),
+				))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(InvalidManifestError{manifestro.InvalidUnitError{Field: "memory", AppName: "example", Value: "256"}}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(response.String()).To(ContainSubstring("invalid memory for application example"))
+				Expect(fetcher.FetchCall.Received.DeploymentInfo.ArtifactURL).To(BeEmpty())
+			})
+		})
+
+		Context("when the manifest has a valid but unnormalized memory unit", func() {
+			It("fetches the artifact with the normalized manifest", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest": "%s"}`,
+					artifactURL,
+					base64.StdEncoding.EncodeToString([]byte("---\napplications:\n- name: example\n  memory: 256MB\n")),
+				))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(fetcher.FetchCall.Received.Manifest).To(ContainSubstring("256M"))
+				Expect(fetcher.FetchCall.Received.Manifest).ToNot(ContainSubstring("256MB"))
+			})
+		})
+
+		Context("when the manifest in the extracted zip is malformed yaml", func() {
+			It("returns an error and http.StatusBadRequest before pushing", func() {
+				Expect(af.WriteFile(testManifestLocation+"/manifest.yml", []byte("applications:\n- name: [bork"), 0644)).To(Succeed())
+				fetcher.FetchFromZipCall.Returns.AppPath = testManifestLocation
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/zip", response)
+				Expect(err).To(BeAssignableToTypeOf(InvalidManifestError{}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(blueGreener.PushCall.Received.AppPath).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("reading the manifest of a zip from a custom path", func() {
+		BeforeEach(func() {
+			fetcher.FetchFromZipCall.Returns.AppPath = testManifestLocation
+		})
+
+		It("pushes with the manifest at the manifest_path", func() {
+			Expect(af.MkdirAll(testManifestLocation+"/config", 0755)).To(Succeed())
+			Expect(af.WriteFile(testManifestLocation+"/config/manifest.prod.yml", []byte(testManifest), 0644)).To(Succeed())
+			req, _ = http.NewRequest("POST", "/?manifest_path=config/manifest.prod.yml", requestBody)
+
+			_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/zip", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.Manifest).To(ContainSubstring("name: deployadactyl"))
+		})
+
+		It("reads the app name prefix and suffix from the query", func() {
+			req, _ = http.NewRequest("POST", "/?app_name_prefix=v2-&app_name_suffix=-build-1234", requestBody)
+
+			_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/zip", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.AppNamePrefix).To(Equal("v2-"))
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.AppNameSuffix).To(Equal("-build-1234"))
+		})
+
+		It("reads the hostname from the query", func() {
+			req, _ = http.NewRequest("POST", "/?hostname=example-host", requestBody)
+
+			_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/zip", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.Hostname).To(Equal("example-host"))
+		})
+
+		Context("when the file at the manifest_path is missing", func() {
+			It("returns a ManifestNotFoundError and http.StatusBadRequest", func() {
+				req, _ = http.NewRequest("POST", "/?manifest_path=manifest.prod.yml", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/zip", response)
+				Expect(err).To(MatchError(ManifestNotFoundError{"manifest.prod.yml"}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(response.String()).To(ContainSubstring("manifest not found in the zip: manifest.prod.yml"))
+				Expect(blueGreener.PushCall.Received.AppPath).To(BeEmpty())
+			})
+		})
+
+		Context("when the manifest_path is outside of the zip", func() {
+			It("returns an InvalidManifestPathError and http.StatusBadRequest", func() {
+				req, _ = http.NewRequest("POST", "/?manifest_path=../manifest.yml", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/zip", response)
+				Expect(err).To(MatchError(InvalidManifestPathError{"../manifest.yml"}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("when there is no manifest_path and no manifest.yml", func() {
+			It("deploys without a manifest", func() {
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/zip", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.Manifest).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("validating required env vars", func() {
+		BeforeEach(func() {
+			envManifest := `---
+applications:
+- name: example
+  env:
+    SPRING_PROFILES_ACTIVE: cloud
+    LOG_LEVEL: debug`
+
+			requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest": "%s"}`,
+				artifactURL,
+				base64.StdEncoding.EncodeToString([]byte(envManifest)),
+			))
+
+			req, _ = http.NewRequest("POST", "", requestBody)
+		})
+
+		Context("when the manifest has all of the required env vars", func() {
+			It("deploys and returns http.StatusOK", func() {
+				e := environments[environment]
+				e.RequiredEnvVars = []string{"SPRING_PROFILES_ACTIVE", "LOG_LEVEL"}
+				deployer.Config.Environments[environment] = e
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the manifest is missing some of the required env vars", func() {
+			It("returns an error listing the missing env vars and http.StatusBadRequest", func() {
+				e := environments[environment]
+				e.RequiredEnvVars = []string{"SPRING_PROFILES_ACTIVE", "DATABASE_URL", "REGION"}
+				deployer.Config.Environments[environment] = e
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(MissingEnvVarsError{[]string{"DATABASE_URL", "REGION"}}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(response.String()).To(ContainSubstring("manifest is missing required env vars: DATABASE_URL, REGION"))
+				Expect(blueGreener.PushCall.Received.AppPath).To(BeEmpty())
+			})
+		})
+
+		Context("when the environment does not require any env vars", func() {
+			It("deploys and returns http.StatusOK", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s"}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+			})
+		})
+	})
+
+	Describe("limiting the number of routes", func() {
+		BeforeEach(func() {
+			routesManifest := `---
+applications:
+- name: example
+  routes:
+  - route: example.domain.com
+  - route: example.other.com`
+
+			requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest": "%s"}`,
+				artifactURL,
+				base64.StdEncoding.EncodeToString([]byte(routesManifest)),
+			))
+
+			req, _ = http.NewRequest("POST", "", requestBody)
+		})
+
+		Context("when the routes are within the limit", func() {
+			It("deploys and returns http.StatusOK", func() {
+				e := environments[environment]
+				e.MaxRoutesPerApp = 3
+				deployer.Config.Environments[environment] = e
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("when the manifest routes and the mapped route exceed the limit", func() {
+			It("returns an error and http.StatusBadRequest", func() {
+				e := environments[environment]
+				e.MaxRoutesPerApp = 2
+				deployer.Config.Environments[environment] = e
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(TooManyRoutesError{3, 2}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(response.String()).To(ContainSubstring("application has too many routes: 3"))
+				Expect(blueGreener.PushCall.Received.AppPath).To(BeEmpty())
+			})
+		})
+
+		Context("when the routes mapped on the custom domains exceed the limit", func() {
+			It("returns an error and http.StatusBadRequest", func() {
+				e := environments[environment]
+				e.MaxRoutesPerApp = 3
+				e.CustomDomains = []string{"internal-" + domain}
+				deployer.Config.Environments[environment] = e
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(TooManyRoutesError{4, 3}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("when the environment does not limit routes", func() {
+			It("deploys and returns http.StatusOK", func() {
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+			})
+		})
+	})
+
+	Describe("running a preflight push", func() {
+		BeforeEach(func() {
+			e := environments[environment]
+			e.PreflightPush = true
+			deployer.Config.Environments[environment] = e
+
+			fetcher.FetchCall.Returns.AppPath = appPath
+		})
+
+		Context("when the account can push to the space", func() {
+			It("checks the foundations with a probe and then deploys", func() {
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PreflightCall.Received.Environment).To(Equal(environments[environment]))
+				Expect(blueGreener.PreflightCall.Received.DeploymentInfo.AppName).To(Equal(appName))
+				Expect(blueGreener.PreflightCall.Received.DeploymentInfo.Org).To(Equal(org))
+				Expect(blueGreener.PreflightCall.Received.DeploymentInfo.Space).To(Equal(space))
+				Expect(fetcher.FetchCall.Received.DeploymentInfo.ArtifactURL).To(Equal(artifactURL))
+				Expect(blueGreener.PushCall.Received.AppPath).To(Equal(appPath))
+			})
+
+			It("removes the probe afterwards", func() {
+				_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				exists, _ := af.DirExists(blueGreener.PreflightCall.Received.ProbePath)
+				Expect(exists).To(BeFalse())
+			})
+		})
+
+		Context("when the account cannot push to the space", func() {
+			It("returns an error and http.StatusForbidden without fetching the artifact", func() {
+				blueGreener.PreflightCall.Returns.Error = errors.New("preflight failed: cannot push to the space")
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError("preflight failed: cannot push to the space"))
+
+				Expect(statusCode).To(Equal(http.StatusForbidden))
+				Expect(response.String()).To(ContainSubstring("cannot push to the space"))
+				Expect(fetcher.FetchCall.Received.DeploymentInfo.ArtifactURL).To(BeEmpty())
+				Expect(blueGreener.PushCall.Received.AppPath).To(BeEmpty())
+			})
+		})
+
+		Context("when the preflight cannot log in", func() {
+			It("returns an error and http.StatusBadRequest", func() {
+				blueGreener.PreflightCall.Returns.Error = errors.New("push failed: login failed: bork")
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(fetcher.FetchCall.Received.DeploymentInfo.ArtifactURL).To(BeEmpty())
+			})
+		})
+
+		Context("when the deploy is a dry run", func() {
+			It("does not push a probe", func() {
+				req, _ = http.NewRequest("POST", "?dry_run=true", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PreflightCall.Received.ProbePath).To(BeEmpty())
+				Expect(response.String()).To(ContainSubstring("dry run passed"))
+			})
+		})
+
+		Context("when the environment does not enable preflight pushes", func() {
+			It("deploys without a preflight push", func() {
+				e := environments[environment]
+				e.PreflightPush = false
+				deployer.Config.Environments[environment] = e
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PreflightCall.Received.ProbePath).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("overriding instances and memory from the request body", func() {
+		var multipleAppsManifest = `---
+applications:
+- name: first
+  instances: 1
+  memory: 256M
+- name: second
+  instances: 1
+`
+
+		Context("when instances and memory are given", func() {
+			It("overrides them on every application in the manifest", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest": "%s", "instances": 4, "memory": "1GB"}`,
+					artifactURL,
+					base64.StdEncoding.EncodeToString([]byte(multipleAppsManifest)),
+				))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.Instances).To(Equal(uint16(4)))
+				Expect(fetcher.FetchCall.Received.Manifest).ToNot(ContainSubstring("256M"))
+				Expect(strings.Count(fetcher.FetchCall.Received.Manifest, "1G")).To(Equal(2))
+			})
+		})
+
+		Context("when neither is given", func() {
+			It("uses the manifest verbatim", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest": "%s"}`,
+					artifactURL,
+					base64.StdEncoding.EncodeToString([]byte(multipleAppsManifest)),
+				))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(fetcher.FetchCall.Received.Manifest).To(Equal(multipleAppsManifest))
+			})
+		})
+
+		Context("when instances are given without a manifest", func() {
+			It("deploys with the given instances", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "instances": 4}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.Instances).To(Equal(uint16(4)))
+			})
+		})
+
+		Context("when memory is given without a manifest", func() {
+			It("returns an error and http.StatusBadRequest", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "memory": "1G"}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(MemoryOverrideError{}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("when the memory is malformed", func() {
+			It("returns an error naming the field and http.StatusBadRequest", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest": "%s", "memory": "lots"}`,
+					artifactURL,
+					base64.StdEncoding.EncodeToString([]byte(multipleAppsManifest)),
+				))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err.Error()).To(ContainSubstring("invalid memory for application first"))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
+	Describe("injecting environment variables from the request body", func() {
+		var (
+			envManifest string
+			secret      string
+		)
+
+		BeforeEach(func() {
+			envManifest = `---
+applications:
+- name: example
+  env:
+    KEEP: kept-value
+    FEATURE_FLAG: manifest-value
+`
+			secret = "secret-" + randomizer.StringRunes(10)
+
+			requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest": "%s", "environment_variables": {"FEATURE_FLAG": "request-value", "API_SECRET": "%s"}}`,
+				artifactURL,
+				base64.StdEncoding.EncodeToString([]byte(envManifest)),
+				secret,
+			))
+			req, _ = http.NewRequest("POST", "", requestBody)
+		})
+
+		It("merges them into the env of the manifest", func() {
+			_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(fetcher.FetchCall.Received.Manifest).To(ContainSubstring("kept-value"))
+			Expect(fetcher.FetchCall.Received.Manifest).To(ContainSubstring("request-value"))
+			Expect(fetcher.FetchCall.Received.Manifest).ToNot(ContainSubstring("manifest-value"))
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.Manifest).To(ContainSubstring(secret))
+		})
+
+		It("writes the names but not the values to the deploy output", func() {
+			_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(response.String()).To(ContainSubstring("Environment Variables: API_SECRET, FEATURE_FLAG"))
+			Expect(response.String()).ToNot(ContainSubstring(secret))
+			Expect(logBuffer).ToNot(Say(secret))
+		})
+
+		It("satisfies the required env vars of the environment", func() {
+			e := environments[environment]
+			e.RequiredEnvVars = []string{"API_SECRET"}
+			deployer.Config.Environments[environment] = e
+
+			_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statusCode).To(Equal(http.StatusOK))
+		})
+
+		Context("when no manifest is given", func() {
+			It("returns an error and http.StatusBadRequest", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "environment_variables": {"FEATURE_FLAG": "on"}}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(EnvironmentVariablesError{}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(fetcher.FetchCall.Received.DeploymentInfo.ArtifactURL).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("deploying to some of the foundations", func() {
+		var canary, second, third string
+
+		BeforeEach(func() {
+			canary = "https://canary-" + randomizer.StringRunes(10)
+			second = "https://second-" + randomizer.StringRunes(10)
+			third = "https://third-" + randomizer.StringRunes(10)
+
+			e := deployer.Config.Environments[environment]
+			e.Foundations = []string{canary, second, third}
+			deployer.Config.Environments[environment] = e
+		})
+
+		It("pushes to only the foundations in the request", func() {
+			requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "foundations": ["%s", "%s"]}`, artifactURL, third, canary))
+			req, _ = http.NewRequest("POST", "", requestBody)
+
+			_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(blueGreener.PushCall.Received.Environment.Foundations).To(Equal([]string{canary, third}))
+			Expect(deployer.Config.Environments[environment].Foundations).To(Equal([]string{canary, second, third}))
+			Expect(response.String()).To(ContainSubstring(fmt.Sprintf("Foundations:  %s, %s", canary, third)))
+		})
+
+		It("prechecks only the foundations in the request", func() {
+			requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "foundations": ["%s"]}`, artifactURL, canary))
+			req, _ = http.NewRequest("POST", "", requestBody)
+
+			_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(prechecker.AssertAllFoundationsUpCall.Received.Environment.Foundations).To(Equal([]string{canary}))
+		})
+
+		Context("when identical deploys are skipped", func() {
+			deploy := func(body string) (int, error) {
+				Expect(af.MkdirAll(testManifestLocation, 0755)).To(Succeed())
+				Expect(af.WriteFile(testManifestLocation+"/index.html", []byte("artifact"), 0644)).To(Succeed())
+
+				blueGreener.PushCall.Received.AppPath = ""
+				response = &bytes.Buffer{}
+				req, _ = http.NewRequest("POST", "", bytes.NewBufferString(body))
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				return statusCode, err
+			}
+
+			BeforeEach(func() {
+				deployer.Fingerprints = fingerprints.New(10 * time.Minute)
+				fetcher.FetchCall.Returns.AppPath = testManifestLocation
+			})
+
+			It("does not skip the rollout to every foundation after a canary of the same artifact", func() {
+				_, err := deploy(fmt.Sprintf(`{"artifact_url": "%s", "foundations": ["%s"]}`, artifactURL, canary))
+				Expect(err).ToNot(HaveOccurred())
+
+				statusCode, err := deploy(fmt.Sprintf(`{"artifact_url": "%s"}`, artifactURL))
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(response.String()).ToNot(ContainSubstring("already deployed"))
+				Expect(blueGreener.PushCall.Received.AppPath).To(Equal(testManifestLocation))
+				Expect(blueGreener.PushCall.Received.Environment.Foundations).To(Equal([]string{canary, second, third}))
+			})
+
+			It("skips the same foundations in a different order", func() {
+				_, err := deploy(fmt.Sprintf(`{"artifact_url": "%s", "foundations": ["%s", "%s"]}`, artifactURL, canary, third))
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = deploy(fmt.Sprintf(`{"artifact_url": "%s", "foundations": ["%s", "%s"]}`, artifactURL, third, canary))
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(response.String()).To(ContainSubstring("already deployed, skipping"))
+			})
+
+			It("does not skip the same artifact deployed with another strategy", func() {
+				_, err := deploy(fmt.Sprintf(`{"artifact_url": "%s"}`, artifactURL))
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = deploy(fmt.Sprintf(`{"artifact_url": "%s", "strategy": "rolling"}`, artifactURL))
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(response.String()).ToNot(ContainSubstring("already deployed"))
+			})
+		})
+
+		It("pushes to every foundation when the request has none", func() {
+			_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(blueGreener.PushCall.Received.Environment.Foundations).To(Equal([]string{canary, second, third}))
+		})
+
+		Context("when the foundations in the request are empty", func() {
+			It("returns an error and http.StatusBadRequest", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "foundations": []}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(NoFoundationsSelectedError{}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(blueGreener.PushCall.Received.AppPath).To(BeEmpty())
+			})
+		})
+
+		Context("when a foundation in the request is not configured for the environment", func() {
+			It("returns an error and http.StatusBadRequest", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "foundations": ["%s", "https://unknown.example.com"]}`, artifactURL, canary))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(UnknownFoundationError{"https://unknown.example.com", environment}))
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(blueGreener.PushCall.Received.AppPath).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("overriding skip_ssl from the request body", func() {
+		setSkipSSL := func(skipSSL bool) {
+			e := deployer.Config.Environments[environment]
+			e.SkipSSL = skipSSL
+			deployer.Config.Environments[environment] = e
+		}
+
+		It("skips ssl validation when the environment does not", func() {
+			setSkipSSL(false)
+			requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "skip_ssl": true}`, artifactURL))
+			req, _ = http.NewRequest("POST", "", requestBody)
+
+			_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.SkipSSL).To(BeTrue())
+			Expect(response.String()).To(ContainSubstring("Skip SSL:     true, overriding false of the environment for this deploy"))
+		})
+
+		It("validates ssl when the environment skips it", func() {
+			setSkipSSL(true)
+			requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "skip_ssl": false}`, artifactURL))
+			req, _ = http.NewRequest("POST", "", requestBody)
+
+			_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.SkipSSL).To(BeFalse())
+			Expect(response.String()).To(ContainSubstring("Skip SSL:     false, overriding true of the environment for this deploy"))
+		})
+
+		It("uses the skip_ssl of the environment when the request has none", func() {
+			setSkipSSL(true)
+
+			_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.SkipSSL).To(BeTrue())
+			Expect(response.String()).ToNot(ContainSubstring("Skip SSL"))
+		})
+	})
+
+	Describe("setting the number of instances in the deployment", func() {
+		Context("when a manifest with instances is provided", func() {
+			It("uses the instances declared in the manifest", func() {
+				deploymentInfo.Manifest = `---
+applications:
+- name: deployadactyl
+  instances: 1337
+`
+				base64Manifest := base64.StdEncoding.EncodeToString([]byte(deploymentInfo.Manifest))
+
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{
+	 					"artifact_url": "%s",
+	 					"manifest": "%s"
+	 				}`,
+					artifactURL,
+					base64Manifest,
+				))
+
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.Instances).To(Equal(uint16(1337)))
+			})
+		})
+
+		Context("when a manifest is not provided", func() {
+			It("uses the instances declared in the deployadactyl config", func() {
+				deployer.Config.Environments[environment] = config.Environment{Instances: 303}
+
+				deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.Instances).To(Equal(uint16(303)))
+			})
+		})
+	})
+
+	Describe("not finding an environment in the config", func() {
+		It("returns an error and an http.StatusInternalServerError", func() {
+			deployer = Deployer{
+				config.Config{},
+				blueGreener,
+				fetcher,
+				prechecker,
+				eventManager,
+				randomizerMock,
+				log,
+				&afero.Afero{Fs: afero.NewMemMapFs()},
+				metrics,
+				rollingGreener,
+				nil,
+				nil,
+				nil,
+			}
+
+			_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).To(MatchError(fmt.Sprintf("environment not found: %s", environment)))
+
+			Expect(statusCode).To(Equal(http.StatusInternalServerError))
+			Expect(response.String()).To(ContainSubstring(fmt.Sprintf("environment not found: %s", environment)))
+		})
+	})
+
+	Describe("mapping custom domains", func() {
+		It("passes the custom domains of the environment to the BlueGreener", func() {
+			e := environments[environment]
+			e.CustomDomains = []string{"internal-" + domain, "external-" + domain}
+			environments[environment] = e
+
+			_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.Domain).To(Equal(domain))
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.CustomDomains).To(Equal([]string{"internal-" + domain, "external-" + domain}))
+		})
+	})
+
+	Describe("configuring the smoke test", func() {
+		BeforeEach(func() {
+			e := environments[environment]
+			e.SmokeTestURL = "https://" + appName + "." + domain + "/health"
+			e.SmokeTestStatus = http.StatusNoContent
+			environments[environment] = e
+		})
+
+		It("passes the smoke test of the environment to the BlueGreener", func() {
+			_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.SmokeTestURL).To(Equal("https://" + appName + "." + domain + "/health"))
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.SmokeTestStatus).To(Equal(http.StatusNoContent))
+		})
+
+		It("uses the smoke test of the request over the one of the environment", func() {
+			requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "smoke_test_url": "https://smoke.example.com", "smoke_test_status": 200}`, artifactURL))
+			req, _ = http.NewRequest("POST", "", requestBody)
+
+			_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.SmokeTestURL).To(Equal("https://smoke.example.com"))
+			Expect(blueGreener.PushCall.Received.DeploymentInfo.SmokeTestStatus).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("printing the application URLs", func() {
+		var deployManifest = func(manifest string) {
+			requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest": "%s"}`,
+				artifactURL,
+				base64.StdEncoding.EncodeToString([]byte(manifest)),
+			))
+			req, _ = http.NewRequest("POST", "", requestBody)
+		}
+
+		It("writes the URL of the app name on the domain to the response", func() {
+			_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(response.String()).To(ContainSubstring(fmt.Sprintf("Application URLs:\nhttps://%s.%s\n", appName, domain)))
+		})
+
+		It("writes a URL for every custom domain of the environment", func() {
+			e := environments[environment]
+			e.CustomDomains = []string{"internal-" + domain}
+			environments[environment] = e
+
+			_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(response.String()).To(ContainSubstring(fmt.Sprintf("https://%s.%s\nhttps://%s.internal-%s\n", appName, domain, appName, domain)))
+		})
+
+		Context("when the manifest has a host", func() {
+			It("uses the host instead of the app name", func() {
+				deployManifest("---\napplications:\n- name: example\n  host: example-host\n")
 
-					statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
-					Expect(err.Error()).To(ContainSubstring("base64 encoded manifest could not be decoded"))
+				_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
 
-					Expect(statusCode).To(Equal(http.StatusBadRequest))
-				})
+				Expect(response.String()).To(ContainSubstring(fmt.Sprintf("https://example-host.%s\n", domain)))
+				Expect(response.String()).ToNot(ContainSubstring(fmt.Sprintf("https://%s.%s", appName, domain)))
 			})
 		})
 
-		Describe("fetching an artifact from an artifact url", func() {
-			Context("when Fetcher fails", func() {
-				It("returns an error and http.StatusInternalServerError", func() {
-					fetcher.FetchCall.Returns.AppPath = ""
-					fetcher.FetchCall.Returns.Error = errors.New("fetcher error")
+		Context("when the request has a hostname", func() {
+			It("uses the hostname instead of the app name", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "hostname": "example-host"}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
 
-					statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
-					Expect(err).To(MatchError("fetcher error"))
+				_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
 
-					Expect(statusCode).To(Equal(http.StatusInternalServerError))
-					Expect(fetcher.FetchCall.Received.ArtifactURL).To(Equal(artifactURL))
-					Expect(fetcher.FetchCall.Received.Manifest).To(Equal(manifest))
-				})
+				Expect(response.String()).To(ContainSubstring(fmt.Sprintf("Application URLs:\nhttps://example-host.%s\n", domain)))
+				Expect(response.String()).ToNot(ContainSubstring(fmt.Sprintf("https://%s.%s", appName, domain)))
 			})
-		})
-	})
 
-	Describe("deploying with a zip file in the request body", func() {
-		Context("when manifest file cannot be found in the extracted zip", func() {
-			It("deploys successfully and returns http.StatusOK because manifest is optional", func() {
-				statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/zip", response)
-				Expect(err).To(BeNil())
+			It("uses the hostname instead of the host and routes of the manifest", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "hostname": "example-host", "manifest": "%s"}`,
+					artifactURL,
+					base64.StdEncoding.EncodeToString([]byte("---\napplications:\n- name: example\n  host: manifest-host\n  routes:\n  - route: example.domain.com\n")),
+				))
+				req, _ = http.NewRequest("POST", "", requestBody)
 
-				Expect(statusCode).To(Equal(http.StatusOK))
-				Expect(response.String()).To(ContainSubstring("deploy was successful"))
+				_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(response.String()).To(ContainSubstring(fmt.Sprintf("Application URLs:\nhttps://example-host.%s\n", domain)))
+				Expect(response.String()).ToNot(ContainSubstring("manifest-host"))
+				Expect(response.String()).ToNot(ContainSubstring("https://example.domain.com"))
 			})
 		})
 
-		Describe("fetching an artifact from the request body", func() {
-			Context("when Fetcher fails", func() {
-				It("returns an error and http.StatusInternalServerError", func() {
-					fetcher.FetchFromZipCall.Returns.AppPath = ""
-					fetcher.FetchFromZipCall.Returns.Error = errors.New("fetcher error")
+		Context("when the manifest has routes", func() {
+			It("writes the URL of every route", func() {
+				deployManifest("---\napplications:\n- name: example\n  routes:\n  - route: example.domain.com\n  - route: example.other.com/path\n")
 
-					statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/zip", response)
-					Expect(err).To(MatchError("fetcher error"))
+				_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
 
-					Expect(statusCode).To(Equal(http.StatusInternalServerError))
-				})
+				Expect(response.String()).To(ContainSubstring("Application URLs:\nhttps://example.domain.com\nhttps://example.other.com/path\n"))
 			})
 		})
 	})
 
-	Describe("deploying with an unknown request type", func() {
-		It("returns an http.StatusBadRequest and an error", func() {
+	Describe("deploying an application without a route", func() {
+		Context("when the request has no_route", func() {
+			It("deploys the application without a route", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "no_route": true}`, artifactURL))
+				req, _ = http.NewRequest("POST", "", requestBody)
 
-			statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/bork", response)
-			Expect(err).To(MatchError(InvalidContentTypeError{}))
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
 
-			Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.NoRoute).To(BeTrue())
+				Expect(response.String()).ToNot(ContainSubstring("Application URLs:"))
+			})
 		})
-	})
 
-	Describe("setting the number of instances in the deployment", func() {
-		Context("when a manifest with instances is provided", func() {
-			It("uses the instances declared in the manifest", func() {
-				deploymentInfo.Manifest = `---
-applications:
-- name: deployadactyl
-  instances: 1337
-`
-				base64Manifest := base64.StdEncoding.EncodeToString([]byte(deploymentInfo.Manifest))
-
-				requestBody = bytes.NewBufferString(fmt.Sprintf(`{
-	 					"artifact_url": "%s",
-	 					"manifest": "%s"
-	 				}`,
+		Context("when the manifest declares no-route", func() {
+			It("deploys the application without a route", func() {
+				workerManifest := "---\napplications:\n- name: example-worker\n  no-route: true\n"
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest": "%s"}`,
 					artifactURL,
-					base64Manifest,
+					base64.StdEncoding.EncodeToString([]byte(workerManifest)),
 				))
-
 				req, _ = http.NewRequest("POST", "", requestBody)
 
-				_, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
 				Expect(err).ToNot(HaveOccurred())
 
-				Expect(blueGreener.PushCall.Received.DeploymentInfo.Instances).To(Equal(uint16(1337)))
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.NoRoute).To(BeTrue())
 			})
 		})
 
-		Context("when a manifest is not provided", func() {
-			It("uses the instances declared in the deployadactyl config", func() {
-				deployer.Config.Environments[environment] = config.Environment{Instances: 303}
-
-				deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+		Context("when neither the request nor the manifest asks for no route", func() {
+			It("deploys the application with a route", func() {
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
 
-				Expect(blueGreener.PushCall.Received.DeploymentInfo.Instances).To(Equal(uint16(303)))
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.NoRoute).To(BeFalse())
 			})
 		})
 	})
 
-	Describe("not finding an environment in the config", func() {
-		It("returns an error and an http.StatusInternalServerError", func() {
-			deployer = Deployer{
-				config.Config{},
-				blueGreener,
-				fetcher,
-				prechecker,
-				eventManager,
-				randomizerMock,
-				log,
-				&afero.Afero{Fs: afero.NewMemMapFs()},
+	Describe("templating the org and space", func() {
+		BeforeEach(func() {
+			environments[environment] = config.Environment{
+				Name:          environment,
+				Domain:        domain,
+				Foundations:   foundations,
+				Instances:     instances,
+				OrgTemplate:   "{{.Environment}}-org",
+				SpaceTemplate: "{{.AppName}}-space",
 			}
+		})
 
-			statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
-			Expect(err).To(MatchError(fmt.Sprintf("environment not found: %s", environment)))
+		Context("when the org and space are empty", func() {
+			It("renders them from the environment templates", func() {
+				target, statusCode, err := deployer.Deploy(ctx, req, environment, "", "", appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
 
-			Expect(statusCode).To(Equal(http.StatusInternalServerError))
-			Expect(response.String()).To(ContainSubstring(fmt.Sprintf("environment not found: %s", environment)))
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.Org).To(Equal(environment + "-org"))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.Space).To(Equal(appName + "-space"))
+				Expect(target).To(Equal(S.DeployTarget{Org: environment + "-org", Space: appName + "-space"}))
+			})
+		})
+
+		Context("when the org and space are provided", func() {
+			It("uses them instead of the templates", func() {
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.Org).To(Equal(org))
+				Expect(blueGreener.PushCall.Received.DeploymentInfo.Space).To(Equal(space))
+			})
+		})
+
+		Context("when the space is empty and the environment has no template", func() {
+			It("returns an error and http.StatusBadRequest", func() {
+				environments[environment] = config.Environment{Name: environment, Foundations: foundations}
+
+				target, statusCode, err := deployer.Deploy(ctx, req, environment, org, "", appName, "application/json", response)
+				Expect(err).To(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(target).To(Equal(S.DeployTarget{Org: org}))
+			})
 		})
 	})
 
 	Describe("deployment output", func() {
 		It("shows the user deployment info properties", func() {
-			statusCode, _ := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+			_, statusCode, _ := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
 
 			Expect(statusCode).To(Equal(http.StatusOK))
 			Expect(response.String()).To(ContainSubstring(artifactURL))
@@ -388,7 +1996,7 @@ applications:
 		})
 
 		It("shows the user their deploy was successful", func() {
-			deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+			deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
 
 			Expect(response.String()).To(ContainSubstring("deploy was successful"))
 		})
@@ -404,7 +2012,7 @@ applications:
 				eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, errors.New("deploy.start error"))
 				eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, nil)
 
-				statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
 				Expect(err).To(MatchError(EventError{"deploy.start", errors.New("deploy.start error")}))
 
 				Expect(statusCode).To(Equal(http.StatusInternalServerError))
@@ -412,12 +2020,44 @@ applications:
 				Expect(eventManager.EmitCall.TimesCalled).To(Equal(2), eventManagerNotEnoughCalls)
 			})
 
+			Context("when more than one deploy.start handler fails", func() {
+				It("outputs every handler error", func() {
+					handlerError := eventmanager.HandlerError{EventType: "deploy.start", Errs: []error{errors.New("first handler error"), errors.New("third handler error")}}
+
+					eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, handlerError)
+					eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, nil)
+
+					_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+					Expect(err).To(MatchError(EventError{"deploy.start", handlerError}))
+
+					Expect(statusCode).To(Equal(http.StatusInternalServerError))
+					Expect(response.String()).To(ContainSubstring("first handler error; third handler error"))
+				})
+			})
+
+			Context("when a deploy.start handler vetoes the deploy", func() {
+				It("returns the status code and message of the veto", func() {
+					veto := eventmanager.VetoError{StatusCode: http.StatusForbidden, Message: "production is frozen until the change window opens"}
+					handlerError := eventmanager.HandlerError{EventType: "deploy.start", Errs: []error{veto}}
+
+					eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, handlerError)
+					eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, nil)
+
+					_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+					Expect(err).To(MatchError(veto))
+
+					Expect(statusCode).To(Equal(http.StatusForbidden))
+					Expect(response.String()).To(ContainSubstring("production is frozen until the change window opens"))
+					Expect(blueGreener.PushCall.Received.AppPath).To(BeEmpty())
+				})
+			})
+
 			Context("when EventManager also fails on deploy.finish", func() {
 				It("outputs deploy.finish error", func() {
 					eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, errors.New("deploy.start error"))
 					eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, errors.New("deploy.finish error"))
 
-					statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+					_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
 					Expect(err).To(MatchError("an error occurred in the deploy.start event: deploy.start error: an error occurred in the deploy.finish event: deploy.finish error"))
 
 					Expect(statusCode).To(Equal(http.StatusInternalServerError))
@@ -436,12 +2076,78 @@ applications:
 
 				blueGreener.PushCall.Returns.Error = errors.New("blue greener failed")
 
-				statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
 				Expect(err).To(MatchError("blue greener failed"))
 
 				Expect(statusCode).To(Equal(http.StatusInternalServerError))
 				Expect(eventManager.EmitCall.Received.Events[1].Type).To(Equal("deploy.failure"))
 			})
+
+			It("names the push stage in the deploy.failure event", func() {
+				blueGreener.PushCall.Returns.Error = errors.New("blue greener failed")
+
+				deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+
+				Expect(eventManager.EmitCall.Received.Events[1].Type).To(Equal("deploy.failure"))
+
+				eventData := eventManager.EmitCall.Received.Events[1].Data.(S.DeployEventData)
+				Expect(eventData.Stage).To(Equal(S.PushStage))
+				Expect(eventData.Error).To(Equal("blue greener failed"))
+			})
+
+			It("does not emit a deploy.error event", func() {
+				blueGreener.PushCall.Returns.Error = errors.New("blue greener failed")
+
+				deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+
+				for _, event := range eventManager.EmitCall.Received.Events {
+					Expect(event.Type).ToNot(Equal("deploy.error"))
+				}
+			})
+		})
+
+		Context("when the deploy is cancelled", func() {
+			It("returns a DeployCancelledError and emits a deploy.cancelled event", func() {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithCancel(ctx)
+				cancel()
+
+				blueGreener.PushCall.Returns.Error = context.Canceled
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(DeployCancelledError{context.Canceled}))
+
+				Expect(statusCode).To(Equal(http.StatusInternalServerError))
+				Expect(eventManager.EmitCall.Received.Events[1].Type).To(Equal("deploy.cancelled"))
+				Expect(eventManager.EmitCall.Received.Events[2].Type).To(Equal("deploy.failure"))
+				Expect(eventManager.EmitCall.Received.Events[3].Type).To(Equal("deploy.finish"))
+			})
+		})
+
+		Context("when the deploy finishes", func() {
+			It("includes the status code in the deploy.finish event of a successful deploy", func() {
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(statusCode).To(Equal(http.StatusOK))
+
+				finishEvent := eventManager.EmitCall.Received.Events[2]
+				Expect(finishEvent.Type).To(Equal("deploy.finish"))
+				Expect(finishEvent.Data.(S.DeployEventData).StatusCode).To(Equal(http.StatusOK))
+				Expect(finishEvent.Data.(S.DeployEventData).Error).To(BeEmpty())
+			})
+
+			It("includes the status code and the error in the deploy.finish event of a failed deploy", func() {
+				blueGreener.PushCall.Returns.Error = errors.New("login failed")
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(HaveOccurred())
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+
+				finishEvent := eventManager.EmitCall.Received.Events[2]
+				Expect(finishEvent.Type).To(Equal("deploy.finish"))
+				Expect(finishEvent.Data.(S.DeployEventData).StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(finishEvent.Data.(S.DeployEventData).Error).To(Equal("login failed"))
+			})
 		})
 
 		Context("when blue greener succeeds", func() {
@@ -450,20 +2156,37 @@ applications:
 				eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, nil)
 				eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, nil)
 
-				statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
 				Expect(err).To(BeNil())
 
 				Expect(statusCode).To(Equal(http.StatusOK))
 				Expect(eventManager.EmitCall.Received.Events[1].Type).To(Equal("deploy.success"))
 			})
 
+			It("includes the app guids in the deploy.success event and the response", func() {
+				eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, nil)
+				eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, nil)
+				eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, nil)
+
+				appGUIDs := map[string]string{foundations[0]: "guid-" + randomizer.StringRunes(10)}
+				blueGreener.PushCall.Returns.AppGUIDs = appGUIDs
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(eventManager.EmitCall.Received.Events[1].Type).To(Equal("deploy.success"))
+				Expect(eventManager.EmitCall.Received.Events[1].Data.(S.DeployEventData).AppGUIDs).To(Equal(appGUIDs))
+				Expect(response.String()).To(ContainSubstring(fmt.Sprintf("%s: %s", foundations[0], appGUIDs[foundations[0]])))
+			})
+
 			Context("when emitting a deploy.succes event fails", func() {
 				It("return an error and outputs a deploy.success and http.StatusOK", func() {
 					eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, nil)
 					eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, errors.New("event error"))
 					eventManager.EmitCall.Returns.Error = append(eventManager.EmitCall.Returns.Error, nil)
 
-					statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+					_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
 					Expect(err).To(BeNil())
 
 					Expect(statusCode).To(Equal(http.StatusOK))
@@ -475,17 +2198,40 @@ applications:
 	})
 
 	Describe("BlueGreener.Push", func() {
+		It("passes the context to the BlueGreener", func() {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithCancel(ctx)
+			defer cancel()
+
+			_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(blueGreener.PushCall.Received.Context).To(Equal(ctx))
+		})
+
 		Context("when BlueGreener fails with a login failed error", func() {
 			It("returns an error and a http.StatusUnauthorized", func() {
 				blueGreener.PushCall.Returns.Error = errors.New("login failed")
 
-				statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
 				Expect(err).To(MatchError("login failed"))
 
 				Expect(statusCode).To(Equal(http.StatusBadRequest))
 			})
 		})
 
+		Context("when BlueGreener rolls back the deploy", func() {
+			It("returns an error stating the rollback and a http.StatusInternalServerError", func() {
+				blueGreener.PushCall.Returns.Error = bluegreen.PushFailRollbackError{Errs: []error{errors.New("push error")}}
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).To(MatchError(bluegreen.PushFailRollbackError{Errs: []error{errors.New("push error")}}))
+				Expect(err.Error()).To(ContainSubstring("rolled back"))
+
+				Expect(statusCode).To(Equal(http.StatusInternalServerError))
+			})
+		})
+
 		Context("when BlueGreener fails during a deploy with a zip file in the request body", func() {
 			It("returns an error and a http.StatusInternalServerError", func() {
 				Expect(af.WriteFile(testManifestLocation+"/manifest.yml", []byte(testManifest), 0644)).To(Succeed())
@@ -494,7 +2240,7 @@ applications:
 
 				blueGreener.PushCall.Returns.Error = errors.New("blue green error")
 
-				statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/zip", response)
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/zip", response)
 				Expect(err).To(MatchError("blue green error"))
 
 				Expect(statusCode).To(Equal(http.StatusInternalServerError))
@@ -510,7 +2256,7 @@ applications:
 
 				blueGreener.PushCall.Returns.Error = errors.New("blue green error")
 
-				statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
 				Expect(err).To(MatchError("blue green error"))
 
 				Expect(statusCode).To(Equal(http.StatusInternalServerError))
@@ -520,6 +2266,171 @@ applications:
 		})
 	})
 
+	Describe("dry runs", func() {
+		BeforeEach(func() {
+			requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest": "%s", "dry_run": true}`,
+				artifactURL,
+				base64.StdEncoding.EncodeToString([]byte(testManifest)),
+			))
+
+			req, _ = http.NewRequest("POST", "", requestBody)
+			fetcher.FetchCall.Returns.AppPath = appPath
+		})
+
+		It("prechecks and fetches without pushing and returns http.StatusOK", func() {
+			_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(response.String()).To(ContainSubstring("dry run passed"))
+			Expect(prechecker.AssertAllFoundationsUpCall.Received.Environment).To(Equal(environments[environment]))
+			Expect(fetcher.FetchCall.Received.DeploymentInfo.ArtifactURL).To(Equal(artifactURL))
+			Expect(blueGreener.PushCall.Received.AppPath).To(BeEmpty())
+		})
+
+		It("emits a deploy.dryrun event instead of deploy.start and deploy.finish", func() {
+			_, _, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(eventManager.EmitCall.Received.Events).To(HaveLen(1))
+			Expect(eventManager.EmitCall.Received.Events[0].Type).To(Equal("deploy.dryrun"))
+			Expect(eventManager.EmitCall.Received.Events[0].Data.(S.DeployEventData).DeploymentInfo.DryRun).To(BeTrue())
+		})
+
+		Context("when dry_run is given as a query parameter", func() {
+			It("does not push", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s"}`, artifactURL))
+				req, _ = http.NewRequest("POST", "?dry_run=true", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(statusCode).To(Equal(http.StatusOK))
+				Expect(response.String()).To(ContainSubstring("dry run passed"))
+				Expect(blueGreener.PushCall.Received.AppPath).To(BeEmpty())
+			})
+		})
+
+		Context("when the manifest is not valid yaml", func() {
+			It("returns an error and http.StatusBadRequest", func() {
+				requestBody = bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s", "manifest": "%s", "dry_run": true}`,
+					artifactURL,
+					base64.StdEncoding.EncodeToString([]byte("applications:\n- name: [bork")),
+				))
+				req, _ = http.NewRequest("POST", "", requestBody)
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+
+				Expect(err).To(BeAssignableToTypeOf(InvalidManifestError{}))
+				Expect(statusCode).To(Equal(http.StatusBadRequest))
+				Expect(response.String()).To(ContainSubstring("invalid manifest"))
+				Expect(eventManager.EmitCall.Received.Events).To(BeEmpty())
+			})
+		})
+
+		Context("when EventManager fails on deploy.dryrun", func() {
+			It("returns an error and http.StatusInternalServerError", func() {
+				eventManager.EmitCall.Returns.Error[0] = errors.New("bork")
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+
+				Expect(err).To(MatchError(EventError{"deploy.dryrun", errors.New("bork")}))
+				Expect(statusCode).To(Equal(http.StatusInternalServerError))
+			})
+		})
+	})
+
+	Describe("skipping identical deploys", func() {
+		var (
+			deployFingerprints *fingerprints.Fingerprints
+			now                time.Time
+			deploy             func(url, artifact string) (int, error)
+		)
+
+		BeforeEach(func() {
+			now = time.Now()
+			deployFingerprints = fingerprints.New(10 * time.Minute)
+			deployFingerprints.Now = func() time.Time { return now }
+			deployer.Fingerprints = deployFingerprints
+
+			fetcher.FetchCall.Returns.AppPath = testManifestLocation
+
+			deploy = func(url, artifact string) (int, error) {
+				Expect(af.MkdirAll(testManifestLocation, 0755)).To(Succeed())
+				Expect(af.WriteFile(testManifestLocation+"/index.html", []byte(artifact), 0644)).To(Succeed())
+
+				blueGreener.PushCall.Received.AppPath = ""
+				response = &bytes.Buffer{}
+				req, _ = http.NewRequest("POST", url, bytes.NewBufferString(fmt.Sprintf(`{"artifact_url": "%s"}`, artifactURL)))
+
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
+				return statusCode, err
+			}
+		})
+
+		It("skips a deploy identical to one that completed within the redeploy window", func() {
+			_, err := deploy("", "artifact")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blueGreener.PushCall.Received.AppPath).To(Equal(testManifestLocation))
+
+			statusCode, err := deploy("", "artifact")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(statusCode).To(Equal(http.StatusOK))
+			Expect(response.String()).To(ContainSubstring("already deployed, skipping"))
+			Expect(blueGreener.PushCall.Received.AppPath).To(BeEmpty())
+		})
+
+		It("does not skip a deploy of a different artifact", func() {
+			_, err := deploy("", "artifact")
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = deploy("", "other artifact")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(response.String()).ToNot(ContainSubstring("already deployed"))
+			Expect(blueGreener.PushCall.Received.AppPath).To(Equal(testManifestLocation))
+		})
+
+		It("does not skip a deploy after the redeploy window has passed", func() {
+			_, err := deploy("", "artifact")
+			Expect(err).ToNot(HaveOccurred())
+
+			now = now.Add(11 * time.Minute)
+
+			_, err = deploy("", "artifact")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(response.String()).ToNot(ContainSubstring("already deployed"))
+			Expect(blueGreener.PushCall.Received.AppPath).To(Equal(testManifestLocation))
+		})
+
+		It("does not skip an identical deploy that is forced", func() {
+			_, err := deploy("", "artifact")
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = deploy("?force=true", "artifact")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(response.String()).ToNot(ContainSubstring("already deployed"))
+			Expect(blueGreener.PushCall.Received.AppPath).To(Equal(testManifestLocation))
+		})
+
+		Context("when the deploy fails", func() {
+			It("does not skip the next identical deploy", func() {
+				blueGreener.PushCall.Returns.Error = errors.New("push failed")
+				_, err := deploy("", "artifact")
+				Expect(err).To(HaveOccurred())
+
+				blueGreener.PushCall.Returns.Error = nil
+				_, err = deploy("", "artifact")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(blueGreener.PushCall.Received.AppPath).To(Equal(testManifestLocation))
+			})
+		})
+	})
+
 	Describe("removing files after deploying", func() {
 		It("deletes the unzipped folder from the fetcher", func() {
 			af = &afero.Afero{Fs: afero.NewMemMapFs()}
@@ -532,6 +2443,11 @@ applications:
 				randomizerMock,
 				log,
 				af,
+				metrics,
+				rollingGreener,
+				nil,
+				nil,
+				nil,
 			}
 
 			directoryName, err := af.TempDir("", "deployadactyl-")
@@ -539,7 +2455,7 @@ applications:
 
 			fetcher.FetchCall.Returns.AppPath = directoryName
 
-			deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+			deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
 
 			exists, err := af.DirExists(directoryName)
 			Expect(err).ToNot(HaveOccurred())
@@ -553,7 +2469,7 @@ applications:
 			It("accepts the request and returns http.StatusOK", func() {
 				fetcher.FetchCall.Returns.AppPath = appPath
 
-				statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/json", response)
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/json", response)
 				Expect(err).To(BeNil())
 
 				Expect(statusCode).To(Equal(http.StatusOK))
@@ -561,17 +2477,17 @@ applications:
 				Expect(response.String()).To(ContainSubstring("Deployment Parameters"))
 				Expect(response.String()).To(ContainSubstring("deploy was successful"))
 
-				Eventually(logBuffer).Should(Say("prechecking the foundations"))
 				Eventually(logBuffer).Should(Say("checking for basic auth"))
 				Eventually(logBuffer).Should(Say("deploying from json request"))
 				Eventually(logBuffer).Should(Say("building deploymentInfo"))
+				Eventually(logBuffer).Should(Say("prechecking the foundations"))
 				Eventually(logBuffer).Should(Say("Deployment Parameters"))
 				Eventually(logBuffer).Should(Say("emitting a deploy.start event"))
 				Eventually(logBuffer).Should(Say("emitting a deploy.success event"))
 				Eventually(logBuffer).Should(Say("emitting a deploy.finish event"))
 
 				Expect(prechecker.AssertAllFoundationsUpCall.Received.Environment).To(Equal(environments[environment]))
-				Expect(fetcher.FetchCall.Received.ArtifactURL).To(Equal(artifactURL))
+				Expect(fetcher.FetchCall.Received.DeploymentInfo.ArtifactURL).To(Equal(artifactURL))
 				Expect(fetcher.FetchCall.Received.Manifest).To(Equal(manifest))
 				Expect(eventManager.EmitCall.Received.Events[0].Type).To(Equal("deploy.start"))
 				Expect(eventManager.EmitCall.Received.Events[1].Type).To(Equal("deploy.success"))
@@ -590,7 +2506,7 @@ applications:
 
 				fetcher.FetchFromZipCall.Returns.AppPath = testManifestLocation
 
-				statusCode, err := deployer.Deploy(req, environment, org, space, appName, "application/zip", response)
+				_, statusCode, err := deployer.Deploy(ctx, req, environment, org, space, appName, "application/zip", response)
 				Expect(err).To(BeNil())
 
 				Expect(statusCode).To(Equal(http.StatusOK))
@@ -598,16 +2514,16 @@ applications:
 				Expect(response.String()).To(ContainSubstring("Deployment Parameters"))
 				Expect(response.String()).To(ContainSubstring("deploy was successful"))
 
-				Eventually(logBuffer).Should(Say("prechecking the foundations"))
 				Eventually(logBuffer).Should(Say("checking for basic auth"))
 				Eventually(logBuffer).Should(Say("deploying from zip request"))
+				Eventually(logBuffer).Should(Say("prechecking the foundations"))
 				Eventually(logBuffer).Should(Say("Deployment Parameters"))
 				Eventually(logBuffer).Should(Say("emitting a deploy.start event"))
 				Eventually(logBuffer).Should(Say("emitting a deploy.success event"))
 				Eventually(logBuffer).Should(Say("emitting a deploy.finish event"))
 
 				Expect(prechecker.AssertAllFoundationsUpCall.Received.Environment).To(Equal(environments[environment]))
-				Expect(fetcher.FetchFromZipCall.Received.Request).To(Equal(req))
+				Expect(fetcher.FetchFromZipCall.Received.Body).To(Equal(req.Body))
 				Expect(eventManager.EmitCall.Received.Events[0].Type).To(Equal("deploy.start"))
 				Expect(eventManager.EmitCall.Received.Events[1].Type).To(Equal("deploy.success"))
 				Expect(eventManager.EmitCall.Received.Events[2].Type).To(Equal("deploy.finish"))
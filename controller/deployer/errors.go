@@ -1,6 +1,9 @@
 package deployer
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type BasicAuthError struct{}
 
@@ -16,10 +19,170 @@ func (e ManifestError) Error() string {
 	return fmt.Sprintf("base64 encoded manifest could not be decoded: %s", e.Err)
 }
 
+type InvalidManifestError struct {
+	Err error
+}
+
+func (e InvalidManifestError) Error() string {
+	return fmt.Sprintf("invalid manifest: %s", e.Err)
+}
+
+type MemoryOverrideError struct{}
+
+func (e MemoryOverrideError) Error() string {
+	return "memory can only be overridden when a manifest is provided"
+}
+
+type EnvironmentVariablesError struct{}
+
+func (e EnvironmentVariablesError) Error() string {
+	return "environment variables can only be set when a manifest is provided"
+}
+
+type ManifestSourceError struct{}
+
+func (e ManifestSourceError) Error() string {
+	return "manifest and manifest_url cannot both be provided"
+}
+
+type RawManifestSourceError struct{}
+
+func (e RawManifestSourceError) Error() string {
+	return "manifest and manifest_raw cannot both be provided"
+}
+
+type DockerImageSourceError struct{}
+
+func (e DockerImageSourceError) Error() string {
+	return "docker_image and artifact_url cannot both be provided"
+}
+
+type InvalidManifestPathError struct {
+	Path string
+}
+
+func (e InvalidManifestPathError) Error() string {
+	return fmt.Sprintf("invalid manifest_path: %s: must be relative to the root of the zip", e.Path)
+}
+
+type ManifestNotFoundError struct {
+	Path string
+}
+
+func (e ManifestNotFoundError) Error() string {
+	return fmt.Sprintf("manifest not found in the zip: %s", e.Path)
+}
+
+type MissingEnvVarsError struct {
+	EnvVars []string
+}
+
+func (e MissingEnvVarsError) Error() string {
+	return fmt.Sprintf("manifest is missing required env vars: %s", strings.Join(e.EnvVars, ", "))
+}
+
+type TooManyRoutesError struct {
+	Routes    int
+	MaxRoutes int
+}
+
+func (e TooManyRoutesError) Error() string {
+	return fmt.Sprintf("application has too many routes: %d: the environment allows at most %d", e.Routes, e.MaxRoutes)
+}
+
+type InvalidStrategyError struct {
+	Strategy string
+}
+
+func (e InvalidStrategyError) Error() string {
+	return fmt.Sprintf("invalid strategy: %s: must be bluegreen or rolling", e.Strategy)
+}
+
+type NoFoundationsSelectedError struct{}
+
+func (e NoFoundationsSelectedError) Error() string {
+	return "foundations must name at least one foundation of the environment"
+}
+
+type UnknownFoundationError struct {
+	Foundation  string
+	Environment string
+}
+
+func (e UnknownFoundationError) Error() string {
+	return fmt.Sprintf("foundation %s is not configured for environment %s", e.Foundation, e.Environment)
+}
+
+type VersionedRollingError struct{}
+
+func (e VersionedRollingError) Error() string {
+	return "app_name_prefix and app_name_suffix cannot be used with the rolling strategy"
+}
+
+type MultipleAppsRollingError struct{}
+
+func (e MultipleAppsRollingError) Error() string {
+	return "a manifest with more than one application cannot be deployed with the rolling strategy"
+}
+
+type MultipleAppsHostnameError struct{}
+
+func (e MultipleAppsHostnameError) Error() string {
+	return "a manifest with more than one application cannot be deployed with a hostname, as every application would be mapped to the same route"
+}
+
+type InvalidHealthCheckPathError struct {
+	Path string
+}
+
+func (e InvalidHealthCheckPathError) Error() string {
+	return fmt.Sprintf("invalid health_check_path: %s: must start with /", e.Path)
+}
+
+type InvalidHealthCheckTimeoutError struct {
+	Timeout string
+}
+
+func (e InvalidHealthCheckTimeoutError) Error() string {
+	return fmt.Sprintf("invalid health_check_timeout: %s: must be a positive duration such as 90s", e.Timeout)
+}
+
+type InvalidStartupTimeoutError struct {
+	Timeout int
+}
+
+func (e InvalidStartupTimeoutError) Error() string {
+	return fmt.Sprintf("invalid startup_timeout: %d: must be a positive number of seconds", e.Timeout)
+}
+
+type InvalidHostnameError struct {
+	Hostname string
+}
+
+func (e InvalidHostnameError) Error() string {
+	return fmt.Sprintf("invalid hostname: %s: must be letters, digits and hyphens, up to 63 characters, and cannot start or end with a hyphen", e.Hostname)
+}
+
 type InvalidContentTypeError struct{}
 
 func (e InvalidContentTypeError) Error() string {
-	return "must be application/json or application/zip"
+	return "must be application/json, application/zip or multipart/form-data"
+}
+
+type DeployCancelledError struct {
+	Err error
+}
+
+func (e DeployCancelledError) Error() string {
+	return fmt.Sprintf("deploy cancelled: the client closed the connection: %s", e.Err)
+}
+
+type FingerprintError struct {
+	Err error
+}
+
+func (e FingerprintError) Error() string {
+	return fmt.Sprintf("cannot fingerprint the deploy: %s", e.Err)
 }
 
 type EventError struct {
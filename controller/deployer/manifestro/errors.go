@@ -0,0 +1,30 @@
+package manifestro
+
+import "fmt"
+
+type NoApplicationsError struct{}
+
+func (e NoApplicationsError) Error() string {
+	return "manifest does not declare any applications"
+}
+
+type MissingAppNameError struct {
+	Index int
+}
+
+func (e MissingAppNameError) Error() string {
+	return fmt.Sprintf("application %d in the manifest has no name", e.Index)
+}
+
+type InvalidUnitError struct {
+	Field   string
+	AppName string
+	Value   string
+}
+
+func (e InvalidUnitError) Error() string {
+	if e.AppName == "" {
+		return fmt.Sprintf("invalid %s: %s: must be a whole number followed by M, MB, G or GB", e.Field, e.Value)
+	}
+	return fmt.Sprintf("invalid %s for application %s: %s: must be a whole number followed by M, MB, G or GB", e.Field, e.AppName, e.Value)
+}
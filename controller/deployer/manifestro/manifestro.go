@@ -1,10 +1,29 @@
 package manifestro
 
-import "github.com/cloudfoundry-incubator/candiedyaml"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/candiedyaml"
+)
+
+var (
+	unitFields  = []string{"memory", "disk_quota"}
+	unitPattern = regexp.MustCompile(`(?i)^([0-9]+)(M|G)B?$`)
+)
 
 type manifestYaml struct {
+	Env          map[string]interface{}
 	Applications []struct {
+		Name      string
+		Host      string
+		NoRoute   bool `yaml:"no-route"`
 		Instances *uint16
+		Env       map[string]interface{}
+		Routes    []struct {
+			Route string
+		}
 	}
 }
 
@@ -22,3 +41,312 @@ func GetInstances(manifest string) *uint16 {
 
 	return m.Applications[0].Instances
 }
+
+// GetAppNames reads a Cloud Foundry manifest as a string and returns the name of every application in the order
+// they are declared.
+//
+// Returns nil if the manifest cannot be parsed or has no applications.
+func GetAppNames(manifest string) []string {
+	var m manifestYaml
+
+	err := candiedyaml.Unmarshal([]byte(manifest), &m)
+	if err != nil {
+		return nil
+	}
+
+	var appNames []string
+	for _, application := range m.Applications {
+		appNames = append(appNames, application.Name)
+	}
+
+	return appNames
+}
+
+// GetAppInstances reads a Cloud Foundry manifest as a string and returns the number of instances of the application
+// called appName, if it has any.
+//
+// Returns nil if the application is not found or its instances are not set or less than 1.
+func GetAppInstances(manifest, appName string) *uint16 {
+	var m manifestYaml
+
+	err := candiedyaml.Unmarshal([]byte(manifest), &m)
+	if err != nil {
+		return nil
+	}
+
+	for _, application := range m.Applications {
+		if application.Name == appName && application.Instances != nil && *application.Instances >= 1 {
+			return application.Instances
+		}
+	}
+
+	return nil
+}
+
+// Validate reads a Cloud Foundry manifest as a string and checks that it can be parsed
+// and declares at least one application, each with a name.
+//
+// Returns an error if the manifest is not valid yaml, has no applications or has an application without a name.
+func Validate(manifest string) error {
+	var m manifestYaml
+
+	err := candiedyaml.Unmarshal([]byte(manifest), &m)
+	if err != nil {
+		return err
+	}
+
+	if len(m.Applications) == 0 {
+		return NoApplicationsError{}
+	}
+
+	for i, application := range m.Applications {
+		if application.Name == "" {
+			return MissingAppNameError{i + 1}
+		}
+	}
+
+	return nil
+}
+
+// GetRoutes reads a Cloud Foundry manifest as a string and returns the routes declared by the first application.
+//
+// Returns a slice of routes. If the manifest cannot be parsed or has no routes, it returns nil.
+func GetRoutes(manifest string) []string {
+	var m manifestYaml
+
+	err := candiedyaml.Unmarshal([]byte(manifest), &m)
+	if err != nil || len(m.Applications) == 0 {
+		return nil
+	}
+
+	var routes []string
+	for _, route := range m.Applications[0].Routes {
+		routes = append(routes, route.Route)
+	}
+
+	return routes
+}
+
+// GetHost reads a Cloud Foundry manifest as a string and returns the host declared by the first application.
+//
+// Returns an empty string if the manifest cannot be parsed or has no host.
+func GetHost(manifest string) string {
+	var m manifestYaml
+
+	err := candiedyaml.Unmarshal([]byte(manifest), &m)
+	if err != nil || len(m.Applications) == 0 {
+		return ""
+	}
+
+	return m.Applications[0].Host
+}
+
+// GetNoRoute reads a Cloud Foundry manifest as a string and returns true when the first application is declared with no-route,
+// such as a background worker that has no route.
+//
+// Returns false if the manifest cannot be parsed.
+func GetNoRoute(manifest string) bool {
+	var m manifestYaml
+
+	err := candiedyaml.Unmarshal([]byte(manifest), &m)
+	if err != nil || len(m.Applications) == 0 {
+		return false
+	}
+
+	return m.Applications[0].NoRoute
+}
+
+// GetAppNoRoute reads a Cloud Foundry manifest as a string and returns true when the application called appName
+// is declared with no-route.
+//
+// Returns false if the manifest cannot be parsed or the application is not found.
+func GetAppNoRoute(manifest, appName string) bool {
+	var m manifestYaml
+
+	err := candiedyaml.Unmarshal([]byte(manifest), &m)
+	if err != nil {
+		return false
+	}
+
+	for _, application := range m.Applications {
+		if application.Name == appName {
+			return application.NoRoute
+		}
+	}
+
+	return false
+}
+
+// GetEnvVars reads a Cloud Foundry manifest as a string and returns the env block of the first application
+// merged over the top level env block of the manifest.
+//
+// Returns a map of env var names to their values. If the manifest cannot be parsed, it returns nil.
+func GetEnvVars(manifest string) map[string]interface{} {
+	var m manifestYaml
+
+	err := candiedyaml.Unmarshal([]byte(manifest), &m)
+	if err != nil {
+		return nil
+	}
+
+	envVars := map[string]interface{}{}
+	for name, value := range m.Env {
+		envVars[name] = value
+	}
+	if len(m.Applications) > 0 {
+		for name, value := range m.Applications[0].Env {
+			envVars[name] = value
+		}
+	}
+
+	return envVars
+}
+
+// NormalizeUnits reads a Cloud Foundry manifest as a string and checks that the memory and disk_quota of the
+// manifest and of every application are a whole number followed by M, MB, G or GB. Units are normalized to M or G.
+//
+// Returns the manifest unchanged if every unit is already normalized. Returns an InvalidUnitError naming the field
+// if a unit is malformed.
+func NormalizeUnits(manifest string) (string, error) {
+	var m map[interface{}]interface{}
+
+	err := candiedyaml.Unmarshal([]byte(manifest), &m)
+	if err != nil {
+		return "", err
+	}
+
+	changed, err := normalizeUnits(m, "")
+	if err != nil {
+		return "", err
+	}
+
+	applications, _ := m["applications"].([]interface{})
+	for _, application := range applications {
+		fields, ok := application.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		appChanged, err := normalizeUnits(fields, fmt.Sprint(fields["name"]))
+		if err != nil {
+			return "", err
+		}
+		changed = changed || appChanged
+	}
+
+	if !changed {
+		return manifest, nil
+	}
+
+	normalized, err := candiedyaml.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+
+	return string(normalized), nil
+}
+
+func normalizeUnits(fields map[interface{}]interface{}, appName string) (bool, error) {
+	changed := false
+
+	for _, field := range unitFields {
+		value, ok := fields[field]
+		if !ok {
+			continue
+		}
+
+		matches := unitPattern.FindStringSubmatch(fmt.Sprint(value))
+		if matches == nil {
+			return false, InvalidUnitError{Field: field, AppName: appName, Value: fmt.Sprint(value)}
+		}
+
+		normalized := matches[1] + strings.ToUpper(matches[2])
+		if normalized != value {
+			fields[field] = normalized
+			changed = true
+		}
+	}
+
+	return changed, nil
+}
+
+// Override reads a Cloud Foundry manifest as a string and sets the instances and memory of every application
+// in the manifest. Instances are not set when nil and memory is not set when empty.
+//
+// Returns the manifest unchanged if there is nothing to override.
+func Override(manifest string, instances *uint16, memory string) (string, error) {
+	if instances == nil && memory == "" {
+		return manifest, nil
+	}
+
+	var m map[interface{}]interface{}
+
+	err := candiedyaml.Unmarshal([]byte(manifest), &m)
+	if err != nil {
+		return "", err
+	}
+
+	applications, _ := m["applications"].([]interface{})
+	for _, application := range applications {
+		fields, ok := application.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		if instances != nil {
+			fields["instances"] = *instances
+		}
+		if memory != "" {
+			fields["memory"] = memory
+		}
+	}
+
+	overridden, err := candiedyaml.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+
+	return string(overridden), nil
+}
+
+// SetEnvVars reads a Cloud Foundry manifest as a string and merges envVars into the env block of every application.
+// Env vars already in the manifest are kept unless envVars has a value with the same name.
+//
+// Returns the manifest unchanged if envVars is empty.
+func SetEnvVars(manifest string, envVars map[string]string) (string, error) {
+	if len(envVars) == 0 {
+		return manifest, nil
+	}
+
+	var m map[interface{}]interface{}
+
+	err := candiedyaml.Unmarshal([]byte(manifest), &m)
+	if err != nil {
+		return "", err
+	}
+
+	applications, _ := m["applications"].([]interface{})
+	for _, application := range applications {
+		fields, ok := application.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		env, ok := fields["env"].(map[interface{}]interface{})
+		if !ok {
+			env = map[interface{}]interface{}{}
+			fields["env"] = env
+		}
+
+		for name, value := range envVars {
+			env[name] = value
+		}
+	}
+
+	merged, err := candiedyaml.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+
+	return string(merged), nil
+}
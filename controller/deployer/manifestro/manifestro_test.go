@@ -1,6 +1,7 @@
 package manifestro_test
 
 import (
+	"github.com/cloudfoundry-incubator/candiedyaml"
 	. "github.com/compozed/deployadactyl/controller/deployer/manifestro"
 
 	. "github.com/onsi/ginkgo"
@@ -112,4 +113,379 @@ applications:
 			})
 		})
 	})
+
+	Describe("getting env vars", func() {
+		It("returns the env vars of the first application merged over the top level env", func() {
+			manifest := `---
+env:
+  SHARED: top
+  TOP_ONLY: top
+applications:
+- name: example
+  env:
+    SHARED: app
+    SPRING_PROFILES_ACTIVE: cloud
+- name: example2
+  env:
+    OTHER: other`
+
+			result := GetEnvVars(manifest)
+
+			Expect(result).To(HaveLen(3))
+			Expect(result).To(HaveKeyWithValue("SHARED", "app"))
+			Expect(result).To(HaveKeyWithValue("TOP_ONLY", "top"))
+			Expect(result).To(HaveKeyWithValue("SPRING_PROFILES_ACTIVE", "cloud"))
+		})
+
+		Context("when there is no env", func() {
+			It("returns an empty map", func() {
+				manifest := `
+applications:
+- name: example`
+
+				Expect(GetEnvVars(manifest)).To(BeEmpty())
+			})
+		})
+
+		Context("when manifest not valid", func() {
+			It("returns nil", func() {
+				Expect(GetEnvVars("bork")).To(BeNil())
+			})
+		})
+	})
+
+	Describe("validating a manifest", func() {
+		Context("when the manifest is valid yaml", func() {
+			It("does not return an error", func() {
+				manifest := `
+applications:
+- name: example
+  instances: 2`
+
+				Expect(Validate(manifest)).To(Succeed())
+			})
+		})
+
+		Context("when the manifest is not valid yaml", func() {
+			It("returns an error", func() {
+				manifest := `
+applications:
+- name: [example`
+
+				Expect(Validate(manifest)).ToNot(Succeed())
+			})
+		})
+
+		Context("when the manifest has no applications", func() {
+			It("returns an error", func() {
+				manifest := `
+env:
+  LOG_LEVEL: debug`
+
+				Expect(Validate(manifest)).To(MatchError(NoApplicationsError{}))
+			})
+		})
+
+		Context("when an application has no name", func() {
+			It("returns an error naming the application", func() {
+				manifest := `
+applications:
+- name: example
+- instances: 2`
+
+				Expect(Validate(manifest)).To(MatchError(MissingAppNameError{2}))
+			})
+		})
+	})
+
+	Describe("getting the routes", func() {
+		Context("when the manifest declares routes", func() {
+			It("returns the routes of the first application", func() {
+				manifest := `
+applications:
+- name: example
+  routes:
+  - route: example.domain.com
+  - route: example.other.com/path`
+
+				Expect(GetRoutes(manifest)).To(Equal([]string{"example.domain.com", "example.other.com/path"}))
+			})
+		})
+
+		Context("when the manifest does not declare routes", func() {
+			It("returns nil", func() {
+				manifest := `
+applications:
+- name: example`
+
+				Expect(GetRoutes(manifest)).To(BeNil())
+			})
+		})
+
+		Context("when the manifest is not valid", func() {
+			It("returns nil", func() {
+				Expect(GetRoutes("bork")).To(BeNil())
+			})
+		})
+	})
+
+	Describe("getting the host", func() {
+		It("returns the host of the first application", func() {
+			manifest := `
+applications:
+- name: example
+  host: example-host`
+
+			Expect(GetHost(manifest)).To(Equal("example-host"))
+		})
+
+		Context("when the manifest does not declare a host", func() {
+			It("returns an empty string", func() {
+				Expect(GetHost("applications:\n- name: example")).To(BeEmpty())
+				Expect(GetHost("bork")).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("getting no-route", func() {
+		var manifest = `
+applications:
+- name: example-worker
+  no-route: true
+- name: example-api`
+
+		It("returns true when the first application has no route", func() {
+			Expect(GetNoRoute(manifest)).To(BeTrue())
+		})
+
+		It("returns whether an application has no route", func() {
+			Expect(GetAppNoRoute(manifest, "example-worker")).To(BeTrue())
+			Expect(GetAppNoRoute(manifest, "example-api")).To(BeFalse())
+		})
+
+		Context("when the manifest is not valid", func() {
+			It("returns false", func() {
+				Expect(GetNoRoute("bork")).To(BeFalse())
+				Expect(GetAppNoRoute("bork", "example-worker")).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("getting the applications", func() {
+		var manifest = `
+applications:
+- name: example-api
+  instances: 3
+- name: example-worker`
+
+		It("returns the name of every application in order", func() {
+			Expect(GetAppNames(manifest)).To(Equal([]string{"example-api", "example-worker"}))
+		})
+
+		It("returns the instances of an application", func() {
+			Expect(*GetAppInstances(manifest, "example-api")).To(Equal(uint16(3)))
+		})
+
+		Context("when the application does not set its instances", func() {
+			It("returns nil", func() {
+				Expect(GetAppInstances(manifest, "example-worker")).To(BeNil())
+			})
+		})
+
+		Context("when the manifest is not valid", func() {
+			It("returns nil", func() {
+				Expect(GetAppNames("bork")).To(BeNil())
+				Expect(GetAppInstances("bork", "example-api")).To(BeNil())
+			})
+		})
+	})
+
+	Describe("normalizing memory and disk units", func() {
+		Context("when the units are already normalized", func() {
+			It("returns the manifest unchanged", func() {
+				manifest := `
+applications:
+- name: example
+  memory: 256M
+  disk_quota: 1G`
+
+				normalized, err := NormalizeUnits(manifest)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(normalized).To(Equal(manifest))
+			})
+		})
+
+		Context("when the units are valid but not normalized", func() {
+			It("normalizes them to M or G", func() {
+				manifest := `
+memory: 1gb
+applications:
+- name: example
+  memory: 256MB
+  disk_quota: 2GB`
+
+				normalized, err := NormalizeUnits(manifest)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(normalized).To(ContainSubstring("1G"))
+				Expect(normalized).To(ContainSubstring("256M"))
+				Expect(normalized).To(ContainSubstring("2G"))
+				Expect(normalized).ToNot(ContainSubstring("MB"))
+				Expect(normalized).ToNot(ContainSubstring("GB"))
+				Expect(normalized).ToNot(ContainSubstring("gb"))
+			})
+		})
+
+		Context("when a unit is invalid", func() {
+			It("returns an error naming the field", func() {
+				manifest := `
+applications:
+- name: example
+  memory: 256KB`
+
+				_, err := NormalizeUnits(manifest)
+
+				Expect(err).To(MatchError(InvalidUnitError{Field: "memory", AppName: "example", Value: "256KB"}))
+				Expect(err.Error()).To(ContainSubstring("invalid memory for application example"))
+			})
+		})
+
+		Context("when a unit is missing", func() {
+			It("returns an error naming the field", func() {
+				manifest := `
+applications:
+- name: example
+  disk_quota: 256`
+
+				_, err := NormalizeUnits(manifest)
+
+				Expect(err).To(MatchError(InvalidUnitError{Field: "disk_quota", AppName: "example", Value: "256"}))
+			})
+		})
+	})
+
+	Describe("overriding instances and memory", func() {
+		var parseApplications = func(manifest string) []map[string]interface{} {
+			var m struct {
+				Applications []map[string]interface{}
+			}
+			Expect(candiedyaml.Unmarshal([]byte(manifest), &m)).To(Succeed())
+
+			return m.Applications
+		}
+
+		Context("when there is nothing to override", func() {
+			It("returns the manifest unchanged", func() {
+				manifest := `
+applications:
+- name: example
+  instances: 2`
+
+				overridden, err := Override(manifest, nil, "")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(overridden).To(Equal(manifest))
+			})
+		})
+
+		Context("when the manifest has multiple applications", func() {
+			It("overrides every application", func() {
+				manifest := `
+applications:
+- name: first
+  instances: 2
+  memory: 256M
+- name: second`
+				instances := uint16(5)
+
+				overridden, err := Override(manifest, &instances, "1G")
+				Expect(err).ToNot(HaveOccurred())
+
+				applications := parseApplications(overridden)
+				Expect(applications).To(HaveLen(2))
+				for _, application := range applications {
+					Expect(application["instances"]).To(BeEquivalentTo(5))
+					Expect(application["memory"]).To(Equal("1G"))
+				}
+				Expect(applications[0]["name"]).To(Equal("first"))
+				Expect(applications[1]["name"]).To(Equal("second"))
+			})
+		})
+
+		Context("when only instances are given", func() {
+			It("does not set the memory", func() {
+				manifest := `
+applications:
+- name: example
+  memory: 256M`
+				instances := uint16(3)
+
+				overridden, err := Override(manifest, &instances, "")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(*GetInstances(overridden)).To(Equal(uint16(3)))
+				Expect(parseApplications(overridden)[0]["memory"]).To(Equal("256M"))
+			})
+		})
+	})
+
+	Describe("setting env vars", func() {
+		Context("when there are no env vars", func() {
+			It("returns the manifest unchanged", func() {
+				manifest := `
+applications:
+- name: example`
+
+				merged, err := SetEnvVars(manifest, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(merged).To(Equal(manifest))
+			})
+		})
+
+		Context("when the manifest already has env vars", func() {
+			It("keeps them unless they are overridden", func() {
+				manifest := `
+applications:
+- name: example
+  env:
+    KEEP: kept
+    FEATURE_FLAG: "off"`
+
+				merged, err := SetEnvVars(manifest, map[string]string{"FEATURE_FLAG": "on", "NEW": "new"})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(GetEnvVars(merged)).To(Equal(map[string]interface{}{
+					"KEEP":         "kept",
+					"FEATURE_FLAG": "on",
+					"NEW":          "new",
+				}))
+			})
+		})
+
+		Context("when the manifest has multiple applications without env blocks", func() {
+			It("adds the env vars to every application", func() {
+				manifest := `
+applications:
+- name: first
+- name: second`
+
+				merged, err := SetEnvVars(manifest, map[string]string{"FEATURE_FLAG": "on"})
+				Expect(err).ToNot(HaveOccurred())
+
+				var m struct {
+					Applications []struct {
+						Env map[string]string
+					}
+				}
+				Expect(candiedyaml.Unmarshal([]byte(merged), &m)).To(Succeed())
+
+				Expect(m.Applications).To(HaveLen(2))
+				for _, application := range m.Applications {
+					Expect(application.Env).To(Equal(map[string]string{"FEATURE_FLAG": "on"}))
+				}
+			})
+		})
+	})
 })
@@ -0,0 +1,21 @@
+package orgspace
+
+import "fmt"
+
+type TemplateError struct {
+	Name string
+	Err  error
+}
+
+func (e TemplateError) Error() string {
+	return fmt.Sprintf("cannot render %s: %s", e.Name, e.Err)
+}
+
+type MissingOrgSpaceError struct {
+	Org   string
+	Space string
+}
+
+func (e MissingOrgSpaceError) Error() string {
+	return fmt.Sprintf("org and space must be provided or templated by the environment: org: '%s', space: '%s'", e.Org, e.Space)
+}
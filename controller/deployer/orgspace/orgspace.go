@@ -0,0 +1,73 @@
+// Package orgspace resolves the Cloud Foundry org and space for a deployment.
+package orgspace
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/compozed/deployadactyl/config"
+)
+
+// TemplateData is the data available to the org and space templates of an environment.
+type TemplateData struct {
+	AppName     string
+	Environment string
+	Org         string
+}
+
+// Resolve returns the effective org and space for a deployment.
+// An org or space that is provided explicitly is always used. If either is empty, it is rendered
+// from the org_template or space_template of the environment. The space template can reference
+// the resolved org with {{.Org}}.
+//
+// Returns an error if a template cannot be rendered or if the org or space is still empty.
+func Resolve(environment config.Environment, environmentName, org, space, appName string) (string, string, error) {
+	data := TemplateData{
+		AppName:     appName,
+		Environment: environmentName,
+	}
+
+	var err error
+
+	if org == "" {
+		org, err = render("org_template", environment.OrgTemplate, data)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	data.Org = org
+
+	if space == "" {
+		space, err = render("space_template", environment.SpaceTemplate, data)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	if org == "" || space == "" {
+		return "", "", MissingOrgSpaceError{org, space}
+	}
+
+	return org, space, nil
+}
+
+func render(name, text string, data TemplateData) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", TemplateError{name, err}
+	}
+
+	var rendered bytes.Buffer
+
+	err = tmpl.Execute(&rendered, data)
+	if err != nil {
+		return "", TemplateError{name, err}
+	}
+
+	return rendered.String(), nil
+}
@@ -0,0 +1,13 @@
+package orgspace_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestOrgspace(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Orgspace Suite")
+}
@@ -0,0 +1,102 @@
+package orgspace_test
+
+import (
+	"github.com/compozed/deployadactyl/config"
+	. "github.com/compozed/deployadactyl/controller/deployer/orgspace"
+	"github.com/compozed/deployadactyl/randomizer"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Orgspace", func() {
+	var (
+		environment     config.Environment
+		environmentName string
+		appName         string
+		org             string
+		space           string
+	)
+
+	BeforeEach(func() {
+		environmentName = "environmentName-" + randomizer.StringRunes(10)
+		appName = "appName-" + randomizer.StringRunes(10)
+		org = "org-" + randomizer.StringRunes(10)
+		space = "space-" + randomizer.StringRunes(10)
+
+		environment = config.Environment{
+			Name:          environmentName,
+			OrgTemplate:   "{{.Environment}}-org",
+			SpaceTemplate: "{{.AppName}}-space",
+		}
+	})
+
+	Context("when the org and space are provided", func() {
+		It("uses them instead of the templates", func() {
+			resolvedOrg, resolvedSpace, err := Resolve(environment, environmentName, org, space, appName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(resolvedOrg).To(Equal(org))
+			Expect(resolvedSpace).To(Equal(space))
+		})
+	})
+
+	Context("when the org and space are empty", func() {
+		It("renders them from the templates", func() {
+			resolvedOrg, resolvedSpace, err := Resolve(environment, environmentName, "", "", appName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(resolvedOrg).To(Equal(environmentName + "-org"))
+			Expect(resolvedSpace).To(Equal(appName + "-space"))
+		})
+
+		It("lets the space template use the resolved org", func() {
+			environment.SpaceTemplate = "{{.Org}}-{{.AppName}}"
+
+			resolvedOrg, resolvedSpace, err := Resolve(environment, environmentName, org, "", appName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(resolvedOrg).To(Equal(org))
+			Expect(resolvedSpace).To(Equal(org + "-" + appName))
+		})
+	})
+
+	Context("when only one of the org and space is provided", func() {
+		It("renders only the missing one", func() {
+			resolvedOrg, resolvedSpace, err := Resolve(environment, environmentName, "", space, appName)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(resolvedOrg).To(Equal(environmentName + "-org"))
+			Expect(resolvedSpace).To(Equal(space))
+		})
+	})
+
+	Context("when there is no template for an empty value", func() {
+		It("returns an error", func() {
+			environment.SpaceTemplate = ""
+
+			_, _, err := Resolve(environment, environmentName, org, "", appName)
+
+			Expect(err).To(MatchError(MissingOrgSpaceError{org, ""}))
+		})
+	})
+
+	Context("when a template is invalid", func() {
+		It("returns an error", func() {
+			environment.OrgTemplate = "{{.Bork"
+
+			_, _, err := Resolve(environment, environmentName, "", space, appName)
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cannot render org_template"))
+		})
+
+		It("returns an error when the template references an unknown field", func() {
+			environment.OrgTemplate = "{{.Bork}}"
+
+			_, _, err := Resolve(environment, environmentName, "", space, appName)
+
+			Expect(err.Error()).To(ContainSubstring("cannot render org_template"))
+		})
+	})
+})
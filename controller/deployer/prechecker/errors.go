@@ -1,6 +1,9 @@
 package prechecker
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type NoFoundationsConfiguredError struct{}
 
@@ -25,3 +28,15 @@ type FoundationUnavailableError struct {
 func (e FoundationUnavailableError) Error() string {
 	return fmt.Sprintf("deploy aborted: one or more CF foundations unavailable: %s: %s", e.FoundationURL, e.Status)
 }
+
+type FoundationsUnavailableError struct {
+	Errs []error
+}
+
+func (e FoundationsUnavailableError) Error() string {
+	errs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		errs[i] = err.Error()
+	}
+	return fmt.Sprintf("deploy aborted: %d CF foundations unavailable: %s", len(e.Errs), strings.Join(errs, "; "))
+}
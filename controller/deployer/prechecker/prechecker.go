@@ -7,52 +7,122 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/compozed/deployadactyl/clock"
 	"github.com/compozed/deployadactyl/config"
 	I "github.com/compozed/deployadactyl/interfaces"
 	S "github.com/compozed/deployadactyl/structs"
 )
 
+// DefaultTimeout is how long a foundation is given to respond when its environment has no Timeout.
+const DefaultTimeout = 15 * time.Second
+
 // Prechecker has an eventmanager used to manage event if prechecks fail.
+// No event is emitted when the EventManager is nil, so that the foundations can be probed without alerting anyone.
+// MinTLSVersion is the minimum TLS version accepted when connecting to a foundation.
+// Retries is the number of times the foundations that are down are checked again, waiting RetryDelay before each retry.
+// Clock is used to wait between retries. The time package is used when it is nil.
 type Prechecker struct {
-	EventManager I.EventManager
+	EventManager  I.EventManager
+	MinTLSVersion uint16
+	Retries       int
+	RetryDelay    time.Duration
+	Clock         I.Clock
 }
 
 // AssertAllFoundationsUp will send a request to each Cloud Foundry instance and check that the response status code is 200 OK.
+// A foundation that does not respond within the Timeout of the environment is treated as down.
+// The ClientCertificate of the environment is presented to foundations that require mutual TLS.
+// The foundations that are down are retried, and only the ones that are still down after every retry are in the error.
 func (p Prechecker) AssertAllFoundationsUp(environment config.Environment) error {
 	precheckerEventData := S.PrecheckerEventData{Environment: environment}
 
 	if len(environment.Foundations) == 0 {
 		precheckerEventData.Description = "no foundations configured"
 
-		p.EventManager.Emit(S.Event{Type: "validate.foundationsUnavailable", Data: precheckerEventData})
+		p.emit(precheckerEventData)
 
 		return NoFoundationsConfiguredError{}
 	}
 
+	timeout := environment.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: true, MinVersion: p.MinTLSVersion}
+	if environment.ClientCertificate != nil {
+		tlsConfig.Certificates = []tls.Certificate{*environment.ClientCertificate}
+	}
+
 	insecureClient := &http.Client{
 		Transport: &http.Transport{
-			TLSClientConfig:       &tls.Config{InsecureSkipVerify: true},
-			ResponseHeaderTimeout: 15 * time.Second,
+			TLSClientConfig:       tlsConfig,
+			ResponseHeaderTimeout: timeout,
 		},
 	}
 
-	for _, foundationURL := range environment.Foundations {
-		resp, err := insecureClient.Get(fmt.Sprintf("%s/v2/info", foundationURL))
-		if err != nil {
-			return InvalidGetRequestError{foundationURL, err}
+	foundationURLs := environment.Foundations
+	var errs []error
+
+	for attempt := 0; ; attempt++ {
+		foundationURLs, errs = checkFoundations(insecureClient, foundationURLs)
+		if len(errs) == 0 {
+			return nil
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
-			err := FoundationUnavailableError{foundationURL, resp.Status}
+		if attempt >= p.Retries {
+			break
+		}
+		p.clock().Sleep(p.RetryDelay)
+	}
 
-			precheckerEventData.Description = err.Error()
+	var err error = FoundationsUnavailableError{errs}
+	if len(errs) == 1 {
+		err = errs[0]
+	}
+
+	precheckerEventData.Description = err.Error()
 
-			p.EventManager.Emit(S.Event{Type: "validate.foundationsUnavailable", Data: precheckerEventData})
+	p.emit(precheckerEventData)
 
-			return err
+	return err
+}
+
+func (p Prechecker) emit(precheckerEventData S.PrecheckerEventData) {
+	if p.EventManager == nil {
+		return
+	}
+	p.EventManager.Emit(S.Event{Type: "validate.foundationsUnavailable", Data: precheckerEventData})
+}
+
+// checkFoundations returns the foundations that are down along with why each of them is down.
+func checkFoundations(client *http.Client, foundationURLs []string) ([]string, []error) {
+	var (
+		downURLs []string
+		errs     []error
+	)
+
+	for _, foundationURL := range foundationURLs {
+		resp, err := client.Get(fmt.Sprintf("%s/v2/info", foundationURL))
+		if err != nil {
+			downURLs = append(downURLs, foundationURL)
+			errs = append(errs, InvalidGetRequestError{foundationURL, err})
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			downURLs = append(downURLs, foundationURL)
+			errs = append(errs, FoundationUnavailableError{foundationURL, resp.Status})
 		}
 	}
 
-	return nil
+	return downURLs, errs
+}
+
+func (p Prechecker) clock() I.Clock {
+	if p.Clock == nil {
+		return clock.Clock{}
+	}
+	return p.Clock
 }
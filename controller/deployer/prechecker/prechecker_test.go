@@ -1,9 +1,17 @@
 package prechecker_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"errors"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"time"
 
 	"github.com/compozed/deployadactyl/config"
 	. "github.com/compozed/deployadactyl/controller/deployer/prechecker"
@@ -115,6 +123,156 @@ var _ = Describe("Prechecker", func() {
 			})
 		})
 
+		Context("when there is no EventManager", func() {
+			It("returns an error without emitting an event", func() {
+				prechecker.EventManager = nil
+
+				httpStatus = http.StatusInternalServerError
+
+				Expect(prechecker.AssertAllFoundationsUp(environment)).ToNot(Succeed())
+
+				Expect(foundationURls).To(ConsistOf("/v2/info"))
+			})
+		})
+
+		Context("when a foundation is down", func() {
+			var attempts int
+
+			BeforeEach(func() {
+				attempts = 0
+				testServer.Close()
+				testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					attempts++
+					if attempts == 1 {
+						w.WriteHeader(http.StatusServiceUnavailable)
+						return
+					}
+					w.WriteHeader(http.StatusOK)
+				}))
+
+				environment.Foundations = []string{testServer.URL}
+				prechecker.Retries = 2
+				prechecker.RetryDelay = time.Millisecond
+			})
+
+			It("succeeds when the foundation is up on the second attempt", func() {
+				Expect(prechecker.AssertAllFoundationsUp(environment)).To(Succeed())
+
+				Expect(attempts).To(Equal(2))
+				Expect(eventManager.EmitCall.Received.Events).To(BeEmpty())
+			})
+
+			It("waits the RetryDelay before retrying", func() {
+				clock := &mocks.Clock{}
+				prechecker.Clock = clock
+				prechecker.RetryDelay = 5 * time.Second
+
+				Expect(prechecker.AssertAllFoundationsUp(environment)).To(Succeed())
+
+				Expect(clock.SleepCall.Received.Durations).To(Equal([]time.Duration{5 * time.Second}))
+			})
+
+			It("only retries the foundations that are down", func() {
+				var upAttempts int
+				upServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					upAttempts++
+					w.WriteHeader(http.StatusOK)
+				}))
+				defer upServer.Close()
+
+				environment.Foundations = []string{upServer.URL, testServer.URL}
+
+				Expect(prechecker.AssertAllFoundationsUp(environment)).To(Succeed())
+
+				Expect(upAttempts).To(Equal(1))
+				Expect(attempts).To(Equal(2))
+			})
+
+			It("only reports the foundations that are still down after every retry", func() {
+				downServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusInternalServerError)
+				}))
+				defer downServer.Close()
+
+				environment.Foundations = []string{testServer.URL, downServer.URL}
+
+				err := prechecker.AssertAllFoundationsUp(environment)
+
+				Expect(err).To(MatchError(FoundationUnavailableError{downServer.URL, "500 Internal Server Error"}))
+				Expect(err.Error()).ToNot(ContainSubstring(testServer.URL))
+				Expect(eventManager.EmitCall.Received.Events).To(HaveLen(1))
+			})
+
+			It("reports every foundation that is still down", func() {
+				prechecker.Retries = 0
+
+				downServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusInternalServerError)
+				}))
+				defer downServer.Close()
+
+				environment.Foundations = []string{testServer.URL, downServer.URL}
+
+				err := prechecker.AssertAllFoundationsUp(environment)
+
+				Expect(err).To(MatchError(FoundationsUnavailableError{[]error{
+					FoundationUnavailableError{testServer.URL, "503 Service Unavailable"},
+					FoundationUnavailableError{downServer.URL, "500 Internal Server Error"},
+				}}))
+			})
+		})
+
+		Context("when a foundation only supports a TLS version below the minimum", func() {
+			It("rejects the connection", func() {
+				tlsServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				}))
+				tlsServer.TLS = &tls.Config{MaxVersion: tls.VersionTLS11}
+				tlsServer.StartTLS()
+				defer tlsServer.Close()
+
+				environment.Foundations = []string{tlsServer.URL}
+				prechecker.MinTLSVersion = tls.VersionTLS12
+
+				err := prechecker.AssertAllFoundationsUp(environment)
+
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(tlsServer.URL))
+			})
+		})
+
+		Context("when a foundation requires a client certificate", func() {
+			var tlsServer *httptest.Server
+
+			BeforeEach(func() {
+				tlsServer = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				}))
+				tlsServer.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+				tlsServer.StartTLS()
+
+				environment.Foundations = []string{tlsServer.URL}
+			})
+
+			AfterEach(func() {
+				tlsServer.Close()
+			})
+
+			It("presents the client certificate of the environment", func() {
+				certificate := newClientCertificate()
+				environment.ClientCertificate = &certificate
+
+				Expect(prechecker.AssertAllFoundationsUp(environment)).To(Succeed())
+			})
+
+			It("cannot connect without a client certificate", func() {
+				err := prechecker.AssertAllFoundationsUp(environment)
+
+				Expect(err).To(BeAssignableToTypeOf(InvalidGetRequestError{}))
+				Expect(err.Error()).To(ContainSubstring(tlsServer.URL))
+			})
+		})
+
 		Context("when a foundation returns a 404 not found", func() {
 			It("returns an error and emits an event", func() {
 				event = S.Event{
@@ -133,5 +291,42 @@ var _ = Describe("Prechecker", func() {
 				Expect(eventManager.EmitCall.Received.Events[0]).ToNot(BeNil())
 			})
 		})
+
+		Context("when a foundation does not respond within the timeout of the environment", func() {
+			It("returns an error", func() {
+				release := make(chan struct{})
+				slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					<-release
+				}))
+				defer slowServer.Close()
+				defer close(release)
+
+				environment.Foundations = []string{slowServer.URL}
+				environment.Timeout = 10 * time.Millisecond
+
+				err := prechecker.AssertAllFoundationsUp(environment)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(slowServer.URL))
+			})
+		})
 	})
 })
+
+// newClientCertificate returns a self-signed client certificate.
+func newClientCertificate() tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "deployadactyl"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).ToNot(HaveOccurred())
+
+	return tls.Certificate{Certificate: [][]byte{certificate}, PrivateKey: key}
+}
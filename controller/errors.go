@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+)
+
+type MultipartFormError struct {
+	Err error
+}
+
+func (e MultipartFormError) Error() string {
+	return fmt.Sprintf("cannot read multipart form: %s", e.Err)
+}
+
+type MissingArtifactFileError struct{}
+
+func (e MissingArtifactFileError) Error() string {
+	return "multipart form has no artifact file"
+}
+
+type DecompressError struct {
+	Err error
+}
+
+func (e DecompressError) Error() string {
+	return fmt.Sprintf("cannot decompress request body: %s", e.Err)
+}
+
+type EnvironmentNotFoundError struct {
+	Environment string
+}
+
+func (e EnvironmentNotFoundError) Error() string {
+	return fmt.Sprintf("environment not found: %s", e.Environment)
+}
+
+type RequestBodyTooLargeError struct {
+	Limit int64
+}
+
+func (e RequestBodyTooLargeError) Error() string {
+	return fmt.Sprintf("payload too large: the request body is larger than %d bytes", e.Limit)
+}
+
+// OverflowError is a deploy the Limiter rejected because too many deploys are running and waiting.
+// RetryAfter is how long the client should wait before retrying it.
+type OverflowError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e OverflowError) Error() string {
+	return e.Err.Error()
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+func isGzipped(r deployRequest) bool {
+	return strings.EqualFold(strings.TrimSpace(r.request.Header.Get("Content-Encoding")), "gzip")
+}
+
+// decompressed returns a deployRequest with the gzip compressed body of the request decompressed into a temp file.
+// The whole body is decompressed up front so that a malformed body is rejected before the deploy starts.
+// A body that decompresses to more than maxSize bytes is rejected with a RequestBodyTooLargeError. It is not limited when maxSize is not positive.
+//
+// The returned function closes and removes the temp file.
+func (r deployRequest) decompressed(maxSize int64) (deployRequest, func(), error) {
+	body, err := gzip.NewReader(r.request.Body)
+	if err != nil {
+		return r, nil, DecompressError{err}
+	}
+	defer body.Close()
+
+	file, err := ioutil.TempFile("", "deployadactyl-")
+	if err != nil {
+		return r, nil, err
+	}
+	cleanUp := func() {
+		file.Close()
+		os.Remove(file.Name())
+	}
+
+	var reader io.Reader = body
+	if maxSize > 0 {
+		reader = io.LimitReader(body, maxSize+1)
+	}
+
+	size, err := io.Copy(file, reader)
+	if err != nil {
+		cleanUp()
+		return r, nil, DecompressError{err}
+	}
+	if maxSize > 0 && size > maxSize {
+		cleanUp()
+		return r, nil, RequestBodyTooLargeError{maxSize}
+	}
+
+	_, err = file.Seek(0, 0)
+	if err != nil {
+		cleanUp()
+		return r, nil, err
+	}
+
+	decompressedRequest := *r.request
+	decompressedRequest.Body = file
+	decompressedRequest.ContentLength = -1
+	r.request = &decompressedRequest
+
+	return r, cleanUp, nil
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"mime"
+	"sort"
+)
+
+const (
+	multipartContentType = "multipart/form-data"
+	artifactField        = "artifact"
+	maxMultipartMemory   = 32 << 20
+)
+
+func isMultipart(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == multipartContentType
+}
+
+// fromMultipartForm returns a deployRequest for the zip file uploaded in a multipart form, the same as an application/zip request.
+// The file is read from the artifact field, or from the first file field when there is no artifact field.
+// Non-empty org and space fields override the org and space of the request.
+//
+// The returned function closes the file and removes anything stored on disk while reading the form.
+func (r deployRequest) fromMultipartForm() (deployRequest, func(), error) {
+	err := r.request.ParseMultipartForm(maxMultipartMemory)
+	if err != nil {
+		return r, nil, MultipartFormError{err}
+	}
+	form := r.request.MultipartForm
+
+	fieldNames := make([]string, 0, len(form.File))
+	for name, files := range form.File {
+		if len(files) > 0 {
+			fieldNames = append(fieldNames, name)
+		}
+	}
+	if len(fieldNames) == 0 {
+		form.RemoveAll()
+		return r, nil, MissingArtifactFileError{}
+	}
+	sort.Strings(fieldNames)
+
+	fieldName := fieldNames[0]
+	if len(form.File[artifactField]) > 0 {
+		fieldName = artifactField
+	}
+
+	file, err := form.File[fieldName][0].Open()
+	if err != nil {
+		form.RemoveAll()
+		return r, nil, MultipartFormError{err}
+	}
+
+	if org := r.request.FormValue("org"); org != "" {
+		r.org = org
+	}
+	if space := r.request.FormValue("space"); space != "" {
+		r.space = space
+	}
+
+	zipRequest := *r.request
+	zipRequest.Body = file
+	r.request = &zipRequest
+	r.contentType = "application/zip"
+
+	return r, func() {
+		file.Close()
+		form.RemoveAll()
+	}, nil
+}
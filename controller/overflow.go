@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	retryAfterPerDeploy = time.Second
+	maxRetryAfter       = time.Minute
+	maxRetryAfterShift  = 6
+)
+
+// overflow counts the deploys held by the Limiter and the deploys it rejected in a row,
+// so that rejected clients are told to retry later the busier the server is.
+type overflow struct {
+	mutex    sync.Mutex
+	pending  int
+	rejected int
+}
+
+// hold counts a deploy that is waiting for or holding a slot of the Limiter.
+func (o *overflow) hold() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.pending++
+}
+
+// admit counts a deploy that got a slot, which ends the run of rejected deploys.
+func (o *overflow) admit() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.rejected = 0
+}
+
+// release counts a deploy that gave its slot back.
+func (o *overflow) release() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.pending--
+}
+
+// reject counts a deploy that was rejected and returns how long its client should wait before retrying.
+// The wait is a second for every deploy running or queued, doubled for every deploy rejected in a row before it,
+// up to a minute. Up to half of it is added as jitter so that the rejected clients do not all retry at once.
+func (o *overflow) reject() time.Duration {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	o.pending--
+	o.rejected++
+
+	retryAfter := time.Duration(o.pending) * retryAfterPerDeploy
+	if retryAfter < retryAfterPerDeploy {
+		retryAfter = retryAfterPerDeploy
+	}
+
+	shift := o.rejected - 1
+	if shift > maxRetryAfterShift {
+		shift = maxRetryAfterShift
+	}
+	retryAfter <<= uint(shift)
+
+	if retryAfter > maxRetryAfter {
+		retryAfter = maxRetryAfter
+	}
+
+	return retryAfter + time.Duration(rand.Int63n(int64(retryAfter/2)+1))
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/controller/deployer/orgspace"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+const jsonContentType = "application/json"
+
+// bodyTarget is the org and space of a JSON deploy request body.
+type bodyTarget struct {
+	Org   string `json:"org"`
+	Space string `json:"space"`
+}
+
+// withTarget returns a deployRequest whose target is the org and space the Deployer is expected to resolve,
+// so that the deploys of an application to different orgs and spaces have different keys.
+// An org or space that is missing from the URL is taken from a JSON request body and then rendered from the templates
+// of the environment, the same as the Deployer does. The body is read into memory so that the Deployer can still read it.
+func (r deployRequest) withTarget(environments map[string]config.Environment) (deployRequest, error) {
+	org, space := r.org, r.space
+
+	if (org == "" || space == "") && r.contentType == jsonContentType && r.request.Body != nil {
+		body, err := ioutil.ReadAll(r.request.Body)
+		if err != nil {
+			return r, err
+		}
+
+		request := *r.request
+		request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.request = &request
+
+		// A body that is not valid JSON is rejected by the Deployer.
+		var target bodyTarget
+		json.Unmarshal(body, &target)
+
+		if org == "" {
+			org = target.Org
+		}
+		if space == "" {
+			space = target.Space
+		}
+	}
+
+	if environment, found := environments[r.environment]; found && (org == "" || space == "") {
+		resolvedOrg, resolvedSpace, err := orgspace.Resolve(environment, r.environment, org, space, r.appName)
+		if err == nil {
+			org, space = resolvedOrg, resolvedSpace
+		}
+	}
+
+	*r.target = S.DeployTarget{Org: org, Space: space}
+
+	return r, nil
+}
@@ -8,9 +8,12 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"strings"
+	"time"
 
 	"github.com/compozed/deployadactyl/artifetcher"
 	"github.com/compozed/deployadactyl/artifetcher/extractor"
+	"github.com/compozed/deployadactyl/clock"
 	"github.com/compozed/deployadactyl/config"
 	"github.com/compozed/deployadactyl/controller"
 	"github.com/compozed/deployadactyl/controller/deployer"
@@ -18,11 +21,22 @@ import (
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/pusher"
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/pusher/courier"
 	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/pusher/courier/executor"
+	"github.com/compozed/deployadactyl/controller/deployer/bluegreen/pusher/tokenfetcher"
 	"github.com/compozed/deployadactyl/controller/deployer/prechecker"
+	"github.com/compozed/deployadactyl/debouncer"
+	"github.com/compozed/deployadactyl/deploylogs"
 	"github.com/compozed/deployadactyl/eventmanager"
+	"github.com/compozed/deployadactyl/eventstream"
+	"github.com/compozed/deployadactyl/fingerprints"
+	"github.com/compozed/deployadactyl/history"
 	I "github.com/compozed/deployadactyl/interfaces"
+	"github.com/compozed/deployadactyl/jobs"
+	"github.com/compozed/deployadactyl/limiter"
 	"github.com/compozed/deployadactyl/logger"
+	"github.com/compozed/deployadactyl/metrics"
 	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/compozed/deployadactyl/readiness"
+	"github.com/compozed/deployadactyl/signer"
 	"github.com/gin-gonic/gin"
 	"github.com/op/go-logging"
 	"github.com/spf13/afero"
@@ -31,10 +45,60 @@ import (
 // ENDPOINT is used by the handler to define the deployment endpoint.
 const ENDPOINT = "/v1/apps/:environment/:org/:space/:appName"
 
-// Creator has a config, eventManager, logger and writer for creating dependencies.
+// QUERY_ENDPOINT is used by the handler to define the deployment endpoint of clients that send the
+// environment, org, space and appName as query parameters.
+const QUERY_ENDPOINT = "/v1/apps"
+
+// HISTORY_ENDPOINT is used by the handler to define the deploy history endpoint.
+const HISTORY_ENDPOINT = "/v1/history"
+
+// DEPLOY_HISTORY_ENDPOINT is used by the handler to define the deploy history endpoint under /deploy.
+const DEPLOY_HISTORY_ENDPOINT = "/deploy/history"
+
+// EVENTS_ENDPOINT is used by the handler to define the endpoint for resuming a deploy event stream.
+const EVENTS_ENDPOINT = "/v1/deploys/:deployID/events"
+
+// JOB_STATUS_ENDPOINT is used by the handler to define the asynchronous deploy status endpoint.
+const JOB_STATUS_ENDPOINT = "/v1/deploy/status/:jobID"
+
+// DEPLOY_LOGS_ENDPOINT is used by the handler to define the endpoint that serves the log of a deploy.
+const DEPLOY_LOGS_ENDPOINT = "/v1/deploy/logs/:requestID"
+
+// UNVERSIONED_DEPLOY_LOGS_ENDPOINT is used by the handler to define the endpoint that serves the log of a deploy under /deploy.
+const UNVERSIONED_DEPLOY_LOGS_ENDPOINT = "/deploy/logs/:requestID"
+
+// ENVIRONMENTS_ENDPOINT is used by the handler to define the endpoint that lists the configured environments.
+const ENVIRONMENTS_ENDPOINT = "/environments"
+
+// METRICS_ENDPOINT is used by the handler to define the Prometheus metrics endpoint.
+const METRICS_ENDPOINT = "/metrics"
+
+// HEALTH_ENDPOINT is used by the handler to define the liveness endpoint.
+const HEALTH_ENDPOINT = "/health"
+
+// READY_ENDPOINT is used by the handler to define the readiness endpoint.
+const READY_ENDPOINT = "/ready"
+
+// VERSION_ENDPOINT is used by the handler to define the endpoint that reports the build that is running.
+const VERSION_ENDPOINT = "/version"
+
+// Creator has a config, eventManager, history, eventStreams, jobs, debouncer, limiter, fingerprints, metrics, readiness, tokenFetcher, logger and writer for creating dependencies.
+// handlerIDs are the ids of the webhook and Slack handlers registered with the eventManager for the environments of the config.
 type Creator struct {
 	config       config.Config
 	eventManager I.EventManager
+	handlerIDs   []int
+	history      I.History
+	eventStreams I.EventStreams
+	jobs         I.Jobs
+	debouncer    I.Debouncer
+	limiter      I.Limiter
+	fingerprints I.Fingerprints
+	metrics      I.Metrics
+	readiness    *readiness.Readiness
+	cache        *artifetcher.Cache
+	deployLogs   I.DeployLogs
+	tokenFetcher I.TokenFetcher
 	logger       *logging.Logger
 	writer       io.Writer
 	fileSystem   *afero.Afero
@@ -50,13 +114,14 @@ func Default() (Creator, error) {
 }
 
 // Custom returns a custom Creator with an Error.
-func Custom(level string, configFilename string) (Creator, error) {
+// The environments of every config file are merged.
+func Custom(level string, configFilenames ...string) (Creator, error) {
 	l, err := getLevel(level)
 	if err != nil {
 		return Creator{}, err
 	}
 
-	cfg, err := config.Custom(os.Getenv, configFilename)
+	cfg, err := config.CustomMulti(os.Getenv, configFilenames...)
 	if err != nil {
 		return Creator{}, err
 	}
@@ -66,14 +131,67 @@ func Custom(level string, configFilename string) (Creator, error) {
 // CreateControllerHandler returns a gin.Engine that implements http.Handler.
 // Sets up the controller endpoint.
 func (c Creator) CreateControllerHandler() *gin.Engine {
+	return c.createHandler(c.createController())
+}
+
+// CreateReloadableControllerHandler returns a gin.Engine like CreateControllerHandler and a function that swaps the
+// environments of its controller and readiness check and the config of its deployer for the ones of cfg.
+// The webhook and Slack handlers of the old environments are replaced with the ones of the new environments.
+// Deploys that have already started keep the config they started with.
+// The settings that are only read at start up are logged when cfg changes them, since they still need a restart.
+//
+// The reload function returns an error and keeps the old config when the handlers of cfg cannot be created.
+func (c Creator) CreateReloadableControllerHandler() (*gin.Engine, func(cfg config.Config) error) {
 	controller := c.createController()
+	current := c
 
+	reload := func(cfg config.Config) error {
+		handlerIDs, err := addEnvironmentHandlers(c.eventManager, cfg, c.logger)
+		if err != nil {
+			return err
+		}
+
+		reloaded := current
+		reloaded.config = cfg
+		reloaded.handlerIDs = handlerIDs
+
+		controller.Reload(reloaded.createDeployer(), cfg.Environments)
+		c.readiness.Reload(cfg.Environments)
+
+		for _, id := range current.handlerIDs {
+			c.eventManager.RemoveHandler(id)
+		}
+
+		if keys := restartKeys(current.config, cfg); len(keys) > 0 {
+			c.logger.Warningf("the config was reloaded but these settings need a restart to change: %s", strings.Join(keys, ", "))
+		}
+
+		current = reloaded
+		return nil
+	}
+
+	return c.createHandler(controller), reload
+}
+
+func (c Creator) createHandler(controller *controller.Controller) *gin.Engine {
 	r := gin.New()
 	r.Use(gin.Recovery())
 	r.Use(gin.LoggerWithWriter(c.createWriter()))
 	r.Use(gin.ErrorLogger())
 
 	r.POST(ENDPOINT, controller.Deploy)
+	r.POST(QUERY_ENDPOINT, controller.Deploy)
+	r.GET(HISTORY_ENDPOINT, controller.GetHistory)
+	r.GET(DEPLOY_HISTORY_ENDPOINT, controller.GetHistory)
+	r.GET(EVENTS_ENDPOINT, controller.GetEvents)
+	r.GET(JOB_STATUS_ENDPOINT, controller.GetJobStatus)
+	r.GET(DEPLOY_LOGS_ENDPOINT, controller.GetDeployLog)
+	r.GET(UNVERSIONED_DEPLOY_LOGS_ENDPOINT, controller.GetDeployLog)
+	r.GET(METRICS_ENDPOINT, controller.GetMetrics)
+	r.GET(ENVIRONMENTS_ENDPOINT, controller.ListEnvironments)
+	r.GET(HEALTH_ENDPOINT, controller.GetHealth)
+	r.GET(READY_ENDPOINT, controller.GetReady)
+	r.GET(VERSION_ENDPOINT, controller.GetVersion)
 
 	return r
 }
@@ -104,7 +222,11 @@ func (c Creator) CreatePusher() (I.Pusher, error) {
 		Courier: courier.Courier{
 			Executor: ex,
 		},
-		Log: c.CreateLogger(),
+		TokenFetcher:    c.tokenFetcher,
+		Log:             c.CreateLogger(),
+		LoginRetries:    2,
+		LoginRetryDelay: time.Second,
+		Clock:           c.createClock(),
 	}
 
 	return p, nil
@@ -125,10 +247,70 @@ func (c Creator) CreateEventManager() I.EventManager {
 	return c.eventManager
 }
 
-func (c Creator) createController() controller.Controller {
-	return controller.Controller{
-		Deployer: c.createDeployer(),
-		Log:      c.CreateLogger(),
+// CreateHistory returns a History.
+func (c Creator) CreateHistory() I.History {
+	return c.history
+}
+
+// CreateEventStreams returns EventStreams.
+func (c Creator) CreateEventStreams() I.EventStreams {
+	return c.eventStreams
+}
+
+// CreateJobs returns Jobs.
+func (c Creator) CreateJobs() I.Jobs {
+	return c.jobs
+}
+
+// CreateDebouncer returns a Debouncer.
+func (c Creator) CreateDebouncer() I.Debouncer {
+	return c.debouncer
+}
+
+// CreateLimiter returns a Limiter.
+func (c Creator) CreateLimiter() I.Limiter {
+	return c.limiter
+}
+
+// CreateFingerprints returns Fingerprints, or nil when identical deploys are never skipped.
+func (c Creator) CreateFingerprints() I.Fingerprints {
+	return c.fingerprints
+}
+
+// CreateMetrics returns Metrics.
+func (c Creator) CreateMetrics() I.Metrics {
+	return c.metrics
+}
+
+// CreateDeployLogs returns DeployLogs, or nil when deploys are not logged.
+func (c Creator) CreateDeployLogs() I.DeployLogs {
+	return c.deployLogs
+}
+
+// CreateReadiness returns a Readiness.
+func (c Creator) CreateReadiness() I.Readiness {
+	return c.readiness
+}
+
+func (c Creator) createController() *controller.Controller {
+	return &controller.Controller{
+		Deployer:       c.createDeployer(),
+		History:        c.CreateHistory(),
+		EventStreams:   c.CreateEventStreams(),
+		Jobs:           c.CreateJobs(),
+		Debouncer:      c.CreateDebouncer(),
+		Limiter:        c.CreateLimiter(),
+		Metrics:        c.CreateMetrics(),
+		Readiness:      c.CreateReadiness(),
+		DeployLogs:     c.CreateDeployLogs(),
+		Environments:   c.CreateConfig().Environments,
+		Signer:         signer.New(c.CreateConfig().ResultSigningKey),
+		Randomizer:     c.createRandomizer(),
+		ResultSentinel: c.CreateConfig().ResultSentinel,
+		AuthRealm:      c.CreateConfig().AuthRealm,
+		Log:            c.CreateLogger(),
+
+		MaxRequestBodySize: c.CreateConfig().MaxRequestBodySize,
 	}
 }
 
@@ -142,6 +324,12 @@ func (c Creator) createDeployer() I.Deployer {
 		Randomizer:   c.createRandomizer(),
 		Log:          c.CreateLogger(),
 		FileSystem:   c.createFileSystem(),
+		Metrics:      c.CreateMetrics(),
+
+		RollingGreener: c.createRollingGreener(),
+		Fingerprints:   c.CreateFingerprints(),
+		DeployLogs:     c.CreateDeployLogs(),
+		Clock:          c.createClock(),
 	}
 }
 
@@ -152,16 +340,41 @@ func (c Creator) createFetcher() I.Fetcher {
 			Log:        c.CreateLogger(),
 			FileSystem: c.createFileSystem(),
 		},
-		Log: c.CreateLogger(),
+		Log:           c.CreateLogger(),
+		MinTLSVersion: c.CreateConfig().MinTLSVersion,
+		Retries:       3,
+		RetryDelay:    time.Second,
+		S3Region:      c.CreateConfig().S3Region,
+		S3Credentials: c.createS3Credentials(),
+		Cache:         c.cache,
+		Clock:         c.createClock(),
+		EventManager:  c.CreateEventManager(),
+	}
+}
+
+func (c Creator) createS3Credentials() func() (artifetcher.S3Credentials, error) {
+	if c.CreateConfig().S3CredentialSource == config.S3CredentialsSharedFile {
+		return artifetcher.SharedFileS3Credentials(os.Getenv, c.createFileSystem())
 	}
+	return artifetcher.EnvironmentS3Credentials(os.Getenv)
 }
 
 func (c Creator) createRandomizer() I.Randomizer {
 	return randomizer.Randomizer{}
 }
 
+func (c Creator) createClock() I.Clock {
+	return clock.Clock{}
+}
+
 func (c Creator) createPrechecker() I.Prechecker {
-	return prechecker.Prechecker{c.CreateEventManager()}
+	return prechecker.Prechecker{
+		EventManager:  c.CreateEventManager(),
+		MinTLSVersion: c.CreateConfig().MinTLSVersion,
+		Retries:       2,
+		RetryDelay:    5 * time.Second,
+		Clock:         c.createClock(),
+	}
 }
 
 func (c Creator) createWriter() io.Writer {
@@ -169,8 +382,17 @@ func (c Creator) createWriter() io.Writer {
 }
 
 func (c Creator) createBlueGreener() I.BlueGreener {
+	return c.createBlueGreen()
+}
+
+func (c Creator) createRollingGreener() I.BlueGreener {
+	return bluegreen.RollingGreener{BlueGreen: c.createBlueGreen()}
+}
+
+func (c Creator) createBlueGreen() bluegreen.BlueGreen {
 	return bluegreen.BlueGreen{
 		PusherCreator: c,
+		EventManager:  c.CreateEventManager(),
 		Log:           c.CreateLogger(),
 	}
 }
@@ -181,19 +403,199 @@ func createCreator(l logging.Level, cfg config.Config) (Creator, error) {
 		return Creator{}, err
 	}
 
-	logger := logger.DefaultLogger(os.Stdout, l, "controller")
+	logger := logger.DefaultLogger(os.Stdout, l, "controller", cfg.LogFormat)
 	eventManager := eventmanager.NewEventManager(logger)
 
+	handlerIDs, err := addEnvironmentHandlers(eventManager, cfg, logger)
+	if err != nil {
+		return Creator{}, err
+	}
+
+	_, err = eventManager.AddHandler(eventmanager.NewSmokeTestHandler(cfg.MinTLSVersion, logger), eventmanager.SmokeTestEventType)
+	if err != nil {
+		return Creator{}, err
+	}
+
+	var deployFingerprints I.Fingerprints
+	if cfg.RedeployWindow > 0 {
+		deployFingerprints = fingerprints.New(cfg.RedeployWindow)
+	}
+
+	fileSystem := &afero.Afero{Fs: afero.NewOsFs()}
+
+	readinessExecutor, err := executor.New(fileSystem)
+	if err != nil {
+		return Creator{}, err
+	}
+
+	// The prechecker of the readiness check has no EventManager, so a foundation that is down does not send an alert on every probe.
+	deployReadiness := readiness.New(
+		courier.Courier{Executor: readinessExecutor},
+		prechecker.Prechecker{MinTLSVersion: cfg.MinTLSVersion},
+		cfg.Environments,
+	)
+
+	var artifactCache *artifetcher.Cache
+	if cfg.ArtifactCacheDirectory != "" {
+		artifactCache, err = artifetcher.NewCache(fileSystem, cfg.ArtifactCacheDirectory, cfg.ArtifactCacheSize)
+		if err != nil {
+			return Creator{}, err
+		}
+	}
+
+	var deployLogs I.DeployLogs
+	if cfg.DeployLogDirectory != "" {
+		logs, err := deploylogs.New(fileSystem, cfg.DeployLogDirectory, cfg.DeployLogRetention)
+		if err != nil {
+			return Creator{}, err
+		}
+		deployLogs = logs
+	}
+
+	deployHistory := history.New(cfg.HistorySize)
+	if cfg.HistoryFile != "" {
+		deployHistory, err = history.NewFile(fileSystem, cfg.HistoryFile, cfg.HistorySize, logger)
+		if err != nil {
+			return Creator{}, err
+		}
+	}
+
 	return Creator{
 		cfg,
 		eventManager,
+		handlerIDs,
+		deployHistory,
+		eventstream.New(eventstream.DefaultStreams, eventstream.DefaultBufferSize),
+		jobs.New(cfg.JobTTL),
+		debouncer.New(cfg.DeployDebounce),
+		limiter.New(cfg.MaxConcurrentDeploys, cfg.MaxQueuedDeploys),
+		deployFingerprints,
+		metrics.New(),
+		deployReadiness,
+		artifactCache,
+		deployLogs,
+		tokenfetcher.New(cfg.MinTLSVersion, logger),
 		logger,
 		os.Stdout,
-		&afero.Afero{Fs: afero.NewOsFs()},
+		fileSystem,
 	}, nil
 
 }
 
+// addEnvironmentHandlers registers the webhook and Slack handlers of every environment of cfg.
+// No handler is left registered when one of them cannot be registered.
+//
+// Returns the ids of the registered handlers.
+func addEnvironmentHandlers(eventManager I.EventManager, cfg config.Config, logger *logging.Logger) ([]int, error) {
+	webhookIDs, err := addWebhookHandlers(eventManager, cfg, logger)
+	if err == nil {
+		var slackIDs []int
+		slackIDs, err = addSlackHandlers(eventManager, cfg, logger)
+		webhookIDs = append(webhookIDs, slackIDs...)
+	}
+	if err != nil {
+		for _, id := range webhookIDs {
+			eventManager.RemoveHandler(id)
+		}
+		return nil, err
+	}
+
+	return webhookIDs, nil
+}
+
+// addWebhookHandlers registers a WebhookHandler for every event type of each environment that has a webhook url.
+//
+// Returns the ids of the handlers registered, even when it fails.
+func addWebhookHandlers(eventManager I.EventManager, cfg config.Config, logger *logging.Logger) ([]int, error) {
+	var ids []int
+
+	for _, environment := range cfg.Environments {
+		if environment.WebhookURL == "" {
+			continue
+		}
+
+		handler := eventmanager.NewWebhookHandler(environment.WebhookURL, environment.Name, cfg.MinTLSVersion, logger)
+		for _, eventType := range eventmanager.WebhookEventTypes {
+			id, err := eventManager.AddHandler(handler, eventType)
+			if err != nil {
+				return ids, err
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// addSlackHandlers registers a SlackHandler for the deploy outcomes of each environment with a Slack webhook url.
+// An environment without its own slack_webhook_url uses the global one.
+//
+// Returns the ids of the handlers registered, even when it fails.
+func addSlackHandlers(eventManager I.EventManager, cfg config.Config, logger *logging.Logger) ([]int, error) {
+	var ids []int
+
+	for _, environment := range cfg.Environments {
+		url := environment.SlackWebhookURL
+		if url == "" {
+			url = cfg.SlackWebhookURL
+		}
+		if url == "" {
+			continue
+		}
+
+		handler, err := eventmanager.NewSlackHandler(url, environment.Name, cfg.SlackTemplate, cfg.MinTLSVersion, logger)
+		if err != nil {
+			return ids, err
+		}
+
+		for _, eventType := range eventmanager.SlackEventTypes {
+			id, err := eventManager.AddHandler(handler, eventType)
+			if err != nil {
+				return ids, err
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// restartKeys returns the config keys that changed from old to new but are only read when Deployadactyl starts.
+func restartKeys(old, new config.Config) []string {
+	changed := []struct {
+		key     string
+		changed bool
+	}{
+		{"PORT", old.Port != new.Port},
+		{"min_tls_version", old.MinTLSVersion != new.MinTLSVersion},
+		{"max_concurrent_deploys", old.MaxConcurrentDeploys != new.MaxConcurrentDeploys},
+		{"max_queued_deploys", old.MaxQueuedDeploys != new.MaxQueuedDeploys},
+		{"deploy_debounce", old.DeployDebounce != new.DeployDebounce},
+		{"redeploy_window", old.RedeployWindow != new.RedeployWindow},
+		{"job_ttl", old.JobTTL != new.JobTTL},
+		{"history_size", old.HistorySize != new.HistorySize},
+		{"history_file", old.HistoryFile != new.HistoryFile},
+		{"artifact_cache_directory", old.ArtifactCacheDirectory != new.ArtifactCacheDirectory},
+		{"artifact_cache_size", old.ArtifactCacheSize != new.ArtifactCacheSize},
+		{"deploy_log_directory", old.DeployLogDirectory != new.DeployLogDirectory},
+		{"deploy_log_retention", old.DeployLogRetention != new.DeployLogRetention},
+		{"result_sentinel", old.ResultSentinel != new.ResultSentinel},
+		{"auth_realm", old.AuthRealm != new.AuthRealm},
+		{"max_request_body_size", old.MaxRequestBodySize != new.MaxRequestBodySize},
+		{"RESULT_SIGNING_KEY", old.ResultSigningKey != new.ResultSigningKey},
+		{"LOG_FORMAT", old.LogFormat != new.LogFormat},
+	}
+
+	var keys []string
+	for _, c := range changed {
+		if c.changed {
+			keys = append(keys, c.key)
+		}
+	}
+
+	return keys
+}
+
 func (c Creator) createFileSystem() *afero.Afero {
 	return c.fileSystem
 }
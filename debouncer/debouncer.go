@@ -0,0 +1,62 @@
+// Package debouncer coalesces rapid successive deploys of the same application so only the newest one runs.
+package debouncer
+
+import (
+	"sync"
+	"time"
+)
+
+// Debouncer holds each deploy for a window. A newer deploy with the same key during the window supersedes the held one.
+type Debouncer struct {
+	mutex   sync.Mutex
+	window  time.Duration
+	pending map[string]chan struct{}
+}
+
+// New returns a Debouncer that holds deploys for the window.
+// A window of zero or less does not hold deploys.
+func New(window time.Duration) *Debouncer {
+	return &Debouncer{
+		window:  window,
+		pending: make(map[string]chan struct{}),
+	}
+}
+
+// Wait holds the caller for the window and supersedes any deploy with the same key that is still being held.
+//
+// Returns a SupersededError if a newer deploy with the same key arrives within the window.
+func (d *Debouncer) Wait(key string) error {
+	if d.window <= 0 {
+		return nil
+	}
+
+	d.mutex.Lock()
+	if superseded, ok := d.pending[key]; ok {
+		close(superseded)
+	}
+
+	supersede := make(chan struct{})
+	d.pending[key] = supersede
+	d.mutex.Unlock()
+
+	timer := time.NewTimer(d.window)
+	defer timer.Stop()
+
+	select {
+	case <-supersede:
+		return SupersededError{key}
+	case <-timer.C:
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	select {
+	case <-supersede:
+		return SupersededError{key}
+	default:
+	}
+
+	delete(d.pending, key)
+	return nil
+}
@@ -0,0 +1,13 @@
+package debouncer_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDebouncer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Debouncer Suite")
+}
@@ -0,0 +1,83 @@
+package debouncer_test
+
+import (
+	"sync"
+	"time"
+
+	. "github.com/compozed/deployadactyl/debouncer"
+	"github.com/compozed/deployadactyl/randomizer"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Debouncer", func() {
+	var (
+		debouncer *Debouncer
+		key       string
+	)
+
+	BeforeEach(func() {
+		debouncer = New(100 * time.Millisecond)
+
+		key = "key-" + randomizer.StringRunes(10)
+	})
+
+	It("runs a single deploy after the window", func() {
+		start := time.Now()
+
+		Expect(debouncer.Wait(key)).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically(">=", 100*time.Millisecond))
+	})
+
+	It("only runs the last of three rapid deploys", func() {
+		var (
+			wg   sync.WaitGroup
+			errs = make([]error, 3)
+		)
+
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = debouncer.Wait(key)
+			}(i)
+
+			time.Sleep(10 * time.Millisecond)
+		}
+		wg.Wait()
+
+		Expect(errs[0]).To(MatchError(SupersededError{key}))
+		Expect(errs[1]).To(MatchError(SupersededError{key}))
+		Expect(errs[2]).ToNot(HaveOccurred())
+	})
+
+	It("does not supersede deploys with a different key", func() {
+		var (
+			wg    sync.WaitGroup
+			first error
+		)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			first = debouncer.Wait(key)
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+
+		Expect(debouncer.Wait("other-" + key)).To(Succeed())
+		wg.Wait()
+		Expect(first).ToNot(HaveOccurred())
+	})
+
+	Context("when the window is zero", func() {
+		It("does not hold the deploy", func() {
+			debouncer = New(0)
+			start := time.Now()
+
+			Expect(debouncer.Wait(key)).To(Succeed())
+			Expect(time.Since(start)).To(BeNumerically("<", 100*time.Millisecond))
+		})
+	})
+})
@@ -0,0 +1,11 @@
+package debouncer
+
+import "fmt"
+
+type SupersededError struct {
+	Key string
+}
+
+func (e SupersededError) Error() string {
+	return fmt.Sprintf("deploy superseded by a newer deploy: %s", e.Key)
+}
@@ -0,0 +1,141 @@
+// Package deploylogs persists the output of every deploy to a file named by its request id.
+package deploylogs
+
+import (
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/compozed/deployadactyl/logger"
+	"github.com/spf13/afero"
+)
+
+const logExtension = ".log"
+
+// DeployLogs writes the output of deploys to files in Directory named by their request id.
+// A log is removed once it is older than the Retention. Logs are never removed when it is zero.
+type DeployLogs struct {
+	FileSystem *afero.Afero
+	Directory  string
+	Retention  time.Duration
+	Now        func() time.Time
+}
+
+// New returns DeployLogs in directory that keeps every log for the retention.
+// The directory is created when it does not exist.
+func New(fileSystem *afero.Afero, directory string, retention time.Duration) (*DeployLogs, error) {
+	err := fileSystem.MkdirAll(directory, 0755)
+	if err != nil {
+		return nil, CreateDirectoryError{directory, err}
+	}
+
+	return &DeployLogs{
+		FileSystem: fileSystem,
+		Directory:  directory,
+		Retention:  retention,
+		Now:        time.Now,
+	}, nil
+}
+
+// Create removes the logs that are older than the Retention and starts the log of a deploy.
+// Writing to the log never fails, so that a deploy is not failed by its log. The first write error is returned by Close.
+// An existing log is never overwritten.
+//
+// Returns the log to write the output of the deploy to and an error. The error is a LogExistsError when the request id
+// already has a log.
+func (d *DeployLogs) Create(requestID string) (io.WriteCloser, error) {
+	if !logger.ValidRequestID(requestID) {
+		return nil, InvalidRequestIDError{requestID}
+	}
+
+	d.expire()
+
+	if d.Exists(requestID) {
+		return nil, LogExistsError{requestID}
+	}
+
+	file, err := d.FileSystem.OpenFile(d.path(requestID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return nil, LogExistsError{requestID}
+	}
+	if err != nil {
+		return nil, CreateLogError{requestID, err}
+	}
+
+	return &deployLog{file: file}, nil
+}
+
+// Exists says whether the request id already has a log, so that a deploy can be given another request id before it starts.
+func (d *DeployLogs) Exists(requestID string) bool {
+	if !logger.ValidRequestID(requestID) {
+		return false
+	}
+
+	exists, err := d.FileSystem.Exists(d.path(requestID))
+	return err == nil && exists
+}
+
+// Open returns the log of the deploy with the request id. The log of a deploy that is still running is not complete.
+//
+// Returns an UnknownLogError when there is no log for the request id.
+func (d *DeployLogs) Open(requestID string) (io.ReadCloser, error) {
+	if !logger.ValidRequestID(requestID) {
+		return nil, UnknownLogError{requestID}
+	}
+
+	d.expire()
+
+	file, err := d.FileSystem.Open(d.path(requestID))
+	if os.IsNotExist(err) {
+		return nil, UnknownLogError{requestID}
+	}
+	if err != nil {
+		return nil, OpenLogError{requestID, err}
+	}
+
+	return file, nil
+}
+
+func (d *DeployLogs) expire() {
+	if d.Retention <= 0 {
+		return
+	}
+
+	files, err := d.FileSystem.ReadDir(d.Directory)
+	if err != nil {
+		return
+	}
+
+	oldest := d.Now().Add(-d.Retention)
+	for _, file := range files {
+		if !file.IsDir() && path.Ext(file.Name()) == logExtension && file.ModTime().Before(oldest) {
+			d.FileSystem.Remove(path.Join(d.Directory, file.Name()))
+		}
+	}
+}
+
+func (d *DeployLogs) path(requestID string) string {
+	return path.Join(d.Directory, requestID+logExtension)
+}
+
+// deployLog is the log file of one deploy. It stops writing to the file after the first write error.
+type deployLog struct {
+	file afero.File
+	err  error
+}
+
+func (l *deployLog) Write(p []byte) (int, error) {
+	if l.err == nil {
+		_, l.err = l.file.Write(p)
+	}
+	return len(p), nil
+}
+
+func (l *deployLog) Close() error {
+	err := l.file.Close()
+	if l.err != nil {
+		return WriteLogError{l.err}
+	}
+	return err
+}
@@ -0,0 +1,13 @@
+package deploylogs_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDeployLogs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DeployLogs Suite")
+}
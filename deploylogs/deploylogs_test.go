@@ -0,0 +1,147 @@
+package deploylogs_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	. "github.com/compozed/deployadactyl/deploylogs"
+	"github.com/compozed/deployadactyl/randomizer"
+	"github.com/spf13/afero"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DeployLogs", func() {
+	var (
+		af         *afero.Afero
+		deployLogs *DeployLogs
+		requestID  string
+		now        time.Time
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		af = &afero.Afero{Fs: afero.NewMemMapFs()}
+		requestID = "requestID-" + randomizer.StringRunes(10)
+		now = time.Now()
+
+		deployLogs, err = New(af, "/logs", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+		deployLogs.Now = func() time.Time { return now }
+	})
+
+	writeLog := func(requestID, output string) {
+		deployLog, err := deployLogs.Create(requestID)
+		Expect(err).ToNot(HaveOccurred())
+
+		fmt.Fprint(deployLog, output)
+		Expect(deployLog.Close()).To(Succeed())
+	}
+
+	readLog := func(requestID string) (string, error) {
+		deployLog, err := deployLogs.Open(requestID)
+		if err != nil {
+			return "", err
+		}
+		defer deployLog.Close()
+
+		output, err := ioutil.ReadAll(deployLog)
+		return string(output), err
+	}
+
+	It("creates the log directory", func() {
+		Expect(af.IsDir("/logs")).To(BeTrue())
+	})
+
+	It("returns the output written to the log of a deploy", func() {
+		writeLog(requestID, "deploy output")
+
+		output, err := readLog(requestID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(output).To(Equal("deploy output"))
+	})
+
+	It("names the log file by the request id", func() {
+		writeLog(requestID, "deploy output")
+
+		Expect(af.Exists("/logs/" + requestID + ".log")).To(BeTrue())
+	})
+
+	Context("when the request id already has a log", func() {
+		It("keeps the earlier log and does not create another", func() {
+			writeLog(requestID, "first deploy output")
+
+			Expect(deployLogs.Exists(requestID)).To(BeTrue())
+
+			_, err := deployLogs.Create(requestID)
+			Expect(err).To(MatchError(LogExistsError{requestID}))
+
+			Expect(readLog(requestID)).To(Equal("first deploy output"))
+		})
+	})
+
+	It("does not say a request id without a log exists", func() {
+		Expect(deployLogs.Exists(requestID)).To(BeFalse())
+	})
+
+	It("returns an UnknownLogError for a deploy without a log", func() {
+		_, err := readLog(requestID)
+
+		Expect(err).To(MatchError(UnknownLogError{requestID}))
+	})
+
+	It("does not create a log for an invalid request id", func() {
+		_, err := deployLogs.Create("../requestID")
+
+		Expect(err).To(MatchError(InvalidRequestIDError{"../requestID"}))
+	})
+
+	It("does not open a log for an invalid request id", func() {
+		_, err := readLog("../requestID")
+
+		Expect(err).To(MatchError(UnknownLogError{"../requestID"}))
+	})
+
+	Describe("the retention", func() {
+		It("removes the logs older than the retention", func() {
+			writeLog(requestID, "deploy output")
+			Expect(af.Chtimes("/logs/"+requestID+".log", now.Add(-2*time.Hour), now.Add(-2*time.Hour))).To(Succeed())
+
+			_, err := readLog(requestID)
+
+			Expect(err).To(MatchError(UnknownLogError{requestID}))
+		})
+
+		It("keeps the logs within the retention", func() {
+			writeLog(requestID, "deploy output")
+			Expect(af.Chtimes("/logs/"+requestID+".log", now.Add(-30*time.Minute), now.Add(-30*time.Minute))).To(Succeed())
+
+			_, err := readLog(requestID)
+
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("removes the old logs when a deploy starts", func() {
+			writeLog(requestID, "deploy output")
+			Expect(af.Chtimes("/logs/"+requestID+".log", now.Add(-2*time.Hour), now.Add(-2*time.Hour))).To(Succeed())
+
+			writeLog("other-"+requestID, "deploy output")
+
+			Expect(af.Exists("/logs/" + requestID + ".log")).To(BeFalse())
+		})
+
+		It("keeps every log when the retention is zero", func() {
+			deployLogs.Retention = 0
+
+			writeLog(requestID, "deploy output")
+			Expect(af.Chtimes("/logs/"+requestID+".log", now.Add(-24*time.Hour), now.Add(-24*time.Hour))).To(Succeed())
+
+			_, err := readLog(requestID)
+
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,62 @@
+package deploylogs
+
+import "fmt"
+
+type CreateDirectoryError struct {
+	Directory string
+	Err       error
+}
+
+func (e CreateDirectoryError) Error() string {
+	return fmt.Sprintf("cannot create deploy log directory %s: %s", e.Directory, e.Err)
+}
+
+type InvalidRequestIDError struct {
+	RequestID string
+}
+
+func (e InvalidRequestIDError) Error() string {
+	return fmt.Sprintf("cannot log deploy: invalid request id: %s", e.RequestID)
+}
+
+type CreateLogError struct {
+	RequestID string
+	Err       error
+}
+
+func (e CreateLogError) Error() string {
+	return fmt.Sprintf("cannot create the log of deploy %s: %s", e.RequestID, e.Err)
+}
+
+type LogExistsError struct {
+	RequestID string
+}
+
+func (e LogExistsError) Error() string {
+	return fmt.Sprintf("cannot log deploy: request id %s already has a log", e.RequestID)
+}
+
+type WriteLogError struct {
+	Err error
+}
+
+func (e WriteLogError) Error() string {
+	return fmt.Sprintf("cannot write deploy log: %s", e.Err)
+}
+
+type UnknownLogError struct {
+	RequestID string
+}
+
+func (e UnknownLogError) Error() string {
+	return fmt.Sprintf("unknown deploy log: %s", e.RequestID)
+}
+
+type OpenLogError struct {
+	RequestID string
+	Err       error
+}
+
+func (e OpenLogError) Error() string {
+	return fmt.Sprintf("cannot open the log of deploy %s: %s", e.RequestID, e.Err)
+}
@@ -1,7 +1,119 @@
 package eventmanager
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 type InvalidArgumentError struct{}
 
 func (e InvalidArgumentError) Error() string {
 	return "invalid argument: error handler does not exist"
 }
+
+type HandlerError struct {
+	EventType string
+	Errs      []error
+}
+
+func (e HandlerError) Error() string {
+	messages := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("%d %s handler(s) failed: %s", len(e.Errs), e.EventType, strings.Join(messages, "; "))
+}
+
+// VetoError is returned by a deploy.start handler to reject the deploy.
+// The client gets the StatusCode and Message instead of the error of a failed event.
+type VetoError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e VetoError) Error() string {
+	return e.Message
+}
+
+type HandlerTimeoutError struct {
+	EventType string
+	Timeout   time.Duration
+}
+
+func (e HandlerTimeoutError) Error() string {
+	return fmt.Sprintf("a %s handler did not finish within %s", e.EventType, e.Timeout)
+}
+
+type HandlerWriterClosedError struct {
+	EventType string
+}
+
+func (e HandlerWriterClosedError) Error() string {
+	return fmt.Sprintf("a %s handler timed out and can no longer write to the deploy output", e.EventType)
+}
+
+type WebhookPostError struct {
+	URL       string
+	EventType string
+	Err       error
+}
+
+func (e WebhookPostError) Error() string {
+	return fmt.Sprintf("cannot post the %s event to the webhook %s: %s", e.EventType, e.URL, e.Err)
+}
+
+type WebhookStatusError struct {
+	URL        string
+	EventType  string
+	StatusCode int
+}
+
+func (e WebhookStatusError) Error() string {
+	return fmt.Sprintf("the webhook %s responded to the %s event with status %d", e.URL, e.EventType, e.StatusCode)
+}
+
+type SlackTemplateError struct {
+	Err error
+}
+
+func (e SlackTemplateError) Error() string {
+	return fmt.Sprintf("cannot parse the slack template: %s", e.Err)
+}
+
+type SlackPostError struct {
+	EventType string
+	Err       error
+}
+
+func (e SlackPostError) Error() string {
+	return fmt.Sprintf("cannot post the %s event to slack: %s", e.EventType, e.Err)
+}
+
+type SlackStatusError struct {
+	EventType  string
+	StatusCode int
+}
+
+func (e SlackStatusError) Error() string {
+	return fmt.Sprintf("slack responded to the %s event with status %d", e.EventType, e.StatusCode)
+}
+
+type SmokeTestRequestError struct {
+	URL string
+	Err error
+}
+
+func (e SmokeTestRequestError) Error() string {
+	return fmt.Sprintf("smoke test of %s failed: %s", e.URL, e.Err)
+}
+
+type SmokeTestStatusError struct {
+	URL            string
+	ExpectedStatus int
+	StatusCode     int
+}
+
+func (e SmokeTestStatusError) Error() string {
+	return fmt.Sprintf("smoke test of %s failed: expected status %d but got %d", e.URL, e.ExpectedStatus, e.StatusCode)
+}
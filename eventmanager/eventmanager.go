@@ -2,42 +2,214 @@
 package eventmanager
 
 import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
 	I "github.com/compozed/deployadactyl/interfaces"
 	S "github.com/compozed/deployadactyl/structs"
 	"github.com/op/go-logging"
+	"golang.org/x/net/context"
 )
 
+// DefaultTimeout is the handler Timeout NewEventManager gives an EventManager.
+const DefaultTimeout = time.Minute
+
 // EventManager has handlers for each registered event type.
+// Handlers can be added and removed while events are being emitted.
+//
+// Timeout limits how long a single handler can run before Emit treats it as failed.
 type EventManager struct {
-	handlers map[string][]I.Handler
+	mutex    sync.RWMutex
+	handlers map[string][]registeredHandler
+	nextID   int
+	Timeout  time.Duration
 	Log      *logging.Logger
 }
 
+type registeredHandler struct {
+	id       int
+	priority int
+	handler  I.Handler
+}
+
 // NewEventManager returns an EventManager.
 func NewEventManager(l *logging.Logger) *EventManager {
 	return &EventManager{
-		handlers: make(map[string][]I.Handler),
+		handlers: make(map[string][]registeredHandler),
+		Timeout:  DefaultTimeout,
 		Log:      l,
 	}
 }
 
 // AddHandler takes a handler and eventType and returns an error if a handler is not provided.
-func (e *EventManager) AddHandler(handler I.Handler, eventType string) error {
+// The handler is registered with a priority of 0.
+//
+// Returns an id that can be given to RemoveHandler to detach the handler.
+func (e *EventManager) AddHandler(handler I.Handler, eventType string) (int, error) {
+	return e.AddHandlerWithPriority(handler, eventType, 0)
+}
+
+// AddHandlerWithPriority takes a handler, eventType and priority and returns an error if a handler is not provided.
+//
+// Emit invokes handlers with a lower priority first. Handlers with the same priority are
+// invoked in the order they were added.
+func (e *EventManager) AddHandlerWithPriority(handler I.Handler, eventType string, priority int) (int, error) {
 	if handler == nil {
-		return InvalidArgumentError{}
+		return 0, InvalidArgumentError{}
 	}
-	e.handlers[eventType] = append(e.handlers[eventType], handler)
-	return nil
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.nextID++
+
+	handlers := e.handlers[eventType]
+	i := len(handlers)
+	for i > 0 && handlers[i-1].priority > priority {
+		i--
+	}
+
+	sorted := make([]registeredHandler, 0, len(handlers)+1)
+	sorted = append(sorted, handlers[:i]...)
+	sorted = append(sorted, registeredHandler{e.nextID, priority, handler})
+	sorted = append(sorted, handlers[i:]...)
+	e.handlers[eventType] = sorted
+
+	return e.nextID, nil
 }
 
-// Emit emits an event.
+// RemoveHandler detaches the handler with the id returned by AddHandler so that it is not invoked by later events.
+// Removing an id that is not registered does nothing.
+func (e *EventManager) RemoveHandler(id int) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for eventType, handlers := range e.handlers {
+		for i, registered := range handlers {
+			if registered.id != id {
+				continue
+			}
+
+			remaining := make([]registeredHandler, 0, len(handlers)-1)
+			remaining = append(remaining, handlers[:i]...)
+			remaining = append(remaining, handlers[i+1:]...)
+			e.handlers[eventType] = remaining
+
+			return
+		}
+	}
+}
+
+// Emit emits an event to every handler registered for its type.
+//
+// A failing handler does not stop the remaining handlers from running. A handler that runs
+// longer than the Timeout fails with a HandlerTimeoutError. If any of them fail a HandlerError
+// holding every returned error is returned.
 func (e *EventManager) Emit(event S.Event) error {
-	for _, handler := range e.handlers[event.Type] {
-		err := handler.OnEvent(event)
+	e.mutex.RLock()
+	handlers := e.handlers[event.Type]
+	e.mutex.RUnlock()
+
+	var errs []error
+
+	for _, registered := range handlers {
+		err := e.invoke(registered.handler, event)
 		if err != nil {
-			return err
+			errs = append(errs, err)
+			continue
 		}
 		e.Log.Debugf("a %s event has been emitted", event.Type)
 	}
+
+	if len(errs) > 0 {
+		return HandlerError{event.Type, errs}
+	}
 	return nil
 }
+
+// FindVeto returns the VetoError of the first handler that vetoed the event when err was returned by Emit.
+// A veto without an error StatusCode is given http.StatusForbidden.
+func FindVeto(err error) (VetoError, bool) {
+	errs := []error{err}
+	if handlerErr, ok := err.(HandlerError); ok {
+		errs = handlerErr.Errs
+	}
+
+	for _, err := range errs {
+		if veto, ok := err.(VetoError); ok {
+			if veto.StatusCode < http.StatusBadRequest {
+				veto.StatusCode = http.StatusForbidden
+			}
+			return veto, true
+		}
+	}
+
+	return VetoError{}, false
+}
+
+// invoke runs the handler until it returns or the Timeout of the EventManager has passed.
+// A ContextHandler is given a context that is done once the Timeout has passed so that it stops as well.
+//
+// The Writer of a DeployEventData is closed to the handler once it has timed out, so a handler that is
+// left running cannot write to the deploy output while the deploy carries on, and stops at its next write.
+//
+// The result channel is buffered so a handler that times out can still finish and exit
+// after Emit has moved on.
+func (e *EventManager) invoke(handler I.Handler, event S.Event) error {
+	ctx, cancel := context.WithTimeout(context.Background(), e.Timeout)
+	defer cancel()
+
+	var writer *handlerWriter
+	if data, ok := event.Data.(S.DeployEventData); ok && data.Writer != nil {
+		writer = &handlerWriter{writer: data.Writer, eventType: event.Type}
+		data.Writer = writer
+		event.Data = data
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		if contextHandler, ok := handler.(I.ContextHandler); ok {
+			result <- contextHandler.OnEventContext(ctx, event)
+			return
+		}
+		result <- handler.OnEvent(event)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		if writer != nil {
+			writer.close()
+		}
+		return HandlerTimeoutError{event.Type, e.Timeout}
+	}
+}
+
+// handlerWriter passes the writes of a handler on to the Writer of its event until it is closed.
+type handlerWriter struct {
+	mutex     sync.Mutex
+	writer    io.Writer
+	eventType string
+	closed    bool
+}
+
+func (w *handlerWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return 0, HandlerWriterClosedError{w.eventType}
+	}
+	return w.writer.Write(p)
+}
+
+// close stops passing writes on. A write in progress finishes before close returns.
+func (w *handlerWriter) close() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.closed = true
+}
@@ -2,6 +2,8 @@ package eventmanager_test
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -13,8 +15,61 @@ import (
 	"github.com/compozed/deployadactyl/mocks"
 	"github.com/compozed/deployadactyl/randomizer"
 	S "github.com/compozed/deployadactyl/structs"
+	"golang.org/x/net/context"
 )
 
+type blockingHandler struct {
+	release  chan struct{}
+	finished chan struct{}
+}
+
+func (b blockingHandler) OnEvent(event S.Event) error {
+	<-b.release
+	close(b.finished)
+	return nil
+}
+
+type contextHandler struct {
+	finished chan struct{}
+}
+
+func (c contextHandler) OnEvent(event S.Event) error {
+	select {}
+}
+
+func (c contextHandler) OnEventContext(ctx context.Context, event S.Event) error {
+	<-ctx.Done()
+	close(c.finished)
+	return ctx.Err()
+}
+
+type writingHandler struct {
+	finished chan struct{}
+}
+
+func (w writingHandler) OnEvent(event S.Event) error {
+	defer close(w.finished)
+
+	writer := event.Data.(S.DeployEventData).Writer
+	for {
+		_, err := fmt.Fprintln(writer, "still running")
+		if err != nil {
+			return err
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type recordingHandler struct {
+	name  string
+	order *[]string
+}
+
+func (r recordingHandler) OnEvent(event S.Event) error {
+	*r.order = append(*r.order, r.name)
+	return nil
+}
+
 var _ = Describe("Events", func() {
 	var (
 		eventType       string
@@ -35,27 +90,43 @@ var _ = Describe("Events", func() {
 		eventHandlerOne = &mocks.Handler{}
 		eventHandlerTwo = &mocks.Handler{}
 
-		eventManager = NewEventManager(log)
-
 		logBuffer = gbytes.NewBuffer()
 
-		log = logger.DefaultLogger(logBuffer, logging.DEBUG, "eventmanager_test")
+		log = logger.DefaultLogger(logBuffer, logging.DEBUG, "eventmanager_test", logger.TextFormat)
+
+		eventManager = NewEventManager(log)
 	})
 
 	Context("when an event handler is registered", func() {
 		It("should be successful", func() {
 			eventManager := NewEventManager(log)
 
-			Expect(eventManager.AddHandler(eventHandler, eventType)).To(Succeed())
+			_, err := eventManager.AddHandler(eventHandler, eventType)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns a different id for every handler", func() {
+			eventManager := NewEventManager(log)
+
+			idOne, err := eventManager.AddHandler(eventHandlerOne, eventType)
+			Expect(err).ToNot(HaveOccurred())
+			idTwo, err := eventManager.AddHandler(eventHandlerTwo, eventType)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(idOne).ToNot(Equal(idTwo))
 		})
 
 		It("should fail if a nil value is passed in as an argument", func() {
 			eventManager := NewEventManager(log)
 
-			err := eventManager.AddHandler(nil, eventType)
+			_, err := eventManager.AddHandler(nil, eventType)
 
 			Expect(err).To(MatchError(InvalidArgumentError{}))
 		})
+
+		It("has the default handler timeout", func() {
+			Expect(NewEventManager(log).Timeout).To(Equal(DefaultTimeout))
+		})
 	})
 
 	Context("when an event is emitted", func() {
@@ -81,10 +152,32 @@ var _ = Describe("Events", func() {
 
 			eventManager.AddHandler(eventHandler, eventType)
 
-			Expect(eventManager.Emit(event)).To(MatchError("on event error"))
+			Expect(eventManager.Emit(event)).To(MatchError(HandlerError{eventType, []error{errors.New("on event error")}}))
 			Expect(eventHandler.OnEventCall.Received.Event).To(Equal(event))
 		})
 
+		It("should run every handler and return all of their errors", func() {
+			eventHandlerThree := &mocks.Handler{}
+
+			eventHandlerOne.OnEventCall.Returns.Error = errors.New("first handler error")
+			eventHandlerTwo.OnEventCall.Returns.Error = nil
+			eventHandlerThree.OnEventCall.Returns.Error = errors.New("third handler error")
+
+			event := S.Event{Type: eventType, Data: eventData}
+
+			eventManager.AddHandler(eventHandlerOne, eventType)
+			eventManager.AddHandler(eventHandlerTwo, eventType)
+			eventManager.AddHandler(eventHandlerThree, eventType)
+
+			err := eventManager.Emit(event)
+
+			Expect(err).To(MatchError(HandlerError{eventType, []error{errors.New("first handler error"), errors.New("third handler error")}}))
+			Expect(err.Error()).To(ContainSubstring("first handler error; third handler error"))
+			Expect(eventHandlerOne.OnEventCall.Received.Event).To(Equal(event))
+			Expect(eventHandlerTwo.OnEventCall.Received.Event).To(Equal(event))
+			Expect(eventHandlerThree.OnEventCall.Received.Event).To(Equal(event))
+		})
+
 		It("should log that the event is emitted", func() {
 			eventHandler.OnEventCall.Returns.Error = nil
 
@@ -99,6 +192,183 @@ var _ = Describe("Events", func() {
 		})
 	})
 
+	Context("when handlers are registered with a priority", func() {
+		It("invokes them in ascending priority order", func() {
+			var order []string
+
+			eventManager.AddHandlerWithPriority(recordingHandler{"notify", &order}, eventType, 10)
+			eventManager.AddHandler(recordingHandler{"audit", &order}, eventType)
+			eventManager.AddHandlerWithPriority(recordingHandler{"validate", &order}, eventType, -5)
+			eventManager.AddHandlerWithPriority(recordingHandler{"record", &order}, eventType, 0)
+
+			Expect(eventManager.Emit(S.Event{Type: eventType, Data: eventData})).To(Succeed())
+
+			Expect(order).To(Equal([]string{"validate", "audit", "record", "notify"}))
+		})
+
+		It("should fail if a nil value is passed in as an argument", func() {
+			_, err := eventManager.AddHandlerWithPriority(nil, eventType, 1)
+
+			Expect(err).To(MatchError(InvalidArgumentError{}))
+		})
+	})
+
+	Context("when a handler runs longer than the timeout", func() {
+		var slowHandler blockingHandler
+
+		BeforeEach(func() {
+			slowHandler = blockingHandler{make(chan struct{}), make(chan struct{})}
+			eventManager.Timeout = 10 * time.Millisecond
+		})
+
+		AfterEach(func() {
+			select {
+			case <-slowHandler.release:
+			default:
+				close(slowHandler.release)
+			}
+		})
+
+		It("returns a timeout error and invokes the next handler", func() {
+			event := S.Event{Type: eventType, Data: eventData}
+
+			eventManager.AddHandler(slowHandler, eventType)
+			eventManager.AddHandler(eventHandler, eventType)
+
+			err := eventManager.Emit(event)
+
+			Expect(err).To(MatchError(HandlerError{eventType, []error{HandlerTimeoutError{eventType, 10 * time.Millisecond}}}))
+			Expect(eventHandler.OnEventCall.Received.Event).To(Equal(event))
+		})
+
+		It("lets the timed out handler finish after Emit returns", func() {
+			eventManager.AddHandler(slowHandler, eventType)
+
+			Expect(eventManager.Emit(S.Event{Type: eventType, Data: eventData})).ToNot(Succeed())
+
+			close(slowHandler.release)
+
+			Eventually(slowHandler.finished).Should(BeClosed())
+		})
+
+		It("closes the writer of a handler that only implements OnEvent so that it stops", func() {
+			response := gbytes.NewBuffer()
+			runawayHandler := writingHandler{make(chan struct{})}
+			eventManager.AddHandler(runawayHandler, eventType)
+
+			err := eventManager.Emit(S.Event{Type: eventType, Data: S.DeployEventData{Writer: response}})
+
+			Expect(err).To(MatchError(HandlerError{eventType, []error{HandlerTimeoutError{eventType, 10 * time.Millisecond}}}))
+			Eventually(runawayHandler.finished).Should(BeClosed())
+
+			output := string(response.Contents())
+			Consistently(func() string { return string(response.Contents()) }).Should(Equal(output))
+		})
+
+		It("cancels the context of a handler that never returns", func() {
+			hungHandler := contextHandler{make(chan struct{})}
+			eventManager.AddHandler(hungHandler, eventType)
+
+			err := eventManager.Emit(S.Event{Type: eventType, Data: eventData})
+
+			Expect(err).To(MatchError(HandlerError{eventType, []error{HandlerTimeoutError{eventType, 10 * time.Millisecond}}}))
+			Eventually(hungHandler.finished).Should(BeClosed())
+		})
+	})
+
+	Context("when a handler vetoes the event", func() {
+		It("finds the veto among the errors of the handlers", func() {
+			eventHandlerOne.OnEventCall.Returns.Error = errors.New("on event error")
+			eventHandlerTwo.OnEventCall.Returns.Error = VetoError{StatusCode: 403, Message: "production is frozen until the change window opens"}
+
+			eventManager.AddHandler(eventHandlerOne, eventType)
+			eventManager.AddHandler(eventHandlerTwo, eventType)
+
+			veto, ok := FindVeto(eventManager.Emit(S.Event{Type: eventType, Data: eventData}))
+
+			Expect(ok).To(BeTrue())
+			Expect(veto).To(Equal(VetoError{StatusCode: 403, Message: "production is frozen until the change window opens"}))
+		})
+
+		It("rejects with http.StatusForbidden when the veto has no error status code", func() {
+			eventHandler.OnEventCall.Returns.Error = VetoError{Message: "vetoed"}
+
+			eventManager.AddHandler(eventHandler, eventType)
+
+			veto, ok := FindVeto(eventManager.Emit(S.Event{Type: eventType, Data: eventData}))
+
+			Expect(ok).To(BeTrue())
+			Expect(veto.StatusCode).To(Equal(403))
+		})
+
+		It("does not find a veto when no handler vetoed", func() {
+			eventHandler.OnEventCall.Returns.Error = errors.New("on event error")
+
+			eventManager.AddHandler(eventHandler, eventType)
+
+			_, ok := FindVeto(eventManager.Emit(S.Event{Type: eventType, Data: eventData}))
+
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("when a handler is removed", func() {
+		It("is not invoked by later events", func() {
+			event := S.Event{Type: eventType, Data: eventData}
+
+			id, _ := eventManager.AddHandler(eventHandler, eventType)
+			eventManager.RemoveHandler(id)
+
+			Expect(eventManager.Emit(event)).To(Succeed())
+
+			Expect(eventHandler.OnEventCall.Received.Event).ToNot(Equal(event))
+		})
+
+		It("keeps invoking the handlers registered before and after it", func() {
+			event := S.Event{Type: eventType, Data: eventData}
+
+			eventManager.AddHandler(eventHandlerOne, eventType)
+			id, _ := eventManager.AddHandler(eventHandler, eventType)
+			eventManager.AddHandler(eventHandlerTwo, eventType)
+
+			eventManager.RemoveHandler(id)
+
+			Expect(eventManager.Emit(event)).To(Succeed())
+
+			Expect(eventHandlerOne.OnEventCall.Received.Event).To(Equal(event))
+			Expect(eventHandler.OnEventCall.Received.Event).ToNot(Equal(event))
+			Expect(eventHandlerTwo.OnEventCall.Received.Event).To(Equal(event))
+		})
+
+		It("only removes the handler for the event type it was added with", func() {
+			otherEventType := "otherEventType-" + randomizer.StringRunes(10)
+			event := S.Event{Type: otherEventType, Data: eventData}
+
+			id, _ := eventManager.AddHandler(eventHandler, eventType)
+			eventManager.AddHandler(eventHandler, otherEventType)
+
+			eventManager.RemoveHandler(id)
+
+			Expect(eventManager.Emit(event)).To(Succeed())
+
+			Expect(eventHandler.OnEventCall.Received.Event).To(Equal(event))
+		})
+	})
+
+	Context("when a handler that was never added is removed", func() {
+		It("does nothing", func() {
+			event := S.Event{Type: eventType, Data: eventData}
+
+			eventManager.AddHandler(eventHandler, eventType)
+
+			Expect(func() { eventManager.RemoveHandler(42) }).ToNot(Panic())
+
+			Expect(eventManager.Emit(event)).To(Succeed())
+
+			Expect(eventHandler.OnEventCall.Received.Event).To(Equal(event))
+		})
+	})
+
 	Context("when there are handlers registered for two different types of events", func() {
 		It("only emits to the specified event", func() {
 			eventHandlerOne.OnEventCall.Returns.Error = nil
@@ -0,0 +1,132 @@
+package eventmanager
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"text/template"
+
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/op/go-logging"
+	"golang.org/x/net/context"
+)
+
+// DefaultSlackTemplate is the message posted to Slack when no template is configured.
+const DefaultSlackTemplate = `{{if .Success}}:white_check_mark: Deployed{{else}}:x: Failed to deploy{{end}} {{.AppName}} to {{.Environment}} ({{.Org}}/{{.Space}}) for {{.User}}`
+
+// SlackEventTypes are the event types a SlackHandler is registered for.
+var SlackEventTypes = []string{
+	"deploy.success",
+	"deploy.failure",
+}
+
+// SlackHandler posts a message to a Slack incoming webhook when a deploy to its environment succeeds or fails.
+// Failing to post a message is logged and never fails the deploy.
+type SlackHandler struct {
+	URL         string
+	Environment string
+	Template    *template.Template
+	Client      *http.Client
+	Log         *logging.Logger
+}
+
+// SlackMessage is the data the template of a SlackHandler is executed with.
+type SlackMessage struct {
+	Success     bool
+	Type        string
+	AppName     string
+	Environment string
+	Org         string
+	Space       string
+	User        string
+}
+
+// NewSlackHandler returns a SlackHandler for the environment with a client that uses minTLSVersion.
+// The message is rendered from messageTemplate, or from DefaultSlackTemplate when it is empty.
+func NewSlackHandler(url, environment, messageTemplate string, minTLSVersion uint16, log *logging.Logger) (*SlackHandler, error) {
+	if messageTemplate == "" {
+		messageTemplate = DefaultSlackTemplate
+	}
+
+	tmpl, err := template.New("slack").Parse(messageTemplate)
+	if err != nil {
+		return nil, SlackTemplateError{err}
+	}
+
+	return &SlackHandler{
+		URL:         url,
+		Environment: environment,
+		Template:    tmpl,
+		Client: &http.Client{
+			Timeout: webhookTimeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{MinVersion: minTLSVersion},
+			},
+		},
+		Log: log,
+	}, nil
+}
+
+// OnEvent posts a message for the deploy.success and deploy.failure events of the environment of the SlackHandler.
+// The URL of the SlackHandler is left out of the logs because it carries the secret of the Slack webhook.
+//
+// Always returns nil so that Slack cannot fail a deploy.
+func (h *SlackHandler) OnEvent(event S.Event) error {
+	return h.OnEventContext(context.Background(), event)
+}
+
+// OnEventContext posts the message the same as OnEvent, cancelling the post when ctx is done.
+func (h *SlackHandler) OnEventContext(ctx context.Context, event S.Event) error {
+	data, ok := event.Data.(S.DeployEventData)
+	if !ok || data.DeploymentInfo == nil || data.DeploymentInfo.Environment != h.Environment {
+		return nil
+	}
+
+	message := SlackMessage{
+		Success:     event.Type == "deploy.success",
+		Type:        event.Type,
+		AppName:     data.DeploymentInfo.AppName,
+		Environment: data.DeploymentInfo.Environment,
+		Org:         data.DeploymentInfo.Org,
+		Space:       data.DeploymentInfo.Space,
+		User:        data.DeploymentInfo.Username,
+	}
+
+	var text bytes.Buffer
+	err := h.Template.Execute(&text, message)
+	if err != nil {
+		h.Log.Error(SlackPostError{event.Type, err}.Error())
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]string{"text": text.String()})
+	if err != nil {
+		h.Log.Error(SlackPostError{event.Type, err}.Error())
+		return nil
+	}
+
+	request, err := http.NewRequest("POST", h.URL, bytes.NewReader(body))
+	if err != nil {
+		h.Log.Error(SlackPostError{event.Type, err}.Error())
+		return nil
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := h.Client.Do(request.WithContext(ctx))
+	if err != nil {
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		h.Log.Error(SlackPostError{event.Type, err}.Error())
+		return nil
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusMultipleChoices {
+		h.Log.Error(SlackStatusError{event.Type, response.StatusCode}.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package eventmanager_test
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+
+	. "github.com/compozed/deployadactyl/eventmanager"
+	"github.com/compozed/deployadactyl/logger"
+	"github.com/compozed/deployadactyl/randomizer"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+var _ = Describe("SlackHandler", func() {
+	var (
+		server      *httptest.Server
+		messages    []string
+		statusCode  int
+		environment string
+		appName     string
+		password    string
+		handler     *SlackHandler
+		logBuffer   *gbytes.Buffer
+		log         *logging.Logger
+		deployEvent S.Event
+	)
+
+	BeforeEach(func() {
+		messages = nil
+		statusCode = http.StatusOK
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+
+			Expect(r.Method).To(Equal("POST"))
+			Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+
+			body, err := ioutil.ReadAll(r.Body)
+			Expect(err).ToNot(HaveOccurred())
+
+			var decoded map[string]string
+			Expect(json.Unmarshal(body, &decoded)).To(Succeed())
+			messages = append(messages, decoded["text"])
+
+			w.WriteHeader(statusCode)
+		}))
+
+		environment = "environment-" + randomizer.StringRunes(10)
+		appName = "appName-" + randomizer.StringRunes(10)
+		password = "password-" + randomizer.StringRunes(10)
+
+		logBuffer = gbytes.NewBuffer()
+		log = logger.DefaultLogger(logBuffer, logging.DEBUG, "slack_test", logger.TextFormat)
+
+		var err error
+		handler, err = NewSlackHandler(server.URL, environment, "", tls.VersionTLS12, log)
+		Expect(err).ToNot(HaveOccurred())
+
+		deployEvent = S.Event{
+			Type: "deploy.success",
+			Data: S.DeployEventData{
+				DeploymentInfo: &S.DeploymentInfo{
+					Environment: environment,
+					AppName:     appName,
+					Org:         "org",
+					Space:       "space",
+					Username:    "username",
+					Password:    password,
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("posts a message about a successful deploy", func() {
+		Expect(handler.OnEvent(deployEvent)).To(Succeed())
+
+		Expect(messages).To(Equal([]string{":white_check_mark: Deployed " + appName + " to " + environment + " (org/space) for username"}))
+	})
+
+	It("posts a message about a failed deploy", func() {
+		deployEvent.Type = "deploy.failure"
+
+		Expect(handler.OnEvent(deployEvent)).To(Succeed())
+
+		Expect(messages).To(Equal([]string{":x: Failed to deploy " + appName + " to " + environment + " (org/space) for username"}))
+	})
+
+	It("does not post the credentials of the deployment", func() {
+		handler, _ = NewSlackHandler(server.URL, environment, "{{.}}", tls.VersionTLS12, log)
+
+		Expect(handler.OnEvent(deployEvent)).To(Succeed())
+
+		Expect(messages[0]).ToNot(ContainSubstring(password))
+	})
+
+	Context("when the template is overridden", func() {
+		It("renders the message from the template", func() {
+			handler, err := NewSlackHandler(server.URL, environment, "{{.Type}} {{.AppName}} {{.User}}", tls.VersionTLS12, log)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(handler.OnEvent(deployEvent)).To(Succeed())
+
+			Expect(messages).To(Equal([]string{"deploy.success " + appName + " username"}))
+		})
+
+		It("returns an error when the template cannot be parsed", func() {
+			_, err := NewSlackHandler(server.URL, environment, "{{.AppName", tls.VersionTLS12, log)
+
+			Expect(err).To(BeAssignableToTypeOf(SlackTemplateError{}))
+		})
+	})
+
+	Context("when the event belongs to another environment", func() {
+		It("does not post it", func() {
+			deployEvent.Data.(S.DeployEventData).DeploymentInfo.Environment = "other-environment"
+
+			Expect(handler.OnEvent(deployEvent)).To(Succeed())
+
+			Expect(messages).To(BeEmpty())
+		})
+	})
+
+	Context("when slack responds with an error", func() {
+		It("logs the failure without failing the deploy", func() {
+			statusCode = http.StatusInternalServerError
+
+			Expect(handler.OnEvent(deployEvent)).To(Succeed())
+
+			Eventually(logBuffer).Should(gbytes.Say("slack responded to the deploy.success event with status 500"))
+		})
+	})
+
+	Context("when slack cannot be reached", func() {
+		It("logs the failure without the webhook url", func() {
+			server.Close()
+
+			Expect(handler.OnEvent(deployEvent)).To(Succeed())
+
+			Eventually(logBuffer).Should(gbytes.Say("cannot post the deploy.success event to slack"))
+			Expect(string(logBuffer.Contents())).ToNot(ContainSubstring(server.URL))
+		})
+	})
+})
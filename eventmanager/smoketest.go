@@ -0,0 +1,91 @@
+package eventmanager
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/op/go-logging"
+	"golang.org/x/net/context"
+)
+
+const smokeTestTimeout = 30 * time.Second
+
+// SmokeTestEventType is the event type a SmokeTestHandler is registered for.
+// It is emitted after every foundation has been pushed and before the deploy is declared successful.
+const SmokeTestEventType = "deploy.smoketest"
+
+// SmokeTestHandler requests the SmokeTestURL of a deploy and fails the deploy unless it responds with the SmokeTestStatus.
+// Unlike a deploy.success handler its error is acted on: the deploy fails and is rolled back.
+type SmokeTestHandler struct {
+	Client *http.Client
+	Log    *logging.Logger
+}
+
+// NewSmokeTestHandler returns a SmokeTestHandler with a client that uses minTLSVersion.
+func NewSmokeTestHandler(minTLSVersion uint16, log *logging.Logger) *SmokeTestHandler {
+	return &SmokeTestHandler{
+		Client: &http.Client{
+			Timeout: smokeTestTimeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{MinVersion: minTLSVersion},
+			},
+		},
+		Log: log,
+	}
+}
+
+// OnEvent requests the SmokeTestURL of the deploy with a GET. The SmokeTestStatus defaults to 200 when it is zero.
+// The outcome is written to the Writer of the event when it has one.
+//
+// Returns a SmokeTestRequestError when the request fails and a SmokeTestStatusError when it responds with another status.
+func (h *SmokeTestHandler) OnEvent(event S.Event) error {
+	return h.OnEventContext(context.Background(), event)
+}
+
+// OnEventContext smoke tests the deploy the same as OnEvent, cancelling the request when ctx is done.
+func (h *SmokeTestHandler) OnEventContext(ctx context.Context, event S.Event) error {
+	data, ok := event.Data.(S.DeployEventData)
+	if !ok || data.DeploymentInfo == nil || data.DeploymentInfo.SmokeTestURL == "" {
+		return nil
+	}
+
+	url := data.DeploymentInfo.SmokeTestURL
+	expectedStatus := data.DeploymentInfo.SmokeTestStatus
+	if expectedStatus == 0 {
+		expectedStatus = http.StatusOK
+	}
+
+	h.Log.Debugf("smoke testing %s", url)
+
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return h.fail(data, SmokeTestRequestError{url, err})
+	}
+
+	response, err := h.Client.Do(request.WithContext(ctx))
+	if err != nil {
+		return h.fail(data, SmokeTestRequestError{url, err})
+	}
+	response.Body.Close()
+
+	if response.StatusCode != expectedStatus {
+		return h.fail(data, SmokeTestStatusError{url, expectedStatus, response.StatusCode})
+	}
+
+	if data.Writer != nil {
+		fmt.Fprintf(data.Writer, "smoke test passed: %s responded with %d\n", url, response.StatusCode)
+	}
+
+	return nil
+}
+
+func (h *SmokeTestHandler) fail(data S.DeployEventData, err error) error {
+	h.Log.Error(err.Error())
+	if data.Writer != nil {
+		fmt.Fprintln(data.Writer, err)
+	}
+	return err
+}
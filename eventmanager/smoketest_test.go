@@ -0,0 +1,103 @@
+package eventmanager_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+
+	. "github.com/compozed/deployadactyl/eventmanager"
+	"github.com/compozed/deployadactyl/logger"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+var _ = Describe("SmokeTestHandler", func() {
+	var (
+		server         *httptest.Server
+		requests       int
+		statusCode     int
+		handler        *SmokeTestHandler
+		logBuffer      *gbytes.Buffer
+		response       *gbytes.Buffer
+		deploymentInfo *S.DeploymentInfo
+		smokeTestEvent S.Event
+	)
+
+	BeforeEach(func() {
+		requests = 0
+		statusCode = http.StatusOK
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+
+			Expect(r.Method).To(Equal("GET"))
+			requests++
+
+			w.WriteHeader(statusCode)
+		}))
+
+		logBuffer = gbytes.NewBuffer()
+		response = gbytes.NewBuffer()
+		handler = NewSmokeTestHandler(tls.VersionTLS12, logger.DefaultLogger(logBuffer, logging.DEBUG, "smoketest_test", logger.TextFormat))
+
+		deploymentInfo = &S.DeploymentInfo{SmokeTestURL: server.URL + "/health"}
+		smokeTestEvent = S.Event{
+			Type: SmokeTestEventType,
+			Data: S.DeployEventData{Writer: response, DeploymentInfo: deploymentInfo},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("when the smoke test passes", func() {
+		It("succeeds and writes the outcome to the deploy output", func() {
+			Expect(handler.OnEvent(smokeTestEvent)).To(Succeed())
+
+			Expect(requests).To(Equal(1))
+			Expect(response).To(gbytes.Say("smoke test passed: %s/health responded with 200", server.URL))
+		})
+
+		It("succeeds when the expected status is given", func() {
+			statusCode = http.StatusNoContent
+			deploymentInfo.SmokeTestStatus = http.StatusNoContent
+
+			Expect(handler.OnEvent(smokeTestEvent)).To(Succeed())
+		})
+	})
+
+	Context("when the smoke test fails", func() {
+		It("returns an error when the status is not the expected one", func() {
+			statusCode = http.StatusServiceUnavailable
+
+			err := handler.OnEvent(smokeTestEvent)
+
+			Expect(err).To(MatchError(SmokeTestStatusError{server.URL + "/health", http.StatusOK, http.StatusServiceUnavailable}))
+			Expect(response).To(gbytes.Say("expected status 200 but got 503"))
+			Expect(logBuffer).To(gbytes.Say("expected status 200 but got 503"))
+		})
+
+		It("returns an error when the url cannot be requested", func() {
+			server.Close()
+
+			err := handler.OnEvent(smokeTestEvent)
+
+			Expect(err).To(BeAssignableToTypeOf(SmokeTestRequestError{}))
+		})
+	})
+
+	Context("when the deploy has no smoke test url", func() {
+		It("does not make a request", func() {
+			deploymentInfo.SmokeTestURL = ""
+
+			Expect(handler.OnEvent(smokeTestEvent)).To(Succeed())
+
+			Expect(requests).To(Equal(0))
+		})
+	})
+})
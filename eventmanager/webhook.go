@@ -0,0 +1,167 @@
+package eventmanager
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/op/go-logging"
+	"golang.org/x/net/context"
+)
+
+const webhookTimeout = 5 * time.Second
+
+// WebhookEventTypes are the event types a WebhookHandler is registered for.
+var WebhookEventTypes = []string{
+	"deploy.start",
+	"deploy.success",
+	"deploy.failure",
+	"deploy.error",
+	"deploy.finish",
+	"deploy.dryrun",
+	"deploy.progress",
+	"deploy.rollback",
+	"deploy.smoketest",
+	"deploy.cancelled",
+	"artifact.fetched",
+	"validate.foundationsUnavailable",
+}
+
+// WebhookHandler posts the events of one environment to a URL as JSON.
+// Failing to post an event is logged and never fails the deploy. A 5xx response is retried once.
+type WebhookHandler struct {
+	URL         string
+	Environment string
+	Client      *http.Client
+	Log         *logging.Logger
+}
+
+// webhookPayload is the JSON body posted for an event. It leaves out the credentials in the DeploymentInfo.
+type webhookPayload struct {
+	Type              string            `json:"type"`
+	Environment       string            `json:"environment"`
+	Org               string            `json:"org,omitempty"`
+	Space             string            `json:"space,omitempty"`
+	AppName           string            `json:"app_name,omitempty"`
+	UUID              string            `json:"uuid,omitempty"`
+	ArtifactURL       string            `json:"artifact_url,omitempty"`
+	AppGUIDs          map[string]string `json:"app_guids,omitempty"`
+	Progress          *S.DeployProgress `json:"progress,omitempty"`
+	PushedFoundations []string          `json:"pushed_foundations,omitempty"`
+	FailedFoundations []string          `json:"failed_foundations,omitempty"`
+	Description       string            `json:"description,omitempty"`
+	Stage             string            `json:"stage,omitempty"`
+	Bytes             int64             `json:"bytes,omitempty"`
+	Cached            bool              `json:"cached,omitempty"`
+}
+
+// NewWebhookHandler returns a WebhookHandler for the environment with a client that uses minTLSVersion.
+func NewWebhookHandler(url, environment string, minTLSVersion uint16, log *logging.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		URL:         url,
+		Environment: environment,
+		Client: &http.Client{
+			Timeout: webhookTimeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{MinVersion: minTLSVersion},
+			},
+		},
+		Log: log,
+	}
+}
+
+// OnEvent posts the event to the URL of the WebhookHandler if the event belongs to its environment.
+//
+// Always returns nil so that a webhook cannot fail a deploy.
+func (h *WebhookHandler) OnEvent(event S.Event) error {
+	return h.OnEventContext(context.Background(), event)
+}
+
+// OnEventContext posts the event the same as OnEvent, cancelling the post when ctx is done.
+func (h *WebhookHandler) OnEventContext(ctx context.Context, event S.Event) error {
+	payload := newWebhookPayload(event)
+	if payload.Environment != h.Environment {
+		return nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		h.Log.Errorf("cannot encode the %s event for the webhook: %s", event.Type, err)
+		return nil
+	}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		var statusCode int
+		statusCode, err = h.post(ctx, body)
+		if err != nil {
+			h.Log.Error(WebhookPostError{h.URL, event.Type, err}.Error())
+			return nil
+		}
+
+		if statusCode < http.StatusInternalServerError {
+			if statusCode >= http.StatusMultipleChoices {
+				h.Log.Error(WebhookStatusError{h.URL, event.Type, statusCode}.Error())
+			}
+			return nil
+		}
+
+		h.Log.Error(WebhookStatusError{h.URL, event.Type, statusCode}.Error())
+	}
+
+	return nil
+}
+
+func (h *WebhookHandler) post(ctx context.Context, body []byte) (int, error) {
+	request, err := http.NewRequest("POST", h.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := h.Client.Do(request.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	return response.StatusCode, nil
+}
+
+func newWebhookPayload(event S.Event) webhookPayload {
+	payload := webhookPayload{Type: event.Type}
+
+	var deploymentInfo *S.DeploymentInfo
+
+	switch data := event.Data.(type) {
+	case S.DeployEventData:
+		deploymentInfo = data.DeploymentInfo
+		payload.AppGUIDs = data.AppGUIDs
+		payload.Progress = data.Progress
+		payload.Stage = data.Stage
+	case S.RollbackEventData:
+		deploymentInfo = data.DeploymentInfo
+		payload.PushedFoundations = data.PushedFoundations
+		payload.FailedFoundations = data.FailedFoundations
+	case S.ArtifactFetchedEventData:
+		deploymentInfo = data.DeploymentInfo
+		payload.Bytes = data.Bytes
+		payload.Cached = data.Cached
+	case S.PrecheckerEventData:
+		payload.Environment = data.Environment.Name
+		payload.Description = data.Description
+	}
+
+	if deploymentInfo != nil {
+		payload.Environment = deploymentInfo.Environment
+		payload.Org = deploymentInfo.Org
+		payload.Space = deploymentInfo.Space
+		payload.AppName = deploymentInfo.AppName
+		payload.UUID = deploymentInfo.UUID
+		payload.ArtifactURL = deploymentInfo.ArtifactURL
+	}
+
+	return payload
+}
@@ -0,0 +1,264 @@
+package eventmanager_test
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+
+	"github.com/compozed/deployadactyl/config"
+	. "github.com/compozed/deployadactyl/eventmanager"
+	"github.com/compozed/deployadactyl/logger"
+	"github.com/compozed/deployadactyl/randomizer"
+	S "github.com/compozed/deployadactyl/structs"
+	"golang.org/x/net/context"
+)
+
+var _ = Describe("WebhookHandler", func() {
+	var (
+		server       *httptest.Server
+		mutex        sync.Mutex
+		bodies       []map[string]interface{}
+		statusCodes  []int
+		environment  string
+		appName      string
+		password     string
+		handler      *WebhookHandler
+		logBuffer    *gbytes.Buffer
+		deployEvent  S.Event
+		requestCount func() int
+	)
+
+	BeforeEach(func() {
+		bodies = nil
+		statusCodes = nil
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			Expect(r.Method).To(Equal("POST"))
+			Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+
+			body, err := ioutil.ReadAll(r.Body)
+			Expect(err).ToNot(HaveOccurred())
+
+			var decoded map[string]interface{}
+			Expect(json.Unmarshal(body, &decoded)).To(Succeed())
+			bodies = append(bodies, decoded)
+
+			statusCode := http.StatusOK
+			if len(statusCodes) >= len(bodies) {
+				statusCode = statusCodes[len(bodies)-1]
+			}
+			w.WriteHeader(statusCode)
+		}))
+
+		requestCount = func() int {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			return len(bodies)
+		}
+
+		environment = "environment-" + randomizer.StringRunes(10)
+		appName = "appName-" + randomizer.StringRunes(10)
+		password = "password-" + randomizer.StringRunes(10)
+
+		logBuffer = gbytes.NewBuffer()
+		handler = NewWebhookHandler(server.URL, environment, tls.VersionTLS12, logger.DefaultLogger(logBuffer, logging.DEBUG, "webhook_test", logger.TextFormat))
+
+		deployEvent = S.Event{
+			Type: "deploy.success",
+			Data: S.DeployEventData{
+				DeploymentInfo: &S.DeploymentInfo{
+					Environment: environment,
+					AppName:     appName,
+					Password:    password,
+				},
+				AppGUIDs: map[string]string{"api.example.com": "app-guid"},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("posts the event as JSON", func() {
+		Expect(handler.OnEvent(deployEvent)).To(Succeed())
+
+		Expect(bodies).To(HaveLen(1))
+		Expect(bodies[0]["type"]).To(Equal("deploy.success"))
+		Expect(bodies[0]["environment"]).To(Equal(environment))
+		Expect(bodies[0]["app_name"]).To(Equal(appName))
+		Expect(bodies[0]["app_guids"]).To(Equal(map[string]interface{}{"api.example.com": "app-guid"}))
+	})
+
+	It("does not post the credentials of the deployment", func() {
+		Expect(handler.OnEvent(deployEvent)).To(Succeed())
+
+		body, err := json.Marshal(bodies[0])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).ToNot(ContainSubstring(password))
+	})
+
+	It("posts the failed stage of deploy.error events", func() {
+		event := S.Event{
+			Type: "deploy.error",
+			Data: S.DeployEventData{
+				DeploymentInfo: &S.DeploymentInfo{Environment: environment, AppName: appName},
+				Error:          "prechecker failed",
+				Stage:          S.PrecheckStage,
+			},
+		}
+
+		Expect(handler.OnEvent(event)).To(Succeed())
+
+		Expect(bodies[0]["type"]).To(Equal("deploy.error"))
+		Expect(bodies[0]["stage"]).To(Equal("precheck"))
+	})
+
+	It("posts rollback events with the pushed and failed foundations", func() {
+		event := S.Event{
+			Type: "deploy.rollback",
+			Data: S.RollbackEventData{
+				DeploymentInfo:    &S.DeploymentInfo{Environment: environment, AppName: appName},
+				PushedFoundations: []string{"api1.example.com"},
+				FailedFoundations: []string{"api2.example.com"},
+			},
+		}
+
+		Expect(handler.OnEvent(event)).To(Succeed())
+
+		Expect(bodies[0]["pushed_foundations"]).To(Equal([]interface{}{"api1.example.com"}))
+		Expect(bodies[0]["failed_foundations"]).To(Equal([]interface{}{"api2.example.com"}))
+	})
+
+	It("posts artifact.fetched events of its environment", func() {
+		event := S.Event{
+			Type: "artifact.fetched",
+			Data: S.ArtifactFetchedEventData{
+				URL:            "https://example.com/artifact.jar",
+				Bytes:          1024,
+				Cached:         true,
+				DeploymentInfo: &S.DeploymentInfo{Environment: environment, AppName: appName, ArtifactURL: "https://example.com/artifact.jar"},
+			},
+		}
+
+		Expect(handler.OnEvent(event)).To(Succeed())
+
+		Expect(bodies[0]["app_name"]).To(Equal(appName))
+		Expect(bodies[0]["artifact_url"]).To(Equal("https://example.com/artifact.jar"))
+		Expect(bodies[0]["bytes"]).To(Equal(float64(1024)))
+		Expect(bodies[0]["cached"]).To(BeTrue())
+	})
+
+	It("posts prechecker events of its environment", func() {
+		event := S.Event{
+			Type: "validate.foundationsUnavailable",
+			Data: S.PrecheckerEventData{Environment: config.Environment{Name: environment}, Description: "a foundation is down"},
+		}
+
+		Expect(handler.OnEvent(event)).To(Succeed())
+
+		Expect(bodies[0]["description"]).To(Equal("a foundation is down"))
+	})
+
+	Context("when the event belongs to another environment", func() {
+		It("does not post it", func() {
+			deployEvent.Data.(S.DeployEventData).DeploymentInfo.Environment = "other-environment"
+
+			Expect(handler.OnEvent(deployEvent)).To(Succeed())
+
+			Expect(requestCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the webhook responds with a 5xx", func() {
+		It("retries once", func() {
+			statusCodes = []int{http.StatusBadGateway, http.StatusOK}
+
+			Expect(handler.OnEvent(deployEvent)).To(Succeed())
+
+			Expect(requestCount()).To(Equal(2))
+			Eventually(logBuffer).Should(gbytes.Say("status 502"))
+		})
+
+		It("logs the failure without failing the deploy when the retry also fails", func() {
+			statusCodes = []int{http.StatusInternalServerError, http.StatusServiceUnavailable}
+
+			Expect(handler.OnEvent(deployEvent)).To(Succeed())
+
+			Expect(requestCount()).To(Equal(2))
+			Eventually(logBuffer).Should(gbytes.Say("status 503"))
+		})
+	})
+
+	Context("when the webhook responds with a 4xx", func() {
+		It("logs the failure without retrying", func() {
+			statusCodes = []int{http.StatusNotFound}
+
+			Expect(handler.OnEvent(deployEvent)).To(Succeed())
+
+			Expect(requestCount()).To(Equal(1))
+			Eventually(logBuffer).Should(gbytes.Say("status 404"))
+		})
+	})
+
+	Context("when the webhook cannot be reached", func() {
+		It("logs the failure without failing the deploy", func() {
+			server.Close()
+
+			Expect(handler.OnEvent(deployEvent)).To(Succeed())
+
+			Eventually(logBuffer).Should(gbytes.Say("cannot post the deploy.success event to the webhook"))
+		})
+	})
+
+	Context("when the webhook does not respond in time", func() {
+		It("gives up and logs the failure", func() {
+			slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(time.Second)
+			}))
+			defer slowServer.Close()
+
+			handler.URL = slowServer.URL
+			handler.Client.Timeout = 50 * time.Millisecond
+
+			Expect(handler.OnEvent(deployEvent)).To(Succeed())
+
+			Eventually(logBuffer).Should(gbytes.Say("cannot post the deploy.success event to the webhook"))
+		})
+	})
+
+	Context("when the context of the event is done", func() {
+		It("cancels the post", func() {
+			release := make(chan struct{})
+			hungServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-release
+			}))
+			defer hungServer.Close()
+			defer close(release)
+
+			handler.URL = hungServer.URL
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			Expect(handler.OnEventContext(ctx, deployEvent)).To(Succeed())
+
+			Eventually(logBuffer).Should(gbytes.Say("cannot post the deploy.success event to the webhook"))
+		})
+	})
+})
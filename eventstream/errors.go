@@ -0,0 +1,20 @@
+package eventstream
+
+import "fmt"
+
+type UnknownStreamError struct {
+	DeployID string
+}
+
+func (e UnknownStreamError) Error() string {
+	return fmt.Sprintf("unknown deploy: %s", e.DeployID)
+}
+
+type EventsExpiredError struct {
+	Since  int
+	Oldest int
+}
+
+func (e EventsExpiredError) Error() string {
+	return fmt.Sprintf("events after %d are no longer buffered: the oldest buffered event is %d", e.Since, e.Oldest)
+}
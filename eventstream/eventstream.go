@@ -0,0 +1,199 @@
+// Package eventstream buffers the output of recent deploys as numbered events so clients can resume a stream.
+package eventstream
+
+import (
+	"bytes"
+	"sync"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+const (
+	// DefaultStreams is the default number of deploys that are buffered.
+	DefaultStreams = 100
+
+	// DefaultBufferSize is the default number of events that are buffered for each deploy.
+	DefaultBufferSize = 1000
+)
+
+// Streams keeps the event streams of the most recent deploys.
+// When there are too many streams the oldest one is dropped.
+type Streams struct {
+	mutex      sync.Mutex
+	streams    map[string]*Stream
+	order      []string
+	size       int
+	bufferSize int
+}
+
+// New returns Streams that hold up to size deploys of up to bufferSize events each.
+// Sizes less than one are treated as one.
+func New(size, bufferSize int) *Streams {
+	if size < 1 {
+		size = 1
+	}
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+
+	return &Streams{
+		streams:    make(map[string]*Stream),
+		size:       size,
+		bufferSize: bufferSize,
+	}
+}
+
+// Open creates the event stream of a deploy, dropping the oldest stream if there are too many.
+func (s *Streams) Open(deployID string) I.EventStream {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stream := newStream(s.bufferSize)
+
+	if _, ok := s.streams[deployID]; !ok {
+		s.order = append(s.order, deployID)
+	}
+	s.streams[deployID] = stream
+
+	for len(s.order) > s.size {
+		delete(s.streams, s.order[0])
+		s.order = s.order[1:]
+	}
+
+	return stream
+}
+
+// Follow sends every event of a deploy with a sequence number greater than since, in order.
+// It blocks until the stream is closed or send returns an error.
+//
+// Returns an UnknownStreamError if the deploy has no stream, an EventsExpiredError if events after since are no longer buffered, or the error from send.
+func (s *Streams) Follow(deployID string, since int, send func(S.StreamEvent) error) error {
+	s.mutex.Lock()
+	stream, ok := s.streams[deployID]
+	s.mutex.Unlock()
+
+	if !ok {
+		return UnknownStreamError{DeployID: deployID}
+	}
+
+	return stream.follow(since, send)
+}
+
+// Stream is the event stream of a single deploy.
+// Each line written to the Stream becomes an event with the next sequence number.
+type Stream struct {
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	events  []S.StreamEvent
+	size    int
+	lastSeq int
+	partial bytes.Buffer
+	closed  bool
+}
+
+func newStream(size int) *Stream {
+	stream := &Stream{size: size}
+	stream.cond = sync.NewCond(&stream.mutex)
+
+	return stream
+}
+
+// Write adds an event for every complete line in p.
+// An incomplete line is held until the rest of it is written or the Stream is closed.
+func (s *Stream) Write(p []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.partial.Write(p)
+
+	for {
+		index := bytes.IndexByte(s.partial.Bytes(), '\n')
+		if index < 0 {
+			break
+		}
+
+		line := s.partial.Next(index + 1)
+		s.add(S.StreamEvent{Line: string(line[:index])})
+	}
+
+	s.cond.Broadcast()
+
+	return len(p), nil
+}
+
+// Close adds any incomplete line and then the result of the deploy as the last event.
+func (s *Stream) Close(result S.DeployResult) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	if s.partial.Len() > 0 {
+		s.add(S.StreamEvent{Line: s.partial.String()})
+		s.partial.Reset()
+	}
+
+	s.add(S.StreamEvent{Result: &result})
+	s.closed = true
+
+	s.cond.Broadcast()
+}
+
+func (s *Stream) add(event S.StreamEvent) {
+	s.lastSeq++
+	event.Seq = s.lastSeq
+
+	s.events = append(s.events, event)
+	if len(s.events) > s.size {
+		s.events = append(s.events[:0], s.events[1:]...)
+	}
+}
+
+func (s *Stream) follow(since int, send func(S.StreamEvent) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	next := since + 1
+
+	for {
+		oldest := s.lastSeq - len(s.events) + 1
+		if next < oldest {
+			return EventsExpiredError{Since: next - 1, Oldest: oldest}
+		}
+
+		var pending []S.StreamEvent
+		if next <= s.lastSeq {
+			pending = append(pending, s.events[next-oldest:]...)
+		}
+
+		s.mutex.Unlock()
+		err := sendAll(pending, send)
+		s.mutex.Lock()
+
+		if err != nil {
+			return err
+		}
+		next += len(pending)
+
+		if s.closed && next > s.lastSeq {
+			return nil
+		}
+		if next > s.lastSeq {
+			s.cond.Wait()
+		}
+	}
+}
+
+func sendAll(events []S.StreamEvent, send func(S.StreamEvent) error) error {
+	for _, event := range events {
+		err := send(event)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
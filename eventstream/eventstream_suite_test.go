@@ -0,0 +1,13 @@
+package eventstream_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestEventStream(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "EventStream Suite")
+}
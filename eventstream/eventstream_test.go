@@ -0,0 +1,134 @@
+package eventstream_test
+
+import (
+	"errors"
+	"fmt"
+
+	. "github.com/compozed/deployadactyl/eventstream"
+	"github.com/compozed/deployadactyl/randomizer"
+	S "github.com/compozed/deployadactyl/structs"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EventStream", func() {
+	var (
+		streams  *Streams
+		deployID string
+		appName  string
+		events   []S.StreamEvent
+		collect  func(S.StreamEvent) error
+	)
+
+	BeforeEach(func() {
+		streams = New(10, 100)
+
+		deployID = "deployID-" + randomizer.StringRunes(10)
+		appName = "appName-" + randomizer.StringRunes(10)
+
+		events = nil
+		collect = func(event S.StreamEvent) error {
+			events = append(events, event)
+			return nil
+		}
+	})
+
+	It("numbers each line and ends with the result", func() {
+		stream := streams.Open(deployID)
+
+		fmt.Fprint(stream, "first\nsec")
+		fmt.Fprint(stream, "ond\nthird")
+		stream.Close(S.DeployResult{AppName: appName})
+
+		Expect(streams.Follow(deployID, 0, collect)).To(Succeed())
+
+		Expect(events).To(HaveLen(4))
+		Expect(events[0]).To(Equal(S.StreamEvent{Seq: 1, Line: "first"}))
+		Expect(events[1]).To(Equal(S.StreamEvent{Seq: 2, Line: "second"}))
+		Expect(events[2]).To(Equal(S.StreamEvent{Seq: 3, Line: "third"}))
+		Expect(events[3].Seq).To(Equal(4))
+		Expect(events[3].Result.AppName).To(Equal(appName))
+	})
+
+	It("only sends the events after since", func() {
+		stream := streams.Open(deployID)
+
+		fmt.Fprint(stream, "first\nsecond\nthird\n")
+		stream.Close(S.DeployResult{})
+
+		Expect(streams.Follow(deployID, 2, collect)).To(Succeed())
+
+		Expect(events).To(HaveLen(2))
+		Expect(events[0]).To(Equal(S.StreamEvent{Seq: 3, Line: "third"}))
+		Expect(events[1].Result).ToNot(BeNil())
+	})
+
+	It("resumes a disconnected stream from since without losing or duplicating events", func() {
+		stream := streams.Open(deployID)
+		disconnect := errors.New("disconnected")
+
+		go func() {
+			defer GinkgoRecover()
+
+			for i := 1; i <= 50; i++ {
+				fmt.Fprintf(stream, "line %d\n", i)
+			}
+			stream.Close(S.DeployResult{AppName: appName})
+		}()
+
+		err := streams.Follow(deployID, 0, func(event S.StreamEvent) error {
+			if len(events) == 20 {
+				return disconnect
+			}
+
+			events = append(events, event)
+			return nil
+		})
+		Expect(err).To(Equal(disconnect))
+
+		lastSeq := events[len(events)-1].Seq
+		Expect(streams.Follow(deployID, lastSeq, collect)).To(Succeed())
+
+		Expect(events).To(HaveLen(51))
+		for i, event := range events[:50] {
+			Expect(event).To(Equal(S.StreamEvent{Seq: i + 1, Line: fmt.Sprintf("line %d", i+1)}))
+		}
+		Expect(events[50].Seq).To(Equal(51))
+		Expect(events[50].Result.AppName).To(Equal(appName))
+	})
+
+	Context("when the events after since are no longer buffered", func() {
+		It("returns an error", func() {
+			streams = New(10, 2)
+			stream := streams.Open(deployID)
+
+			fmt.Fprint(stream, "first\nsecond\nthird\n")
+
+			err := streams.Follow(deployID, 0, collect)
+
+			Expect(err).To(Equal(EventsExpiredError{Since: 0, Oldest: 2}))
+			Expect(events).To(BeEmpty())
+		})
+	})
+
+	Context("when the deploy has no stream", func() {
+		It("returns an error", func() {
+			err := streams.Follow(deployID, 0, collect)
+
+			Expect(err).To(Equal(UnknownStreamError{DeployID: deployID}))
+		})
+	})
+
+	Context("when there are too many streams", func() {
+		It("drops the oldest stream", func() {
+			streams = New(1, 100)
+
+			streams.Open(deployID).Close(S.DeployResult{})
+			streams.Open("newer-" + deployID).Close(S.DeployResult{})
+
+			Expect(streams.Follow(deployID, 0, collect)).To(Equal(UnknownStreamError{DeployID: deployID}))
+			Expect(streams.Follow("newer-"+deployID, 0, collect)).To(Succeed())
+		})
+	})
+})
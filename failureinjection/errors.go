@@ -0,0 +1,19 @@
+package failureinjection
+
+import "fmt"
+
+type InjectedFailureError struct {
+	Stage string
+}
+
+func (e InjectedFailureError) Error() string {
+	return fmt.Sprintf("injected failure: %s", e.Stage)
+}
+
+type InvalidStageError struct {
+	Stage string
+}
+
+func (e InvalidStageError) Error() string {
+	return fmt.Sprintf("invalid %s header: %s: must be one of %s, %s or %s", Header, e.Stage, Precheck, Fetch, Push)
+}
@@ -0,0 +1,32 @@
+// Package failureinjection forces stages of a deployment to fail so that the error and rollback paths can be chaos tested.
+package failureinjection
+
+import "net/http"
+
+// Header is the request header that names the stage to fail.
+const Header = "X-Inject-Failure"
+
+// Stages that can be forced to fail.
+const (
+	Precheck = "precheck"
+	Fetch    = "fetch"
+	Push     = "push"
+)
+
+// Stage returns the stage named in the X-Inject-Failure header of the request.
+// The header is ignored and an empty stage is returned unless failure injection is enabled.
+//
+// Returns the stage to fail and an error if the stage is unknown.
+func Stage(req *http.Request, enabled bool) (string, error) {
+	stage := req.Header.Get(Header)
+	if !enabled || stage == "" {
+		return "", nil
+	}
+
+	switch stage {
+	case Precheck, Fetch, Push:
+		return stage, nil
+	default:
+		return "", InvalidStageError{stage}
+	}
+}
@@ -0,0 +1,13 @@
+package failureinjection_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestFailureinjection(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Failureinjection Suite")
+}
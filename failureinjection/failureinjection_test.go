@@ -0,0 +1,57 @@
+package failureinjection_test
+
+import (
+	"net/http"
+
+	. "github.com/compozed/deployadactyl/failureinjection"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Failure injection", func() {
+	var req *http.Request
+
+	BeforeEach(func() {
+		req, _ = http.NewRequest("POST", "", nil)
+	})
+
+	Context("when failure injection is disabled", func() {
+		It("ignores the header", func() {
+			req.Header.Set(Header, Push)
+
+			stage, err := Stage(req, false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(stage).To(BeEmpty())
+		})
+	})
+
+	Context("when failure injection is enabled", func() {
+		It("returns the stage for each injection point", func() {
+			for _, injected := range []string{Precheck, Fetch, Push} {
+				req.Header.Set(Header, injected)
+
+				stage, err := Stage(req, true)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(stage).To(Equal(injected))
+			}
+		})
+
+		It("returns an empty stage when the header is missing", func() {
+			stage, err := Stage(req, true)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(stage).To(BeEmpty())
+		})
+
+		It("returns an error when the stage is unknown", func() {
+			req.Header.Set(Header, "bork")
+
+			_, err := Stage(req, true)
+
+			Expect(err).To(MatchError(InvalidStageError{"bork"}))
+		})
+	})
+})
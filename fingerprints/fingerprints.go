@@ -0,0 +1,61 @@
+// Package fingerprints remembers recently completed deploys so that an identical deploy can be skipped.
+package fingerprints
+
+import (
+	"sync"
+	"time"
+)
+
+// Fingerprints is a concurrency safe cache of the fingerprints of completed deploys.
+// A fingerprint is dropped once it has been cached for longer than the TTL.
+type Fingerprints struct {
+	mutex        sync.Mutex
+	fingerprints map[string]time.Time
+	TTL          time.Duration
+	Now          func() time.Time
+}
+
+// New returns Fingerprints that keep each fingerprint for the ttl.
+// A ttl of zero or less does not keep any fingerprints.
+func New(ttl time.Duration) *Fingerprints {
+	return &Fingerprints{
+		fingerprints: make(map[string]time.Time),
+		TTL:          ttl,
+		Now:          time.Now,
+	}
+}
+
+// Add caches the fingerprint of a completed deploy.
+func (f *Fingerprints) Add(fingerprint string) {
+	if f.TTL <= 0 {
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.expire()
+
+	f.fingerprints[fingerprint] = f.Now()
+}
+
+// Seen returns whether a deploy with the fingerprint completed within the TTL.
+func (f *Fingerprints) Seen(fingerprint string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.expire()
+
+	_, ok := f.fingerprints[fingerprint]
+	return ok
+}
+
+func (f *Fingerprints) expire() {
+	now := f.Now()
+
+	for fingerprint, added := range f.fingerprints {
+		if now.Sub(added) > f.TTL {
+			delete(f.fingerprints, fingerprint)
+		}
+	}
+}
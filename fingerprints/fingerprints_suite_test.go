@@ -0,0 +1,13 @@
+package fingerprints_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestFingerprints(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Fingerprints Suite")
+}
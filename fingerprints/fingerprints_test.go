@@ -0,0 +1,70 @@
+package fingerprints_test
+
+import (
+	"time"
+
+	. "github.com/compozed/deployadactyl/fingerprints"
+	"github.com/compozed/deployadactyl/randomizer"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fingerprints", func() {
+	var (
+		fingerprints *Fingerprints
+		fingerprint  string
+		now          time.Time
+	)
+
+	BeforeEach(func() {
+		now = time.Now()
+
+		fingerprints = New(time.Minute)
+		fingerprints.Now = func() time.Time { return now }
+
+		fingerprint = "fingerprint-" + randomizer.StringRunes(10)
+	})
+
+	It("has seen a fingerprint that was added", func() {
+		fingerprints.Add(fingerprint)
+
+		Expect(fingerprints.Seen(fingerprint)).To(BeTrue())
+	})
+
+	It("has not seen a fingerprint that was not added", func() {
+		fingerprints.Add(fingerprint)
+
+		Expect(fingerprints.Seen("other-" + fingerprint)).To(BeFalse())
+	})
+
+	Context("when the TTL has passed", func() {
+		It("has not seen the fingerprint", func() {
+			fingerprints.Add(fingerprint)
+
+			now = now.Add(time.Minute + time.Second)
+
+			Expect(fingerprints.Seen(fingerprint)).To(BeFalse())
+		})
+	})
+
+	Context("when the TTL has not passed", func() {
+		It("has seen the fingerprint", func() {
+			fingerprints.Add(fingerprint)
+
+			now = now.Add(time.Minute)
+
+			Expect(fingerprints.Seen(fingerprint)).To(BeTrue())
+		})
+	})
+
+	Context("when the TTL is zero", func() {
+		It("does not keep any fingerprints", func() {
+			fingerprints = New(0)
+
+			fingerprints.Add(fingerprint)
+
+			Expect(fingerprints.Seen(fingerprint)).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,21 @@
+package history
+
+import "fmt"
+
+type ReadHistoryError struct {
+	Filename string
+	Err      error
+}
+
+func (e ReadHistoryError) Error() string {
+	return fmt.Sprintf("cannot read deploy history %s: %s", e.Filename, e.Err)
+}
+
+type SaveHistoryError struct {
+	Filename string
+	Err      error
+}
+
+func (e SaveHistoryError) Error() string {
+	return fmt.Sprintf("cannot save deploy history %s: %s", e.Filename, e.Err)
+}
@@ -0,0 +1,166 @@
+// Package history keeps a bounded history of recent deployments, in memory and optionally in a file.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/op/go-logging"
+	"github.com/spf13/afero"
+)
+
+const historyTempSuffix = ".tmp"
+
+// History is a concurrency safe ring of the most recent DeployResults.
+// When the ring is full the oldest DeployResult is dropped.
+type History struct {
+	mutex   sync.RWMutex
+	results []S.DeployResult
+	next    int
+	count   int
+
+	fileSystem *afero.Afero
+	filename   string
+	log        *logging.Logger
+}
+
+// New returns a History that holds up to size DeployResults.
+// A size less than one is treated as one.
+func New(size int) *History {
+	if size < 1 {
+		size = 1
+	}
+
+	return &History{
+		results: make([]S.DeployResult, size),
+	}
+}
+
+// NewFile returns a History that holds up to size DeployResults and saves them to filename,
+// starting with the DeployResults already saved there. The file is created by the first Add when it does not exist.
+// Failing to save the DeployResults is logged to log.
+func NewFile(fileSystem *afero.Afero, filename string, size int, log *logging.Logger) (*History, error) {
+	history := New(size)
+	history.fileSystem = fileSystem
+	history.filename = filename
+	history.log = log
+
+	file, err := fileSystem.Open(filename)
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, ReadHistoryError{filename, err}
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var result S.DeployResult
+
+		err = json.Unmarshal(scanner.Bytes(), &result)
+		if err != nil {
+			return nil, ReadHistoryError{filename, err}
+		}
+
+		history.add(result)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, ReadHistoryError{filename, err}
+	}
+
+	return history, nil
+}
+
+// Add stores a DeployResult, dropping the oldest one if the History is full.
+// A History with a file saves every DeployResult it holds to it. A DeployResult that cannot be saved is logged and still kept in memory.
+func (h *History) Add(result S.DeployResult) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.add(result)
+
+	if h.filename != "" {
+		err := h.save()
+		if err != nil {
+			h.log.Error(SaveHistoryError{h.filename, err}.Error())
+		}
+	}
+}
+
+func (h *History) add(result S.DeployResult) {
+	h.results[h.next] = result
+	h.next = (h.next + 1) % len(h.results)
+
+	if h.count < len(h.results) {
+		h.count++
+	}
+}
+
+// Query returns the DeployResults that match the environment, app name and status of the query, newest first.
+// Offset and Limit are applied after filtering. A Limit less than one returns every match after the Offset.
+//
+// Returns a page of DeployResults and the total number of matches.
+func (h *History) Query(query S.HistoryQuery) ([]S.DeployResult, int) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	matches := []S.DeployResult{}
+
+	for i := 1; i <= h.count; i++ {
+		result := h.results[(h.next-i+len(h.results))%len(h.results)]
+
+		if matchesQuery(result, query) {
+			matches = append(matches, result)
+		}
+	}
+
+	total := len(matches)
+
+	if query.Offset >= total {
+		return []S.DeployResult{}, total
+	}
+	if query.Offset > 0 {
+		matches = matches[query.Offset:]
+	}
+	if query.Limit > 0 && query.Limit < len(matches) {
+		matches = matches[:query.Limit]
+	}
+
+	return matches, total
+}
+
+// save writes the DeployResults, oldest first, to a temporary file next to the file of the History and renames it,
+// so that a partly written history never replaces the last one.
+func (h *History) save() error {
+	tempName := h.filename + historyTempSuffix
+
+	file, err := h.fileSystem.Create(tempName)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	for i := h.count; i >= 1; i-- {
+		err = encoder.Encode(h.results[(h.next-i+len(h.results))%len(h.results)])
+		if err != nil {
+			break
+		}
+	}
+	file.Close()
+	if err != nil {
+		h.fileSystem.Remove(tempName)
+		return err
+	}
+
+	return h.fileSystem.Rename(tempName, h.filename)
+}
+
+func matchesQuery(result S.DeployResult, query S.HistoryQuery) bool {
+	return (query.Environment == "" || query.Environment == result.Environment) &&
+		(query.AppName == "" || query.AppName == result.AppName) &&
+		(query.Status == "" || query.Status == result.Status)
+}
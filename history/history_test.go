@@ -0,0 +1,256 @@
+package history_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+
+	. "github.com/compozed/deployadactyl/history"
+	"github.com/compozed/deployadactyl/logger"
+	"github.com/compozed/deployadactyl/randomizer"
+	S "github.com/compozed/deployadactyl/structs"
+	"github.com/op/go-logging"
+	"github.com/spf13/afero"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("History", func() {
+	var (
+		history     *History
+		environment string
+		appName     string
+	)
+
+	BeforeEach(func() {
+		history = New(10)
+
+		environment = "environment-" + randomizer.StringRunes(10)
+		appName = "appName-" + randomizer.StringRunes(10)
+	})
+
+	Context("when the history is empty", func() {
+		It("returns no results", func() {
+			results, total := history.Query(S.HistoryQuery{})
+
+			Expect(results).To(BeEmpty())
+			Expect(total).To(Equal(0))
+		})
+	})
+
+	Context("when results are added", func() {
+		It("returns them newest first", func() {
+			first := S.DeployResult{AppName: "first"}
+			second := S.DeployResult{AppName: "second"}
+
+			history.Add(first)
+			history.Add(second)
+
+			results, total := history.Query(S.HistoryQuery{})
+
+			Expect(results).To(Equal([]S.DeployResult{second, first}))
+			Expect(total).To(Equal(2))
+		})
+	})
+
+	Describe("filtering", func() {
+		var (
+			match        S.DeployResult
+			otherEnv     S.DeployResult
+			otherApp     S.DeployResult
+			otherOutcome S.DeployResult
+		)
+
+		BeforeEach(func() {
+			match = S.DeployResult{Environment: environment, AppName: appName, Status: "success"}
+			otherEnv = S.DeployResult{Environment: "otherEnvironment", AppName: appName, Status: "success"}
+			otherApp = S.DeployResult{Environment: environment, AppName: "otherAppName", Status: "success"}
+			otherOutcome = S.DeployResult{Environment: environment, AppName: appName, Status: "failure"}
+
+			history.Add(match)
+			history.Add(otherEnv)
+			history.Add(otherApp)
+			history.Add(otherOutcome)
+		})
+
+		It("filters by environment", func() {
+			results, total := history.Query(S.HistoryQuery{Environment: "otherEnvironment"})
+
+			Expect(results).To(Equal([]S.DeployResult{otherEnv}))
+			Expect(total).To(Equal(1))
+		})
+
+		It("filters by app name", func() {
+			results, total := history.Query(S.HistoryQuery{AppName: "otherAppName"})
+
+			Expect(results).To(Equal([]S.DeployResult{otherApp}))
+			Expect(total).To(Equal(1))
+		})
+
+		It("filters by status", func() {
+			results, total := history.Query(S.HistoryQuery{Status: "failure"})
+
+			Expect(results).To(Equal([]S.DeployResult{otherOutcome}))
+			Expect(total).To(Equal(1))
+		})
+
+		It("combines filters", func() {
+			results, total := history.Query(S.HistoryQuery{Environment: environment, AppName: appName, Status: "success"})
+
+			Expect(results).To(Equal([]S.DeployResult{match}))
+			Expect(total).To(Equal(1))
+		})
+	})
+
+	Describe("pagination", func() {
+		BeforeEach(func() {
+			for i := 0; i < 5; i++ {
+				history.Add(S.DeployResult{AppName: fmt.Sprintf("app-%d", i)})
+			}
+		})
+
+		It("applies the offset and limit", func() {
+			results, total := history.Query(S.HistoryQuery{Offset: 1, Limit: 2})
+
+			Expect(results).To(Equal([]S.DeployResult{{AppName: "app-3"}, {AppName: "app-2"}}))
+			Expect(total).To(Equal(5))
+		})
+
+		It("returns no results when the offset is past the end", func() {
+			results, total := history.Query(S.HistoryQuery{Offset: 5})
+
+			Expect(results).To(BeEmpty())
+			Expect(total).To(Equal(5))
+		})
+	})
+
+	Context("when the history overflows", func() {
+		It("drops the oldest results", func() {
+			history = New(3)
+
+			for i := 0; i < 5; i++ {
+				history.Add(S.DeployResult{AppName: fmt.Sprintf("app-%d", i)})
+			}
+
+			results, total := history.Query(S.HistoryQuery{})
+
+			Expect(results).To(Equal([]S.DeployResult{{AppName: "app-4"}, {AppName: "app-3"}, {AppName: "app-2"}}))
+			Expect(total).To(Equal(3))
+		})
+	})
+
+	Context("when results are added concurrently", func() {
+		It("keeps every result up to the size of the history", func() {
+			history = New(50)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 100; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+
+					history.Add(S.DeployResult{AppName: fmt.Sprintf("app-%d", i)})
+					history.Query(S.HistoryQuery{})
+				}(i)
+			}
+			wg.Wait()
+
+			_, total := history.Query(S.HistoryQuery{})
+
+			Expect(total).To(Equal(50))
+		})
+	})
+
+	Describe("saving to a file", func() {
+		var (
+			fileSystem *afero.Afero
+			filename   string
+			logBuffer  *gbytes.Buffer
+			log        *logging.Logger
+		)
+
+		BeforeEach(func() {
+			fileSystem = &afero.Afero{Fs: afero.NewMemMapFs()}
+			filename = "/history-" + randomizer.StringRunes(10) + ".json"
+			logBuffer = gbytes.NewBuffer()
+			log = logger.DefaultLogger(logBuffer, logging.DEBUG, "history_test", logger.TextFormat)
+		})
+
+		It("starts empty when the file does not exist", func() {
+			history, err := NewFile(fileSystem, filename, 10, log)
+			Expect(err).ToNot(HaveOccurred())
+
+			results, total := history.Query(S.HistoryQuery{})
+
+			Expect(results).To(BeEmpty())
+			Expect(total).To(Equal(0))
+		})
+
+		It("loads the results saved by a previous history", func() {
+			first := S.DeployResult{Environment: environment, AppName: appName, User: "user", RequestID: "first"}
+			second := S.DeployResult{Environment: environment, AppName: "other-app", User: "user", RequestID: "second"}
+
+			previous, err := NewFile(fileSystem, filename, 10, log)
+			Expect(err).ToNot(HaveOccurred())
+			previous.Add(first)
+			previous.Add(second)
+
+			history, err := NewFile(fileSystem, filename, 10, log)
+			Expect(err).ToNot(HaveOccurred())
+
+			results, total := history.Query(S.HistoryQuery{Environment: environment, AppName: appName})
+
+			Expect(results).To(Equal([]S.DeployResult{first}))
+			Expect(total).To(Equal(1))
+		})
+
+		It("only saves the results that fit in the history", func() {
+			previous, err := NewFile(fileSystem, filename, 2, log)
+			Expect(err).ToNot(HaveOccurred())
+			for i := 0; i < 3; i++ {
+				previous.Add(S.DeployResult{AppName: fmt.Sprintf("app-%d", i)})
+			}
+
+			history, err := NewFile(fileSystem, filename, 10, log)
+			Expect(err).ToNot(HaveOccurred())
+
+			results, _ := history.Query(S.HistoryQuery{})
+
+			Expect(results).To(Equal([]S.DeployResult{{AppName: "app-2"}, {AppName: "app-1"}}))
+		})
+
+		It("returns an error when the file cannot be read", func() {
+			Expect(fileSystem.WriteFile(filename, []byte("not json\n"), 0644)).To(Succeed())
+
+			_, err := NewFile(fileSystem, filename, 10, log)
+
+			Expect(err).To(BeAssignableToTypeOf(ReadHistoryError{}))
+		})
+
+		Context("when the file cannot be written", func() {
+			It("logs the error and keeps the result in memory", func() {
+				directory, err := ioutil.TempDir("", "history-test-")
+				Expect(err).ToNot(HaveOccurred())
+				defer os.RemoveAll(directory)
+
+				fileSystem = &afero.Afero{Fs: afero.NewOsFs()}
+				filename = path.Join(directory, "missing", "history.json")
+
+				history, err := NewFile(fileSystem, filename, 10, log)
+				Expect(err).ToNot(HaveOccurred())
+
+				result := S.DeployResult{Environment: environment, AppName: appName}
+				history.Add(result)
+
+				Eventually(logBuffer).Should(gbytes.Say("cannot save deploy history " + filename))
+
+				results, _ := history.Query(S.HistoryQuery{})
+				Expect(results).To(Equal([]S.DeployResult{result}))
+			})
+		})
+	})
+})
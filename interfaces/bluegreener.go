@@ -5,14 +5,23 @@ import (
 
 	"github.com/compozed/deployadactyl/config"
 	S "github.com/compozed/deployadactyl/structs"
+	"golang.org/x/net/context"
 )
 
 // BlueGreener interface.
 type BlueGreener interface {
 	Push(
+		ctx context.Context,
 		environment config.Environment,
 		appPath string,
 		deploymentInfo S.DeploymentInfo,
 		response io.Writer,
+	) (map[string]string, error)
+	Preflight(
+		ctx context.Context,
+		environment config.Environment,
+		probePath string,
+		deploymentInfo S.DeploymentInfo,
+		response io.Writer,
 	) error
 }
@@ -0,0 +1,10 @@
+package interfaces
+
+import "time"
+
+// Clock interface.
+type Clock interface {
+	Now() time.Time
+	After(duration time.Duration) <-chan time.Time
+	Sleep(duration time.Duration)
+}
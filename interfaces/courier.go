@@ -1,15 +1,33 @@
 package interfaces
 
+import (
+	"io"
+
+	"golang.org/x/net/context"
+)
+
 // Courier interface.
 type Courier interface {
-	Login(api, username, password, org, space string, skipSSL bool) ([]byte, error)
-	Delete(appName string) ([]byte, error)
-	Push(appName, appLocation string, instances uint16) ([]byte, error)
-	Rename(oldName, newName string) ([]byte, error)
-	MapRoute(appName, domain string) ([]byte, error)
-	Logs(appName string) ([]byte, error)
-	Exists(appName string) bool
-	Cups(appName string, body string) ([]byte, error)
-	Uups(appName string, body string) ([]byte, error)
+	Login(ctx context.Context, api, username, password, org, space string, skipSSL bool) ([]byte, error)
+	Auth(ctx context.Context, api, token, org, space string, skipSSL bool) ([]byte, error)
+	Delete(ctx context.Context, appName string) ([]byte, error)
+	Push(ctx context.Context, appName, appLocation string, instances uint16, healthCheckPath string, startupTimeout int, noRoute bool, out io.Writer) ([]byte, error)
+	PushDocker(ctx context.Context, appName, appLocation, dockerImage string, instances uint16, healthCheckPath string, startupTimeout int, noRoute bool, out io.Writer) ([]byte, error)
+	PushRolling(ctx context.Context, appName, appLocation, dockerImage string, instances uint16, healthCheckPath string, startupTimeout int, noRoute bool, out io.Writer) ([]byte, error)
+	Healthy(ctx context.Context, appName string) (bool, error)
+	CanPush(ctx context.Context, appName, appLocation string) ([]byte, error)
+	Rename(ctx context.Context, oldName, newName string) ([]byte, error)
+	MapRoute(ctx context.Context, appName, hostname, domain string) ([]byte, error)
+	UnmapRoute(ctx context.Context, appName, hostname, domain string) ([]byte, error)
+	DeleteRoute(ctx context.Context, hostname, domain string) ([]byte, error)
+	Logs(ctx context.Context, appName string) ([]byte, error)
+	Exists(ctx context.Context, appName string) bool
+	List(ctx context.Context, prefix string) ([]string, error)
+	RoutedApps(ctx context.Context, hostname, domain string) ([]string, error)
+	Stop(ctx context.Context, appName string) ([]byte, error)
+	AppGUID(ctx context.Context, appName string) ([]byte, error)
+	Cups(ctx context.Context, appName string, body string) ([]byte, error)
+	Uups(ctx context.Context, appName string, body string) ([]byte, error)
+	Version() ([]byte, error)
 	CleanUp() error
 }
@@ -0,0 +1,6 @@
+package interfaces
+
+// Debouncer interface.
+type Debouncer interface {
+	Wait(key string) error
+}
@@ -3,11 +3,15 @@ package interfaces
 import (
 	"io"
 	"net/http"
+
+	S "github.com/compozed/deployadactyl/structs"
+	"golang.org/x/net/context"
 )
 
 // Deployer interface.
 type Deployer interface {
 	Deploy(
+		ctx context.Context,
 		req *http.Request,
 		environment,
 		org,
@@ -15,5 +19,5 @@ type Deployer interface {
 		appName,
 		contentType string,
 		response io.Writer,
-	) (int, error)
+	) (S.DeployTarget, int, error)
 }
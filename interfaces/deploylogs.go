@@ -0,0 +1,10 @@
+package interfaces
+
+import "io"
+
+// DeployLogs interface.
+type DeployLogs interface {
+	Create(requestID string) (io.WriteCloser, error)
+	Open(requestID string) (io.ReadCloser, error)
+	Exists(requestID string) bool
+}
@@ -4,6 +4,8 @@ import S "github.com/compozed/deployadactyl/structs"
 
 // EventManager interface.
 type EventManager interface {
-	AddHandler(handler Handler, eventType string) error
+	AddHandler(handler Handler, eventType string) (int, error)
+	AddHandlerWithPriority(handler Handler, eventType string, priority int) (int, error)
+	RemoveHandler(id int)
 	Emit(event S.Event) error
 }
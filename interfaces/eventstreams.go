@@ -0,0 +1,19 @@
+package interfaces
+
+import (
+	"io"
+
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// EventStreams interface.
+type EventStreams interface {
+	Open(deployID string) EventStream
+	Follow(deployID string, since int, send func(S.StreamEvent) error) error
+}
+
+// EventStream interface.
+type EventStream interface {
+	io.Writer
+	Close(result S.DeployResult)
+}
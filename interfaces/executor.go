@@ -1,8 +1,16 @@
 package interfaces
 
+import (
+	"io"
+
+	"golang.org/x/net/context"
+)
+
 // Executor interface.
 type Executor interface {
-	Execute(args ...string) ([]byte, error)
-	ExecuteInDirectory(directory string, args ...string) ([]byte, error)
+	Execute(ctx context.Context, args ...string) ([]byte, error)
+	ExecuteInDirectory(ctx context.Context, directory string, args ...string) ([]byte, error)
+	StreamInDirectory(ctx context.Context, directory string, out io.Writer, args ...string) ([]byte, error)
+	SetAccessToken(token string) error
 	CleanUp() error
 }
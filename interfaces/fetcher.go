@@ -1,9 +1,14 @@
 package interfaces
 
-import "net/http"
+import (
+	"io"
+
+	S "github.com/compozed/deployadactyl/structs"
+)
 
 // Fetcher interface.
 type Fetcher interface {
-	Fetch(url, manifest string) (string, error)
-	FetchZipFromRequest(*http.Request) (string, error)
+	Fetch(deploymentInfo S.DeploymentInfo, manifest string, out io.Writer) (string, error)
+	FetchManifest(url string) (string, error)
+	FetchFromZip(body io.Reader) (string, error)
 }
@@ -0,0 +1,7 @@
+package interfaces
+
+// Fingerprints interface.
+type Fingerprints interface {
+	Add(fingerprint string)
+	Seen(fingerprint string) bool
+}
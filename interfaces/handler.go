@@ -1,8 +1,17 @@
 package interfaces
 
-import S "github.com/compozed/deployadactyl/structs"
+import (
+	S "github.com/compozed/deployadactyl/structs"
+	"golang.org/x/net/context"
+)
 
 // Handler interface.
 type Handler interface {
 	OnEvent(event S.Event) error
 }
+
+// ContextHandler is a Handler that stops handling the event when ctx is done.
+type ContextHandler interface {
+	Handler
+	OnEventContext(ctx context.Context, event S.Event) error
+}
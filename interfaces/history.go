@@ -0,0 +1,9 @@
+package interfaces
+
+import S "github.com/compozed/deployadactyl/structs"
+
+// History interface.
+type History interface {
+	Add(result S.DeployResult)
+	Query(query S.HistoryQuery) ([]S.DeployResult, int)
+}
@@ -0,0 +1,19 @@
+package interfaces
+
+import (
+	"io"
+
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// Jobs interface.
+type Jobs interface {
+	Start(jobID string) Job
+	Status(jobID string) (S.JobStatus, bool)
+}
+
+// Job interface.
+type Job interface {
+	io.Writer
+	Finish(result S.DeployResult)
+}
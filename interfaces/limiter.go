@@ -0,0 +1,8 @@
+package interfaces
+
+import "golang.org/x/net/context"
+
+// Limiter interface.
+type Limiter interface {
+	Acquire(ctx context.Context) (func(), error)
+}
@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"io"
+	"time"
+)
+
+// Metrics interface.
+type Metrics interface {
+	RecordDeploy(environment, result string, duration time.Duration)
+	Write(w io.Writer) error
+}
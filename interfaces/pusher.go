@@ -4,14 +4,19 @@ import (
 	"io"
 
 	S "github.com/compozed/deployadactyl/structs"
+	"golang.org/x/net/context"
 )
 
 // Pusher interface.
 type Pusher interface {
-	Login(foundationURL string, deploymentInfo S.DeploymentInfo, response io.Writer) error
-	Push(appPath string, deploymentInfo S.DeploymentInfo, response io.Writer) error
-	Rollback(deploymentInfo S.DeploymentInfo) error
-	DeleteVenerable(deploymentInfo S.DeploymentInfo) error
+	Login(ctx context.Context, foundationURL string, deploymentInfo S.DeploymentInfo, response io.Writer) error
+	Push(ctx context.Context, appPath string, deploymentInfo S.DeploymentInfo, response io.Writer) error
+	PushInPlace(ctx context.Context, appPath string, deploymentInfo S.DeploymentInfo, response io.Writer) error
+	CanPush(ctx context.Context, probePath string, deploymentInfo S.DeploymentInfo, response io.Writer) error
+	UnmapVenerable(ctx context.Context, deploymentInfo S.DeploymentInfo) error
+	Rollback(ctx context.Context, deploymentInfo S.DeploymentInfo) error
+	DeleteVenerable(ctx context.Context, deploymentInfo S.DeploymentInfo) error
 	CleanUp() error
-	Exists(appName string)
+	Exists(ctx context.Context, appName string)
+	AppGUID() string
 }
@@ -3,4 +3,5 @@ package interfaces
 // Randomizer interface.
 type Randomizer interface {
 	StringRunes(length int) string
+	UUID() string
 }
@@ -0,0 +1,6 @@
+package interfaces
+
+// Readiness interface.
+type Readiness interface {
+	Check() error
+}
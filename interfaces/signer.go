@@ -0,0 +1,9 @@
+package interfaces
+
+import S "github.com/compozed/deployadactyl/structs"
+
+// Signer interface.
+type Signer interface {
+	Sign(result S.DeployResult) S.DeployResult
+	Verify(result S.DeployResult) error
+}
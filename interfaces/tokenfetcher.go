@@ -0,0 +1,6 @@
+package interfaces
+
+// TokenFetcher interface.
+type TokenFetcher interface {
+	Token(tokenURL, clientID, clientSecret string) (string, error)
+}
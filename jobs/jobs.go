@@ -0,0 +1,131 @@
+// Package jobs keeps track of asynchronous deploys.
+package jobs
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	I "github.com/compozed/deployadactyl/interfaces"
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// The states of a Job.
+const (
+	Running   = "running"
+	Succeeded = "succeeded"
+	Failed    = "failed"
+)
+
+// Jobs is a concurrency safe registry of asynchronous deploys.
+// Finished jobs are dropped once they have been finished for longer than the TTL.
+type Jobs struct {
+	mutex sync.Mutex
+	jobs  map[string]*Job
+	TTL   time.Duration
+	Now   func() time.Time
+}
+
+// New returns Jobs that keep finished jobs for the ttl.
+func New(ttl time.Duration) *Jobs {
+	return &Jobs{
+		jobs: make(map[string]*Job),
+		TTL:  ttl,
+		Now:  time.Now,
+	}
+}
+
+// Start adds a running job.
+func (j *Jobs) Start(jobID string) I.Job {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.expire()
+
+	job := &Job{
+		id:    jobID,
+		state: Running,
+		now:   j.Now,
+	}
+	j.jobs[jobID] = job
+
+	return job
+}
+
+// Status returns the JobStatus of a job and whether the job is known.
+func (j *Jobs) Status(jobID string) (S.JobStatus, bool) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.expire()
+
+	job, ok := j.jobs[jobID]
+	if !ok {
+		return S.JobStatus{}, false
+	}
+
+	return job.status(), true
+}
+
+func (j *Jobs) expire() {
+	now := j.Now()
+
+	for jobID, job := range j.jobs {
+		if job.expired(now, j.TTL) {
+			delete(j.jobs, jobID)
+		}
+	}
+}
+
+// Job is a single asynchronous deploy.
+// The deploy output is written to the Job and its result is set when it finishes.
+type Job struct {
+	mutex    sync.Mutex
+	id       string
+	state    string
+	output   bytes.Buffer
+	result   *S.DeployResult
+	finished time.Time
+	now      func() time.Time
+}
+
+// Write appends to the output of the Job.
+func (j *Job) Write(p []byte) (int, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return j.output.Write(p)
+}
+
+// Finish sets the result of the Job. The Job succeeded if the deploy succeeded.
+func (j *Job) Finish(result S.DeployResult) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.state = Failed
+	if result.Status == "success" {
+		j.state = Succeeded
+	}
+
+	j.result = &result
+	j.finished = j.now()
+}
+
+func (j *Job) status() S.JobStatus {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return S.JobStatus{
+		ID:     j.id,
+		State:  j.state,
+		Output: j.output.String(),
+		Result: j.result,
+	}
+}
+
+func (j *Job) expired(now time.Time, ttl time.Duration) bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return j.state != Running && now.Sub(j.finished) > ttl
+}
@@ -0,0 +1,88 @@
+package jobs_test
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/compozed/deployadactyl/jobs"
+	"github.com/compozed/deployadactyl/randomizer"
+	S "github.com/compozed/deployadactyl/structs"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Jobs", func() {
+	var (
+		jobs  *Jobs
+		jobID string
+		now   time.Time
+	)
+
+	BeforeEach(func() {
+		now = time.Now()
+
+		jobs = New(time.Hour)
+		jobs.Now = func() time.Time { return now }
+
+		jobID = "jobID-" + randomizer.StringRunes(10)
+	})
+
+	It("is running with the output written so far", func() {
+		job := jobs.Start(jobID)
+		fmt.Fprint(job, "deploying")
+
+		status, ok := jobs.Status(jobID)
+
+		Expect(ok).To(BeTrue())
+		Expect(status).To(Equal(S.JobStatus{ID: jobID, State: Running, Output: "deploying"}))
+	})
+
+	It("succeeds when the deploy succeeds", func() {
+		jobs.Start(jobID).Finish(S.DeployResult{Status: "success"})
+
+		status, _ := jobs.Status(jobID)
+
+		Expect(status.State).To(Equal(Succeeded))
+		Expect(status.Result.Status).To(Equal("success"))
+	})
+
+	It("fails when the deploy fails", func() {
+		jobs.Start(jobID).Finish(S.DeployResult{Status: "failure", Error: "bork"})
+
+		status, _ := jobs.Status(jobID)
+
+		Expect(status.State).To(Equal(Failed))
+		Expect(status.Result.Error).To(Equal("bork"))
+	})
+
+	Context("when the job is unknown", func() {
+		It("is not found", func() {
+			_, ok := jobs.Status(jobID)
+
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("when a finished job is older than the ttl", func() {
+		It("is dropped", func() {
+			jobs.Start(jobID).Finish(S.DeployResult{Status: "success"})
+
+			now = now.Add(time.Hour + time.Second)
+
+			_, ok := jobs.Status(jobID)
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("when a running job is older than the ttl", func() {
+		It("is kept", func() {
+			jobs.Start(jobID)
+
+			now = now.Add(2 * time.Hour)
+
+			_, ok := jobs.Status(jobID)
+			Expect(ok).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,11 @@
+package limiter
+
+import "fmt"
+
+type BusyError struct {
+	MaxConcurrent int
+}
+
+func (e BusyError) Error() string {
+	return fmt.Sprintf("server busy, retry later: %d deploys are already running", e.MaxConcurrent)
+}
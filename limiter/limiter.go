@@ -0,0 +1,70 @@
+// Package limiter limits the number of deploys that run at the same time.
+package limiter
+
+import (
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// Limiter is a semaphore that lets a maximum number of deploys run at once.
+// Deploys over the maximum wait for a running deploy to finish, up to a maximum number of waiting deploys.
+type Limiter struct {
+	mutex     sync.Mutex
+	slots     chan struct{}
+	queued    int
+	maxQueued int
+}
+
+// New returns a Limiter that runs maxConcurrent deploys at once and queues up to maxQueued more.
+// A maxConcurrent of zero or less does not limit deploys.
+func New(maxConcurrent, maxQueued int) *Limiter {
+	limiter := &Limiter{maxQueued: maxQueued}
+	if maxConcurrent > 0 {
+		limiter.slots = make(chan struct{}, maxConcurrent)
+	}
+
+	return limiter
+}
+
+// Acquire holds the caller until fewer than the maximum number of deploys are running or ctx is done.
+// The returned function must be called once the deploy has finished.
+//
+// Returns a BusyError without waiting when the queue of waiting deploys is full, and the error of ctx when it is done
+// before the deploy could run.
+func (l *Limiter) Acquire(ctx context.Context) (func(), error) {
+	if l.slots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return l.release, nil
+	default:
+	}
+
+	l.mutex.Lock()
+	if l.queued >= l.maxQueued {
+		l.mutex.Unlock()
+		return nil, BusyError{cap(l.slots)}
+	}
+	l.queued++
+	l.mutex.Unlock()
+
+	defer func() {
+		l.mutex.Lock()
+		l.queued--
+		l.mutex.Unlock()
+	}()
+
+	select {
+	case l.slots <- struct{}{}:
+		return l.release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *Limiter) release() {
+	<-l.slots
+}
@@ -0,0 +1,150 @@
+package limiter_test
+
+import (
+	"sync"
+	"time"
+
+	. "github.com/compozed/deployadactyl/limiter"
+	"golang.org/x/net/context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Limiter", func() {
+	var limiter *Limiter
+
+	acquireAll := func(deploys int) ([]func(), []error) {
+		var (
+			wg       sync.WaitGroup
+			releases = make([]func(), deploys)
+			errs     = make([]error, deploys)
+		)
+
+		for i := 0; i < deploys; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				releases[i], errs[i] = limiter.Acquire(context.Background())
+			}(i)
+		}
+		wg.Wait()
+
+		return releases, errs
+	}
+
+	It("runs the maximum number of deploys at once and rejects one more", func() {
+		limiter = New(3, 0)
+
+		releases, errs := acquireAll(4)
+
+		var rejected int
+		for i, err := range errs {
+			if err != nil {
+				Expect(err).To(MatchError(BusyError{3}))
+				Expect(releases[i]).To(BeNil())
+				rejected++
+			}
+		}
+		Expect(rejected).To(Equal(1))
+	})
+
+	It("runs another deploy once a running deploy is released", func() {
+		limiter = New(1, 0)
+
+		release, err := limiter.Acquire(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = limiter.Acquire(context.Background())
+		Expect(err).To(MatchError(BusyError{1}))
+
+		release()
+
+		_, err = limiter.Acquire(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Context("when deploys can be queued", func() {
+		It("holds a queued deploy until a running deploy is released", func() {
+			limiter = New(1, 1)
+
+			release, err := limiter.Acquire(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+
+			acquired := make(chan error, 1)
+			go func() {
+				_, err := limiter.Acquire(context.Background())
+				acquired <- err
+			}()
+
+			Consistently(acquired, 50*time.Millisecond).ShouldNot(Receive())
+
+			release()
+
+			Eventually(acquired).Should(Receive(BeNil()))
+		})
+
+		It("rejects a deploy when the queue is full", func() {
+			limiter = New(1, 1)
+
+			release, err := limiter.Acquire(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+
+			queued := make(chan error, 1)
+			go func() {
+				_, err := limiter.Acquire(context.Background())
+				queued <- err
+			}()
+
+			Consistently(queued, 50*time.Millisecond).ShouldNot(Receive())
+
+			_, err = limiter.Acquire(context.Background())
+			Expect(err).To(MatchError(BusyError{1}))
+
+			release()
+			Eventually(queued).Should(Receive(BeNil()))
+		})
+
+		It("gives up the place of a queued deploy when its context is cancelled", func() {
+			limiter = New(1, 1)
+
+			release, err := limiter.Acquire(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancelled := make(chan error, 1)
+			go func() {
+				_, err := limiter.Acquire(ctx)
+				cancelled <- err
+			}()
+
+			Consistently(cancelled, 50*time.Millisecond).ShouldNot(Receive())
+
+			cancel()
+			Eventually(cancelled).Should(Receive(Equal(context.Canceled)))
+
+			queued := make(chan error, 1)
+			go func() {
+				_, err := limiter.Acquire(context.Background())
+				queued <- err
+			}()
+
+			Consistently(queued, 50*time.Millisecond).ShouldNot(Receive())
+
+			release()
+			Eventually(queued).Should(Receive(BeNil()))
+		})
+	})
+
+	Context("when the maximum is zero", func() {
+		It("does not limit deploys", func() {
+			limiter = New(0, 0)
+
+			_, errs := acquireAll(10)
+
+			for _, err := range errs {
+				Expect(err).ToNot(HaveOccurred())
+			}
+		})
+	})
+})
@@ -0,0 +1,38 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/op/go-logging"
+)
+
+// jsonFormatter writes every log line as a JSON object so that the logs can be parsed by log aggregators.
+type jsonFormatter struct {
+	requestID string
+}
+
+type jsonLine struct {
+	Level     string `json:"level"`
+	Timestamp string `json:"timestamp"`
+	Module    string `json:"module"`
+	RequestID string `json:"request_id,omitempty"`
+	Message   string `json:"message"`
+}
+
+func (f jsonFormatter) Format(calldepth int, r *logging.Record, w io.Writer) error {
+	line, err := json.Marshal(jsonLine{
+		Level:     r.Level.String(),
+		Timestamp: r.Time.Format(time.RFC3339Nano),
+		Module:    r.Module,
+		RequestID: f.requestID,
+		Message:   r.Message(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(line)
+	return err
+}
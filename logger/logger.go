@@ -2,25 +2,157 @@
 package logger
 
 import (
+	"bytes"
+	"fmt"
 	"io"
+	"regexp"
+	"sync"
 
 	"github.com/op/go-logging"
 )
 
+// RequestIDHeader is the header that carries the id of a request in and out of Deployadactyl.
+const RequestIDHeader = "X-Request-Id"
+
+// The formats a logger can write its log lines in.
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
+const textFormat = `%{time:2006/01/02 15:04:05} %{level:.4s} ▶ (%{shortfunc}) `
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// outputs holds where each logger made by DefaultLogger writes, so that WithRequestID logs to the same place.
+var outputs = struct {
+	sync.RWMutex
+	byLogger map[*logging.Logger]*loggerOutput
+}{byLogger: map[*logging.Logger]*loggerOutput{}}
+
+// loggerOutput is the writer, level and format of a logger made by DefaultLogger.
+// The text formatter is built once and shared by the request loggers made from the logger.
+type loggerOutput struct {
+	mutex  sync.Mutex
+	out    io.Writer
+	level  logging.Level
+	format string
+	text   logging.Formatter
+}
+
 // DefaultLogger returns a logging.Logger with a specific logging format.
-func DefaultLogger(out io.Writer, level logging.Level, module string) *logging.Logger {
+// The format is either TextFormat or JSONFormat. Any other format is treated as TextFormat.
+func DefaultLogger(out io.Writer, level logging.Level, module, format string) *logging.Logger {
 
 	var log = logging.MustGetLogger(module)
 
-	var format = logging.MustStringFormatter(
-		`%{time:2006/01/02 15:04:05} %{level:.4s} ▶ (%{shortfunc}) %{message}`,
-	)
+	backend := newBackend(out, level, module, newFormatter(format))
+	log.SetBackend(backend)
+	logging.SetBackend(backend)
+
+	outputs.Lock()
+	outputs.byLogger[log] = &loggerOutput{
+		out:    out,
+		level:  level,
+		format: format,
+		text:   logging.MustStringFormatter(textFormat),
+	}
+	outputs.Unlock()
+
+	return log
+}
+
+// WithRequestID returns a logging.Logger for the module of log that adds the request id to every log line.
+// The log lines are written to the writer of log, at its level and in its format.
+// log is returned as it is when the request id is not valid or log was not made by DefaultLogger.
+func WithRequestID(log *logging.Logger, requestID string) *logging.Logger {
+	outputs.RLock()
+	output, found := outputs.byLogger[log]
+	outputs.RUnlock()
+
+	if !found || !ValidRequestID(requestID) {
+		return log
+	}
+
+	requestLog := logging.MustGetLogger(log.Module)
+	requestLog.ExtraCalldepth = log.ExtraCalldepth
+	requestLog.SetBackend(requestBackend{output, requestID})
+
+	return requestLog
+}
+
+// ValidRequestID returns true if the request id is safe to put in log lines and URLs.
+func ValidRequestID(requestID string) bool {
+	return validRequestID.MatchString(requestID)
+}
+
+// newFormatter returns a formatter for format.
+func newFormatter(format string) logging.Formatter {
+	if format == JSONFormat {
+		return jsonFormatter{}
+	}
 
+	return logging.MustStringFormatter(textFormat + `%{message}`)
+}
+
+func newBackend(out io.Writer, level logging.Level, module string, formatter logging.Formatter) logging.LeveledBackend {
 	backend := logging.NewLogBackend(out, "", 0)
-	backendFormatter := logging.NewBackendFormatter(backend, format)
+	backendFormatter := logging.NewBackendFormatter(backend, formatter)
 	backendLeveledFormatter := logging.AddModuleLevel(backendFormatter)
 	backendLeveledFormatter.SetLevel(level, module)
-	logging.SetBackend(backendLeveledFormatter)
 
-	return log
+	return backendLeveledFormatter
+}
+
+// requestBackend writes the log lines of a request logger to the output of the logger it was made from.
+type requestBackend struct {
+	output    *loggerOutput
+	requestID string
+}
+
+func (b requestBackend) Log(level logging.Level, calldepth int, r *logging.Record) error {
+	var formatter logging.Formatter = jsonFormatter{b.requestID}
+	if b.output.format != JSONFormat {
+		formatter = requestFormatter{b.output.text, b.requestID}
+	}
+
+	var line bytes.Buffer
+	err := formatter.Format(calldepth+1, r, &line)
+	if err != nil {
+		return err
+	}
+	line.WriteByte('\n')
+
+	b.output.mutex.Lock()
+	defer b.output.mutex.Unlock()
+
+	_, err = b.output.out.Write(line.Bytes())
+	return err
+}
+
+func (b requestBackend) GetLevel(module string) logging.Level {
+	return b.output.level
+}
+
+// SetLevel does nothing because a request logger keeps the level of the logger it was made from.
+func (b requestBackend) SetLevel(level logging.Level, module string) {}
+
+func (b requestBackend) IsEnabledFor(level logging.Level, module string) bool {
+	return level <= b.output.level
+}
+
+// requestFormatter writes the text format of a log line with the request id in front of the message.
+type requestFormatter struct {
+	prefix    logging.Formatter
+	requestID string
+}
+
+func (f requestFormatter) Format(calldepth int, r *logging.Record, w io.Writer) error {
+	err := f.prefix.Format(calldepth+1, r, w)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "[%s] %s", f.requestID, r.Message())
+	return err
 }
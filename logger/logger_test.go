@@ -0,0 +1,113 @@
+package logger_test
+
+import (
+	"encoding/json"
+
+	. "github.com/compozed/deployadactyl/logger"
+	"github.com/compozed/deployadactyl/randomizer"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+	"github.com/op/go-logging"
+)
+
+var _ = Describe("Logger", func() {
+	var (
+		logBuffer *Buffer
+		log       *logging.Logger
+		message   string
+	)
+
+	BeforeEach(func() {
+		logBuffer = NewBuffer()
+		log = DefaultLogger(logBuffer, logging.INFO, "logger_test", TextFormat)
+		message = "message-" + randomizer.StringRunes(10)
+	})
+
+	Describe("the json format", func() {
+		var parseLine = func() map[string]string {
+			var line map[string]string
+			Expect(json.Unmarshal(logBuffer.Contents(), &line)).To(Succeed())
+
+			return line
+		}
+
+		BeforeEach(func() {
+			log = DefaultLogger(logBuffer, logging.INFO, "logger_test", JSONFormat)
+		})
+
+		It("writes every log line as a json object", func() {
+			log.Infof("logging %s", message)
+
+			line := parseLine()
+			Expect(line["level"]).To(Equal("INFO"))
+			Expect(line["module"]).To(Equal("logger_test"))
+			Expect(line["message"]).To(Equal("logging " + message))
+			Expect(line["timestamp"]).ToNot(BeEmpty())
+			Expect(line).ToNot(HaveKey("request_id"))
+		})
+
+		It("adds the request id as a field", func() {
+			WithRequestID(log, "requestID").Info(message)
+
+			line := parseLine()
+			Expect(line["request_id"]).To(Equal("requestID"))
+			Expect(line["message"]).To(Equal(message))
+		})
+	})
+
+	Describe("WithRequestID", func() {
+		It("prefixes every message with the request id", func() {
+			requestID := "requestID-" + randomizer.StringRunes(10)
+
+			WithRequestID(log, requestID).Infof("logging %s", message)
+
+			Eventually(logBuffer).Should(Say(`INFO ▶ \(.*\) \[%s\] logging %s`, requestID, message))
+		})
+
+		It("keeps the level of the logger", func() {
+			WithRequestID(log, "requestID").Debug(message)
+
+			Consistently(logBuffer).ShouldNot(Say(message))
+		})
+
+		It("logs to the writer of the logger it is given", func() {
+			otherBuffer := NewBuffer()
+			DefaultLogger(otherBuffer, logging.INFO, "other_logger_test", TextFormat)
+
+			WithRequestID(log, "requestID").Info(message)
+
+			Eventually(logBuffer).Should(Say(`\[requestID\] %s`, message))
+			Consistently(otherBuffer).ShouldNot(Say(message))
+		})
+
+		Context("when the logger was not made by DefaultLogger", func() {
+			It("returns the logger", func() {
+				otherLog := logging.MustGetLogger("logger_test")
+
+				Expect(WithRequestID(otherLog, "requestID") == otherLog).To(BeTrue())
+			})
+		})
+
+		Context("when the request id is not valid", func() {
+			It("returns the logger", func() {
+				Expect(WithRequestID(log, "") == log).To(BeTrue())
+				Expect(WithRequestID(log, "request id\n%{level}") == log).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("ValidRequestID", func() {
+		It("accepts letters, digits, dots, dashes and underscores", func() {
+			Expect(ValidRequestID("Request.ID-1_2")).To(BeTrue())
+		})
+
+		It("rejects empty, long and unsafe request ids", func() {
+			Expect(ValidRequestID("")).To(BeFalse())
+			Expect(ValidRequestID(randomizer.StringRunes(129))).To(BeFalse())
+			Expect(ValidRequestID("request id")).To(BeFalse())
+			Expect(ValidRequestID("%{message}")).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,145 @@
+// Package metrics keeps deploy metrics and writes them in the Prometheus text format.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContentType is the content type of the Prometheus text format written by Write.
+const ContentType = "text/plain; version=0.0.4"
+
+// DefaultBuckets are the upper bounds in seconds of the deploy_duration_seconds histogram buckets.
+var DefaultBuckets = []float64{5, 10, 30, 60, 120, 300, 600, 1200, 1800}
+
+// Metrics counts deploys by environment and result and keeps a histogram of deploy durations by environment.
+// It is safe for concurrent use.
+type Metrics struct {
+	mutex     sync.Mutex
+	buckets   []float64
+	deploys   map[deployKey]int
+	durations map[string]*histogram
+}
+
+type deployKey struct {
+	environment string
+	result      string
+}
+
+type histogram struct {
+	counts []int
+	sum    float64
+	count  int
+}
+
+// New returns Metrics with a histogram for each of the bucket upper bounds.
+// DefaultBuckets are used when no buckets are given.
+func New(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+
+	return &Metrics{
+		buckets:   sorted,
+		deploys:   map[deployKey]int{},
+		durations: map[string]*histogram{},
+	}
+}
+
+// RecordDeploy counts a deploy to the environment with the result and observes its duration.
+func (m *Metrics) RecordDeploy(environment, result string, duration time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.deploys[deployKey{environment, result}]++
+
+	h, ok := m.durations[environment]
+	if !ok {
+		h = &histogram{counts: make([]int, len(m.buckets))}
+		m.durations[environment] = h
+	}
+
+	seconds := duration.Seconds()
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// Write writes the deploys_total counter and the deploy_duration_seconds histogram in the Prometheus text format.
+func (m *Metrics) Write(w io.Writer) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintln(out, "# HELP deploys_total The number of deploys by environment and result.")
+	fmt.Fprintln(out, "# TYPE deploys_total counter")
+
+	keys := make([]deployKey, 0, len(m.deploys))
+	for key := range m.deploys {
+		keys = append(keys, key)
+	}
+	sort.Sort(byLabels(keys))
+
+	for _, key := range keys {
+		fmt.Fprintf(out, "deploys_total{environment=%s,result=%s} %d\n", quote(key.environment), quote(key.result), m.deploys[key])
+	}
+
+	fmt.Fprintln(out, "# HELP deploy_duration_seconds How long deploys take by environment.")
+	fmt.Fprintln(out, "# TYPE deploy_duration_seconds histogram")
+
+	environments := make([]string, 0, len(m.durations))
+	for environment := range m.durations {
+		environments = append(environments, environment)
+	}
+	sort.Strings(environments)
+
+	for _, environment := range environments {
+		h := m.durations[environment]
+		label := "environment=" + quote(environment)
+
+		for i, bound := range m.buckets {
+			fmt.Fprintf(out, "deploy_duration_seconds_bucket{%s,le=%q} %d\n", label, formatFloat(bound), h.counts[i])
+		}
+		fmt.Fprintf(out, "deploy_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", label, h.count)
+		fmt.Fprintf(out, "deploy_duration_seconds_sum{%s} %s\n", label, formatFloat(h.sum))
+		fmt.Fprintf(out, "deploy_duration_seconds_count{%s} %d\n", label, h.count)
+	}
+
+	return out.Flush()
+}
+
+type byLabels []deployKey
+
+func (k byLabels) Len() int      { return len(k) }
+func (k byLabels) Swap(i, j int) { k[i], k[j] = k[j], k[i] }
+func (k byLabels) Less(i, j int) bool {
+	if k[i].environment != k[j].environment {
+		return k[i].environment < k[j].environment
+	}
+	return k[i].result < k[j].result
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quote returns value as a quoted Prometheus label value.
+func quote(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,79 @@
+package metrics_test
+
+import (
+	"bytes"
+	"time"
+
+	. "github.com/compozed/deployadactyl/metrics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metrics", func() {
+	var (
+		metrics *Metrics
+		out     *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		metrics = New(10, 60)
+		out = &bytes.Buffer{}
+	})
+
+	It("writes the metric types without any deploys", func() {
+		Expect(metrics.Write(out)).To(Succeed())
+
+		Expect(out.String()).To(Equal(`# HELP deploys_total The number of deploys by environment and result.
+# TYPE deploys_total counter
+# HELP deploy_duration_seconds How long deploys take by environment.
+# TYPE deploy_duration_seconds histogram
+`))
+	})
+
+	It("counts deploys by environment and result", func() {
+		metrics.RecordDeploy("production", "success", time.Second)
+		metrics.RecordDeploy("production", "success", time.Second)
+		metrics.RecordDeploy("production", "failure", time.Second)
+		metrics.RecordDeploy("preproduction", "success", time.Second)
+
+		Expect(metrics.Write(out)).To(Succeed())
+
+		Expect(out.String()).To(ContainSubstring(`deploys_total{environment="preproduction",result="success"} 1
+deploys_total{environment="production",result="failure"} 1
+deploys_total{environment="production",result="success"} 2
+`))
+	})
+
+	It("observes deploy durations in cumulative buckets", func() {
+		metrics.RecordDeploy("production", "success", 5*time.Second)
+		metrics.RecordDeploy("production", "failure", 30*time.Second)
+		metrics.RecordDeploy("production", "success", 90*time.Second)
+
+		Expect(metrics.Write(out)).To(Succeed())
+
+		Expect(out.String()).To(ContainSubstring(`deploy_duration_seconds_bucket{environment="production",le="10"} 1
+deploy_duration_seconds_bucket{environment="production",le="60"} 2
+deploy_duration_seconds_bucket{environment="production",le="+Inf"} 3
+deploy_duration_seconds_sum{environment="production"} 125
+deploy_duration_seconds_count{environment="production"} 3
+`))
+	})
+
+	It("escapes label values", func() {
+		metrics.RecordDeploy("pro\"duc\\tion\n", "success", time.Second)
+
+		Expect(metrics.Write(out)).To(Succeed())
+
+		Expect(out.String()).To(ContainSubstring(`deploys_total{environment="pro\"duc\\tion\n",result="success"} 1`))
+	})
+
+	It("uses the default buckets when none are given", func() {
+		metrics = New()
+		metrics.RecordDeploy("production", "success", time.Second)
+
+		Expect(metrics.Write(out)).To(Succeed())
+
+		Expect(out.String()).To(ContainSubstring(`le="1800"`))
+	})
+})
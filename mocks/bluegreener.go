@@ -5,17 +5,33 @@ import (
 
 	"github.com/compozed/deployadactyl/config"
 	S "github.com/compozed/deployadactyl/structs"
+	"golang.org/x/net/context"
 )
 
 // BlueGreener handmade mock for tests.
 type BlueGreener struct {
 	PushCall struct {
 		Received struct {
+			Context        context.Context
 			Environment    config.Environment
 			AppPath        string
 			DeploymentInfo S.DeploymentInfo
 			Out            io.Writer
 		}
+		Returns struct {
+			AppGUIDs map[string]string
+			Error    error
+		}
+	}
+
+	PreflightCall struct {
+		Received struct {
+			Context        context.Context
+			Environment    config.Environment
+			ProbePath      string
+			DeploymentInfo S.DeploymentInfo
+			Out            io.Writer
+		}
 		Returns struct {
 			Error error
 		}
@@ -23,11 +39,23 @@ type BlueGreener struct {
 }
 
 // Push mock method.
-func (b *BlueGreener) Push(environment config.Environment, appPath string, deploymentInfo S.DeploymentInfo, out io.Writer) error {
+func (b *BlueGreener) Push(ctx context.Context, environment config.Environment, appPath string, deploymentInfo S.DeploymentInfo, out io.Writer) (map[string]string, error) {
+	b.PushCall.Received.Context = ctx
 	b.PushCall.Received.Environment = environment
 	b.PushCall.Received.AppPath = appPath
 	b.PushCall.Received.DeploymentInfo = deploymentInfo
 	b.PushCall.Received.Out = out
 
-	return b.PushCall.Returns.Error
+	return b.PushCall.Returns.AppGUIDs, b.PushCall.Returns.Error
+}
+
+// Preflight mock method.
+func (b *BlueGreener) Preflight(ctx context.Context, environment config.Environment, probePath string, deploymentInfo S.DeploymentInfo, out io.Writer) error {
+	b.PreflightCall.Received.Context = ctx
+	b.PreflightCall.Received.Environment = environment
+	b.PreflightCall.Received.ProbePath = probePath
+	b.PreflightCall.Received.DeploymentInfo = deploymentInfo
+	b.PreflightCall.Received.Out = out
+
+	return b.PreflightCall.Returns.Error
 }
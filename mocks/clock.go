@@ -0,0 +1,53 @@
+package mocks
+
+import "time"
+
+// Clock handmade mock for tests.
+// Sleep and After return straight away and move the time returned by Now forward by the duration.
+// Each call to Now moves the time forward by the Step it returns.
+type Clock struct {
+	NowCall struct {
+		TimesCalled int
+		Returns     struct {
+			Time time.Time
+			Step time.Duration
+		}
+	}
+	AfterCall struct {
+		Received struct {
+			Durations []time.Duration
+		}
+	}
+	SleepCall struct {
+		Received struct {
+			Durations []time.Duration
+		}
+	}
+}
+
+// Now mock method.
+func (c *Clock) Now() time.Time {
+	c.NowCall.TimesCalled++
+
+	now := c.NowCall.Returns.Time
+	c.NowCall.Returns.Time = now.Add(c.NowCall.Returns.Step)
+
+	return now
+}
+
+// After mock method.
+func (c *Clock) After(duration time.Duration) <-chan time.Time {
+	c.AfterCall.Received.Durations = append(c.AfterCall.Received.Durations, duration)
+	c.NowCall.Returns.Time = c.NowCall.Returns.Time.Add(duration)
+
+	after := make(chan time.Time, 1)
+	after <- c.NowCall.Returns.Time
+
+	return after
+}
+
+// Sleep mock method.
+func (c *Clock) Sleep(duration time.Duration) {
+	c.SleepCall.Received.Durations = append(c.SleepCall.Received.Durations, duration)
+	c.NowCall.Returns.Time = c.NowCall.Returns.Time.Add(duration)
+}
@@ -1,9 +1,17 @@
 package mocks
 
+import (
+	"io"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
 // Courier handmade mock for tests.
 type Courier struct {
 	LoginCall struct {
 		Received struct {
+			Context       context.Context
 			FoundationURL string
 			Username      string
 			Password      string
@@ -15,11 +23,30 @@ type Courier struct {
 			Output []byte
 			Error  error
 		}
+		TimesCalled int
+		Delay       time.Duration
+	}
+
+	AuthCall struct {
+		Received struct {
+			Context       context.Context
+			FoundationURL string
+			Token         string
+			Org           string
+			Space         string
+			SkipSSL       bool
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
 	}
 
 	DeleteCall struct {
 		Received struct {
-			AppName string
+			Context  context.Context
+			AppName  string
+			AppNames []string
 		}
 		Returns struct {
 			Output []byte
@@ -29,9 +56,74 @@ type Courier struct {
 
 	PushCall struct {
 		Received struct {
-			AppName   string
-			AppPath   string
-			Instances uint16
+			Context         context.Context
+			AppName         string
+			AppPath         string
+			Instances       uint16
+			HealthCheckPath string
+			StartupTimeout  int
+			NoRoute         bool
+			Out             io.Writer
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	PushDockerCall struct {
+		Received struct {
+			Context         context.Context
+			AppName         string
+			AppPath         string
+			DockerImage     string
+			Instances       uint16
+			HealthCheckPath string
+			StartupTimeout  int
+			NoRoute         bool
+			Out             io.Writer
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	PushRollingCall struct {
+		Received struct {
+			Context         context.Context
+			AppName         string
+			AppPath         string
+			DockerImage     string
+			Instances       uint16
+			HealthCheckPath string
+			StartupTimeout  int
+			NoRoute         bool
+			Out             io.Writer
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	HealthyCall struct {
+		Received struct {
+			Context context.Context
+			AppName string
+		}
+		Returns struct {
+			Healthy bool
+			Error   error
+		}
+		TimesCalled int
+	}
+
+	CanPushCall struct {
+		Received struct {
+			Context context.Context
+			AppName string
+			AppPath string
 		}
 		Returns struct {
 			Output []byte
@@ -41,6 +133,7 @@ type Courier struct {
 
 	RenameCall struct {
 		Received struct {
+			Context          context.Context
 			AppName          string
 			AppNameVenerable string
 		}
@@ -52,6 +145,7 @@ type Courier struct {
 
 	LogsCall struct {
 		Received struct {
+			Context context.Context
 			AppName string
 		}
 		Returns struct {
@@ -62,8 +156,39 @@ type Courier struct {
 
 	MapRouteCall struct {
 		Received struct {
-			AppName string
-			Domain  string
+			Context  context.Context
+			AppName  string
+			Hostname string
+			Domain   string
+			Domains  []string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+
+			// DomainErrors are returned instead of Error when the route is mapped on one of their domains.
+			DomainErrors map[string]error
+		}
+	}
+
+	UnmapRouteCall struct {
+		Received struct {
+			Context  context.Context
+			AppName  string
+			Hostname string
+			Domains  []string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	DeleteRouteCall struct {
+		Received struct {
+			Context  context.Context
+			Hostname string
+			Domain   string
 		}
 		Returns struct {
 			Output []byte
@@ -73,6 +198,7 @@ type Courier struct {
 
 	ExistsCall struct {
 		Received struct {
+			Context context.Context
 			AppName string
 		}
 		Returns struct {
@@ -80,8 +206,20 @@ type Courier struct {
 		}
 	}
 
+	AppGUIDCall struct {
+		Received struct {
+			Context context.Context
+			AppName string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
 	CupsCall struct {
 		Received struct {
+			Context context.Context
 			AppName string
 			Body    string
 		}
@@ -93,6 +231,7 @@ type Courier struct {
 
 	UupsCall struct {
 		Received struct {
+			Context context.Context
 			AppName string
 			Body    string
 		}
@@ -102,6 +241,48 @@ type Courier struct {
 		}
 	}
 
+	ListCall struct {
+		Received struct {
+			Context context.Context
+			Prefix  string
+		}
+		Returns struct {
+			AppNames []string
+			Error    error
+		}
+	}
+
+	RoutedAppsCall struct {
+		Received struct {
+			Context  context.Context
+			Hostname string
+			Domain   string
+		}
+		Returns struct {
+			AppNames []string
+			Error    error
+		}
+	}
+
+	StopCall struct {
+		Received struct {
+			Context context.Context
+			AppName string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	VersionCall struct {
+		TimesCalled int
+		Returns     struct {
+			Output []byte
+			Error  error
+		}
+	}
+
 	CleanUpCall struct {
 		Returns struct {
 			Error error
@@ -110,35 +291,119 @@ type Courier struct {
 }
 
 // Login mock method.
-func (c *Courier) Login(api, username, password, org, space string, skipSSL bool) ([]byte, error) {
+func (c *Courier) Login(ctx context.Context, api, username, password, org, space string, skipSSL bool) ([]byte, error) {
+	c.LoginCall.Received.Context = ctx
 	c.LoginCall.Received.FoundationURL = api
 	c.LoginCall.Received.Username = username
 	c.LoginCall.Received.Password = password
 	c.LoginCall.Received.Org = org
 	c.LoginCall.Received.Space = space
 	c.LoginCall.Received.SkipSSL = skipSSL
+	c.LoginCall.TimesCalled++
+
+	if c.LoginCall.Delay > 0 {
+		select {
+		case <-time.After(c.LoginCall.Delay):
+		case <-ctx.Done():
+			return c.LoginCall.Returns.Output, ctx.Err()
+		}
+	}
 
 	return c.LoginCall.Returns.Output, c.LoginCall.Returns.Error
 }
 
+// Auth mock method.
+func (c *Courier) Auth(ctx context.Context, api, token, org, space string, skipSSL bool) ([]byte, error) {
+	c.AuthCall.Received.Context = ctx
+	c.AuthCall.Received.FoundationURL = api
+	c.AuthCall.Received.Token = token
+	c.AuthCall.Received.Org = org
+	c.AuthCall.Received.Space = space
+	c.AuthCall.Received.SkipSSL = skipSSL
+
+	return c.AuthCall.Returns.Output, c.AuthCall.Returns.Error
+}
+
 // Delete mock method.
-func (c *Courier) Delete(appName string) ([]byte, error) {
+func (c *Courier) Delete(ctx context.Context, appName string) ([]byte, error) {
+	c.DeleteCall.Received.Context = ctx
 	c.DeleteCall.Received.AppName = appName
+	c.DeleteCall.Received.AppNames = append(c.DeleteCall.Received.AppNames, appName)
 
 	return c.DeleteCall.Returns.Output, c.DeleteCall.Returns.Error
 }
 
 // Push mock method.
-func (c *Courier) Push(appName, appLocation string, instances uint16) ([]byte, error) {
+func (c *Courier) Push(ctx context.Context, appName, appLocation string, instances uint16, healthCheckPath string, startupTimeout int, noRoute bool, out io.Writer) ([]byte, error) {
+	c.PushCall.Received.Context = ctx
 	c.PushCall.Received.AppName = appName
 	c.PushCall.Received.AppPath = appLocation
 	c.PushCall.Received.Instances = instances
+	c.PushCall.Received.HealthCheckPath = healthCheckPath
+	c.PushCall.Received.StartupTimeout = startupTimeout
+	c.PushCall.Received.NoRoute = noRoute
+	c.PushCall.Received.Out = out
+
+	out.Write(c.PushCall.Returns.Output)
 
 	return c.PushCall.Returns.Output, c.PushCall.Returns.Error
 }
 
+// PushDocker mock method.
+func (c *Courier) PushDocker(ctx context.Context, appName, appLocation, dockerImage string, instances uint16, healthCheckPath string, startupTimeout int, noRoute bool, out io.Writer) ([]byte, error) {
+	c.PushDockerCall.Received.Context = ctx
+	c.PushDockerCall.Received.AppName = appName
+	c.PushDockerCall.Received.AppPath = appLocation
+	c.PushDockerCall.Received.DockerImage = dockerImage
+	c.PushDockerCall.Received.Instances = instances
+	c.PushDockerCall.Received.HealthCheckPath = healthCheckPath
+	c.PushDockerCall.Received.StartupTimeout = startupTimeout
+	c.PushDockerCall.Received.NoRoute = noRoute
+	c.PushDockerCall.Received.Out = out
+
+	out.Write(c.PushDockerCall.Returns.Output)
+
+	return c.PushDockerCall.Returns.Output, c.PushDockerCall.Returns.Error
+}
+
+// PushRolling mock method.
+func (c *Courier) PushRolling(ctx context.Context, appName, appLocation, dockerImage string, instances uint16, healthCheckPath string, startupTimeout int, noRoute bool, out io.Writer) ([]byte, error) {
+	c.PushRollingCall.Received.Context = ctx
+	c.PushRollingCall.Received.AppName = appName
+	c.PushRollingCall.Received.AppPath = appLocation
+	c.PushRollingCall.Received.DockerImage = dockerImage
+	c.PushRollingCall.Received.Instances = instances
+	c.PushRollingCall.Received.HealthCheckPath = healthCheckPath
+	c.PushRollingCall.Received.StartupTimeout = startupTimeout
+	c.PushRollingCall.Received.NoRoute = noRoute
+	c.PushRollingCall.Received.Out = out
+
+	out.Write(c.PushRollingCall.Returns.Output)
+
+	return c.PushRollingCall.Returns.Output, c.PushRollingCall.Returns.Error
+}
+
+// Healthy mock method.
+func (c *Courier) Healthy(ctx context.Context, appName string) (bool, error) {
+	c.HealthyCall.Received.Context = ctx
+	c.HealthyCall.Received.AppName = appName
+	c.HealthyCall.TimesCalled++
+
+	return c.HealthyCall.Returns.Healthy, c.HealthyCall.Returns.Error
+}
+
+// CanPush mock method.
+func (c *Courier) CanPush(ctx context.Context, appName, appLocation string) ([]byte, error) {
+	c.CanPushCall.Received.Context = ctx
+	c.CanPushCall.Received.AppName = appName
+	c.CanPushCall.Received.AppPath = appLocation
+
+	return c.CanPushCall.Returns.Output, c.CanPushCall.Returns.Error
+}
+
 // Rename mock method.
-func (c *Courier) Rename(appName, newAppName string) ([]byte, error) {
+func (c *Courier) Rename(ctx context.Context, appName, newAppName string) ([]byte, error) {
+	c.RenameCall.Received.Context = ctx
 	c.RenameCall.Received.AppName = appName
 	c.RenameCall.Received.AppNameVenerable = newAppName
 
@@ -146,29 +411,91 @@ func (c *Courier) Rename(appName, newAppName string) ([]byte, error) {
 }
 
 // MapRoute mock method.
-func (c *Courier) MapRoute(appName, domain string) ([]byte, error) {
+func (c *Courier) MapRoute(ctx context.Context, appName, hostname, domain string) ([]byte, error) {
+	c.MapRouteCall.Received.Context = ctx
 	c.MapRouteCall.Received.AppName = appName
+	c.MapRouteCall.Received.Hostname = hostname
 	c.MapRouteCall.Received.Domain = domain
+	c.MapRouteCall.Received.Domains = append(c.MapRouteCall.Received.Domains, domain)
+
+	if err, ok := c.MapRouteCall.Returns.DomainErrors[domain]; ok {
+		return c.MapRouteCall.Returns.Output, err
+	}
 
 	return c.MapRouteCall.Returns.Output, c.MapRouteCall.Returns.Error
 }
 
+// UnmapRoute mock method.
+func (c *Courier) UnmapRoute(ctx context.Context, appName, hostname, domain string) ([]byte, error) {
+	c.UnmapRouteCall.Received.Context = ctx
+	c.UnmapRouteCall.Received.AppName = appName
+	c.UnmapRouteCall.Received.Hostname = hostname
+	c.UnmapRouteCall.Received.Domains = append(c.UnmapRouteCall.Received.Domains, domain)
+
+	return c.UnmapRouteCall.Returns.Output, c.UnmapRouteCall.Returns.Error
+}
+
+// DeleteRoute mock method.
+func (c *Courier) DeleteRoute(ctx context.Context, hostname, domain string) ([]byte, error) {
+	c.DeleteRouteCall.Received.Context = ctx
+	c.DeleteRouteCall.Received.Hostname = hostname
+	c.DeleteRouteCall.Received.Domain = domain
+
+	return c.DeleteRouteCall.Returns.Output, c.DeleteRouteCall.Returns.Error
+}
+
 // Logs mock method.
-func (c *Courier) Logs(appName string) ([]byte, error) {
+func (c *Courier) Logs(ctx context.Context, appName string) ([]byte, error) {
+	c.LogsCall.Received.Context = ctx
 	c.LogsCall.Received.AppName = appName
 
 	return c.LogsCall.Returns.Output, c.LogsCall.Returns.Error
 }
 
 // Exists mock method.
-func (c *Courier) Exists(appName string) bool {
+func (c *Courier) Exists(ctx context.Context, appName string) bool {
+	c.ExistsCall.Received.Context = ctx
 	c.ExistsCall.Received.AppName = appName
 
 	return c.ExistsCall.Returns.Bool
 }
 
+// List mock method.
+func (c *Courier) List(ctx context.Context, prefix string) ([]string, error) {
+	c.ListCall.Received.Context = ctx
+	c.ListCall.Received.Prefix = prefix
+
+	return c.ListCall.Returns.AppNames, c.ListCall.Returns.Error
+}
+
+// RoutedApps mock method.
+func (c *Courier) RoutedApps(ctx context.Context, hostname, domain string) ([]string, error) {
+	c.RoutedAppsCall.Received.Context = ctx
+	c.RoutedAppsCall.Received.Hostname = hostname
+	c.RoutedAppsCall.Received.Domain = domain
+
+	return c.RoutedAppsCall.Returns.AppNames, c.RoutedAppsCall.Returns.Error
+}
+
+// Stop mock method.
+func (c *Courier) Stop(ctx context.Context, appName string) ([]byte, error) {
+	c.StopCall.Received.Context = ctx
+	c.StopCall.Received.AppName = appName
+
+	return c.StopCall.Returns.Output, c.StopCall.Returns.Error
+}
+
+// AppGUID mock method.
+func (c *Courier) AppGUID(ctx context.Context, appName string) ([]byte, error) {
+	c.AppGUIDCall.Received.Context = ctx
+	c.AppGUIDCall.Received.AppName = appName
+
+	return c.AppGUIDCall.Returns.Output, c.AppGUIDCall.Returns.Error
+}
+
 // Cups mock method
-func (c *Courier) Cups(appName string, body string) ([]byte, error) {
+func (c *Courier) Cups(ctx context.Context, appName string, body string) ([]byte, error) {
+	c.CupsCall.Received.Context = ctx
 	c.CupsCall.Received.AppName = appName
 	c.CupsCall.Received.Body = body
 
@@ -176,13 +503,21 @@ func (c *Courier) Cups(appName string, body string) ([]byte, error) {
 }
 
 // Uups mock method
-func (c *Courier) Uups(appName string, body string) ([]byte, error) {
+func (c *Courier) Uups(ctx context.Context, appName string, body string) ([]byte, error) {
+	c.UupsCall.Received.Context = ctx
 	c.UupsCall.Received.AppName = appName
 	c.UupsCall.Received.Body = body
 
 	return c.UupsCall.Returns.Output, c.UupsCall.Returns.Error
 }
 
+// Version mock method.
+func (c *Courier) Version() ([]byte, error) {
+	c.VersionCall.TimesCalled++
+
+	return c.VersionCall.Returns.Output, c.VersionCall.Returns.Error
+}
+
 // CleanUp mock method.
 func (c *Courier) CleanUp() error {
 	return c.CleanUpCall.Returns.Error
@@ -0,0 +1,20 @@
+package mocks
+
+// Debouncer handmade mock for tests.
+type Debouncer struct {
+	WaitCall struct {
+		Received struct {
+			Key string
+		}
+		Returns struct {
+			Error error
+		}
+	}
+}
+
+// Wait mock method.
+func (d *Debouncer) Wait(key string) error {
+	d.WaitCall.Received.Key = key
+
+	return d.WaitCall.Returns.Error
+}
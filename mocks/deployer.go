@@ -3,14 +3,20 @@ package mocks
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+
+	S "github.com/compozed/deployadactyl/structs"
+	"golang.org/x/net/context"
 )
 
 // Deployer handmade mock for tests.
 type Deployer struct {
 	DeployCall struct {
 		Received struct {
+			Context     context.Context
 			Request     *http.Request
+			Body        []byte
 			Environment string
 			Org         string
 			Space       string
@@ -22,6 +28,7 @@ type Deployer struct {
 			Output string
 		}
 		Returns struct {
+			Target     S.DeployTarget
 			Error      error
 			StatusCode int
 		}
@@ -29,8 +36,12 @@ type Deployer struct {
 }
 
 // Deploy mock method.
-func (d *Deployer) Deploy(req *http.Request, environment, org, space, appName, contentType string, out io.Writer) (int, error) {
+func (d *Deployer) Deploy(ctx context.Context, req *http.Request, environment, org, space, appName, contentType string, out io.Writer) (S.DeployTarget, int, error) {
+	d.DeployCall.Received.Context = ctx
 	d.DeployCall.Received.Request = req
+	if req.Body != nil {
+		d.DeployCall.Received.Body, _ = ioutil.ReadAll(req.Body)
+	}
 	d.DeployCall.Received.Environment = environment
 	d.DeployCall.Received.Org = org
 	d.DeployCall.Received.Space = space
@@ -40,5 +51,5 @@ func (d *Deployer) Deploy(req *http.Request, environment, org, space, appName, c
 
 	fmt.Fprint(out, d.DeployCall.Write.Output)
 
-	return d.DeployCall.Returns.StatusCode, d.DeployCall.Returns.Error
+	return d.DeployCall.Returns.Target, d.DeployCall.Returns.StatusCode, d.DeployCall.Returns.Error
 }
@@ -13,9 +13,26 @@ type EventManager struct {
 			EventType string
 		}
 		Returns struct {
+			ID    int
 			Error error
 		}
 	}
+	AddHandlerWithPriorityCall struct {
+		Received struct {
+			Handler   I.Handler
+			EventType string
+			Priority  int
+		}
+		Returns struct {
+			ID    int
+			Error error
+		}
+	}
+	RemoveHandlerCall struct {
+		Received struct {
+			ID int
+		}
+	}
 	EmitCall struct {
 		TimesCalled int
 		Received    struct {
@@ -28,11 +45,25 @@ type EventManager struct {
 }
 
 // AddHandler mock method.
-func (e *EventManager) AddHandler(handler I.Handler, eventType string) error {
+func (e *EventManager) AddHandler(handler I.Handler, eventType string) (int, error) {
 	e.AddHandlerCall.Received.Handler = handler
 	e.AddHandlerCall.Received.EventType = eventType
 
-	return e.AddHandlerCall.Returns.Error
+	return e.AddHandlerCall.Returns.ID, e.AddHandlerCall.Returns.Error
+}
+
+// AddHandlerWithPriority mock method.
+func (e *EventManager) AddHandlerWithPriority(handler I.Handler, eventType string, priority int) (int, error) {
+	e.AddHandlerWithPriorityCall.Received.Handler = handler
+	e.AddHandlerWithPriorityCall.Received.EventType = eventType
+	e.AddHandlerWithPriorityCall.Received.Priority = priority
+
+	return e.AddHandlerWithPriorityCall.Returns.ID, e.AddHandlerWithPriorityCall.Returns.Error
+}
+
+// RemoveHandler mock method.
+func (e *EventManager) RemoveHandler(id int) {
+	e.RemoveHandlerCall.Received.ID = id
 }
 
 // Emit mock method.
@@ -41,5 +72,8 @@ func (e *EventManager) Emit(event S.Event) error {
 
 	e.EmitCall.Received.Events = append(e.EmitCall.Received.Events, event)
 
+	if e.EmitCall.TimesCalled >= len(e.EmitCall.Returns.Error) {
+		return nil
+	}
 	return e.EmitCall.Returns.Error[e.EmitCall.TimesCalled]
 }
@@ -1,10 +1,18 @@
 package mocks
 
+import (
+	"io"
+
+	"golang.org/x/net/context"
+)
+
 // Executor handmade mock for tests.
 type Executor struct {
 	ExecuteCall struct {
 		Received struct {
-			Args []string
+			Context context.Context
+			Args    []string
+			AllArgs [][]string
 		}
 		Returns struct {
 			Output []byte
@@ -14,6 +22,7 @@ type Executor struct {
 
 	ExecuteInDirectoryCall struct {
 		Received struct {
+			Context     context.Context
 			AppLocation string
 			Args        []string
 		}
@@ -23,6 +32,28 @@ type Executor struct {
 		}
 	}
 
+	StreamInDirectoryCall struct {
+		Received struct {
+			Context     context.Context
+			AppLocation string
+			Out         io.Writer
+			Args        []string
+		}
+		Returns struct {
+			Output []byte
+			Error  error
+		}
+	}
+
+	SetAccessTokenCall struct {
+		Received struct {
+			Token string
+		}
+		Returns struct {
+			Error error
+		}
+	}
+
 	CleanUpCall struct {
 		Returns struct {
 			Error error
@@ -31,20 +62,42 @@ type Executor struct {
 }
 
 // Execute mock method.
-func (e *Executor) Execute(args ...string) ([]byte, error) {
+func (e *Executor) Execute(ctx context.Context, args ...string) ([]byte, error) {
+	e.ExecuteCall.Received.Context = ctx
 	e.ExecuteCall.Received.Args = args
+	e.ExecuteCall.Received.AllArgs = append(e.ExecuteCall.Received.AllArgs, args)
 
 	return e.ExecuteCall.Returns.Output, e.ExecuteCall.Returns.Error
 }
 
 // ExecuteInDirectory mock method.
-func (e *Executor) ExecuteInDirectory(appLocation string, args ...string) ([]byte, error) {
+func (e *Executor) ExecuteInDirectory(ctx context.Context, appLocation string, args ...string) ([]byte, error) {
+	e.ExecuteInDirectoryCall.Received.Context = ctx
 	e.ExecuteInDirectoryCall.Received.AppLocation = appLocation
 	e.ExecuteInDirectoryCall.Received.Args = args
 
 	return e.ExecuteInDirectoryCall.Returns.Output, e.ExecuteInDirectoryCall.Returns.Error
 }
 
+// StreamInDirectory mock method.
+func (e *Executor) StreamInDirectory(ctx context.Context, appLocation string, out io.Writer, args ...string) ([]byte, error) {
+	e.StreamInDirectoryCall.Received.Context = ctx
+	e.StreamInDirectoryCall.Received.AppLocation = appLocation
+	e.StreamInDirectoryCall.Received.Out = out
+	e.StreamInDirectoryCall.Received.Args = args
+
+	out.Write(e.StreamInDirectoryCall.Returns.Output)
+
+	return e.StreamInDirectoryCall.Returns.Output, e.StreamInDirectoryCall.Returns.Error
+}
+
+// SetAccessToken mock method.
+func (e *Executor) SetAccessToken(token string) error {
+	e.SetAccessTokenCall.Received.Token = token
+
+	return e.SetAccessTokenCall.Returns.Error
+}
+
 // CleanUp mock method.
 func (e *Executor) CleanUp() error {
 	return e.CleanUpCall.Returns.Error
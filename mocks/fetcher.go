@@ -1,13 +1,18 @@
 package mocks
 
-import "net/http"
+import (
+	"io"
+
+	S "github.com/compozed/deployadactyl/structs"
+)
 
 // Fetcher handmade mock for tests.
 type Fetcher struct {
 	FetchCall struct {
 		Received struct {
-			ArtifactURL string
-			Manifest    string
+			DeploymentInfo S.DeploymentInfo
+			Manifest       string
+			Out            io.Writer
 		}
 		Returns struct {
 			AppPath string
@@ -15,9 +20,19 @@ type Fetcher struct {
 		}
 	}
 
+	FetchManifestCall struct {
+		Received struct {
+			ManifestURL string
+		}
+		Returns struct {
+			Manifest string
+			Error    error
+		}
+	}
+
 	FetchFromZipCall struct {
 		Received struct {
-			Request *http.Request
+			Body io.Reader
 		}
 		Returns struct {
 			AppPath string
@@ -27,16 +42,24 @@ type Fetcher struct {
 }
 
 // Fetch mock method.
-func (f *Fetcher) Fetch(url, manifest string) (string, error) {
-	f.FetchCall.Received.ArtifactURL = url
+func (f *Fetcher) Fetch(deploymentInfo S.DeploymentInfo, manifest string, out io.Writer) (string, error) {
+	f.FetchCall.Received.DeploymentInfo = deploymentInfo
 	f.FetchCall.Received.Manifest = manifest
+	f.FetchCall.Received.Out = out
 
 	return f.FetchCall.Returns.AppPath, f.FetchCall.Returns.Error
 }
 
-// FetchZipFromRequest mock method.
-func (f *Fetcher) FetchZipFromRequest(req *http.Request) (string, error) {
-	f.FetchFromZipCall.Received.Request = req
+// FetchManifest mock method.
+func (f *Fetcher) FetchManifest(url string) (string, error) {
+	f.FetchManifestCall.Received.ManifestURL = url
+
+	return f.FetchManifestCall.Returns.Manifest, f.FetchManifestCall.Returns.Error
+}
+
+// FetchFromZip mock method.
+func (f *Fetcher) FetchFromZip(body io.Reader) (string, error) {
+	f.FetchFromZipCall.Received.Body = body
 
 	return f.FetchFromZipCall.Returns.AppPath, f.FetchFromZipCall.Returns.Error
 }
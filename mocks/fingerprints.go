@@ -0,0 +1,30 @@
+package mocks
+
+// Fingerprints handmade mock for tests.
+type Fingerprints struct {
+	AddCall struct {
+		Received struct {
+			Fingerprints []string
+		}
+	}
+	SeenCall struct {
+		Received struct {
+			Fingerprint string
+		}
+		Returns struct {
+			Seen bool
+		}
+	}
+}
+
+// Add mock method.
+func (f *Fingerprints) Add(fingerprint string) {
+	f.AddCall.Received.Fingerprints = append(f.AddCall.Received.Fingerprints, fingerprint)
+}
+
+// Seen mock method.
+func (f *Fingerprints) Seen(fingerprint string) bool {
+	f.SeenCall.Received.Fingerprint = fingerprint
+
+	return f.SeenCall.Returns.Seen
+}
@@ -0,0 +1,34 @@
+package mocks
+
+import S "github.com/compozed/deployadactyl/structs"
+
+// History handmade mock for tests.
+type History struct {
+	AddCall struct {
+		Received struct {
+			Results []S.DeployResult
+		}
+	}
+
+	QueryCall struct {
+		Received struct {
+			Query S.HistoryQuery
+		}
+		Returns struct {
+			Results []S.DeployResult
+			Total   int
+		}
+	}
+}
+
+// Add mock method.
+func (h *History) Add(result S.DeployResult) {
+	h.AddCall.Received.Results = append(h.AddCall.Received.Results, result)
+}
+
+// Query mock method.
+func (h *History) Query(query S.HistoryQuery) ([]S.DeployResult, int) {
+	h.QueryCall.Received.Query = query
+
+	return h.QueryCall.Returns.Results, h.QueryCall.Returns.Total
+}
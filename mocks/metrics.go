@@ -0,0 +1,40 @@
+package mocks
+
+import (
+	"io"
+	"time"
+)
+
+// Metrics handmade mock for tests.
+type Metrics struct {
+	RecordDeployCall struct {
+		Received struct {
+			Environment string
+			Result      string
+			Duration    time.Duration
+		}
+		TimesCalled int
+	}
+
+	WriteCall struct {
+		Returns struct {
+			Output string
+			Error  error
+		}
+	}
+}
+
+// RecordDeploy mock method.
+func (m *Metrics) RecordDeploy(environment, result string, duration time.Duration) {
+	m.RecordDeployCall.Received.Environment = environment
+	m.RecordDeployCall.Received.Result = result
+	m.RecordDeployCall.Received.Duration = duration
+	m.RecordDeployCall.TimesCalled++
+}
+
+// Write mock method.
+func (m *Metrics) Write(w io.Writer) error {
+	io.WriteString(w, m.WriteCall.Returns.Output)
+
+	return m.WriteCall.Returns.Error
+}
@@ -5,12 +5,14 @@ import (
 	"io"
 
 	S "github.com/compozed/deployadactyl/structs"
+	"golang.org/x/net/context"
 )
 
 // Pusher handmade mock for tests.
 type Pusher struct {
 	LoginCall struct {
 		Received struct {
+			Context        context.Context
 			FoundationURL  string
 			DeploymentInfo S.DeploymentInfo
 			Out            io.Writer
@@ -25,6 +27,7 @@ type Pusher struct {
 
 	PushCall struct {
 		Received struct {
+			Context        context.Context
 			AppPath        string
 			AppExists      bool
 			DeploymentInfo S.DeploymentInfo
@@ -38,8 +41,49 @@ type Pusher struct {
 		}
 	}
 
+	PushInPlaceCall struct {
+		Received struct {
+			Context        context.Context
+			AppPath        string
+			DeploymentInfo S.DeploymentInfo
+			Out            io.Writer
+		}
+		Write struct {
+			Output string
+		}
+		Returns struct {
+			Error error
+		}
+	}
+
+	CanPushCall struct {
+		Received struct {
+			Context        context.Context
+			ProbePath      string
+			DeploymentInfo S.DeploymentInfo
+			Out            io.Writer
+		}
+		Write struct {
+			Output string
+		}
+		Returns struct {
+			Error error
+		}
+	}
+
+	UnmapVenerableCall struct {
+		Received struct {
+			Context        context.Context
+			DeploymentInfo S.DeploymentInfo
+		}
+		Returns struct {
+			Error error
+		}
+	}
+
 	RollbackCall struct {
 		Received struct {
+			Context        context.Context
 			AppExists      bool
 			DeploymentInfo S.DeploymentInfo
 		}
@@ -50,6 +94,7 @@ type Pusher struct {
 
 	DeleteVenerableCall struct {
 		Received struct {
+			Context        context.Context
 			DeploymentInfo S.DeploymentInfo
 		}
 		Returns struct {
@@ -65,13 +110,21 @@ type Pusher struct {
 
 	ExistsCall struct {
 		Received struct {
+			Context context.Context
 			AppName string
 		}
 	}
+
+	AppGUIDCall struct {
+		Returns struct {
+			AppGUID string
+		}
+	}
 }
 
 // Login mock method.
-func (p *Pusher) Login(foundationURL string, deploymentInfo S.DeploymentInfo, out io.Writer) error {
+func (p *Pusher) Login(ctx context.Context, foundationURL string, deploymentInfo S.DeploymentInfo, out io.Writer) error {
+	p.LoginCall.Received.Context = ctx
 	p.LoginCall.Received.FoundationURL = foundationURL
 	p.LoginCall.Received.DeploymentInfo = deploymentInfo
 	p.LoginCall.Received.Out = out
@@ -82,7 +135,8 @@ func (p *Pusher) Login(foundationURL string, deploymentInfo S.DeploymentInfo, ou
 }
 
 // Push mock method.
-func (p *Pusher) Push(appPath string, deploymentInfo S.DeploymentInfo, out io.Writer) error {
+func (p *Pusher) Push(ctx context.Context, appPath string, deploymentInfo S.DeploymentInfo, out io.Writer) error {
+	p.PushCall.Received.Context = ctx
 	p.PushCall.Received.AppPath = appPath
 	p.PushCall.Received.DeploymentInfo = deploymentInfo
 	p.PushCall.Received.Out = out
@@ -92,15 +146,49 @@ func (p *Pusher) Push(appPath string, deploymentInfo S.DeploymentInfo, out io.Wr
 	return p.PushCall.Returns.Error
 }
 
+// PushInPlace mock method.
+func (p *Pusher) PushInPlace(ctx context.Context, appPath string, deploymentInfo S.DeploymentInfo, out io.Writer) error {
+	p.PushInPlaceCall.Received.Context = ctx
+	p.PushInPlaceCall.Received.AppPath = appPath
+	p.PushInPlaceCall.Received.DeploymentInfo = deploymentInfo
+	p.PushInPlaceCall.Received.Out = out
+
+	fmt.Fprint(out, p.PushInPlaceCall.Write.Output)
+
+	return p.PushInPlaceCall.Returns.Error
+}
+
+// CanPush mock method.
+func (p *Pusher) CanPush(ctx context.Context, probePath string, deploymentInfo S.DeploymentInfo, out io.Writer) error {
+	p.CanPushCall.Received.Context = ctx
+	p.CanPushCall.Received.ProbePath = probePath
+	p.CanPushCall.Received.DeploymentInfo = deploymentInfo
+	p.CanPushCall.Received.Out = out
+
+	fmt.Fprint(out, p.CanPushCall.Write.Output)
+
+	return p.CanPushCall.Returns.Error
+}
+
+// UnmapVenerable mock method.
+func (p *Pusher) UnmapVenerable(ctx context.Context, deploymentInfo S.DeploymentInfo) error {
+	p.UnmapVenerableCall.Received.Context = ctx
+	p.UnmapVenerableCall.Received.DeploymentInfo = deploymentInfo
+
+	return p.UnmapVenerableCall.Returns.Error
+}
+
 // Rollback mock method.
-func (p *Pusher) Rollback(deploymentInfo S.DeploymentInfo) error {
+func (p *Pusher) Rollback(ctx context.Context, deploymentInfo S.DeploymentInfo) error {
+	p.RollbackCall.Received.Context = ctx
 	p.RollbackCall.Received.DeploymentInfo = deploymentInfo
 
 	return p.RollbackCall.Returns.Error
 }
 
 // DeleteVenerable mock method.
-func (p *Pusher) DeleteVenerable(deploymentInfo S.DeploymentInfo) error {
+func (p *Pusher) DeleteVenerable(ctx context.Context, deploymentInfo S.DeploymentInfo) error {
+	p.DeleteVenerableCall.Received.Context = ctx
 	p.DeleteVenerableCall.Received.DeploymentInfo = deploymentInfo
 
 	return p.DeleteVenerableCall.Returns.Error
@@ -112,6 +200,12 @@ func (p *Pusher) CleanUp() error {
 }
 
 // Exists mock method.
-func (p *Pusher) Exists(appName string) {
+func (p *Pusher) Exists(ctx context.Context, appName string) {
+	p.ExistsCall.Received.Context = ctx
 	p.ExistsCall.Received.AppName = appName
 }
+
+// AppGUID mock method.
+func (p *Pusher) AppGUID() string {
+	return p.AppGUIDCall.Returns.AppGUID
+}
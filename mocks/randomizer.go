@@ -10,6 +10,11 @@ type Randomizer struct {
 			Runes string
 		}
 	}
+	UUIDCall struct {
+		Returns struct {
+			UUID string
+		}
+	}
 }
 
 // StringRunes mock method.
@@ -18,3 +23,8 @@ func (r *Randomizer) StringRunes(length int) string {
 
 	return r.RandomizeCall.Returns.Runes
 }
+
+// UUID mock method.
+func (r *Randomizer) UUID() string {
+	return r.UUIDCall.Returns.UUID
+}
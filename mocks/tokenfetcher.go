@@ -0,0 +1,25 @@
+package mocks
+
+// TokenFetcher handmade mock for tests.
+type TokenFetcher struct {
+	TokenCall struct {
+		Received struct {
+			TokenURL     string
+			ClientID     string
+			ClientSecret string
+		}
+		Returns struct {
+			Token string
+			Error error
+		}
+	}
+}
+
+// Token mock method.
+func (t *TokenFetcher) Token(tokenURL, clientID, clientSecret string) (string, error) {
+	t.TokenCall.Received.TokenURL = tokenURL
+	t.TokenCall.Received.ClientID = clientID
+	t.TokenCall.Received.ClientSecret = clientSecret
+
+	return t.TokenCall.Returns.Token, t.TokenCall.Returns.Error
+}
@@ -2,6 +2,8 @@
 package randomizer
 
 import (
+	cryptorand "crypto/rand"
+	"fmt"
 	"math/rand"
 	"time"
 )
@@ -25,6 +27,19 @@ func (r Randomizer) StringRunes(length int) string {
 	return generateRunes(length)
 }
 
+// UUID generates a random version 4 UUID as described in RFC 4122.
+func (r Randomizer) UUID() string {
+	b := make([]byte, 16)
+	if _, err := cryptorand.Read(b); err != nil {
+		panic(err)
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
 func generateRunes(length int) string {
 	b := make([]rune, length)
 	for i := range b {
@@ -0,0 +1,13 @@
+package randomizer_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestRandomizer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Randomizer Suite")
+}
@@ -0,0 +1,28 @@
+package randomizer_test
+
+import (
+	. "github.com/compozed/deployadactyl/randomizer"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Randomizer", func() {
+	var randomizer Randomizer
+
+	Describe("StringRunes", func() {
+		It("returns a string of the given length", func() {
+			Expect(randomizer.StringRunes(128)).To(HaveLen(128))
+		})
+	})
+
+	Describe("UUID", func() {
+		It("returns a version 4 UUID", func() {
+			Expect(randomizer.UUID()).To(MatchRegexp("^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$"))
+		})
+
+		It("returns a different UUID every time", func() {
+			Expect(randomizer.UUID()).ToNot(Equal(randomizer.UUID()))
+		})
+	})
+})
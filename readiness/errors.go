@@ -0,0 +1,26 @@
+package readiness
+
+import "fmt"
+
+type NoEnvironmentsError struct{}
+
+func (e NoEnvironmentsError) Error() string {
+	return "not ready: no environments are configured"
+}
+
+type CLIUnavailableError struct {
+	Err error
+}
+
+func (e CLIUnavailableError) Error() string {
+	return fmt.Sprintf("not ready: cannot run the cf CLI: %s", e.Err)
+}
+
+type FoundationsUnavailableError struct {
+	Environment string
+	Err         error
+}
+
+func (e FoundationsUnavailableError) Error() string {
+	return fmt.Sprintf("not ready: the foundations of %s are not reachable: %s", e.Environment, e.Err)
+}
@@ -0,0 +1,94 @@
+// Package readiness checks whether deploys can be served.
+package readiness
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/compozed/deployadactyl/config"
+	I "github.com/compozed/deployadactyl/interfaces"
+)
+
+// DefaultTTL is how long the result of the checks is cached.
+const DefaultTTL = 10 * time.Second
+
+// Readiness checks that environments are configured, the cf CLI can be run with the Courier
+// and the foundations of every environment are up according to the Prechecker.
+// The result of the checks is cached for the TTL so that frequent probes do not hammer the foundations.
+type Readiness struct {
+	Courier      I.Courier
+	Prechecker   I.Prechecker
+	Environments map[string]config.Environment
+	TTL          time.Duration
+	Now          func() time.Time
+	mutex        sync.Mutex
+	checked      bool
+	checkedAt    time.Time
+	err          error
+}
+
+// New returns a Readiness that caches the result of its checks for the DefaultTTL.
+func New(courier I.Courier, prechecker I.Prechecker, environments map[string]config.Environment) *Readiness {
+	return &Readiness{
+		Courier:      courier,
+		Prechecker:   prechecker,
+		Environments: environments,
+		TTL:          DefaultTTL,
+		Now:          time.Now,
+	}
+}
+
+// Check runs the checks unless they were run within the TTL.
+//
+// Returns the error of the first check that failed.
+func (r *Readiness) Check() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := r.Now()
+	if r.checked && now.Sub(r.checkedAt) < r.TTL {
+		return r.err
+	}
+
+	r.err = r.check()
+	r.checked = true
+	r.checkedAt = now
+
+	return r.err
+}
+
+// Reload swaps the Environments for the ones of a new config, so that the next Check runs the checks against them.
+func (r *Readiness) Reload(environments map[string]config.Environment) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.Environments = environments
+	r.checked = false
+}
+
+func (r *Readiness) check() error {
+	if len(r.Environments) == 0 {
+		return NoEnvironmentsError{}
+	}
+
+	_, err := r.Courier.Version()
+	if err != nil {
+		return CLIUnavailableError{err}
+	}
+
+	names := make([]string, 0, len(r.Environments))
+	for name := range r.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		err = r.Prechecker.AssertAllFoundationsUp(r.Environments[name])
+		if err != nil {
+			return FoundationsUnavailableError{name, err}
+		}
+	}
+
+	return nil
+}
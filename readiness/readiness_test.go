@@ -0,0 +1,107 @@
+package readiness_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/compozed/deployadactyl/config"
+	"github.com/compozed/deployadactyl/mocks"
+	"github.com/compozed/deployadactyl/randomizer"
+	. "github.com/compozed/deployadactyl/readiness"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Readiness", func() {
+	var (
+		courier         *mocks.Courier
+		prechecker      *mocks.Prechecker
+		environmentName string
+		environment     config.Environment
+		readiness       *Readiness
+		now             time.Time
+	)
+
+	BeforeEach(func() {
+		courier = &mocks.Courier{}
+		prechecker = &mocks.Prechecker{}
+		environmentName = "environment-" + randomizer.StringRunes(10)
+		environment = config.Environment{Name: environmentName, Foundations: []string{"https://api.example.com"}}
+		now = time.Now()
+
+		courier.VersionCall.Returns.Output = []byte("cf version 6.32.0")
+
+		readiness = New(courier, prechecker, map[string]config.Environment{environmentName: environment})
+		readiness.Now = func() time.Time { return now }
+	})
+
+	It("is ready when the cf CLI is present and the foundations are up", func() {
+		Expect(readiness.Check()).To(Succeed())
+
+		Expect(courier.VersionCall.TimesCalled).To(Equal(1))
+		Expect(prechecker.AssertAllFoundationsUpCall.Received.Environment).To(Equal(environment))
+	})
+
+	Context("when no environments are configured", func() {
+		It("is not ready", func() {
+			readiness.Environments = nil
+
+			Expect(readiness.Check()).To(MatchError(NoEnvironmentsError{}))
+		})
+	})
+
+	Context("when the cf CLI is absent", func() {
+		It("is not ready without checking the foundations", func() {
+			courier.VersionCall.Returns.Error = errors.New("executable file not found")
+
+			Expect(readiness.Check()).To(MatchError(CLIUnavailableError{errors.New("executable file not found")}))
+
+			Expect(prechecker.AssertAllFoundationsUpCall.Received.Environment.Name).To(BeEmpty())
+		})
+	})
+
+	Context("when the foundations of an environment are down", func() {
+		It("is not ready", func() {
+			prechecker.AssertAllFoundationsUpCall.Returns.Error = errors.New("foundation down")
+
+			Expect(readiness.Check()).To(MatchError(FoundationsUnavailableError{environmentName, errors.New("foundation down")}))
+		})
+	})
+
+	Context("when it was checked within the TTL", func() {
+		It("returns the cached result without checking again", func() {
+			courier.VersionCall.Returns.Error = errors.New("executable file not found")
+			Expect(readiness.Check()).ToNot(Succeed())
+
+			courier.VersionCall.Returns.Error = nil
+			now = now.Add(DefaultTTL - time.Second)
+
+			Expect(readiness.Check()).ToNot(Succeed())
+			Expect(courier.VersionCall.TimesCalled).To(Equal(1))
+		})
+	})
+
+	Context("when the TTL has passed", func() {
+		It("checks again", func() {
+			courier.VersionCall.Returns.Error = errors.New("executable file not found")
+			Expect(readiness.Check()).ToNot(Succeed())
+
+			courier.VersionCall.Returns.Error = nil
+			now = now.Add(DefaultTTL)
+
+			Expect(readiness.Check()).To(Succeed())
+			Expect(courier.VersionCall.TimesCalled).To(Equal(2))
+		})
+	})
+
+	Context("when it is reloaded", func() {
+		It("checks the new environments straight away", func() {
+			Expect(readiness.Check()).To(Succeed())
+
+			readiness.Reload(nil)
+
+			Expect(readiness.Check()).To(MatchError(NoEnvironmentsError{}))
+		})
+	})
+})
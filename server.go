@@ -5,7 +5,11 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
+	cfg "github.com/compozed/deployadactyl/config"
 	"github.com/compozed/deployadactyl/creator"
 	"github.com/compozed/deployadactyl/logger"
 	"github.com/op/go-logging"
@@ -17,7 +21,7 @@ const (
 )
 
 func main() {
-	config := flag.String("config", defaultConfig, "location of the config file")
+	config := flag.String("config", defaultConfig, "location of the config file, or a comma separated list of config files")
 	flag.Parse()
 
 	level := os.Getenv("DEPLOYADACTYL_LOGLEVEL")
@@ -30,10 +34,12 @@ func main() {
 		log.Fatal(err)
 	}
 
-	log := logger.DefaultLogger(os.Stdout, logLevel, "deployadactyl")
+	log := logger.DefaultLogger(os.Stdout, logLevel, "deployadactyl", os.Getenv("LOG_FORMAT"))
 	log.Infof("log level : %s", level)
 
-	c, err := creator.Custom(level, *config)
+	configPaths := strings.Split(*config, ",")
+
+	c, err := creator.Custom(level, configPaths...)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -43,7 +49,11 @@ func main() {
 	// em.AddHandler(myInstanceHandler, "deploy.start")
 
 	l := c.CreateListener()
-	deploy := c.CreateControllerHandler()
+	deploy, reload := c.CreateReloadableControllerHandler()
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	go reloadOnHangup(hangups, configPaths, reload, log)
 
 	log.Infof("Listening on Port %d", c.CreateConfig().Port)
 
@@ -52,3 +62,20 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// reloadOnHangup reads the config files again every time a SIGHUP is received and reloads the controller with them.
+// The old config is kept when the config files cannot be read or the controller cannot be reloaded with them.
+func reloadOnHangup(hangups <-chan os.Signal, configPaths []string, reload func(cfg.Config) error, log *logging.Logger) {
+	for range hangups {
+		newConfig, err := cfg.CustomMulti(os.Getenv, configPaths...)
+		if err == nil {
+			err = reload(newConfig)
+		}
+		if err != nil {
+			log.Errorf("cannot reload the config, keeping the old config: %s", err)
+			continue
+		}
+
+		log.Infof("reloaded the config from %s", strings.Join(configPaths, ", "))
+	}
+}
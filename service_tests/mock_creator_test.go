@@ -42,7 +42,7 @@ func New(level string, configFilename string) (Creator, error) {
 		return Creator{}, err
 	}
 
-	logger := logger.DefaultLogger(GinkgoWriter, l, "creator")
+	logger := logger.DefaultLogger(GinkgoWriter, l, "creator", logger.TextFormat)
 
 	eventManager := eventmanager.NewEventManager(logger)
 
@@ -70,8 +70,9 @@ func (c Creator) CreateControllerHandler() *gin.Engine {
 
 func (c Creator) CreateController() controller.Controller {
 	return controller.Controller{
-		Deployer: c.CreateDeployer(),
-		Log:      c.CreateLogger(),
+		Deployer:   c.CreateDeployer(),
+		Randomizer: c.CreateRandomizer(),
+		Log:        c.CreateLogger(),
 	}
 }
 
@@ -89,7 +90,8 @@ func (c Creator) CreateDeployer() I.Deployer {
 				Log:        c.CreateLogger(),
 				FileSystem: c.CreateFileSystem(),
 			},
-			Log: c.CreateLogger(),
+			Log:           c.CreateLogger(),
+			MinTLSVersion: c.CreateConfig().MinTLSVersion,
 		},
 		Prechecker:   c.CreatePrechecker(),
 		EventManager: c.CreateEventManager(),
@@ -124,6 +126,8 @@ func (c Creator) CreatePusher() (I.Pusher, error) {
 	courier.MapRouteCall.Returns.Output = []byte("mapped route\t")
 	courier.MapRouteCall.Returns.Error = nil
 	courier.ExistsCall.Returns.Bool = false
+	courier.AppGUIDCall.Returns.Output = []byte("app-guid\n")
+	courier.AppGUIDCall.Returns.Error = nil
 	courier.CleanUpCall.Returns.Error = nil
 
 	p := &pusher.Pusher{
@@ -161,6 +165,7 @@ func (c Creator) CreateWriter() io.Writer {
 func (c Creator) CreateBlueGreener() I.BlueGreener {
 	return bluegreen.BlueGreen{
 		PusherCreator: c,
+		EventManager:  c.CreateEventManager(),
 		Log:           c.CreateLogger(),
 	}
 }
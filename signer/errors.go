@@ -0,0 +1,13 @@
+package signer
+
+type UnsignedResultError struct{}
+
+func (e UnsignedResultError) Error() string {
+	return "deploy result is not signed"
+}
+
+type InvalidSignatureError struct{}
+
+func (e InvalidSignatureError) Error() string {
+	return "deploy result signature does not match: the record was altered after it was signed"
+}
@@ -0,0 +1,67 @@
+// Package signer signs DeployResults so that an auditor can tell whether a record was altered after it was stored.
+package signer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	S "github.com/compozed/deployadactyl/structs"
+)
+
+// Signer signs DeployResults with an HMAC-SHA256 of their JSON using a secret key.
+type Signer struct {
+	key []byte
+}
+
+// New returns a Signer that signs with the key.
+// An empty key does not sign DeployResults.
+func New(key string) *Signer {
+	return &Signer{key: []byte(key)}
+}
+
+// Sign returns the DeployResult with its Signature set.
+// The DeployResult is returned unchanged when the Signer has no key.
+func (s *Signer) Sign(result S.DeployResult) S.DeployResult {
+	if len(s.key) == 0 {
+		return result
+	}
+
+	result.Signature = ""
+	result.Signature = hex.EncodeToString(s.mac(result))
+
+	return result
+}
+
+// Verify checks that the Signature of the DeployResult matches the rest of its fields.
+//
+// Returns an UnsignedResultError if the DeployResult has no Signature and an InvalidSignatureError
+// if it was altered after it was signed.
+func (s *Signer) Verify(result S.DeployResult) error {
+	if result.Signature == "" {
+		return UnsignedResultError{}
+	}
+
+	signature, err := hex.DecodeString(result.Signature)
+	if err != nil {
+		return InvalidSignatureError{}
+	}
+
+	result.Signature = ""
+	if !hmac.Equal(signature, s.mac(result)) {
+		return InvalidSignatureError{}
+	}
+
+	return nil
+}
+
+func (s *Signer) mac(result S.DeployResult) []byte {
+	// Marshaling a struct cannot fail and always writes the fields in the same order.
+	payload, _ := json.Marshal(result)
+
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(payload)
+
+	return mac.Sum(nil)
+}
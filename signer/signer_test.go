@@ -0,0 +1,87 @@
+package signer_test
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/compozed/deployadactyl/randomizer"
+	. "github.com/compozed/deployadactyl/signer"
+	S "github.com/compozed/deployadactyl/structs"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Signer", func() {
+	var (
+		signer *Signer
+		result S.DeployResult
+	)
+
+	BeforeEach(func() {
+		signer = New("key-" + randomizer.StringRunes(10))
+
+		result = S.DeployResult{
+			Environment: "environment-" + randomizer.StringRunes(10),
+			Org:         "org-" + randomizer.StringRunes(10),
+			Space:       "space-" + randomizer.StringRunes(10),
+			AppName:     "appName-" + randomizer.StringRunes(10),
+			Status:      "success",
+			StatusCode:  200,
+			Time:        time.Now().UTC(),
+			Duration:    time.Minute,
+		}
+	})
+
+	Context("when a result is signed", func() {
+		It("verifies", func() {
+			signed := signer.Sign(result)
+
+			Expect(signed.Signature).ToNot(BeEmpty())
+			Expect(signer.Verify(signed)).To(Succeed())
+		})
+
+		It("still verifies after a round trip through JSON", func() {
+			body, err := json.Marshal(signer.Sign(result))
+			Expect(err).ToNot(HaveOccurred())
+
+			var stored S.DeployResult
+			Expect(json.Unmarshal(body, &stored)).To(Succeed())
+
+			Expect(signer.Verify(stored)).To(Succeed())
+		})
+	})
+
+	Context("when a signed result is tampered with", func() {
+		It("fails verification", func() {
+			signed := signer.Sign(result)
+			signed.Status = "failure"
+
+			Expect(signer.Verify(signed)).To(MatchError(InvalidSignatureError{}))
+		})
+
+		It("fails verification when the signature is not hex", func() {
+			signed := signer.Sign(result)
+			signed.Signature = "not a signature"
+
+			Expect(signer.Verify(signed)).To(MatchError(InvalidSignatureError{}))
+		})
+	})
+
+	Context("when the result was signed with a different key", func() {
+		It("fails verification", func() {
+			signed := New("other-key").Sign(result)
+
+			Expect(signer.Verify(signed)).To(MatchError(InvalidSignatureError{}))
+		})
+	})
+
+	Context("when no key is configured", func() {
+		It("does not sign the result", func() {
+			signed := New("").Sign(result)
+
+			Expect(signed).To(Equal(result))
+			Expect(signer.Verify(signed)).To(MatchError(UnsignedResultError{}))
+		})
+	})
+})
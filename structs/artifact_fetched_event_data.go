@@ -0,0 +1,15 @@
+package structs
+
+import "time"
+
+// ArtifactFetchedEventData describes an artifact that was fetched for a deploy.
+// Bytes is the size of the artifact and Duration is how long it took to fetch, including retries.
+// Cached is true when the artifact was taken from the artifact cache instead of being downloaded.
+// DeploymentInfo is the deployment the artifact was fetched for, with its RequestID and Environment.
+type ArtifactFetchedEventData struct {
+	URL            string
+	Bytes          int64
+	Duration       time.Duration
+	Cached         bool
+	DeploymentInfo *DeploymentInfo
+}
@@ -3,8 +3,44 @@ package structs
 import "io"
 
 // DeployEventData has a RequestBody and DeploymentInfo.
+// AppGUIDs maps each foundation URL to the guid of the pushed application and is only set on a successful deploy.
+// When the manifest declares more than one application it is keyed by the AppGUIDKey of each foundation URL and application.
+// Progress is only set on deploy.progress events.
+// StatusCode and Error are the outcome of the deploy and are only set on deploy.finish events. Error is empty when the deploy succeeded.
+// Stage is the stage of the deploy that failed and is only set on deploy.error and deploy.failure events, along with the Error.
 type DeployEventData struct {
 	Writer         io.Writer
 	DeploymentInfo *DeploymentInfo
 	RequestBody    io.Reader
+	AppGUIDs       map[string]string
+	Progress       *DeployProgress
+	StatusCode     int
+	Error          string
+	Stage          string
+}
+
+// AppGUIDKey is the key of the guid of appName on foundationURL in the AppGUIDs of a manifest with more than one application.
+func AppGUIDKey(foundationURL, appName string) string {
+	return foundationURL + "/" + appName
+}
+
+// The stages of a deploy that can be the Stage of a deploy.error or deploy.failure event.
+const (
+	PrecheckStage    = "precheck"
+	ManifestStage    = "manifest"
+	EnvironmentStage = "environment"
+	PreflightStage   = "preflight"
+	FetchStage       = "fetch"
+	PushStage        = "push"
+)
+
+// DeployProgress describes a foundation that has finished pushing.
+// FoundationIndex is the position of the foundation in the environment and Percent is the share of foundations
+// that have finished so far.
+type DeployProgress struct {
+	FoundationURL   string
+	FoundationIndex int
+	Completed       int
+	Total           int
+	Percent         int
 }
@@ -0,0 +1,31 @@
+package structs
+
+import "time"
+
+// DeployResult is the outcome of a completed deployment.
+type DeployResult struct {
+	Environment string        `json:"environment"`
+	Org         string        `json:"org"`
+	Space       string        `json:"space"`
+	AppName     string        `json:"app_name"`
+	User        string        `json:"user,omitempty"`
+	RequestID   string        `json:"request_id,omitempty"`
+	Status      string        `json:"status"`
+	StatusCode  int           `json:"status_code"`
+	Error       string        `json:"error,omitempty"`
+	Time        time.Time     `json:"time"`
+	Duration    time.Duration `json:"duration"`
+
+	// Signature is an HMAC of the other fields. It is empty when result signing is not configured.
+	Signature string `json:"signature,omitempty"`
+}
+
+// HistoryQuery filters and paginates the deploy history.
+// Empty filters match every DeployResult.
+type HistoryQuery struct {
+	Environment string
+	AppName     string
+	Status      string
+	Offset      int
+	Limit       int
+}
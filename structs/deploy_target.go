@@ -0,0 +1,8 @@
+package structs
+
+// DeployTarget is the org and space a deploy was resolved to.
+// They can come from the JSON request body or the templates of the environment instead of the request path.
+type DeployTarget struct {
+	Org   string
+	Space string
+}
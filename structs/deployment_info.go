@@ -1,21 +1,124 @@
 // Package structs contains structs that are reused in multiple locations.
 package structs
 
+import "time"
+
 // DeploymentInfo is a collection of properties necessary for a deployment.
 type DeploymentInfo struct {
 	ArtifactURL string `json:"artifact_url"`
 	Manifest    string `json:"manifest"`
+	ManifestURL string `json:"manifest_url"`
+
+	// ManifestRaw is a manifest that is not base64 encoded. It cannot be given with Manifest.
+	ManifestRaw string `json:"manifest_raw"`
+
+	// ArtifactToken is sent as a bearer token when downloading the artifact. It is never written to the deploy output.
+	ArtifactToken string `json:"artifact_token"`
+
+	// ArtifactSHA256 is the expected checksum of the downloaded artifact. It is not checked when empty.
+	ArtifactSHA256 string `json:"artifact_sha256"`
+
+	// DockerImage is pushed instead of an artifact when it is set. Only the manifest of the request is used with it.
+	DockerImage string `json:"docker_image"`
+
+	// Strategy is how the application is deployed, either bluegreen or rolling. It defaults to bluegreen.
+	Strategy string `json:"strategy"`
+
+	// DryRun checks the foundations and fetches the artifact without pushing it.
+	DryRun bool `json:"dry_run"`
+
+	// Force deploys the application even when an identical deploy completed within the redeploy window.
+	Force bool `json:"force"`
+
+	// ManifestPath is the path of the manifest in a zip deploy, relative to the root of the zip. It defaults to manifest.yml.
+	ManifestPath string `json:"manifest_path"`
+
+	// AppNamePrefix and AppNameSuffix are added to the name of the pushed application, such as myapp-build-1234.
+	// The route and the venerable application are still named after the application being deployed.
+	AppNamePrefix string `json:"app_name_prefix"`
+	AppNameSuffix string `json:"app_name_suffix"`
+
+	// NoRoute deploys an application without a route, such as a background worker. The route is not mapped on any domain.
+	// It is also set when the manifest declares the application with no-route.
+	NoRoute bool `json:"no_route"`
+
+	// Hostname replaces the application name as the host of the route mapped on the domain and custom domains of the environment.
+	Hostname string `json:"hostname"`
+
+	// SmokeTestURL and SmokeTestStatus replace the smoke test of the environment for this deploy when they are set.
+	SmokeTestURL    string `json:"smoke_test_url"`
+	SmokeTestStatus int    `json:"smoke_test_status"`
+
+	// Foundations limits the deploy to some of the foundations of the environment, such as during a canary. Every foundation is deployed to when it is not set.
+	Foundations []string `json:"foundations"`
+
+	// SkipSSLOverride replaces the skip_ssl of the environment for this deploy only when it is set.
+	SkipSSLOverride *bool `json:"skip_ssl"`
+
+	// OverrideInstances and OverrideMemory replace the instances and memory of every application in the manifest when set.
+	OverrideInstances *uint16 `json:"instances"`
+	OverrideMemory    string  `json:"memory"`
+
+	// EnvironmentVariables are merged into the env block of every application in the manifest.
+	// Only their names are written to the deploy output because the values may be secrets.
+	EnvironmentVariables map[string]string `json:"environment_variables"`
+
 	Username    string
 	Password    string
 	Environment string
-	Org         string
-	Space       string
+	Org         string `json:"org"`
+	Space       string `json:"space"`
 	AppName     string
 	UUID        string
 	SkipSSL     bool
 	Instances   uint16
 	Domain      string
 
+	// CustomDomains are the custom domains of the environment the route is also mapped on. They cannot be set in the request body.
+	CustomDomains []string `json:"-"`
+
+	// HealthCheckPath is the http endpoint Cloud Foundry checks the health of the pushed application on.
+	// HealthCheckTimeout, such as 2m, is how long the pushed application has to become healthy before its route is mapped.
+	// The health of the application is only waited for when one of them is set.
+	HealthCheckPath    string `json:"health_check_path"`
+	HealthCheckTimeout string `json:"health_check_timeout"`
+
+	// StartupTimeout is how many seconds Cloud Foundry gives the pushed application to start. The default of Cloud Foundry is used when it is zero.
+	StartupTimeout int `json:"startup_timeout"`
+
+	// Client credentials used instead of the username and password when the environment has a token URL.
+	TokenURL     string `json:"-"`
+	ClientID     string `json:"-"`
+	ClientSecret string `json:"-"`
+
+	// Timeout is the timeout of the environment, used for each Cloud Foundry command. It cannot be set in the request body.
+	Timeout time.Duration `json:"-"`
+
+	// LoginTimeout is the login timeout of the environment, used instead of the Timeout to log in. It cannot be set in the request body.
+	LoginTimeout time.Duration `json:"-"`
+
+	// Retention is the number of previous versions of the application the environment keeps. It cannot be set in the request body.
+	Retention int `json:"-"`
+
+	// RequestID is the id of the request the deployment was started by. It is prefixed to the log lines of the deployment.
+	RequestID string `json:"-"`
+
+	// InjectFailure names the deploy stage forced to fail during chaos testing. It cannot be set in the request body.
+	InjectFailure string `json:"-"`
+
 	// Generic map used for users to provide their own deployment properties in JSON format.
 	Data map[string]interface{} `json:"data"`
 }
+
+// PushedAppName is the name the application is pushed as, the AppName with the AppNamePrefix and AppNameSuffix added.
+func (d DeploymentInfo) PushedAppName() string {
+	return d.AppNamePrefix + d.AppName + d.AppNameSuffix
+}
+
+// RouteHostname is the host of the route mapped to the pushed application, the Hostname when it is set or else the AppName.
+func (d DeploymentInfo) RouteHostname() string {
+	if d.Hostname != "" {
+		return d.Hostname
+	}
+	return d.AppName
+}
@@ -0,0 +1,10 @@
+package structs
+
+// JobStatus is the state and accumulated output of an asynchronous deploy.
+// Result is only set once the deploy has finished.
+type JobStatus struct {
+	ID     string        `json:"id"`
+	State  string        `json:"state"`
+	Output string        `json:"output"`
+	Result *DeployResult `json:"result,omitempty"`
+}
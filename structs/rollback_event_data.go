@@ -0,0 +1,9 @@
+package structs
+
+// RollbackEventData has the DeploymentInfo of a deploy that was rolled back,
+// the foundations the application was successfully pushed to and the foundations where the push failed.
+type RollbackEventData struct {
+	DeploymentInfo    *DeploymentInfo
+	PushedFoundations []string
+	FailedFoundations []string
+}
@@ -0,0 +1,9 @@
+package structs
+
+// StreamEvent is a single event of a deploy streamed as NDJSON.
+// Every event has a Line of deploy output except the last one, which has the Result of the deploy.
+type StreamEvent struct {
+	Seq    int           `json:"seq"`
+	Line   string        `json:"line,omitempty"`
+	Result *DeployResult `json:"result,omitempty"`
+}
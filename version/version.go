@@ -0,0 +1,39 @@
+// Package version reports the build of Deployadactyl that is running.
+//
+// Version, Commit and BuildDate are set when the binary is built, for example:
+//
+//	go build -ldflags "-X github.com/compozed/deployadactyl/version.Version=v1.2.3 -X github.com/compozed/deployadactyl/version.Commit=$(git rev-parse HEAD)"
+package version
+
+// Unknown is reported for build metadata that was not set when the binary was built.
+const Unknown = "unknown"
+
+// Version, Commit and BuildDate are injected with -ldflags "-X". They are empty in a local build.
+var (
+	Version   string
+	Commit    string
+	BuildDate string
+)
+
+// Info is the build metadata of the running binary.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+}
+
+// Get returns the build metadata of the running binary, with Unknown in place of anything that was not set.
+func Get() Info {
+	return Info{
+		Version:   orUnknown(Version),
+		Commit:    orUnknown(Commit),
+		BuildDate: orUnknown(BuildDate),
+	}
+}
+
+func orUnknown(value string) string {
+	if value == "" {
+		return Unknown
+	}
+	return value
+}
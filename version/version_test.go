@@ -0,0 +1,36 @@
+package version_test
+
+import (
+	. "github.com/compozed/deployadactyl/version"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Version", func() {
+	AfterEach(func() {
+		Version, Commit, BuildDate = "", "", ""
+	})
+
+	It("returns the build metadata that was set", func() {
+		Version, Commit, BuildDate = "v1.2.3", "0123abc", "2017-03-01T12:00:00Z"
+
+		Expect(Get()).To(Equal(Info{
+			Version:   "v1.2.3",
+			Commit:    "0123abc",
+			BuildDate: "2017-03-01T12:00:00Z",
+		}))
+	})
+
+	Context("when the build metadata was not set", func() {
+		It("returns unknown instead of empty strings", func() {
+			Commit = "0123abc"
+
+			Expect(Get()).To(Equal(Info{
+				Version:   Unknown,
+				Commit:    "0123abc",
+				BuildDate: Unknown,
+			}))
+		})
+	})
+})